@@ -0,0 +1,75 @@
+// Command migrate-blobs moves blobs stored under the legacy storage/{file ID}
+// layout to content-hash storage, de-duplicating them against existing
+// content. It is safe to interrupt and run again.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/database"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be migrated without changing anything")
+	batchSize := flag.Int("batch-size", 500, "files loaded per query")
+	limit := flag.Int("limit", 0, "stop after this many legacy blobs (0 = no limit)")
+	after := flag.String("after", "", "resume after this file ID")
+	owner := flag.String("owner", "", "only migrate this user's files")
+	flag.Parse()
+
+	// Load environment variables - try multiple paths
+	envPaths := []string{".env", "../../.env", "../../../.env"}
+	for _, path := range envPaths {
+		if err := godotenv.Load(path); err == nil {
+			log.Printf("Loaded .env from: %s", path)
+			break
+		}
+	}
+
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	opts := services.LegacyBlobMigrationOptions{
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+		Limit:     *limit,
+	}
+	if *after != "" {
+		opts.After, err = uuid.Parse(*after)
+		if err != nil {
+			log.Fatalf("Invalid -after file ID: %v", err)
+		}
+	}
+	if *owner != "" {
+		opts.OwnerID, err = uuid.Parse(*owner)
+		if err != nil {
+			log.Fatalf("Invalid -owner user ID: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := services.NewLegacyBlobMigrator(db, cfg).Run(ctx, opts)
+	if report != nil {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		log.Printf("Legacy blob migration report:\n%s", output)
+	}
+	if err != nil {
+		log.Fatalf("Legacy blob migration stopped: %v (resume with -after %s)", err, report.LastFileID)
+	}
+}
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"file-vault-system/backend/internal/config"
 	"file-vault-system/backend/internal/handlers"
 	"file-vault-system/backend/internal/middleware"
 	"file-vault-system/backend/internal/services"
 	"file-vault-system/backend/pkg/database"
+	"file-vault-system/backend/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -41,19 +44,161 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Initialize services shared by handlers
+	storageBackends := services.NewStorageBackends(db, cfg)
+	blobStorage, err := services.NewStorage(cfg)
+	if err != nil {
+		log.Fatalf("Invalid storage configuration: %v", err)
+	}
+	thumbnailService := services.NewThumbnailService(db, cfg)
+	thumbnailService.SetStorage(blobStorage)
+	thumbnailService.Start(context.Background())
+	uploadProgress := services.NewUploadProgressHub()
+	notifications := services.NewNotificationHub()
+	featureFlags := services.NewFeatureFlagService(db, cfg)
+	featureFlags.Start(context.Background())
+	mimePolicy := services.NewMimePolicyService(db, cfg)
+	mimePolicy.Start(context.Background())
+
+	// Start background jobs
+	integrityScrubber := services.NewIntegrityScrubber(db, cfg)
+	integrityScrubber.SetStorageBackends(storageBackends)
+	integrityScrubber.SetStorage(blobStorage)
+	uploadCleaner := services.NewUploadSessionCleaner(db, cfg)
+	rateLimitCleaner := services.NewRateLimitCleaner(db, cfg)
+	auditCleaner := services.NewAuditLogCleaner(db, cfg)
+	shareExpiryCleaner := services.NewShareExpiryCleaner(db, cfg)
+	trashPurger := services.NewTrashPurger(db, cfg)
+	trashPurger.SetStorage(blobStorage)
+
+	scrubWindow, err := services.ParseTimeWindow(cfg.ScrubWindow)
+	if err != nil {
+		log.Fatalf("Invalid SCRUB_WINDOW: %v", err)
+	}
+	uploadCleanupWindow, err := services.ParseTimeWindow(cfg.UploadCleanupWindow)
+	if err != nil {
+		log.Fatalf("Invalid UPLOAD_CLEANUP_WINDOW: %v", err)
+	}
+	trashPurgeWindow, err := services.ParseTimeWindow(cfg.TrashPurgeWindow)
+	if err != nil {
+		log.Fatalf("Invalid TRASH_PURGE_WINDOW: %v", err)
+	}
+	rateLimitCleanupWindow, err := services.ParseTimeWindow(cfg.RateLimitCleanupWindow)
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMIT_CLEANUP_WINDOW: %v", err)
+	}
+	auditCleanupWindow, err := services.ParseTimeWindow(cfg.AuditCleanupWindow)
+	if err != nil {
+		log.Fatalf("Invalid AUDIT_CLEANUP_WINDOW: %v", err)
+	}
+	shareExpiryCleanupWindow, err := services.ParseTimeWindow(cfg.ShareExpiryCleanupWindow)
+	if err != nil {
+		log.Fatalf("Invalid SHARE_EXPIRY_CLEANUP_WINDOW: %v", err)
+	}
+	batchPause := time.Duration(cfg.MaintenanceBatchPause) * time.Millisecond
+
+	jobScheduler := services.NewJobScheduler()
+	jobScheduler.Register("integrity_scrub", services.JobConfig{
+		Enabled:  cfg.ScrubEnabled,
+		Interval: time.Duration(cfg.ScrubInterval) * time.Second,
+		Window:   scrubWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.ScrubBatchSize,
+			MaxBatches: cfg.ScrubMaxBatches,
+			BatchPause: batchPause,
+		},
+	}, integrityScrubber.RunJob)
+	jobScheduler.Register("upload_cleanup", services.JobConfig{
+		Enabled:  cfg.UploadCleanupEnabled,
+		Interval: time.Duration(cfg.UploadCleanupInterval) * time.Second,
+		Window:   uploadCleanupWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.UploadCleanupBatchSize,
+			BatchPause: batchPause,
+		},
+	}, uploadCleaner.RunJob)
+	jobScheduler.Register("trash_purge", services.JobConfig{
+		Enabled:  cfg.TrashPurgeEnabled && cfg.TrashRetentionDays > 0,
+		Interval: time.Duration(cfg.TrashPurgeInterval) * time.Second,
+		Window:   trashPurgeWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.TrashPurgeBatchSize,
+			BatchPause: batchPause,
+		},
+	}, trashPurger.RunJob)
+	jobScheduler.Register("rate_limit_cleanup", services.JobConfig{
+		Enabled:  cfg.RateLimitCleanupEnabled,
+		Interval: time.Duration(cfg.RateLimitCleanupInterval) * time.Second,
+		Window:   rateLimitCleanupWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.RateLimitCleanupBatchSize,
+			BatchPause: batchPause,
+		},
+	}, rateLimitCleaner.RunJob)
+	jobScheduler.Register("audit_cleanup", services.JobConfig{
+		Enabled:  cfg.AuditCleanupEnabled && cfg.AuditRetentionDays > 0,
+		Interval: time.Duration(cfg.AuditCleanupInterval) * time.Second,
+		Window:   auditCleanupWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.AuditCleanupBatchSize,
+			BatchPause: batchPause,
+		},
+	}, auditCleaner.RunJob)
+	jobScheduler.Register("share_expiry_cleanup", services.JobConfig{
+		Enabled:  cfg.ShareExpiryCleanupEnabled,
+		Interval: time.Duration(cfg.ShareExpiryCleanupInterval) * time.Second,
+		Window:   shareExpiryCleanupWindow,
+		Limits: services.JobLimits{
+			BatchSize:  cfg.ShareExpiryCleanupBatchSize,
+			BatchPause: batchPause,
+		},
+	}, shareExpiryCleaner.RunJob)
+	jobScheduler.Start(context.Background())
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, cfg)
-	fileHandler := handlers.NewFileHandler(db, cfg)
+	authHandler.SetNotifications(notifications)
+	fileHandler := handlers.NewFileHandler(db, cfg, thumbnailService, storageBackends, uploadProgress)
+	mimeOverrides, err := utils.ParseMimeOverrides(cfg.MimeTypeOverrides)
+	if err != nil {
+		log.Fatalf("Invalid MIME_TYPE_OVERRIDES: %v", err)
+	}
+	fileHandler.SetMimeOverrides(mimeOverrides)
+	fileHandler.SetMimePolicy(mimePolicy)
+	fileHandler.SetNotifications(notifications)
+	fileHandler.SetStorage(blobStorage)
 	folderHandler := handlers.NewFolderHandler(db, cfg)
 	adminHandler := handlers.NewAdminHandler(db, cfg)
+	adminHandler.SetStorage(blobStorage)
+	rateLimitPolicy := middleware.NewRateLimitPolicy(db, cfg)
+	uploadAdmission := middleware.NewUploadAdmission(cfg.UploadMemoryLimit, cfg.MaxFileSize, cfg.UploadRetryAfter)
+	adminHandler.SetRateLimitPolicy(rateLimitPolicy)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, cfg)
+	integrityHandler := handlers.NewIntegrityHandler(db, integrityScrubber)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlags)
+	mimePolicyHandler := handlers.NewMimePolicyHandler(db, mimePolicy)
+	storageBackendHandler := handlers.NewStorageBackendHandler(storageBackends)
+	thumbnailHandler := handlers.NewThumbnailHandler(thumbnailService)
+	savedSearchHandler := handlers.NewSavedSearchHandler(db, cfg)
+	jobHandler := handlers.NewJobHandler(jobScheduler)
+	maintenanceHandler := handlers.NewMaintenanceHandler(db, cfg)
+	syncHandler := handlers.NewSyncHandler(db)
+
+	// Record slow requests for the admin summary
+	slowRequests := middleware.NewSlowRequestLog(time.Duration(cfg.SlowRequestThreshold)*time.Millisecond, cfg.SlowRequestLogSize)
+	slowRequestHandler := handlers.NewSlowRequestHandler(slowRequests)
 
 	// Initialize sharing service and handler
-	sharingService := services.NewSharingService(db)
-	sharingHandler := handlers.NewSharingHandler(sharingService)
+	sharingService := services.NewSharingService(db, cfg)
+	sharingHandler := handlers.NewSharingHandler(db, sharingService, cfg)
+	sharingHandler.SetStorage(blobStorage)
+	sharingHandler.SetNotifications(notifications)
+	notificationHandler := handlers.NewNotificationHandler(notifications, cfg)
 
 	// Set up Gin router
 	router := gin.Default()
 	router.Use(middleware.CORS())
+	router.Use(slowRequests.Middleware())
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -66,50 +211,147 @@ func main() {
 	// API routes
 	api := router.Group("/api/v1")
 	{
+		// Tickets opening a live notification connection
+		api.POST("/notifications/ticket", middleware.AuthMiddleware(), notificationHandler.IssueTicket)
+
 		// Auth routes
 		auth := api.Group("/auth")
+		if cfg.RateLimitEnabled {
+			auth.Use(middleware.DatabaseRateLimit(db, rateLimitPolicy))
+		}
 		{
-			auth.POST("/register", authHandler.Register)
+			auth.POST("/register", middleware.RequireFeature(featureFlags, services.FeatureRegistration), authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.GET("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", authHandler.ResendVerification)
 			auth.POST("/logout", middleware.AuthMiddleware(), authHandler.Logout)
 			auth.GET("/me", middleware.AuthMiddleware(), authHandler.GetMe)
+			auth.POST("/sudo", middleware.AuthMiddleware(), authHandler.Sudo)
+			auth.POST("/sessions/revoke", middleware.AuthMiddleware(), middleware.RequireReauth(db, cfg, middleware.OpRevokeSessions), authHandler.RevokeSessions)
 		}
 
 		// Protected file routes
 		files := api.Group("/files")
-		files.Use(middleware.AuthMiddleware())
+		files.Use(middleware.APIKeyMiddleware(db), middleware.AuthMiddleware())
+		if cfg.RateLimitEnabled {
+			files.Use(middleware.DatabaseRateLimit(db, rateLimitPolicy))
+		}
 		{
-			files.POST("/upload", fileHandler.UploadFile)
+			files.POST("/upload", uploadAdmission.Middleware(), middleware.Transaction(db), fileHandler.UploadFile)
+			files.POST("/upload/init", fileHandler.InitUpload)
+			files.GET("/upload/:uploadId", fileHandler.GetUploadStatus)
+			files.GET("/upload/:uploadId/progress", fileHandler.StreamUploadProgress)
+			files.PUT("/upload/:uploadId", uploadAdmission.Middleware(), fileHandler.UploadChunk)
+			files.PUT("/upload/:uploadId/chunk/:index", uploadAdmission.Middleware(), fileHandler.UploadIndexedChunk)
+			files.POST("/upload/:uploadId/complete", fileHandler.CompleteUpload)
+			files.DELETE("/upload/:uploadId", fileHandler.AbortUpload)
 			files.GET("/", fileHandler.ListFiles)
 			files.GET("/stats", fileHandler.GetUserStats)
+			files.GET("/export", fileHandler.ExportFiles)
+			files.GET("/search", fileHandler.SearchFiles)
+			files.GET("/mimetypes", fileHandler.ListMimeTypes)
+			files.GET("/tags", fileHandler.ListFileTags)
+			files.GET("/trash", fileHandler.ListTrash)
+			files.POST("/trash/restore", fileHandler.RestoreTrashedFiles)
+			files.POST("/deduplicate", fileHandler.DeduplicateFiles)
+			files.GET("/by-hash/:hash", fileHandler.GetFilesByHash)
+			files.GET("/shared-with-me", sharingHandler.GetFilesSharedWithMe)
 			files.GET("/:id", fileHandler.GetFile)
+			files.HEAD("/:id", fileHandler.DownloadFile)
 			files.GET("/:id/view", fileHandler.ViewFile)
+			files.HEAD("/:id/view", fileHandler.ViewFile)
+			files.GET("/:id/download", fileHandler.DownloadFile)
+			files.HEAD("/:id/download", fileHandler.DownloadFile)
+			files.GET("/:id/head", fileHandler.GetFileHead)
+			files.GET("/:id/provenance", fileHandler.GetFileProvenance)
+			files.GET("/:id/thumbnail", fileHandler.GetThumbnail)
+			files.GET("/:id/can-access", fileHandler.CheckFileAccess)
+			files.PUT("/:id/access-expiry", fileHandler.SetFileAccessExpiry)
+			files.PUT("/:id/admin-visibility", fileHandler.SetFileAdminVisibility)
 			files.POST("/:id/move", fileHandler.MoveFile)
-			files.DELETE("/:id", fileHandler.DeleteFile)
+			files.PUT("/:id/tags", fileHandler.SetFileTags)
+			files.POST("/:id/tags", fileHandler.AddFileTags)
+			files.DELETE("/:id/tags/:tag", fileHandler.RemoveFileTag)
+			files.DELETE("/:id", middleware.Transaction(db), fileHandler.DeleteFile)
+			files.POST("/:id/restore", fileHandler.RestoreTrashedFile)
+			files.DELETE("/:id/purge", fileHandler.PurgeFile)
+			files.GET("/:id/versions", fileHandler.ListFileVersions)
+			files.POST("/:id/versions/:version/restore", fileHandler.RestoreFileVersion)
 
 			// File sharing routes
-			files.POST("/:id/share", sharingHandler.ShareFileWithUser)
-			files.POST("/:id/share-link", sharingHandler.CreateShareLink)
+			files.POST("/:id/share", middleware.RequireFeature(featureFlags, services.FeatureSharing), sharingHandler.ShareFileWithUser)
+			files.POST("/:id/share-link", middleware.RequireFeature(featureFlags, services.FeatureSharing), middleware.RequireFeature(featureFlags, services.FeaturePublicLinks), sharingHandler.CreateShareLink)
 			files.GET("/:id/shares", sharingHandler.GetFileShares)
+			files.GET("/:id/acl", sharingHandler.GetFileACL)
+			files.POST("/:id/acl", middleware.RequireFeature(featureFlags, services.FeatureSharing), sharingHandler.AddFileACLEntries)
+			files.DELETE("/:id/acl/:entryId", sharingHandler.RemoveFileACLEntry)
 		}
 
 		// Sharing routes under /api/v1
 		api.GET("/shared-files", middleware.AuthMiddleware(), sharingHandler.GetSharedFiles)
+		api.GET("/shared-folders", middleware.AuthMiddleware(), sharingHandler.GetSharedFolders)
 		api.GET("/share-links", middleware.AuthMiddleware(), sharingHandler.GetShareLinks)
+		api.GET("/downloads/history", middleware.AuthMiddleware(), fileHandler.GetDownloadHistory)
 		api.DELETE("/shares/:id", middleware.AuthMiddleware(), sharingHandler.RevokeFileShare)
+		api.DELETE("/shares/user/:id", middleware.AuthMiddleware(), sharingHandler.RedirectUserShareRevoke)
 		api.DELETE("/share-links/:id", middleware.AuthMiddleware(), sharingHandler.RevokeShareLink)
+		api.DELETE("/folder-shares/:id", middleware.AuthMiddleware(), sharingHandler.RevokeFolderShare)
+
+		// API key management (JWT only)
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(middleware.AuthMiddleware())
+		{
+			apiKeys.POST("/", middleware.RequireReauth(db, cfg, middleware.OpCreateAPIKey), apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("/", apiKeyHandler.ListAPIKeys)
+			apiKeys.GET("/:id/files", apiKeyHandler.ListAPIKeyFiles)
+			apiKeys.DELETE("/:id", middleware.RequireReauth(db, cfg, middleware.OpRevokeAPIKey), apiKeyHandler.RevokeAPIKey)
+		}
+
+		// Incremental sync, available to API keys like the file routes
+		sync := api.Group("/sync")
+		sync.Use(middleware.APIKeyMiddleware(db), middleware.AuthMiddleware())
+		{
+			sync.GET("/changes", syncHandler.GetChanges)
+		}
+
+		// Saved searches ("smart folders")
+		savedSearches := api.Group("/saved-searches")
+		savedSearches.Use(middleware.AuthMiddleware())
+		{
+			savedSearches.POST("/", savedSearchHandler.CreateSavedSearch)
+			savedSearches.GET("/", savedSearchHandler.ListSavedSearches)
+			savedSearches.GET("/:id", savedSearchHandler.GetSavedSearch)
+			savedSearches.PUT("/:id", savedSearchHandler.UpdateSavedSearch)
+			savedSearches.DELETE("/:id", savedSearchHandler.DeleteSavedSearch)
+			savedSearches.GET("/:id/files", savedSearchHandler.ListSavedSearchFiles)
+		}
 
 		// Protected folder routes
 		folders := api.Group("/folders")
 		folders.Use(middleware.AuthMiddleware())
+		if cfg.RateLimitEnabled {
+			folders.Use(middleware.DatabaseRateLimit(db, rateLimitPolicy))
+		}
 		{
 			folders.POST("/", folderHandler.CreateFolder)
 			folders.GET("/", folderHandler.ListFolders)
 			folders.GET("/tree", folderHandler.GetFolderTree)
+			folders.GET("/search", folderHandler.SearchFolders)
 			folders.GET("/:id", folderHandler.GetFolder)
+			folders.GET("/:id/tree", folderHandler.GetFolderSubtree)
 			folders.PUT("/:id", folderHandler.UpdateFolder)
 			folders.POST("/:id/move", folderHandler.MoveFolder)
+			folders.PUT("/:id/admin-visibility", folderHandler.SetFolderAdminVisibility)
 			folders.DELETE("/:id", folderHandler.DeleteFolder)
+			folders.GET("/:id/export", fileHandler.ExportFolder)
+			folders.GET("/:id/download", fileHandler.RedirectFolderDownload)
+			folders.GET("/:id/download-stats", folderHandler.GetFolderDownloadStats)
+
+			// Folder sharing routes
+			folders.POST("/:id/share", middleware.RequireFeature(featureFlags, services.FeatureSharing), sharingHandler.ShareFolderWithUser)
+			folders.GET("/:id/shares", sharingHandler.GetFolderShares)
+			folders.POST("/:id/share-link", middleware.RequireFeature(featureFlags, services.FeatureSharing), middleware.RequireFeature(featureFlags, services.FeaturePublicLinks), sharingHandler.CreateFolderShareLink)
 		}
 
 		// Admin routes
@@ -118,13 +360,55 @@ func main() {
 		admin.Use(middleware.RequireAdmin())
 		{
 			admin.GET("/stats", adminHandler.GetStats)
+			admin.GET("/health", adminHandler.GetSystemHealth)
+			admin.GET("/config", adminHandler.GetEffectiveConfig)
 			admin.GET("/users", adminHandler.GetUsers)
+			admin.GET("/users/:id/quota-impact", adminHandler.GetQuotaImpact)
+			admin.PUT("/users/:id/quota", adminHandler.SetUserQuota)
+			admin.GET("/users/:id/rate-limit", adminHandler.GetUserRateLimit)
+			admin.PUT("/users/:id/rate-limit", adminHandler.SetUserRateLimit)
+			admin.GET("/rate-limits", adminHandler.ListEndpointRateLimits)
+			admin.GET("/shares", adminHandler.ListActiveShares)
+			admin.POST("/shares/revoke", adminHandler.RevokeActiveShares)
+			admin.PUT("/rate-limits", adminHandler.UpdateEndpointRateLimits)
+			admin.PUT("/users/:id/role", middleware.RequireReauth(db, cfg, middleware.OpChangeRole), adminHandler.UpdateUserRole)
+			admin.DELETE("/users/:id", middleware.RequireReauth(db, cfg, middleware.OpDeleteAccount), adminHandler.DeleteUser)
+			admin.GET("/audit-logs", adminHandler.ListAuditLogs)
+			admin.POST("/users/:id/transfer-all", adminHandler.TransferAllContent)
+			admin.GET("/files", adminHandler.GetAllFiles)
+			admin.GET("/quarantine", adminHandler.ListQuarantinedFiles)
+			admin.POST("/quarantine/:id/release", adminHandler.ReleaseQuarantinedFile)
+			admin.DELETE("/quarantine/:id", adminHandler.DeleteQuarantinedFile)
+			admin.GET("/integrity", integrityHandler.GetScrubStatus)
+			admin.POST("/integrity/scrub", integrityHandler.TriggerScrub)
+			admin.GET("/feature-flags", featureFlagHandler.ListFeatureFlags)
+			admin.PUT("/feature-flags/:key", featureFlagHandler.UpdateFeatureFlag)
+			admin.GET("/mime-policy", mimePolicyHandler.GetMimePolicy)
+			admin.PUT("/mime-policy", mimePolicyHandler.UpdateMimePolicy)
+			admin.GET("/storage/backends", storageBackendHandler.ListStorageBackends)
+			admin.GET("/storage/verify", integrityHandler.VerifyStorage)
+			admin.POST("/storage/gc", maintenanceHandler.CollectOrphanedBlobs)
+			admin.GET("/thumbnails/queue", thumbnailHandler.GetQueueStats)
+			admin.GET("/slow-requests", slowRequestHandler.GetSlowRequests)
+			admin.GET("/jobs", jobHandler.ListJobs)
+			admin.POST("/jobs/:name/run", jobHandler.TriggerJob)
+			admin.POST("/jobs/:name/pause", jobHandler.PauseJob)
+			admin.POST("/jobs/:name/resume", jobHandler.ResumeJob)
+			admin.POST("/maintenance/rebuild-refcounts", maintenanceHandler.RebuildReferenceCounts)
+			admin.GET("/maintenance/reclaimable", maintenanceHandler.GetReclaimableStorage)
 		}
 	}
 
 	// Public sharing routes (no auth required)
-	router.GET("/share/:token", sharingHandler.AccessSharedFile)
-	router.GET("/share/:token/download", sharingHandler.DownloadSharedFile)
+	publicLinks := middleware.RequireFeature(featureFlags, services.FeaturePublicLinks)
+	router.GET("/share/:token", publicLinks, sharingHandler.AccessSharedFile)
+	router.GET("/share/:token/download", publicLinks, sharingHandler.DownloadSharedFile)
+	router.GET("/share/:token/download/:fileId", publicLinks, sharingHandler.DownloadSharedFolderFile)
+	router.GET("/share/:token/preview", publicLinks, sharingHandler.SharedFilePreview)
+	router.GET("/share/:token/files/:fileId", publicLinks, sharingHandler.RedirectSharedFolderFile)
+
+	// Live notifications, opened with a ticket from /api/v1/notifications/ticket
+	router.GET("/ws", notificationHandler.Connect)
 
 	log.Printf("Server starting on port %s", cfg.Port)
 	log.Fatal(router.Run(":8080"))
@@ -0,0 +1,56 @@
+// Command shard-blobs moves blobs stored under the flat storage/{hash} layout
+// to the sharded storage/ab/cd/{hash} layout. Blobs stay readable throughout
+// and it is safe to interrupt and run again.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/database"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be relocated without changing anything")
+	limit := flag.Int("limit", 0, "stop after this many blobs (0 = no limit)")
+	flag.Parse()
+
+	// Load environment variables - try multiple paths
+	envPaths := []string{".env", "../../.env", "../../../.env"}
+	for _, path := range envPaths {
+		if err := godotenv.Load(path); err == nil {
+			log.Printf("Loaded .env from: %s", path)
+			break
+		}
+	}
+
+	cfg := config.Load()
+
+	db, err := database.Initialize(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := services.ShardFlatBlobs(ctx, db, cfg, services.BlobShardingOptions{
+		DryRun: *dryRun,
+		Limit:  *limit,
+	})
+	if report != nil {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		log.Printf("Blob sharding report:\n%s", output)
+	}
+	if err != nil {
+		log.Fatalf("Blob sharding stopped: %v", err)
+	}
+}
@@ -25,19 +25,89 @@ type Config struct {
 	DatabaseSSLMode  string
 
 	// JWT configuration
-	JWTSecret     string
-	JWTExpiration int // in hours
+	JWTSecret              string
+	JWTExpiration          int // in hours
+	RefreshTokenExpiration int // in hours
+
+	// Email verification
+	RequireVerifiedEmail       bool   // refuse logins from accounts that haven't verified their email
+	EmailVerificationTTL       int    // in hours
+	VerificationResendInterval int    // in seconds, minimum gap between verification emails to one user
+	PublicURL                  string // base URL of the API, used in links sent by email and share previews
+
+	// Outgoing mail (verification emails are logged instead when SMTPHost is empty)
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// Re-authentication for sensitive operations
+	SensitiveOperations []string
+	SudoTokenTTL        int // in minutes
 
 	// Rate limiting
-	RateLimit       int // requests per second
+	RateLimit       int // requests per window
 	RateLimitWindow int // in seconds
 	RateLimitBurst  int
 
+	RateLimitEnabled          bool // apply the per-user database rate limiter to the file and folder API
+	RateLimitOverrideCacheTTL int  // in seconds, how long per-user overrides are cached
+
+	// Upload budget (total ingest per user over a rolling window, 0 = unlimited)
+	UploadBudgetWindow     int   // in seconds
+	UploadBudgetBytes      int64 // bytes per window
+	UploadBudgetFiles      int   // files per window
+	AdminUploadBudgetBytes int64 // bytes per window for admins
+	AdminUploadBudgetFiles int   // files per window for admins
+
+	// Upload admission control (0 = unlimited)
+	UploadMemoryLimit int64 // in bytes, upload bodies accepted concurrently across all users
+	UploadRetryAfter  int   // in seconds, suggested to clients turned away
+
 	// Storage configuration
-	StoragePath      string
-	MaxFileSize      int64 // in bytes
-	DefaultUserQuota int64 // in bytes
-	AllowedMimeTypes []string
+	StoragePath           string
+	MaxFileSize           int64 // in bytes
+	DefaultUserQuota      int64 // in bytes
+	AllowedMimeTypes      []string
+	MimeTypeOverrides     []string // ".ext=type" or "hex:prefix=type", forcing the detected type of matching uploads
+	UploadFieldNames      []string // multipart fields read as files, "*" accepts any
+	BlockExecutables      bool     // reject executables and scripts by their magic bytes
+	DedupVerification     string   // "hash", "size" or "bytes": what must match before content is reused
+	NormalizeFilenames    bool     // NFC-normalize uploaded filenames and strip control characters
+	StorageErrorWindow    int      // in minutes, window for backend error rates
+	LegacyStorageFallback bool     // look for blobs under storage/{file ID} when the content path is missing
+	StorageBackend        string   // "local" or "s3": where content blobs are kept
+	EncryptionMasterKey   string   // 32-byte key, hex or base64, blobs are encrypted at rest when set
+
+	// S3-compatible object storage, used when StorageBackend is "s3"
+	S3Endpoint        string // empty = AWS in S3Region
+	S3Region          string
+	S3Bucket          string
+	S3Prefix          string // key prefix blobs are stored under
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool // address the bucket in the path, as MinIO and most self-hosted services need
+	S3Timeout         int  // in seconds, per request
+
+	// Malware scanning
+	MalwareScanMode    string // "off", "reject" infected uploads, or "quarantine" them for admin review
+	ClamdAddress       string // host:port of the clamd daemon
+	MalwareScanTimeout int    // in seconds
+
+	// Folder tree responses (0 = unlimited)
+	FolderTreeMaxDepth int // levels returned by the tree endpoints
+	FolderTreeMaxNodes int // folders returned by the tree endpoints
+
+	// User deletion
+	DeleteUserFiles bool // trash a deleted user's files, releasing their hold on shared content
+
+	// File deletion
+	SharedFileDeletion string // "revoke" shares along with the file, or "block" deletion while shares are active
+
+	// Quota grace (0 = hard quota)
+	QuotaGracePercent  int // how far past the quota a single upload may go
+	QuotaGraceCooldown int // in hours before grace can be used again, 0 = once per account
 
 	// CORS configuration
 	AllowedOrigins []string
@@ -47,10 +117,116 @@ type Config struct {
 	// File serving
 	MaxDownloadSize int64 // in bytes
 	DownloadTimeout int   // in seconds
+
+	// Share links (0 = no default / no cap)
+	DefaultShareLinkExpiry int  // in hours, applied when a link is created without an expiry
+	MaxShareLinkExpiry     int  // in hours, longest expiry a non-admin may set
+	ShareHTMLIndex         bool // let folder links serve an HTML index instead of only JSON
+	ShareLinkPreviews      bool // serve Open Graph metadata on file links for chat app unfurling
+
+	// Streaming uploads
+	UploadSessionTTL     int   // in hours
+	UploadHashBufferSize int   // in bytes, bounds memory used per streamed chunk
+	UploadChunkSize      int64 // in bytes, size of each chunk sent by index
+	UploadChunkAcks      bool  // return progress acknowledgements for each chunk
+	UploadProgressEvents bool  // serve progress as Server-Sent Events
+	UploadProgressRate   int   // in milliseconds, minimum gap between events while a chunk streams
+
+	// Parallel downloads
+	DownloadChunkSize int64 // in bytes, range size advertised to clients fetching ranges in parallel
+
+	// Thumbnails
+	ThumbnailsEnabled     bool
+	ThumbnailMaxDimension int     // in pixels
+	VideoThumbnailOffset  float64 // in seconds
+	ThumbnailWorkers      int     // thumbnails generated at once
+	ThumbnailQueueSize    int     // thumbnails waiting for a worker before new ones are left for lazy generation
+	FFmpegPath            string
+
+	// Background maintenance jobs. Windows are daily local times such as
+	// "22:00-06:00"; empty allows jobs to run at any time.
+	MaintenanceWindow     string
+	MaintenanceBatchPause int // in milliseconds, sleep between batches
+
+	// Integrity scrubber
+	ScrubEnabled    bool
+	ScrubInterval   int // in seconds
+	ScrubBatchSize  int // blobs verified per batch
+	ScrubMaxBatches int // batches per pass
+	ScrubWindow     string
+	VerifyOnServe   bool // re-hash blobs before serving them whole, refusing corrupt content
+
+	// Chunk checksums, recorded at upload and checked when serving byte ranges
+	ChunkChecksums    bool
+	ChunkChecksumSize int64 // in bytes
+
+	// ZIP exports
+	ExportManifest bool // add checksum manifests listing every exported file
+
+	// Expired upload session cleanup
+	UploadCleanupEnabled   bool
+	UploadCleanupInterval  int // in seconds
+	UploadCleanupBatchSize int // sessions removed per batch
+	UploadCleanupWindow    string
+
+	// Trash, emptied of files deleted more than TrashRetentionDays ago (0 = kept until purged)
+	TrashRetentionDays  int
+	TrashPurgeEnabled   bool
+	TrashPurgeInterval  int // in seconds
+	TrashPurgeBatchSize int // files purged per batch
+	TrashPurgeWindow    string
+
+	// File versioning: re-uploading a name already in a folder adds a version
+	// to that file instead of creating another
+	FileVersioning   bool
+	FileVersionLimit int // versions kept per file, oldest pruned first (0 = unlimited)
+
+	// Audit log retention (0 = kept indefinitely)
+	AuditRetentionDays    int
+	AuditCleanupEnabled   bool
+	AuditCleanupInterval  int // in seconds
+	AuditCleanupBatchSize int // entries deleted per batch
+	AuditCleanupWindow    string
+	UploadClientMetadata  bool // record each upload's IP, user agent, API key and MIME types in the audit log
+
+	// Stale rate limit row cleanup
+	RateLimitCleanupEnabled   bool
+	RateLimitCleanupInterval  int // in seconds
+	RateLimitCleanupAge       int // in seconds past the end of a row's window
+	RateLimitCleanupBatchSize int // rows deleted per batch
+	RateLimitCleanupWindow    string
+
+	// Expired share cleanup
+	ShareExpiryCleanupEnabled   bool
+	ShareExpiryCleanupInterval  int // in seconds
+	ShareExpiryCleanupBatchSize int // rows deleted per batch
+	ShareExpiryCleanupWindow    string
+
+	// Feature flags
+	FeatureFlagRefreshInterval int // in seconds
+
+	// MIME type policy
+	MimePolicyRefreshInterval int // in seconds
+
+	// File name search
+	SearchFuzzyThreshold float64 // 0-1, how similar a name must be to match a fuzzy search
+
+	// Slow request and query logging (0 = disabled)
+	SlowRequestThreshold int // in milliseconds
+	SlowQueryThreshold   int // in milliseconds
+	SlowRequestLogSize   int // recent slow requests kept for the admin summary
+
+	// Admin stats
+	AdminStatsIncludeTrash bool // count trashed files and the content they hold in the totals
+
+	// Admin privacy
+	AdminFilePrivacy bool // honor folders and files users hide from the admin file listing
 }
 
 // Load loads configuration from environment variables with defaults
 func Load() *Config {
+	maintenanceWindow := getEnv("MAINTENANCE_WINDOW", "")
+
 	return &Config{
 		// Server configuration
 		Environment:  getEnv("ENVIRONMENT", "development"),
@@ -69,14 +245,48 @@ func Load() *Config {
 		DatabaseSSLMode:  getEnv("DB_SSL_MODE", "disable"),
 
 		// JWT configuration
-		JWTSecret:     getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
-		JWTExpiration: getEnvAsInt("JWT_EXPIRATION", 24), // 24 hours
+		JWTSecret:              getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
+		JWTExpiration:          getEnvAsInt("JWT_EXPIRATION", 24),            // 24 hours
+		RefreshTokenExpiration: getEnvAsInt("REFRESH_TOKEN_EXPIRATION", 720), // 30 days
+
+		// Email verification
+		RequireVerifiedEmail:       getEnvAsBool("REQUIRE_VERIFIED_EMAIL", false),
+		EmailVerificationTTL:       getEnvAsInt("EMAIL_VERIFICATION_TTL", 48), // 2 days
+		VerificationResendInterval: getEnvAsInt("VERIFICATION_RESEND_INTERVAL", 60),
+		PublicURL:                  getEnv("PUBLIC_URL", "http://localhost:8080"),
+
+		// Outgoing mail
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "File Vault <no-reply@localhost>"),
+
+		// Re-authentication for sensitive operations
+		SensitiveOperations: getEnvAsSlice("SENSITIVE_OPERATIONS", []string{
+			"create_api_key", "revoke_api_key", "delete_account", "change_role", "revoke_sessions",
+		}),
+		SudoTokenTTL: getEnvAsInt("SUDO_TOKEN_TTL", 5), // 5 minutes
 
 		// Rate limiting
 		RateLimit:       getEnvAsInt("RATE_LIMIT", 2),        // 2 requests per second
 		RateLimitWindow: getEnvAsInt("RATE_LIMIT_WINDOW", 1), // 1 second window
 		RateLimitBurst:  getEnvAsInt("RATE_LIMIT_BURST", 5),  // burst of 5
 
+		RateLimitEnabled:          getEnvAsBool("RATE_LIMIT_ENABLED", false),
+		RateLimitOverrideCacheTTL: getEnvAsInt("RATE_LIMIT_OVERRIDE_CACHE_TTL", 60),
+
+		// Upload budget
+		UploadBudgetWindow:     getEnvAsInt("UPLOAD_BUDGET_WINDOW", 3600),        // 1 hour
+		UploadBudgetBytes:      getEnvAsInt64("UPLOAD_BUDGET_BYTES", 1073741824), // 1GB per hour
+		UploadBudgetFiles:      getEnvAsInt("UPLOAD_BUDGET_FILES", 1000),         // 1000 files per hour
+		AdminUploadBudgetBytes: getEnvAsInt64("ADMIN_UPLOAD_BUDGET_BYTES", 0),    // admins exempt
+		AdminUploadBudgetFiles: getEnvAsInt("ADMIN_UPLOAD_BUDGET_FILES", 0),      // admins exempt
+
+		// Upload admission control
+		UploadMemoryLimit: getEnvAsInt64("UPLOAD_MEMORY_LIMIT", 1073741824), // 1GB
+		UploadRetryAfter:  getEnvAsInt("UPLOAD_RETRY_AFTER", 5),
+
 		// Storage configuration
 		StoragePath:      getEnv("STORAGE_PATH", "./uploads"),
 		MaxFileSize:      getEnvAsInt64("MAX_FILE_SIZE", 104857600),     // 100MB
@@ -96,6 +306,44 @@ func Load() *Config {
 			"application/vnd.ms-powerpoint",
 			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
 		}),
+		MimeTypeOverrides:     getEnvAsSlice("MIME_TYPE_OVERRIDES", nil),
+		UploadFieldNames:      getEnvAsSlice("UPLOAD_FIELD_NAMES", []string{"file", "files"}),
+		BlockExecutables:      getEnvAsBool("BLOCK_EXECUTABLE_UPLOADS", false),
+		DedupVerification:     getEnv("DEDUP_VERIFICATION", "size"),
+		NormalizeFilenames:    getEnvAsBool("NORMALIZE_FILENAMES", true),
+		StorageErrorWindow:    getEnvAsInt("STORAGE_ERROR_WINDOW", 15),
+		LegacyStorageFallback: getEnvAsBool("LEGACY_STORAGE_FALLBACK", true),
+		StorageBackend:        getEnv("STORAGE_BACKEND", "local"),
+		EncryptionMasterKey:   getEnv("ENCRYPTION_MASTER_KEY", ""),
+
+		// S3-compatible object storage
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3Prefix:          getEnv("S3_PREFIX", "storage"),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:       getEnvAsBool("S3_PATH_STYLE", false),
+		S3Timeout:         getEnvAsInt("S3_TIMEOUT", 300), // 5 minutes
+
+		// Malware scanning
+		MalwareScanMode:    getEnv("MALWARE_SCAN_MODE", "off"),
+		ClamdAddress:       getEnv("CLAMD_ADDRESS", "localhost:3310"),
+		MalwareScanTimeout: getEnvAsInt("MALWARE_SCAN_TIMEOUT", 30),
+
+		// Folder tree responses
+		FolderTreeMaxDepth: getEnvAsInt("FOLDER_TREE_MAX_DEPTH", 10),
+		FolderTreeMaxNodes: getEnvAsInt("FOLDER_TREE_MAX_NODES", 2000),
+
+		// User deletion
+		DeleteUserFiles: getEnvAsBool("DELETE_USER_FILES", true),
+
+		// File deletion
+		SharedFileDeletion: getEnv("SHARED_FILE_DELETION", "revoke"),
+
+		// Quota grace
+		QuotaGracePercent:  getEnvAsInt("QUOTA_GRACE_PERCENT", 5),
+		QuotaGraceCooldown: getEnvAsInt("QUOTA_GRACE_COOLDOWN", 720), // 30 days
 
 		// CORS configuration
 		AllowedOrigins: getEnvAsSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
@@ -107,6 +355,108 @@ func Load() *Config {
 		// File serving
 		MaxDownloadSize: getEnvAsInt64("MAX_DOWNLOAD_SIZE", 1073741824), // 1GB
 		DownloadTimeout: getEnvAsInt("DOWNLOAD_TIMEOUT", 300),           // 5 minutes
+
+		// Share links
+		DefaultShareLinkExpiry: getEnvAsInt("DEFAULT_SHARE_LINK_EXPIRY", 168), // 7 days
+		MaxShareLinkExpiry:     getEnvAsInt("MAX_SHARE_LINK_EXPIRY", 720),     // 30 days
+		ShareHTMLIndex:         getEnvAsBool("SHARE_HTML_INDEX", true),
+		ShareLinkPreviews:      getEnvAsBool("SHARE_LINK_PREVIEWS", true),
+
+		// Streaming uploads
+		UploadSessionTTL:     getEnvAsInt("UPLOAD_SESSION_TTL", 24),           // 24 hours
+		UploadHashBufferSize: getEnvAsInt("UPLOAD_HASH_BUFFER_SIZE", 1048576), // 1MB
+		UploadChunkSize:      getEnvAsInt64("UPLOAD_CHUNK_SIZE", 8388608),     // 8MB
+		UploadChunkAcks:      getEnvAsBool("UPLOAD_CHUNK_ACKS", true),
+		UploadProgressEvents: getEnvAsBool("UPLOAD_PROGRESS_EVENTS", true),
+		UploadProgressRate:   getEnvAsInt("UPLOAD_PROGRESS_RATE", 250),
+
+		// Parallel downloads
+		DownloadChunkSize: getEnvAsInt64("DOWNLOAD_CHUNK_SIZE", 8388608), // 8MB
+
+		// Thumbnails
+		ThumbnailsEnabled:     getEnvAsBool("THUMBNAILS_ENABLED", true),
+		ThumbnailMaxDimension: getEnvAsInt("THUMBNAIL_MAX_DIMENSION", 256),
+		VideoThumbnailOffset:  getEnvAsFloat("VIDEO_THUMBNAIL_OFFSET", 1.0), // 1 second in
+		ThumbnailWorkers:      getEnvAsInt("THUMBNAIL_WORKERS", 2),
+		ThumbnailQueueSize:    getEnvAsInt("THUMBNAIL_QUEUE_SIZE", 100),
+		FFmpegPath:            getEnv("FFMPEG_PATH", "ffmpeg"),
+
+		// Background maintenance jobs
+		MaintenanceWindow:     maintenanceWindow,
+		MaintenanceBatchPause: getEnvAsInt("MAINTENANCE_BATCH_PAUSE", 0),
+
+		// Integrity scrubber
+		ScrubEnabled:    getEnvAsBool("SCRUB_ENABLED", true),
+		ScrubInterval:   getEnvAsInt("SCRUB_INTERVAL", 3600), // 1 hour
+		ScrubBatchSize:  getEnvAsInt("SCRUB_BATCH_SIZE", 100),
+		ScrubMaxBatches: getEnvAsInt("SCRUB_MAX_BATCHES", 1),
+		ScrubWindow:     getEnv("SCRUB_WINDOW", maintenanceWindow),
+		VerifyOnServe:   getEnvAsBool("VERIFY_ON_SERVE", false),
+
+		// Chunk checksums
+		ChunkChecksums:    getEnvAsBool("CHUNK_CHECKSUMS", false),
+		ChunkChecksumSize: getEnvAsInt64("CHUNK_CHECKSUM_SIZE", 1048576), // 1MB
+
+		// ZIP exports
+		ExportManifest: getEnvAsBool("EXPORT_MANIFEST", true),
+
+		// Expired upload session cleanup
+		UploadCleanupEnabled:   getEnvAsBool("UPLOAD_CLEANUP_ENABLED", true),
+		UploadCleanupInterval:  getEnvAsInt("UPLOAD_CLEANUP_INTERVAL", 3600), // 1 hour
+		UploadCleanupBatchSize: getEnvAsInt("UPLOAD_CLEANUP_BATCH_SIZE", 100),
+		UploadCleanupWindow:    getEnv("UPLOAD_CLEANUP_WINDOW", maintenanceWindow),
+
+		// Trash
+		TrashRetentionDays:  getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeEnabled:   getEnvAsBool("TRASH_PURGE_ENABLED", true),
+		TrashPurgeInterval:  getEnvAsInt("TRASH_PURGE_INTERVAL", 3600), // 1 hour
+		TrashPurgeBatchSize: getEnvAsInt("TRASH_PURGE_BATCH_SIZE", 100),
+		TrashPurgeWindow:    getEnv("TRASH_PURGE_WINDOW", maintenanceWindow),
+
+		// File versioning
+		FileVersioning:   getEnvAsBool("FILE_VERSIONING", false),
+		FileVersionLimit: getEnvAsInt("FILE_VERSION_LIMIT", 10),
+
+		// Audit log retention
+		AuditRetentionDays:    getEnvAsInt("AUDIT_RETENTION_DAYS", 0),
+		AuditCleanupEnabled:   getEnvAsBool("AUDIT_CLEANUP_ENABLED", true),
+		AuditCleanupInterval:  getEnvAsInt("AUDIT_CLEANUP_INTERVAL", 86400), // 1 day
+		AuditCleanupBatchSize: getEnvAsInt("AUDIT_CLEANUP_BATCH_SIZE", 1000),
+		AuditCleanupWindow:    getEnv("AUDIT_CLEANUP_WINDOW", maintenanceWindow),
+		UploadClientMetadata:  getEnvAsBool("UPLOAD_CLIENT_METADATA", false),
+
+		// Stale rate limit row cleanup
+		RateLimitCleanupEnabled:   getEnvAsBool("RATE_LIMIT_CLEANUP_ENABLED", true),
+		RateLimitCleanupInterval:  getEnvAsInt("RATE_LIMIT_CLEANUP_INTERVAL", 3600), // 1 hour
+		RateLimitCleanupAge:       getEnvAsInt("RATE_LIMIT_CLEANUP_AGE", 86400),     // 1 day
+		RateLimitCleanupBatchSize: getEnvAsInt("RATE_LIMIT_CLEANUP_BATCH_SIZE", 1000),
+		RateLimitCleanupWindow:    getEnv("RATE_LIMIT_CLEANUP_WINDOW", maintenanceWindow),
+
+		// Expired share cleanup
+		ShareExpiryCleanupEnabled:   getEnvAsBool("SHARE_EXPIRY_CLEANUP_ENABLED", true),
+		ShareExpiryCleanupInterval:  getEnvAsInt("SHARE_EXPIRY_CLEANUP_INTERVAL", 3600), // 1 hour
+		ShareExpiryCleanupBatchSize: getEnvAsInt("SHARE_EXPIRY_CLEANUP_BATCH_SIZE", 1000),
+		ShareExpiryCleanupWindow:    getEnv("SHARE_EXPIRY_CLEANUP_WINDOW", maintenanceWindow),
+
+		// Feature flags
+		FeatureFlagRefreshInterval: getEnvAsInt("FEATURE_FLAG_REFRESH_INTERVAL", 30),
+
+		// MIME type policy
+		MimePolicyRefreshInterval: getEnvAsInt("MIME_POLICY_REFRESH_INTERVAL", 30),
+
+		// File name search
+		SearchFuzzyThreshold: getEnvAsFloat("SEARCH_FUZZY_THRESHOLD", 0.4),
+
+		// Slow request and query logging
+		SlowRequestThreshold: getEnvAsInt("SLOW_REQUEST_THRESHOLD", 1000),
+		SlowQueryThreshold:   getEnvAsInt("SLOW_QUERY_THRESHOLD", 200),
+		SlowRequestLogSize:   getEnvAsInt("SLOW_REQUEST_LOG_SIZE", 100),
+
+		// Admin stats
+		AdminStatsIncludeTrash: getEnvAsBool("ADMIN_STATS_INCLUDE_TRASH", false),
+
+		// Admin privacy
+		AdminFilePrivacy: getEnvAsBool("ADMIN_FILE_PRIVACY", true),
 	}
 }
 
@@ -161,6 +511,24 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return strings.Split(value, ",")
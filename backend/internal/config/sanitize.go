@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// redactedValue replaces secret settings in the sanitized configuration
+const redactedValue = "[REDACTED]"
+
+// secretFields are settings whose values are never shown, whatever their name
+var secretFields = map[string]bool{
+	"DatabasePassword":    true,
+	"JWTSecret":           true,
+	"EncryptionMasterKey": true,
+}
+
+// isSecretField reports whether a setting holds secret material. Anything
+// named like a password, secret or credential is treated as one, so a new
+// setting is redacted unless its name says otherwise.
+func isSecretField(name string) bool {
+	if secretFields[name] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, marker := range []string{"password", "secret", "credential", "privatekey", "apikey"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitized returns the effective configuration keyed by snake_case setting
+// name, with secrets redacted and credentials removed from the database URL
+func (c *Config) Sanitized() map[string]interface{} {
+	values := reflect.ValueOf(c).Elem()
+	fields := values.Type()
+
+	sanitized := make(map[string]interface{}, fields.NumField())
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Name
+		key := snakeCase(name)
+
+		switch {
+		case isSecretField(name):
+			sanitized[key] = redactedValue
+		case name == "DatabaseURL":
+			sanitized[key] = redactURL(c.DatabaseURL)
+		default:
+			sanitized[key] = values.Field(i).Interface()
+		}
+	}
+	return sanitized
+}
+
+// redactURL hides the password in a connection URL. Anything that doesn't
+// parse is redacted entirely.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" {
+		return redactedValue
+	}
+	if parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), "xxxxx")
+		}
+	}
+	query := parsed.Query()
+	if query.Has("password") {
+		query.Set("password", "xxxxx")
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
+
+// snakeCase turns a field name such as DatabaseSSLMode into database_ssl_mode
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Break before a word, and after an acronym (SSLMode) but not
+			// inside a leading capital pair (FFmpeg)
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i > 1 && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
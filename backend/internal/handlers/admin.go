@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,18 +10,24 @@ import (
 	"gorm.io/gorm"
 
 	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/middleware"
 	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/utils"
 )
 
 type AdminHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db         *gorm.DB
+	cfg        *config.Config
+	rateLimits *middleware.RateLimitPolicy
+	storage    services.Storage
 }
 
 func NewAdminHandler(db *gorm.DB, cfg *config.Config) *AdminHandler {
 	return &AdminHandler{
-		db:  db,
-		cfg: cfg,
+		db:      db,
+		cfg:     cfg,
+		storage: services.NewLocalStorage(cfg.StoragePath),
 	}
 }
 
@@ -36,6 +43,12 @@ type SystemStats struct {
 	ActualStorageBytes   int64   `json:"actualStorageBytes"`
 	GlobalSavedBytes     int64   `json:"globalSavedBytes"`
 	GlobalSavingsPercent float64 `json:"globalSavingsPercent"`
+
+	// Content in trash, reclaimable once purged
+	TrashedFiles        int64 `json:"trashedFiles"`
+	TrashedBytes        int64 `json:"trashedBytes"`        // logical size of trashed files
+	TrashedContentBytes int64 `json:"trashedContentBytes"` // blobs only trashed files still hold
+	IncludesTrash       bool  `json:"includesTrash"`       // whether the totals above count trashed content
 }
 
 // GetStats returns system statistics
@@ -47,9 +60,20 @@ func (h *AdminHandler) GetStats(c *gin.Context) {
 		stats.TotalUsers = 0
 	}
 
-	// Get total files - handle potential errors
-	if err := h.db.Model(&models.File{}).Where("is_deleted = false").Count(&stats.TotalFiles).Error; err != nil {
-		stats.TotalFiles = 0
+	// Get live and trashed files in one pass - handle potential errors
+	var files struct {
+		Live         int64
+		Trashed      int64
+		TrashedBytes int64
+	}
+	if err := h.db.Model(&models.File{}).Select(
+		"COUNT(*) FILTER (WHERE is_deleted = false) AS live, " +
+			"COUNT(*) FILTER (WHERE is_deleted = true) AS trashed, " +
+			"COALESCE(SUM(size) FILTER (WHERE is_deleted = true), 0) AS trashed_bytes",
+	).Scan(&files).Error; err == nil {
+		stats.TotalFiles = files.Live
+		stats.TrashedFiles = files.Trashed
+		stats.TrashedBytes = files.TrashedBytes
 	}
 
 	// Get total storage used - handle potential errors
@@ -89,40 +113,87 @@ func (h *AdminHandler) GetStats(c *gin.Context) {
 		stats.TotalUploadedBytes = totalUploadedBytes
 	}
 
-	// Sum all users' actual storage bytes
-	if err := h.db.Model(&models.User{}).Select("COALESCE(SUM(actual_storage_bytes), 0)").Scan(&actualStorageBytes).Error; err == nil {
+	// Physical storage is counted per stored blob, since users sharing the
+	// same content are each charged for it. Blobs without references are held
+	// by trashed files until they are purged.
+	var blobs struct {
+		Live    int64
+		Trashed int64
+	}
+	if err := h.db.Model(&models.FileHash{}).Select(
+		"COALESCE(SUM(size) FILTER (WHERE reference_count > 0), 0) AS live, " +
+			"COALESCE(SUM(size) FILTER (WHERE reference_count <= 0), 0) AS trashed",
+	).Scan(&blobs).Error; err == nil {
+		actualStorageBytes = blobs.Live
 		stats.ActualStorageBytes = actualStorageBytes
+		stats.TrashedContentBytes = blobs.Trashed
+	}
+
+	if h.cfg.AdminStatsIncludeTrash {
+		stats.IncludesTrash = true
+		stats.TotalFiles += stats.TrashedFiles
+		stats.TotalStorage += stats.TrashedBytes
+		stats.ActualStorageBytes += stats.TrashedContentBytes
 	}
 
-	// Sum all users' saved bytes
-	if err := h.db.Model(&models.User{}).Select("COALESCE(SUM(saved_bytes), 0)").Scan(&savedBytes).Error; err == nil {
+	// Everything stored logically beyond the physical bytes was saved
+	savedBytes = stats.TotalStorage - stats.ActualStorageBytes
+	if savedBytes > 0 {
 		stats.GlobalSavedBytes = savedBytes
 	}
 
 	// Calculate global savings percentage
-	if stats.TotalUploadedBytes > 0 {
-		stats.GlobalSavingsPercent = (float64(stats.GlobalSavedBytes) / float64(stats.TotalUploadedBytes)) * 100
+	if stats.TotalStorage > 0 {
+		stats.GlobalSavingsPercent = (float64(stats.GlobalSavedBytes) / float64(stats.TotalStorage)) * 100
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
 
-// GetUsers returns a list of users (admin only)
+// GetUsers returns a page of users, oldest accounts first, with how much of
+// their quota each has left (admin only)
+// GET /api/admin/users?page=&page_size=
 func (h *AdminHandler) GetUsers(c *gin.Context) {
-	var users []models.User
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := h.db.Model(&models.User{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count users"})
+		return
+	}
 
-	if err := h.db.Select("id, username, email, first_name, last_name, role, storage_quota, storage_used, is_active, email_verified, last_login, created_at").Find(&users).Error; err != nil {
+	var users []models.User
+	if err := h.db.Select("id, username, email, first_name, last_name, role, storage_quota, storage_used, is_active, email_verified, last_login, created_at").
+		Order("created_at ASC").Order("id ASC").Offset(paging.offset()).Limit(paging.pageSize).Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
 		return
 	}
 
+	listed := make([]adminUser, 0, len(users))
+	for _, user := range users {
+		listed = append(listed, newAdminUser(user))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"users": users,
+		"users":      listed,
+		"pagination": paging.envelope(total),
 	})
 }
 
-// GetAllFiles returns a list of all files in the system (admin only)
+// GetAllFiles returns a list of all files in the system (admin only). Files
+// users have hidden from admins are redacted unless the admin overrides with
+// a reason, which is audited.
 func (h *AdminHandler) GetAllFiles(c *gin.Context) {
+	override := c.Query("override") == "true"
+	reason := strings.TrimSpace(c.Query("reason"))
+	if override && reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to override file privacy"})
+		return
+	}
+
 	var files []models.File
 
 	if err := h.db.Preload("Owner", func(db *gorm.DB) *gorm.DB {
@@ -132,12 +203,42 @@ func (h *AdminHandler) GetAllFiles(c *gin.Context) {
 		return
 	}
 
+	hiddenCount, redactedCount := 0, 0
+	if h.cfg.AdminFilePrivacy {
+		hiddenFolders, err := adminHiddenFolderIDs(h.db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve hidden folders"})
+			return
+		}
+
+		for i := range files {
+			file := &files[i]
+			if !file.HiddenFromAdmin && (file.FolderID == nil || !hiddenFolders[*file.FolderID]) {
+				continue
+			}
+			hiddenCount++
+			if !override {
+				redactFile(file)
+				redactedCount++
+			}
+		}
+	}
+
+	if override {
+		recordAudit(h.db, c, "admin_privacy_override", "file_listing", nil, map[string]interface{}{
+			"reason":       reason,
+			"hidden_files": hiddenCount,
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"files": files,
+		"files":          files,
+		"redacted_count": redactedCount,
 	})
 }
 
 // UpdateUserRole updates a user's role (admin only)
+// PUT /api/admin/users/:id/role
 func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 	userID := c.Param("id")
 
@@ -175,6 +276,9 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
 		return
 	}
+	recordAuditChange(h.db, c, "user_role_update", "user", &user.ID,
+		map[string]interface{}{"role": user.Role},
+		map[string]interface{}{"role": request.Role})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User role updated successfully",
@@ -211,18 +315,63 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Trash the user's files so they release their references, revoking
+	// their shares whatever the deletion policy. Content is never removed
+	// here: other users' files may share the same blobs.
+	trashedFiles := 0
+	if h.cfg.DeleteUserFiles {
+		var files []models.File
+		if err := tx.Where("owner_id = ? AND is_deleted = false", uid).Find(&files).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user files"})
+			return
+		}
+		for i := range files {
+			if _, _, err := trashFile(tx, &files[i], false); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user files"})
+				return
+			}
+		}
+		trashedFiles = len(files)
+	}
+
 	// Soft delete user
-	if err := h.db.Delete(&user).Error; err != nil {
+	if err := tx.Delete(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	recordAudit(h.db, c, "user_delete", "user", &user.ID, map[string]interface{}{
+		"username":      user.Username,
+		"email":         user.Email,
+		"trashed_files": trashedFiles,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "User deleted successfully",
+		"message":       "User deleted successfully",
+		"trashed_files": trashedFiles,
 	})
 }
 
-// GetSystemHealth returns system health information (admin only)
+// GetSystemHealth returns system health information (admin only): besides
+// the database, whether the storage path is writable, the free disk space
+// under it and upload sessions still pending. Counting blobs no content record
+// points at walks the whole storage directory, so it is only done with
+// ?deep=true. The public /health stays minimal.
+// GET /api/admin/health
 func (h *AdminHandler) GetSystemHealth(c *gin.Context) {
 	health := gin.H{
 		"status":    "healthy",
@@ -240,7 +389,42 @@ func (h *AdminHandler) GetSystemHealth(c *gin.Context) {
 		health["status"] = "degraded"
 	}
 
+	// Check the storage path takes writes, the other thing that silently
+	// breaks uploads
+	storage, err := services.StorageWritability(h.cfg.StoragePath)
+	health["storage"] = storage
+	if err != nil {
+		health["storage_error"] = err.Error()
+		health["status"] = "degraded"
+	}
+	if free, total, err := utils.DiskSpace(h.cfg.StoragePath); err == nil {
+		health["disk_free_bytes"] = free
+		health["disk_total_bytes"] = total
+	}
+
+	var pendingSessions int64
+	if err := h.db.Model(&models.UploadSession{}).
+		Where("status = ?", models.UploadSessionPending).
+		Count(&pendingSessions).Error; err == nil {
+		health["pending_upload_sessions"] = pendingSessions
+	}
+	if c.Query("deep") == "true" {
+		if orphaned, err := services.OrphanedBlobs(c.Request.Context(), h.db, h.cfg); err == nil {
+			health["orphaned_blobs"] = orphaned
+		}
+	}
+
 	c.JSON(http.StatusOK, health)
 }
 
+// GetEffectiveConfig returns the configuration the server is running with,
+// secrets redacted (admin only)
+// GET /api/admin/config
+func (h *AdminHandler) GetEffectiveConfig(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"config": h.cfg.Sanitized(),
+	})
+}
+
 var startTime = time.Now()
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// redactedName replaces the names of files hidden from admins
+const redactedName = "[hidden]"
+
+// recordAudit writes an audit log entry for the current request. Failures are
+// logged rather than returned so auditing never blocks the action itself.
+func recordAudit(db *gorm.DB, c *gin.Context, action, resourceType string, resourceID *uuid.UUID, values map[string]interface{}) {
+	recordAuditChange(db, c, action, resourceType, resourceID, nil, values)
+}
+
+// recordAuditChange writes an audit log entry for an update, with the values
+// it replaced alongside the new ones
+func recordAuditChange(db *gorm.DB, c *gin.Context, action, resourceType string, resourceID *uuid.UUID, oldValues, newValues map[string]interface{}) {
+	entry := models.AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uuid.UUID); ok {
+			entry.UserID = &id
+		}
+	}
+	if oldValues != nil {
+		encoded, err := json.Marshal(oldValues)
+		if err != nil {
+			log.Printf("Failed to encode audit values for %s: %v", action, err)
+		} else {
+			entry.OldValues = string(encoded)
+		}
+	}
+	if newValues != nil {
+		encoded, err := json.Marshal(newValues)
+		if err != nil {
+			log.Printf("Failed to encode audit values for %s: %v", action, err)
+		} else {
+			entry.NewValues = string(encoded)
+		}
+	}
+
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record audit log for %s: %v", action, err)
+	}
+}
+
+// adminHiddenFolderIDs returns every folder users have hidden from admins,
+// directly or through a hidden ancestor
+func adminHiddenFolderIDs(db *gorm.DB) (map[uuid.UUID]bool, error) {
+	var hidden []models.Folder
+	if err := db.Select("id, owner_id, path").Where("hidden_from_admin = true").Find(&hidden).Error; err != nil {
+		return nil, err
+	}
+
+	hiddenIDs := make(map[uuid.UUID]bool)
+	if len(hidden) == 0 {
+		return hiddenIDs, nil
+	}
+
+	owners := make([]uuid.UUID, 0, len(hidden))
+	for _, folder := range hidden {
+		owners = append(owners, folder.OwnerID)
+	}
+
+	var folders []models.Folder
+	if err := db.Select("id, owner_id, path").Where("owner_id IN ?", owners).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	for _, folder := range folders {
+		for _, root := range hidden {
+			if folder.OwnerID == root.OwnerID && (folder.ID == root.ID || strings.HasPrefix(folder.Path, root.Path+"/")) {
+				hiddenIDs[folder.ID] = true
+				break
+			}
+		}
+	}
+	return hiddenIDs, nil
+}
+
+// redactFile withholds identifying metadata from a file; sizes and ownership
+// stay so quotas and stats still add up
+func redactFile(file *models.File) {
+	file.Filename = redactedName
+	file.OriginalFilename = redactedName
+	file.Description = ""
+	file.Tags = nil
+	file.Redacted = true
+}
+
+// SetFolderAdminVisibility hides or shows a folder, and everything inside
+// it, in the admin file listing
+func (h *FolderHandler) SetFolderAdminVisibility(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Hidden *bool `json:"hidden" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	folder, err := services.FindFolderWithAccess(h.db, folderID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder"})
+		return
+	}
+
+	if err := h.db.Model(folder).Update("hidden_from_admin", *req.Hidden).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder visibility"})
+		return
+	}
+	folder.HiddenFromAdmin = *req.Hidden
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder visibility updated",
+		"folder":  folder,
+	})
+}
+
+// SetFileAdminVisibility hides or shows a single file in the admin file
+// listing
+func (h *FileHandler) SetFileAdminVisibility(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Hidden *bool `json:"hidden" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	if err := h.db.Model(file).Update("hidden_from_admin", *req.Hidden).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update file visibility"})
+		return
+	}
+	file.HiddenFromAdmin = *req.Hidden
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File visibility updated",
+		"file":    file,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// adminUser is a user in the admin listing, with how much of their quota is
+// left
+type adminUser struct {
+	models.User
+	StorageRemaining   int64   `json:"storageRemaining"`   // never negative, even over quota
+	StorageUsedPercent float64 `json:"storageUsedPercent"` // of the quota, above 100 when over it
+}
+
+// newAdminUser computes the quota fields of a user in the admin listing
+func newAdminUser(user models.User) adminUser {
+	remaining := user.StorageQuota - user.StorageUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	percent := float64(0)
+	if user.StorageQuota > 0 {
+		percent = float64(user.StorageUsed) / float64(user.StorageQuota) * 100
+	} else if user.StorageUsed > 0 {
+		percent = 100
+	}
+	return adminUser{User: user, StorageRemaining: remaining, StorageUsedPercent: percent}
+}
+
+// SetUserQuota changes a user's storage quota, for instance to give a power
+// user more space. The quota can't go below what the user already stores;
+// preview a reduction with the quota impact report and have the user free
+// space first. (admin only)
+// PUT /api/admin/users/:id/quota
+func (h *AdminHandler) SetUserQuota(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		StorageQuota *int64 `json:"storage_quota" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if *req.StorageQuota < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "storage_quota must be a non-negative number of bytes"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Select("id, storage_used, storage_quota").First(&user, "id = ?", uid).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	previousQuota := user.StorageQuota
+
+	// Checked in the update itself so an upload finishing meanwhile can't
+	// leave the user over the new quota
+	result := h.db.Model(&models.User{}).
+		Where("id = ? AND storage_used <= ?", uid, *req.StorageQuota).
+		Update("storage_quota", *req.StorageQuota)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quota"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		h.db.Select("id, storage_used, storage_quota").First(&user, "id = ?", uid)
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "The quota can't be lower than the storage the user already uses",
+			"storage_used":  user.StorageUsed,
+			"storage_quota": user.StorageQuota,
+		})
+		return
+	}
+
+	if err := h.db.Select("id, storage_used, storage_quota").First(&user, "id = ?", uid).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	recordAuditChange(h.db, c, "user_quota_update", "user", &user.ID,
+		map[string]interface{}{"storage_quota": previousQuota},
+		map[string]interface{}{"storage_quota": user.StorageQuota})
+
+	updated := newAdminUser(user)
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Quota updated successfully",
+		"user_id":              user.ID,
+		"storage_quota":        updated.StorageQuota,
+		"storage_used":         updated.StorageUsed,
+		"storage_remaining":    updated.StorageRemaining,
+		"storage_used_percent": updated.StorageUsedPercent,
+	})
+}
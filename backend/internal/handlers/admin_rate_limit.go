@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/middleware"
+	"file-vault-system/backend/internal/models"
+)
+
+// SetRateLimitPolicy sets the policy whose cached limits are refreshed when
+// an override changes
+func (h *AdminHandler) SetRateLimitPolicy(policy *middleware.RateLimitPolicy) {
+	h.rateLimits = policy
+}
+
+// rateLimitResponse describes a user's override and the limit in effect
+func (h *AdminHandler) rateLimitResponse(user *models.User) gin.H {
+	effective := h.rateLimits.Resolve(user)
+	defaults := h.rateLimits.Defaults()
+	return gin.H{
+		"user_id": user.ID,
+		"override": gin.H{
+			"max_requests":   user.RateLimitRequests,
+			"window_seconds": user.RateLimitWindow,
+		},
+		"effective": gin.H{
+			"max_requests":   effective.MaxRequests,
+			"window_seconds": int(effective.Window.Seconds()),
+		},
+		"default": gin.H{
+			"max_requests":   defaults.MaxRequests,
+			"window_seconds": int(defaults.Window.Seconds()),
+		},
+	}
+}
+
+// GetUserRateLimit shows a user's rate limit override (admin only)
+// GET /api/admin/users/:id/rate-limit
+func (h *AdminHandler) GetUserRateLimit(c *gin.Context) {
+	user, ok := h.findRateLimitUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.rateLimitResponse(user))
+}
+
+// SetUserRateLimit overrides a user's rate limit, for instance to give an
+// integration account a higher ceiling. A null or missing field falls back
+// to the global limit for that part. (admin only)
+// PUT /api/admin/users/:id/rate-limit
+func (h *AdminHandler) SetUserRateLimit(c *gin.Context) {
+	user, ok := h.findRateLimitUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MaxRequests   *int `json:"max_requests"`
+		WindowSeconds *int `json:"window_seconds"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if req.MaxRequests != nil && *req.MaxRequests < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_requests must be at least 1"})
+		return
+	}
+	if req.WindowSeconds != nil && *req.WindowSeconds < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window_seconds must be at least 1"})
+		return
+	}
+
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"rate_limit_requests": req.MaxRequests,
+		"rate_limit_window":   req.WindowSeconds,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate limit"})
+		return
+	}
+	user.RateLimitRequests = req.MaxRequests
+	user.RateLimitWindow = req.WindowSeconds
+	h.rateLimits.Invalidate(user.ID)
+
+	recordAudit(h.db, c, "user_rate_limit_update", "user", &user.ID, map[string]interface{}{
+		"max_requests":   req.MaxRequests,
+		"window_seconds": req.WindowSeconds,
+	})
+
+	c.JSON(http.StatusOK, h.rateLimitResponse(user))
+}
+
+// findRateLimitUser loads the user named in the URL
+func (h *AdminHandler) findRateLimitUser(c *gin.Context) (*models.User, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.Select("id, rate_limit_requests, rate_limit_window").First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// ListEndpointRateLimits lists the per-endpoint rate limits and the global
+// limit used for endpoints none of them match (admin only)
+// GET /api/admin/rate-limits
+func (h *AdminHandler) ListEndpointRateLimits(c *gin.Context) {
+	var limits []models.RateLimitConfig
+	if err := h.db.Order("endpoint_pattern").Find(&limits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rate limits"})
+		return
+	}
+
+	defaults := h.rateLimits.Defaults()
+	c.JSON(http.StatusOK, gin.H{
+		"limits": limits,
+		"default": gin.H{
+			"max_requests":   defaults.MaxRequests,
+			"window_seconds": int(defaults.Window.Seconds()),
+		},
+	})
+}
+
+// UpdateEndpointRateLimits creates or changes per-endpoint rate limits and
+// applies them without a restart. Each pattern is matched as a prefix of the
+// request path, e.g. /api/v1/files/upload. (admin only)
+// PUT /api/admin/rate-limits
+func (h *AdminHandler) UpdateEndpointRateLimits(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Limits []struct {
+			EndpointPattern string `json:"endpoint_pattern" binding:"required"`
+			MaxRequests     int    `json:"max_requests" binding:"required,min=1"`
+			WindowSeconds   int    `json:"window_seconds" binding:"required,min=1"`
+		} `json:"limits" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	updatedBy := adminID.(uuid.UUID)
+	limits := make([]models.RateLimitConfig, 0, len(req.Limits))
+	for _, limit := range req.Limits {
+		if !strings.HasPrefix(limit.EndpointPattern, "/") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "endpoint_pattern must start with /", "endpoint_pattern": limit.EndpointPattern})
+			return
+		}
+		limits = append(limits, models.RateLimitConfig{
+			EndpointPattern: limit.EndpointPattern,
+			MaxRequests:     limit.MaxRequests,
+			WindowSeconds:   limit.WindowSeconds,
+			UpdatedBy:       &updatedBy,
+		})
+	}
+
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint_pattern"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_requests", "window_seconds", "updated_by", "updated_at"}),
+	}).Create(&limits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate limits"})
+		return
+	}
+	h.rateLimits.InvalidateEndpoints()
+
+	for _, limit := range limits {
+		recordAudit(h.db, c, "endpoint_rate_limit_update", "rate_limit", nil, map[string]interface{}{
+			"endpoint_pattern": limit.EndpointPattern,
+			"max_requests":     limit.MaxRequests,
+			"window_seconds":   limit.WindowSeconds,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"limits": limits})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// activeSharesQuery lists every active share link, file share and folder
+// share in one shape, with the target's name and whether it still exists.
+// A target that was trashed or deleted counts as gone.
+const activeSharesQuery = `
+	SELECT 'link' AS type, sl.id, sl.created_by AS owner_id, u.username AS owner_username,
+		CASE WHEN sl.file_id IS NOT NULL THEN 'file' ELSE 'folder' END AS target_type,
+		COALESCE(sl.file_id, sl.folder_id) AS target_id,
+		COALESCE(f.original_filename, fo.name, '') AS target_name,
+		CASE WHEN sl.file_id IS NOT NULL THEN f.id IS NOT NULL AND f.deleted_at IS NULL
+			ELSE fo.id IS NOT NULL AND fo.deleted_at IS NULL END AS target_alive,
+		NULL::uuid AS shared_with, NULL AS shared_with_username, sl.permission,
+		COALESCE(sl.password_hash, '') <> '' AS password_protected, sl.expires_at, sl.created_at
+	FROM share_links sl
+	JOIN users u ON u.id = sl.created_by
+	LEFT JOIN files f ON f.id = sl.file_id
+	LEFT JOIN folders fo ON fo.id = sl.folder_id
+	WHERE sl.is_active = true AND sl.deleted_at IS NULL
+	UNION ALL
+	SELECT 'file', fs.id, fs.shared_by, u.username, 'file', fs.file_id,
+		COALESCE(f.original_filename, ''), f.id IS NOT NULL AND f.deleted_at IS NULL,
+		fs.shared_with, sw.username, fs.permission, false, fs.expires_at, fs.created_at
+	FROM file_shares fs
+	JOIN users u ON u.id = fs.shared_by
+	LEFT JOIN users sw ON sw.id = fs.shared_with
+	LEFT JOIN files f ON f.id = fs.file_id
+	WHERE fs.is_active = true AND fs.deleted_at IS NULL
+	UNION ALL
+	SELECT 'folder', fsh.id, fsh.shared_by, u.username, 'folder', fsh.folder_id,
+		COALESCE(fo.name, ''), fo.id IS NOT NULL AND fo.deleted_at IS NULL,
+		fsh.shared_with, sw.username, fsh.permission, false, fsh.expires_at, fsh.created_at
+	FROM folder_shares fsh
+	JOIN users u ON u.id = fsh.shared_by
+	LEFT JOIN users sw ON sw.id = fsh.shared_with
+	LEFT JOIN folders fo ON fo.id = fsh.folder_id
+	WHERE fsh.is_active = true AND fsh.deleted_at IS NULL`
+
+// adminShare is one active share as listed for admins
+type adminShare struct {
+	Type               string     `json:"type"` // link, file or folder
+	ID                 uuid.UUID  `json:"id"`
+	OwnerID            uuid.UUID  `json:"owner_id"`
+	OwnerUsername      string     `json:"owner_username"`
+	TargetType         string     `json:"target_type"`
+	TargetID           uuid.UUID  `json:"target_id"`
+	TargetName         string     `json:"target_name"`
+	TargetAlive        bool       `json:"target_alive"`
+	SharedWith         *uuid.UUID `json:"shared_with,omitempty"`
+	SharedWithUsername *string    `json:"shared_with_username,omitempty"`
+	Permission         string     `json:"permission"`
+	PasswordProtected  bool       `json:"password_protected"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	Expired            bool       `json:"expired"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// shareFilter narrows the active shares listed or revoked
+type shareFilter struct {
+	Type    string      `json:"type" form:"type"`         // link, file or folder
+	OwnerID string      `json:"owner_id" form:"owner_id"` // user who created the share
+	Expiry  string      `json:"expiry" form:"expiry"`     // expired or unexpired
+	Target  string      `json:"target" form:"target"`     // alive or gone
+	IDs     []uuid.UUID `json:"ids" form:"-"`             // revoke only these shares
+}
+
+// where builds the conditions for the filter, or an error message when a
+// value is invalid
+func (f shareFilter) where(now time.Time) (string, []interface{}, string) {
+	var conditions []string
+	var args []interface{}
+
+	switch f.Type {
+	case "":
+	case "link", "file", "folder":
+		conditions = append(conditions, "type = ?")
+		args = append(args, f.Type)
+	default:
+		return "", nil, "type must be link, file or folder"
+	}
+	if f.OwnerID != "" {
+		ownerID, err := uuid.Parse(f.OwnerID)
+		if err != nil {
+			return "", nil, "Invalid owner_id"
+		}
+		conditions = append(conditions, "owner_id = ?")
+		args = append(args, ownerID)
+	}
+	switch f.Expiry {
+	case "":
+	case "expired":
+		conditions = append(conditions, "expires_at IS NOT NULL AND expires_at <= ?")
+		args = append(args, now)
+	case "unexpired":
+		conditions = append(conditions, "(expires_at IS NULL OR expires_at > ?)")
+		args = append(args, now)
+	default:
+		return "", nil, "expiry must be expired or unexpired"
+	}
+	switch f.Target {
+	case "":
+	case "alive":
+		conditions = append(conditions, "target_alive")
+	case "gone":
+		conditions = append(conditions, "NOT target_alive")
+	default:
+		return "", nil, "target must be alive or gone"
+	}
+	if len(f.IDs) > 0 {
+		conditions = append(conditions, "id IN ?")
+		args = append(args, f.IDs)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, ""
+}
+
+// ListActiveShares lists active share links and user shares across the
+// system for security review, filterable by type, owner, expiry and whether
+// the target still exists (admin only)
+// GET /api/admin/shares
+func (h *AdminHandler) ListActiveShares(c *gin.Context) {
+	page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	var filter shareFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filter", "details": err.Error()})
+		return
+	}
+	now := time.Now()
+	where, args, invalid := filter.where(now)
+	if invalid != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid})
+		return
+	}
+
+	var total int64
+	if err := h.db.Raw("SELECT COUNT(*) FROM ("+activeSharesQuery+") shares"+where, args...).Scan(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count shares"})
+		return
+	}
+
+	shares := []adminShare{}
+	if err := h.db.Raw("SELECT * FROM ("+activeSharesQuery+") shares"+where+" ORDER BY created_at DESC, id LIMIT ? OFFSET ?",
+		append(args, page.pageSize, page.offset())...).Scan(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list shares"})
+		return
+	}
+	for i := range shares {
+		shares[i].Expired = shares[i].ExpiresAt != nil && !shares[i].ExpiresAt.After(now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shares":     shares,
+		"pagination": page.envelope(total),
+	})
+}
+
+// RevokeActiveShares revokes every active share matching the filters, or
+// the listed share IDs, for incident response. At least one filter or ID is
+// required so a bare request can't revoke every share. (admin only)
+// POST /api/admin/shares/revoke
+func (h *AdminHandler) RevokeActiveShares(c *gin.Context) {
+	var filter shareFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	where, args, invalid := filter.where(time.Now())
+	if invalid != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid})
+		return
+	}
+	if where == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one filter or share ID is required"})
+		return
+	}
+
+	var matched []struct {
+		Type string
+		ID   uuid.UUID
+	}
+	revoked := map[string][]uuid.UUID{}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT type, id FROM ("+activeSharesQuery+") shares"+where, args...).Scan(&matched).Error; err != nil {
+			return err
+		}
+		for _, share := range matched {
+			revoked[share.Type] = append(revoked[share.Type], share.ID)
+		}
+		return services.RevokeSharesByID(tx, revoked["link"], revoked["file"], revoked["folder"])
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke shares"})
+		return
+	}
+
+	counts := gin.H{
+		"links":         len(revoked["link"]),
+		"file_shares":   len(revoked["file"]),
+		"folder_shares": len(revoked["folder"]),
+	}
+	recordAudit(h.db, c, "admin_share_revoke", "share", nil, map[string]interface{}{
+		"filter":  filter,
+		"revoked": counts,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shares revoked successfully",
+		"revoked": counts,
+		"total":   len(matched),
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/internal/testdb"
+)
+
+func TestDeletingUserKeepsContentOthersHold(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	h.cfg.DeleteUserFiles = true
+	first := createTestUser(t, db, 10000)
+	second := createTestUser(t, db, 10000)
+
+	content := uniqueContent(1000)
+	decodeUpload(t, uploadAs(t, h, first.ID, testUpload{"original.bin", content}))
+	kept := decodeUpload(t, uploadAs(t, h, second.ID, testUpload{"copy.bin", content})).Files[0]
+
+	router := gin.New()
+	router.DELETE("/admin/users/:id", NewAdminHandler(db, h.cfg).DeleteUser)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/admin/users/"+first.ID.String(), nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("delete user status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+
+	// Emptying the deleted user's trash must not take the content with it
+	var trashed []models.File
+	if err := db.Where("owner_id = ?", first.ID).Find(&trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 1 || !trashed[0].IsDeleted {
+		t.Fatalf("deleted user has %d files, want 1 in trash", len(trashed))
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		orphaned, err := services.PurgeFileRecord(tx, &trashed[0])
+		if len(orphaned) != 0 {
+			t.Errorf("purging the deleted user's file orphaned %d content records, want 0", len(orphaned))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("PurgeFileRecord: %v", err)
+	}
+
+	if fileHash := loadContent(t, db, content); fileHash.ReferenceCount != 1 {
+		t.Errorf("reference count = %d, want 1", fileHash.ReferenceCount)
+	}
+
+	router = gin.New()
+	router.GET("/files/:id/download", func(c *gin.Context) {
+		c.Set("user_id", second.ID)
+		c.Next()
+	}, h.DownloadFile)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/files/"+kept.FileID.String()+"/download", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("download status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), content) {
+		t.Errorf("downloaded %d bytes that differ from the uploaded content", recorder.Body.Len())
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// transferBatchSize is how many files are loaded at a time when transferring
+// all of a user's content
+const transferBatchSize = 500
+
+// TransferAllContent reassigns every file and folder of a user to another
+// user, typically when offboarding. The source user's folder tree is kept
+// intact under a top-level folder named after them in the target's account,
+// along with their loose root files; a later transfer from the same user
+// reuses that folder. Everything moves in one transaction, each file moving
+// its storage accounting from source to target, so a failed transfer changes
+// nothing (admin only).
+// POST /api/admin/users/:id/transfer-all
+func (h *AdminHandler) TransferAllContent(c *gin.Context) {
+	sourceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		TargetUserID uuid.UUID `json:"target_user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if req.TargetUserID == sourceID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and target must be different users"})
+		return
+	}
+
+	var source, target models.User
+	if err := h.db.First(&source, "id = ?", sourceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := h.db.First(&target, "id = ?", req.TargetUserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target user not found"})
+		return
+	}
+
+	// The target takes on the logical size of every live file
+	var live struct {
+		Files int64
+		Bytes int64
+	}
+	if err := h.db.Model(&models.File{}).
+		Select("COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+		Where("owner_id = ? AND is_deleted = false", sourceID).
+		Scan(&live).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to measure user content"})
+		return
+	}
+	available := target.StorageQuota - target.StorageUsed
+	if available < 0 {
+		available = 0
+	}
+	if live.Bytes > available {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         "Target user doesn't have enough storage quota for the transfer",
+			"total_size":    live.Bytes,
+			"storage_used":  target.StorageUsed,
+			"storage_quota": target.StorageQuota,
+			"available":     available,
+		})
+		return
+	}
+
+	var remaining int64
+	if err := h.db.Model(&models.File{}).Where("owner_id = ?", sourceID).Count(&remaining).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count user files"})
+		return
+	}
+	var folderCount int64
+	if err := h.db.Unscoped().Model(&models.Folder{}).Where("owner_id = ?", sourceID).Count(&folderCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count user folders"})
+		return
+	}
+	if remaining == 0 && folderCount == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "User has no content to transfer",
+			"files_transferred":   0,
+			"folders_transferred": 0,
+		})
+		return
+	}
+
+	var container models.Folder
+	filesMoved, bytesMoved := 0, int64(0)
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		container, err = transferFolder(tx, &source, target.ID)
+		if err != nil {
+			return err
+		}
+		if err := transferFolders(tx, sourceID, &container); err != nil {
+			return err
+		}
+
+		// Files that changed hands no longer match, so each batch is the
+		// next one
+		for {
+			var batch []models.File
+			if err := tx.Where("owner_id = ?", sourceID).Order("id").Limit(transferBatchSize).Find(&batch).Error; err != nil {
+				return fmt.Errorf("failed to get files: %v", err)
+			}
+			for i := range batch {
+				if err := transferFile(tx, &batch[i], target.ID, container.ID); err != nil {
+					return err
+				}
+				if !batch[i].IsDeleted {
+					bytesMoved += batch[i].Size
+				}
+			}
+			filesMoved += len(batch)
+			if len(batch) < transferBatchSize {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer content", "details": err.Error()})
+		return
+	}
+
+	recordAudit(h.db, c, "user_transfer_all", "user", &source.ID, map[string]interface{}{
+		"target_user_id":      target.ID,
+		"container_folder_id": container.ID,
+		"files_transferred":   filesMoved,
+		"folders_transferred": folderCount,
+		"bytes_transferred":   bytesMoved,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Content transferred successfully",
+		"target_user_id":      target.ID,
+		"container_folder":    container,
+		"files_transferred":   filesMoved,
+		"folders_transferred": folderCount,
+		"bytes_transferred":   bytesMoved,
+	})
+}
+
+// transferFolder returns the top-level folder in the target's account that a
+// transferred user's content is placed under. The folder is marked with the
+// source user, so a later transfer finds it again; a new one is named after
+// the source user and numbered if the name is taken.
+func transferFolder(tx *gorm.DB, source *models.User, targetID uuid.UUID) (models.Folder, error) {
+	var existing models.Folder
+	err := tx.Where("owner_id = ? AND parent_id IS NULL AND transferred_from = ?", targetID, source.ID).
+		First(&existing).Error
+	if err == nil {
+		return existing, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return models.Folder{}, fmt.Errorf("failed to find transfer folder: %v", err)
+	}
+
+	base := sanitizeFolderName(source.Username)
+	if base == "" {
+		base = "user-" + source.ID.String()
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.Folder{}).
+			Where("owner_id = ? AND parent_id IS NULL AND name = ?", targetID, name).
+			Count(&count).Error; err != nil {
+			return models.Folder{}, fmt.Errorf("failed to check existing folders: %v", err)
+		}
+		if count == 0 {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+
+	folder := models.Folder{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		Name:            name,
+		OwnerID:         targetID,
+		Path:            "/" + name,
+		TransferredFrom: &source.ID,
+	}
+	if err := tx.Create(&folder).Error; err != nil {
+		return models.Folder{}, fmt.Errorf("failed to create folder: %v", err)
+	}
+	return folder, nil
+}
+
+// transferFolders moves all of a user's folders, including trashed ones,
+// under container, keeping their structure
+func transferFolders(tx *gorm.DB, sourceID uuid.UUID, container *models.Folder) error {
+	if err := tx.Unscoped().Model(&models.Folder{}).
+		Where("owner_id = ? AND parent_id IS NULL", sourceID).
+		Update("parent_id", container.ID).Error; err != nil {
+		return fmt.Errorf("failed to move top-level folders: %v", err)
+	}
+
+	if err := tx.Unscoped().Model(&models.Folder{}).
+		Where("owner_id = ?", sourceID).
+		Updates(map[string]interface{}{
+			"owner_id": container.OwnerID,
+			"path":     gorm.Expr("? || path", container.Path),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to reassign folders: %v", err)
+	}
+	return nil
+}
+
+// transferFile hands one file to the target user, moving it into container
+// if it sat at the source's root. Live files move their storage usage with
+// them; trashed files change hands uncharged.
+func transferFile(tx *gorm.DB, file *models.File, targetID, containerID uuid.UUID) error {
+	if !file.IsDeleted {
+		if _, err := accountFileRemoved(tx, file); err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{"owner_id": targetID}
+	if file.FolderID == nil {
+		updates["folder_id"] = containerID
+		file.FolderID = &containerID
+	}
+	if err := tx.Model(file).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to reassign file %s: %v", file.ID, err)
+	}
+	file.OwnerID = targetID
+
+	if !file.IsDeleted {
+		if _, err := accountFileAdded(tx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
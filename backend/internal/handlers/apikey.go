@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+type APIKeyHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewAPIKeyHandler(db *gorm.DB, cfg *config.Config) *APIKeyHandler {
+	return &APIKeyHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// CreateAPIKey issues a new API key for the authenticated user. The plaintext
+// key is only returned once.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key name must be between 1 and 100 characters"})
+		return
+	}
+
+	rawKey, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API key"})
+		return
+	}
+
+	apiKey := models.APIKey{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		UserID:    userID.(uuid.UUID),
+		Name:      name,
+		KeyPrefix: prefix,
+		KeyHash:   utils.HashAPIKey(rawKey),
+	}
+
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully",
+		"api_key": apiKey,
+		"key":     rawKey,
+	})
+}
+
+// ListAPIKeys lists the authenticated user's API keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var apiKeys []models.APIKey
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": apiKeys,
+		"count":    len(apiKeys),
+	})
+}
+
+// ListAPIKeyFiles lists the non-deleted files uploaded with a given API key
+func (h *APIKeyHandler) ListAPIKeyFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	apiKey, ok := h.findOwnedAPIKey(c, userID)
+	if !ok {
+		return
+	}
+
+	var files []models.File
+	if err := h.db.Where("owner_id = ? AND api_key_id = ? AND is_deleted = false", userID, apiKey.ID).
+		Preload("Folder").Order("created_at DESC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_key": apiKey,
+		"files":   files,
+		"count":   len(files),
+	})
+}
+
+// RevokeAPIKey revokes an API key. With ?delete_files=true every file the key
+// uploaded is deleted in the same transaction, as DeleteFile would.
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	apiKey, ok := h.findOwnedAPIKey(c, userID)
+	if !ok {
+		return
+	}
+
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key is already revoked"})
+		return
+	}
+
+	deleteFiles := c.Query("delete_files") == "true"
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Model(apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	var deletedCount int
+	var logicalStorageFreed, actualStorageFreed int64
+	var revokedShares fileShareCounts
+	if deleteFiles {
+		var files []models.File
+		if err := tx.Where("owner_id = ? AND api_key_id = ? AND is_deleted = false", userID, apiKey.ID).Find(&files).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files uploaded by API key"})
+			return
+		}
+
+		for i := range files {
+			freed, revoked, err := trashFile(tx, &files[i], blocksSharedFileDeletion(h.cfg))
+			var shared *fileSharedError
+			if errors.As(err, &shared) {
+				tx.Rollback()
+				c.JSON(http.StatusConflict, gin.H{
+					"error":       "A file uploaded by the API key has active shares, revoke them before deleting it",
+					"file_id":     shared.fileID,
+					"share_links": shared.shares.links,
+					"user_shares": shared.shares.users,
+					"acl_entries": shared.shares.aclEntries,
+				})
+				return
+			} else if err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to delete files uploaded by API key",
+					"file_id": files[i].ID,
+					"details": err.Error(),
+				})
+				return
+			}
+			deletedCount++
+			logicalStorageFreed += files[i].Size
+			actualStorageFreed += freed
+			revokedShares.links += revoked.links
+			revokedShares.users += revoked.users
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	response := gin.H{
+		"message": "API key revoked successfully",
+	}
+	if deleteFiles {
+		response["deleted_files_count"] = deletedCount
+		response["logical_storage_freed"] = logicalStorageFreed
+		response["actual_storage_freed"] = actualStorageFreed
+		response["revoked_share_links"] = revokedShares.links
+		response["revoked_user_shares"] = revokedShares.users
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// findOwnedAPIKey loads the API key in the :id param, writing an error
+// response and returning false when it doesn't exist or isn't the caller's
+func (h *APIKeyHandler) findOwnedAPIKey(c *gin.Context, userID interface{}) (*models.APIKey, bool) {
+	apiKeyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return nil, false
+	}
+
+	var apiKey models.APIKey
+	if err := h.db.Where("id = ? AND user_id = ?", apiKeyID, userID).First(&apiKey).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API key"})
+		return nil, false
+	}
+
+	return &apiKey, true
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// auditLogEntry is an audit log entry as admins see it, with the values it
+// recorded passed through as JSON
+type auditLogEntry struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       *uuid.UUID      `json:"user_id,omitempty"`
+	Username     string          `json:"username,omitempty"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   *uuid.UUID      `json:"resource_id,omitempty"`
+	OldValues    json.RawMessage `json:"old_values,omitempty"`
+	NewValues    json.RawMessage `json:"new_values,omitempty"`
+	IPAddress    string          `json:"ip_address,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// ListAuditLogs returns a page of audit log entries, newest first, filtered
+// by the user who acted, the action, the resource and a date range
+// (admin only)
+// GET /api/admin/audit-logs?user_id=&action=&resource_type=&resource_id=&from=&to=&page=&page_size=
+func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.AuditLog{})
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		query = query.Where("audit_logs.user_id = ?", userID)
+	}
+	if value := c.Query("action"); value != "" {
+		query = query.Where("audit_logs.action = ?", value)
+	}
+	if value := c.Query("resource_type"); value != "" {
+		query = query.Where("audit_logs.resource_type = ?", value)
+	}
+	if value := c.Query("resource_id"); value != "" {
+		resourceID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resource ID"})
+			return
+		}
+		query = query.Where("audit_logs.resource_id = ?", resourceID)
+	}
+
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		from = &parsed
+		query = query.Where("audit_logs.created_at >= ?", parsed)
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		to = &parsed
+		query = query.Where("audit_logs.created_at < ?", parsed)
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count audit logs"})
+		return
+	}
+
+	var rows []struct {
+		ID           uuid.UUID
+		UserID       *uuid.UUID
+		Username     string
+		Action       string
+		ResourceType string
+		ResourceID   *uuid.UUID
+		OldValues    string
+		NewValues    string
+		IPAddress    string
+		UserAgent    string
+		CreatedAt    time.Time
+	}
+	if err := query.
+		Select("audit_logs.id, audit_logs.user_id, COALESCE(users.username, '') AS username, " +
+			"audit_logs.action, audit_logs.resource_type, audit_logs.resource_id, " +
+			"COALESCE(audit_logs.old_values::text, '') AS old_values, COALESCE(audit_logs.new_values::text, '') AS new_values, " +
+			"COALESCE(host(audit_logs.ip_address), '') AS ip_address, COALESCE(audit_logs.user_agent, '') AS user_agent, " +
+			"audit_logs.created_at").
+		Joins("LEFT JOIN users ON users.id = audit_logs.user_id").
+		Order("audit_logs.created_at DESC").Order("audit_logs.id DESC").
+		Offset(paging.offset()).Limit(paging.pageSize).
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit logs"})
+		return
+	}
+
+	entries := make([]auditLogEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := auditLogEntry{
+			ID:           row.ID,
+			UserID:       row.UserID,
+			Username:     row.Username,
+			Action:       row.Action,
+			ResourceType: row.ResourceType,
+			ResourceID:   row.ResourceID,
+			IPAddress:    row.IPAddress,
+			UserAgent:    row.UserAgent,
+			CreatedAt:    row.CreatedAt,
+		}
+		if row.OldValues != "" {
+			entry.OldValues = json.RawMessage(row.OldValues)
+		}
+		if row.NewValues != "" {
+			entry.NewValues = json.RawMessage(row.NewValues)
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": entries,
+		"pagination": paging.envelope(total),
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
@@ -13,17 +14,22 @@ import (
 	"file-vault-system/backend/internal/config"
 	"file-vault-system/backend/internal/middleware"
 	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/utils"
 )
 
 type AuthHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db            *gorm.DB
+	cfg           *config.Config
+	mailer        services.Mailer
+	notifications *services.NotificationHub
 }
 
 func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		cfg:    cfg,
+		mailer: services.NewMailer(cfg),
 	}
 }
 
@@ -41,8 +47,9 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	User         models.User `json:"user"`
 }
 
 // Register handles user registration
@@ -96,19 +103,45 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		})
 	}
 
+	// Send the link that verifies the email address; the account is created
+	// either way and the user can ask for another link
+	verificationToken, err := h.issueVerificationToken(h.db, user.ID, true)
+	if err == nil {
+		err = h.sendVerificationEmail(&user, verificationToken)
+	}
+	if err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
+	if h.requiresVerification(&user) {
+		user.PasswordHash = ""
+		c.JSON(http.StatusCreated, gin.H{
+			"message":               "Account created, verify your email address before logging in",
+			"verification_required": true,
+			"user":                  user,
+		})
+		return
+	}
+
 	// Generate JWT token
 	token, err := h.generateToken(user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	refreshToken, err := h.issueRefreshToken(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
 
 	// Remove password hash from response
 	user.PasswordHash = ""
 
 	c.JSON(http.StatusCreated, AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         user,
 	})
 }
 
@@ -139,6 +172,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.rejectUnverified(c, &user) {
+		return
+	}
+
 	// Update last login
 	now := time.Now()
 	h.db.Model(&user).Update("last_login", now)
@@ -150,20 +187,48 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	refreshToken, err := h.issueRefreshToken(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
 
 	// Remove password hash from response
 	user.PasswordHash = ""
 
 	c.JSON(http.StatusOK, AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         user,
 	})
 }
 
-// Logout handles user logout
+// Logout handles user logout, revoking the refresh token sent with it.
+// Access tokens stay valid until they expire.
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// In a real application, you might want to blacklist the token
-	// For now, we'll just return a success message
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The body is optional, clients without a refresh token send none
+	_ = c.ShouldBindJSON(&req)
+
+	if req.RefreshToken != "" {
+		if err := h.db.Where("user_id = ? AND token_hash = ?", userID, utils.HashToken(req.RefreshToken)).
+			Delete(&models.RefreshToken{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke refresh token"})
+			return
+		}
+	}
+
+	// Close the live notification connections opened with this access token
+	h.notifications.Disconnect(userID.(uuid.UUID), utils.HashToken(middleware.BearerToken(c)))
+
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
@@ -196,6 +261,57 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// Sudo issues a short-lived token confirming the user just re-entered their
+// password, accepted by sensitive operations in the X-Sudo-Token header
+func (h *AuthHandler) Sudo(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Verify password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(h.cfg.SudoTokenTTL) * time.Minute)
+	claims := &middleware.JWTClaims{
+		UserID:  user.ID,
+		Purpose: middleware.SudoTokenPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.cfg.JWTSecret))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sudo_token": token,
+		"expires_at": expiresAt,
+	})
+}
+
 // generateToken creates a JWT token for the user
 func (h *AuthHandler) generateToken(userID uuid.UUID) (string, error) {
 	// Get user roles for the token
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// errInvalidRefreshToken rejects refresh tokens that are unknown, already
+// used, expired or belong to a disabled account
+var errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// issueRefreshToken stores a new refresh token for the user and returns it.
+// The user's expired tokens are cleared out on the way.
+func (h *AuthHandler) issueRefreshToken(tx *gorm.DB, userID uuid.UUID) (string, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	now := time.Now()
+	if err := tx.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return "", fmt.Errorf("failed to clear expired refresh tokens: %v", err)
+	}
+	if err := tx.Create(&models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(time.Duration(h.cfg.RefreshTokenExpiration) * time.Hour),
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %v", err)
+	}
+	return token, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. The old refresh token is deleted in the same step, so a token can
+// only be used once and two concurrent refreshes can't both succeed.
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	var refreshToken string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var used models.RefreshToken
+		result := tx.Clauses(clause.Returning{}).
+			Where("token_hash = ?", utils.HashToken(req.RefreshToken)).
+			Delete(&used)
+		if result.Error != nil {
+			return fmt.Errorf("failed to use refresh token: %v", result.Error)
+		}
+		if result.RowsAffected == 0 || used.ExpiresAt.Before(time.Now()) {
+			return errInvalidRefreshToken
+		}
+
+		if err := tx.Where("id = ?", used.UserID).First(&user).Error; err == gorm.ErrRecordNotFound {
+			return errInvalidRefreshToken
+		} else if err != nil {
+			return fmt.Errorf("failed to get user: %v", err)
+		}
+		if !user.IsActive || h.requiresVerification(&user) {
+			return errInvalidRefreshToken
+		}
+
+		var err error
+		refreshToken, err = h.issueRefreshToken(tx, user.ID)
+		return err
+	})
+	if errors.Is(err, errInvalidRefreshToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	} else if err != nil {
+		log.Printf("Token refresh failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	token, err := h.generateToken(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	// Remove password hash from response
+	user.PasswordHash = ""
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         user,
+	})
+}
+
+// RevokeSessions signs the user out everywhere by revoking all their refresh
+// tokens and closing their notification connections. Access tokens already
+// issued stay valid until they expire.
+// POST /api/v1/auth/sessions/revoke
+func (h *AuthHandler) RevokeSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := h.db.Where("user_id = ?", userID).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	h.notifications.Disconnect(userID.(uuid.UUID), "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Sessions revoked successfully",
+		"revoked_sessions": result.RowsAffected,
+	})
+}
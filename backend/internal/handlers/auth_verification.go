@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// errVerificationRateLimited rejects a verification email sent too soon
+// after the previous one
+var errVerificationRateLimited = errors.New("verification email sent too recently")
+
+// requiresVerification reports whether a user must verify their email before
+// logging in. Admins are exempt so an instance can't lock out its operators.
+func (h *AuthHandler) requiresVerification(user *models.User) bool {
+	return h.cfg.RequireVerifiedEmail && !user.EmailVerified && user.Role != models.RoleAdmin
+}
+
+// rejectUnverified responds with 403 when the user still has to verify their
+// email address
+func (h *AuthHandler) rejectUnverified(c *gin.Context, user *models.User) bool {
+	if !h.requiresVerification(user) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": "Email address not verified, follow the link sent to it or request a new one",
+		"code":  "email_not_verified",
+	})
+	return true
+}
+
+// issueVerificationToken replaces the user's verification token with a new
+// one and returns it. Unless force is set, it refuses while the previous
+// token is younger than the resend interval.
+func (h *AuthHandler) issueVerificationToken(tx *gorm.DB, userID uuid.UUID, force bool) (string, error) {
+	// Lock the user so concurrent requests can't both pass the interval check
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, "id = ?", userID).Error; err != nil {
+		return "", fmt.Errorf("failed to lock user: %v", err)
+	}
+
+	now := time.Now()
+	if !force {
+		var recent int64
+		if err := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND created_at > ?", userID, now.Add(-time.Duration(h.cfg.VerificationResendInterval)*time.Second)).
+			Count(&recent).Error; err != nil {
+			return "", fmt.Errorf("failed to check previous verification email: %v", err)
+		}
+		if recent > 0 {
+			return "", errVerificationRateLimited
+		}
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %v", err)
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.EmailVerificationToken{}).Error; err != nil {
+		return "", fmt.Errorf("failed to replace verification token: %v", err)
+	}
+	if err := tx.Create(&models.EmailVerificationToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(time.Duration(h.cfg.EmailVerificationTTL) * time.Hour),
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to store verification token: %v", err)
+	}
+	return token, nil
+}
+
+// sendVerificationEmail mails the link that verifies the user's address
+func (h *AuthHandler) sendVerificationEmail(user *models.User, token string) error {
+	link := strings.TrimSuffix(h.cfg.PublicURL, "/") + "/api/v1/auth/verify-email?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\n"+
+		"The link expires in %d hours. If you didn't create an account, ignore this email.\n",
+		user.Username, link, h.cfg.EmailVerificationTTL)
+	return h.mailer.Send(user.Email, "Verify your email address", body)
+}
+
+// VerifyEmail marks the user's email as verified using the token from the
+// link they were sent
+// GET /api/auth/verify-email?token=
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	var verification models.EmailVerificationToken
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", utils.HashToken(token)).First(&verification).Error; err != nil {
+			return err
+		}
+		if verification.ExpiresAt.Before(time.Now()) {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", verification.UserID).
+			Update("email_verified", true).Error; err != nil {
+			return fmt.Errorf("failed to verify email: %v", err)
+		}
+		return tx.Where("user_id = ?", verification.UserID).Delete(&models.EmailVerificationToken{}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link, request a new one"})
+		return
+	} else if err != nil {
+		log.Printf("Email verification failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification link, at most once per resend
+// interval per user. The response doesn't say whether the account exists.
+// POST /api/auth/resend-verification
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sent := gin.H{"message": "If the account exists and isn't verified yet, a verification email has been sent"}
+
+	var user models.User
+	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusOK, sent)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	if user.EmailVerified || !user.IsActive {
+		c.JSON(http.StatusOK, sent)
+		return
+	}
+
+	var token string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, err = h.issueVerificationToken(tx, user.ID, false)
+		return err
+	})
+	if errors.Is(err, errVerificationRateLimited) {
+		c.Header("Retry-After", strconv.Itoa(h.cfg.VerificationResendInterval))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "A verification email was sent recently, try again later",
+			"retry_after": h.cfg.VerificationResendInterval,
+		})
+		return
+	} else if err != nil {
+		log.Printf("Failed to issue verification token for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	if err := h.sendVerificationEmail(&user, token); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sent)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// SetStorage sets where content blobs are kept
+func (h *FileHandler) SetStorage(storage services.Storage) {
+	h.storage = storage
+}
+
+// SetStorage sets where content blobs are kept
+func (h *SharingHandler) SetStorage(storage services.Storage) {
+	h.storage = storage
+}
+
+// SetStorage sets where content blobs are kept
+func (h *AdminHandler) SetStorage(storage services.Storage) {
+	h.storage = storage
+}
+
+// localBlobPath returns the path of a blob on local disk, or false when blobs
+// are kept remotely and features needing a file on disk don't apply
+func localBlobPath(storage services.Storage, hash string) (string, bool) {
+	local, ok := storage.(services.LocalBlobs)
+	if !ok {
+		return "", false
+	}
+	return local.Path(hash), true
+}
+
+// blobExists reports whether a blob is in storage
+func blobExists(storage services.Storage, hash string) (bool, error) {
+	blob, err := storage.Get(hash)
+	if errors.Is(err, services.ErrBlobNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	blob.Close()
+	return true, nil
+}
+
+// streamBlob sends a blob as the response body. Seekable blobs go through
+// http.ServeContent for Range support; others are streamed whole, and HEAD
+// requests get the length without the body.
+func streamBlob(c *gin.Context, blob io.Reader, name string, size int64) {
+	if seeker, ok := blob.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, name, time.Time{}, seeker)
+		return
+	}
+
+	c.Header("Accept-Ranges", "none")
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	io.Copy(c.Writer, blob)
+}
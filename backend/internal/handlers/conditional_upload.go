@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+var contentHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// errContentRequired is returned when new content has to be stored but the
+// upload carried no body
+var errContentRequired = errors.New("file content required")
+
+// parseIfNoneMatchHash extracts a SHA-256 hex digest from an If-None-Match
+// header value, accepting quoted, weak and "sha256:" prefixed forms
+func parseIfNoneMatchHash(header string) (string, bool) {
+	value := strings.TrimSpace(header)
+	value = strings.TrimPrefix(value, "W/")
+	value = strings.Trim(value, `"`)
+	value = strings.TrimPrefix(value, "sha256:")
+	value = strings.ToLower(value)
+
+	return value, contentHashPattern.MatchString(value)
+}
+
+// conditionalUpload handles an upload carrying If-None-Match with a content
+// hash. Existing content is linked to a new file without the client sending
+// the body; otherwise 428 asks the client to perform a full upload.
+func (h *FileHandler) conditionalUpload(c *gin.Context, userID uuid.UUID, folderID *uuid.UUID) {
+	hash, ok := parseIfNoneMatchHash(c.GetHeader("If-None-Match"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-None-Match must be a SHA-256 content hash"})
+		return
+	}
+
+	filename := strings.TrimSpace(c.Query("filename"))
+	if filename == "" {
+		filename = strings.TrimSpace(c.PostForm("filename"))
+	}
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename is required for conditional upload"})
+		return
+	}
+
+	sizeStr := c.Query("size")
+	if sizeStr == "" {
+		sizeStr = c.PostForm("size")
+	}
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil || size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid size is required for conditional upload"})
+		return
+	}
+
+	if size > h.cfg.MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("File %s exceeds size limit", filename),
+			"max_size":  h.cfg.MaxFileSize,
+			"file_size": size,
+		})
+		return
+	}
+
+	// Only short-circuit for content that is stored and known to be intact
+	var fileHash models.FileHash
+	err = h.db.Where("hash = ? AND size = ?", hash, size).
+		Where("integrity_status IS NULL OR integrity_status IN ?", []models.IntegrityStatus{"", models.IntegrityOK, models.IntegrityRepaired}).
+		First(&fileHash).Error
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error":        "Content not found, upload the full file",
+			"content_hash": hash,
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up content"})
+		return
+	}
+
+	// Reuse the MIME type detected when the content was first uploaded
+	mimeType := "application/octet-stream"
+	var existingFile models.File
+	if err := h.db.Select("mime_type").Where("file_hash_id = ?", fileHash.ID).Order("created_at ASC").First(&existingFile).Error; err == nil {
+		mimeType = existingFile.MimeType
+	}
+
+	// The type may have been blocked since the content was first uploaded
+	if h.rejectDisallowedMimeType(c, filename, mimeType) {
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	budget, err := h.loadUploadBudget(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload budget"})
+		return
+	}
+	if !budget.allows(size, 1) {
+		rejectOverBudget(c, budget, size, 1)
+		return
+	}
+
+	quota := h.checkQuota(&user, size)
+	if !quota.allowed {
+		rejectOverQuota(c, &user, size, quota)
+		return
+	}
+
+	uploadFile := FileUploadInfo{
+		Header:   &multipart.FileHeader{Filename: filename, Size: size},
+		Size:     size,
+		Hash:     hash,
+		MimeType: mimeType,
+		IsValid:  true,
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Check again holding the user's row, so a concurrent upload that
+	// committed since can't take both over quota
+	if err := lockUserForQuota(tx, &user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	if quota = h.checkQuota(&user, size); !quota.allowed {
+		tx.Rollback()
+		rejectOverQuota(c, &user, size, quota)
+		return
+	}
+
+	result, savedBytes, orphaned, err := h.processFileUpload(tx, uploadFile, userID, folderID, apiKeyIDFromContext(c))
+	if err != nil {
+		tx.Rollback()
+		// The content was removed after the lookup above
+		if errors.Is(err, errContentRequired) {
+			c.JSON(http.StatusPreconditionRequired, gin.H{
+				"error":        "Content not found, upload the full file",
+				"content_hash": hash,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Failed to process file upload",
+			"filename": filename,
+			"details":  err.Error(),
+		})
+		return
+	}
+	h.recordUploadProvenance(tx, c, uploadFile, apiKeyIDFromContext(c), result)
+
+	if err := h.updateUserStorageStats(tx, userID, size); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+		return
+	}
+
+	if quota.usesGrace {
+		if err := h.markQuotaGraceUsed(tx, userID); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errQuotaGraceUsed) {
+				quota.graceAvailable = false
+				rejectOverQuota(c, &user, size, quota)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit upload transaction"})
+		return
+	}
+	h.removeContent(orphaned)
+
+	setUploadBudgetHeaders(c, budget, size, 1)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Files uploaded successfully",
+		"uploaded_files_count": 1,
+		"total_size":           size,
+		"total_saved_bytes":    savedBytes,
+		"files":                []map[string]interface{}{result},
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// dedupCompareChunk is how much of each side is read at a time when
+// comparing an upload byte for byte against a stored blob
+const dedupCompareChunk = 64 * 1024
+
+// verifyDuplicate checks that an upload whose hash matches existing really
+// is the same content, as strictly as configured: "hash" trusts the hash,
+// "size" also compares sizes and "bytes" compares the content itself. It
+// returns the reason for a mismatch. A missing blob can't be compared and is
+// left to be restored.
+func (h *FileHandler) verifyDuplicate(uploadFile FileUploadInfo, existing *models.FileHash) (bool, string, error) {
+	if h.cfg.DedupVerification == "hash" {
+		return true, "", nil
+	}
+
+	if uploadFile.Size != existing.Size {
+		return false, fmt.Sprintf("size %d differs from stored size %d", uploadFile.Size, existing.Size), nil
+	}
+
+	if h.cfg.DedupVerification != "bytes" || (uploadFile.Content == nil && uploadFile.TempPath == "") {
+		return true, "", nil
+	}
+
+	stored, err := h.storage.Get(existing.Hash)
+	if errors.Is(err, services.ErrBlobNotFound) {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to open stored content: %v", err)
+	}
+	defer stored.Close()
+
+	var upload io.Reader = bytes.NewReader(uploadFile.Content)
+	if uploadFile.TempPath != "" {
+		tempFile, err := os.Open(uploadFile.TempPath)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to open uploaded content: %v", err)
+		}
+		defer tempFile.Close()
+		upload = tempFile
+	}
+
+	same, err := sameContent(stored, upload)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to compare content: %v", err)
+	}
+	if !same {
+		return false, "content differs from stored blob", nil
+	}
+	return true, "", nil
+}
+
+// sameContent reports whether a and b hold exactly the same bytes
+func sameContent(a, b io.Reader) (bool, error) {
+	bufA := make([]byte, dedupCompareChunk)
+	bufB := make([]byte, dedupCompareChunk)
+	for {
+		nA, errA := io.ReadFull(a, bufA)
+		nB, errB := io.ReadFull(b, bufB)
+		if errA != nil && errA != io.EOF && errA != io.ErrUnexpectedEOF {
+			return false, errA
+		}
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		if errA != nil || errB != nil {
+			return errA != nil && errB != nil, nil
+		}
+	}
+}
+
+// storeCollidingContent stores an upload whose hash is already taken by
+// different content. It is keyed by the hash salted with a random value so it
+// never shares a blob with the existing content.
+func (h *FileHandler) storeCollidingContent(tx *gorm.DB, uploadFile FileUploadInfo) (models.FileHash, error) {
+	key := fmt.Sprintf("%x", sha256.Sum256([]byte(uploadFile.Hash+":"+uuid.NewString())))
+	storagePath := services.BlobStoragePath(key)
+	if err := h.storeBlob(uploadFile, key); err != nil {
+		return models.FileHash{}, err
+	}
+	chunkSize, checksums := h.chunkChecksums(key)
+
+	fileHash := models.FileHash{
+		ID:             uuid.New(),
+		Hash:           key,
+		Size:           uploadFile.Size,
+		StoragePath:    storagePath,
+		ReferenceCount: 1,
+		ChunkSize:      chunkSize,
+		ChunkChecksums: checksums,
+	}
+	if err := tx.Create(&fileHash).Error; err != nil {
+		return models.FileHash{}, fmt.Errorf("failed to save file hash: %v", err)
+	}
+	return fileHash, nil
+}
+
+// reportDedupCollision raises an alert for content that matched a stored hash
+// but not the content behind it. The audit entry is written outside the
+// upload's transaction so it survives a rollback.
+func (h *FileHandler) reportDedupCollision(uploadFile FileUploadInfo, existing *models.FileHash, reason string, userID uuid.UUID) {
+	log.Printf("ALERT: deduplication mismatch for hash %s (%s), storing upload %q as separate content",
+		existing.Hash, reason, uploadFile.Header.Filename)
+
+	values, err := json.Marshal(map[string]interface{}{
+		"hash":          existing.Hash,
+		"reason":        reason,
+		"filename":      uploadFile.Header.Filename,
+		"upload_size":   uploadFile.Size,
+		"existing_size": existing.Size,
+	})
+	if err != nil {
+		log.Printf("Failed to encode deduplication alert: %v", err)
+		return
+	}
+
+	entry := models.AuditLog{
+		UserID:       &userID,
+		Action:       "dedup_collision",
+		ResourceType: "file_hash",
+		ResourceID:   &existing.ID,
+		NewValues:    string(values),
+	}
+	if err := h.db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record deduplication alert: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// storedContent puts content in storage and returns the record pointing at it
+func storedContent(t *testing.T, storage services.Storage, content []byte) *models.FileHash {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if err := storage.Put(hash, bytes.NewReader(content)); err != nil {
+		t.Fatalf("failed to store content: %v", err)
+	}
+	return &models.FileHash{Hash: hash, Size: int64(len(content)), StoragePath: services.BlobStoragePath(hash)}
+}
+
+func TestVerifyDuplicateDetectsForcedMismatch(t *testing.T) {
+	stored := []byte("the content already in storage")
+
+	tests := []struct {
+		name         string
+		verification string
+		upload       []byte
+		size         int64 // declared size, taken from upload when 0
+		wantMatch    bool
+		wantReason   string
+	}{
+		{"same content", "bytes", stored, 0, true, ""},
+		{"size mismatch", "size", stored, int64(len(stored)) + 1, false, "differs from stored size"},
+		{"same size, different bytes", "bytes", []byte("the content already in st0rage"), 0, false, "content differs"},
+		{"same size trusted under size", "size", []byte("the content already in st0rage"), 0, true, ""},
+		{"anything trusted under hash", "hash", []byte("other"), 99, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := services.NewLocalStorage(t.TempDir())
+			h := &FileHandler{cfg: &config.Config{DedupVerification: tt.verification}, storage: storage}
+			existing := storedContent(t, storage, stored)
+
+			size := tt.size
+			if size == 0 {
+				size = int64(len(tt.upload))
+			}
+			// The upload claims the stored hash whatever its bytes are, as a
+			// collision would
+			upload := FileUploadInfo{Content: tt.upload, Size: size, Hash: existing.Hash}
+
+			match, reason, err := h.verifyDuplicate(upload, existing)
+			if err != nil {
+				t.Fatalf("verifyDuplicate() error = %v", err)
+			}
+			if match != tt.wantMatch {
+				t.Errorf("verifyDuplicate() match = %v, want %v (reason %q)", match, tt.wantMatch, reason)
+			}
+			if !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("verifyDuplicate() reason = %q, want it to mention %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestVerifyDuplicateLeavesMissingBlobToBeRestored(t *testing.T) {
+	storage := services.NewLocalStorage(t.TempDir())
+	h := &FileHandler{cfg: &config.Config{DedupVerification: "bytes"}, storage: storage}
+	content := []byte("content whose blob went missing")
+	sum := sha256.Sum256(content)
+	existing := &models.FileHash{Hash: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+
+	match, _, err := h.verifyDuplicate(FileUploadInfo{Content: content, Size: existing.Size, Hash: existing.Hash}, existing)
+	if err != nil || !match {
+		t.Errorf("verifyDuplicate() = %v, %v, want a match so the upload restores the blob", match, err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// deduplicationRuns tracks users with a deduplication pass in progress
+var deduplicationRuns sync.Map
+
+// DeduplicateFiles re-hashes the user's files still stored under their file
+// ID, links them to the shared content store and removes the redundant
+// blobs. Pass dry_run=true to only report what would be reclaimed.
+func (h *FileHandler) DeduplicateFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	ownerID := userID.(uuid.UUID)
+	dryRun := c.Query("dry_run") == "true"
+
+	if _, running := deduplicationRuns.LoadOrStore(ownerID, true); running {
+		c.JSON(http.StatusConflict, gin.H{"error": "Deduplication already in progress"})
+		return
+	}
+	defer deduplicationRuns.Delete(ownerID)
+
+	migrator := services.NewLegacyBlobMigrator(h.db, h.cfg)
+	report, err := migrator.Run(c.Request.Context(), services.LegacyBlobMigrationOptions{
+		DryRun:  dryRun,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		log.Printf("Deduplication failed for user %s: %v", ownerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to deduplicate files",
+			"report": report,
+		})
+		return
+	}
+
+	var savedBytes int64
+	if err := h.db.Table("users").Select("saved_bytes").Where("id = ?", ownerID).Scan(&savedBytes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage savings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":          report,
+		"reclaimed_bytes": report.ReclaimedBytes,
+		"saved_bytes":     savedBytes,
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// downloadHistoryEntry is one download in a user's history. Files deleted
+// since keep the name they had when downloaded.
+type downloadHistoryEntry struct {
+	ID           uuid.UUID `json:"id"`
+	FileID       uuid.UUID `json:"file_id"`
+	FileName     string    `json:"file_name"`
+	MimeType     string    `json:"mime_type,omitempty"`
+	DownloadSize int64     `json:"download_size"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	Owned        bool      `json:"owned"`        // the user's own file rather than one shared with them
+	FileDeleted  bool      `json:"file_deleted"` // the file has since been deleted
+}
+
+// recordDownload adds the user's download of a file to the download stats.
+// Range requests only count once, for the request that starts at the
+// beginning of the file.
+func (h *FileHandler) recordDownload(c *gin.Context, file *models.File, userID uuid.UUID) {
+	if rangeHeader := c.GetHeader("Range"); rangeHeader != "" && !strings.HasPrefix(rangeHeader, "bytes=0-") {
+		return
+	}
+
+	stat := models.DownloadStat{
+		FileID:       file.ID,
+		DownloadedBy: &userID,
+		FileName:     file.OriginalFilename,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		DownloadSize: file.Size,
+	}
+	if err := h.db.Create(&stat).Error; err != nil {
+		log.Printf("Failed to record download of file %s: %v", file.ID, err)
+	}
+}
+
+// GetDownloadHistory lists the files the user has downloaded, their own and
+// those shared with them, newest first
+// GET /api/downloads/history?from=&to=&page=&page_size=
+func (h *FileHandler) GetDownloadHistory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := h.db.Table("download_stats AS ds").
+		Joins("LEFT JOIN files f ON f.id = ds.file_id").
+		Where("ds.downloaded_by = ?", userID)
+
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		from = &parsed
+		query = query.Where("ds.downloaded_at >= ?", parsed)
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		to = &parsed
+		query = query.Where("ds.downloaded_at < ?", parsed)
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download history"})
+		return
+	}
+
+	downloads := []downloadHistoryEntry{}
+	if err := query.Session(&gorm.Session{}).
+		Select(`ds.id, ds.file_id, COALESCE(NULLIF(ds.file_name, ''), f.original_filename, '') AS file_name,
+			COALESCE(f.mime_type, '') AS mime_type, ds.download_size, ds.downloaded_at,
+			COALESCE(f.owner_id = ds.downloaded_by, false) AS owned,
+			COALESCE(f.is_deleted, true) AS file_deleted`).
+		Order("ds.downloaded_at DESC").
+		Offset(paging.offset()).
+		Limit(paging.pageSize).
+		Scan(&downloads).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"downloads":  downloads,
+		"pagination": paging.envelope(total),
+	})
+}
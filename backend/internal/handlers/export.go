@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// exportManifestName ends every export unless manifests are turned off. It
+// lists the SHA-256 and size of each file so the archive can be checked once
+// unpacked.
+const exportManifestName = "EXPORT-MANIFEST.txt"
+
+// exportChecksumsName lists the same checksums in the format sha256sum -c
+// reads, so recipients can verify the unpacked files with standard tools
+const exportChecksumsName = "MANIFEST.sha256"
+
+// exportStatusTrailer is sent after the archive: "complete", or "failed" when
+// the stream broke off part way
+const exportStatusTrailer = "X-Export-Status"
+
+// exportEntry is one file placed in an export archive
+type exportEntry struct {
+	file models.File
+	name string // path inside the archive
+}
+
+// ExportFiles streams all of the user's files as a ZIP archive, laid out by
+// folder
+// GET /api/files/export
+func (h *FileHandler) ExportFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var folders []models.Folder
+	if err := h.db.Select("id, path").Where("owner_id = ?", userID).Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get folders"})
+		return
+	}
+
+	var files []models.File
+	if err := h.db.Preload("FileHash").Where("owner_id = ? AND is_deleted = false", userID).
+		Order("original_filename ASC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files"})
+		return
+	}
+
+	h.streamExport(c, "files", exportEntries(files, folders, "/"))
+}
+
+// RedirectFolderDownload sends requests for a folder's download path to its
+// export, the one route that serves it
+// GET /api/folders/:id/download
+func (h *FileHandler) RedirectFolderDownload(c *gin.Context) {
+	target := strings.TrimSuffix(c.Request.URL.EscapedPath(), "/download") + "/export"
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusPermanentRedirect, target)
+}
+
+// ExportFolder streams a folder and everything below it as a ZIP archive
+// GET /api/folders/:id/export
+func (h *FileHandler) ExportFolder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	root, err := services.FindFolderWithAccess(h.db, folderID, userID.(uuid.UUID), services.AccessRead)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get folder"})
+		return
+	}
+
+	var ownerFolders []models.Folder
+	if err := h.db.Select("id, path").Where("owner_id = ?", root.OwnerID).Find(&ownerFolders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get folders"})
+		return
+	}
+
+	var folders []models.Folder
+	var folderIDs []uuid.UUID
+	for _, folder := range ownerFolders {
+		if folder.ID == root.ID || strings.HasPrefix(folder.Path, root.Path+"/") {
+			folders = append(folders, folder)
+			folderIDs = append(folderIDs, folder.ID)
+		}
+	}
+
+	// As in ListFiles, owners export their own files and share recipients
+	// export everything in the folder
+	query := h.db.Preload("FileHash").Where("folder_id IN ? AND is_deleted = false", folderIDs)
+	if root.OwnerID == userID.(uuid.UUID) {
+		query = query.Where("owner_id = ?", userID)
+	}
+	var files []models.File
+	if err := query.Order("original_filename ASC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files"})
+		return
+	}
+
+	h.streamExport(c, root.Name, exportEntries(files, folders, root.Path))
+}
+
+// exportEntries names each file by its folder's path below rootPath, leaving
+// out files whose access has expired or that are quarantined. Clashing names get a numbered suffix.
+func exportEntries(files []models.File, folders []models.Folder, rootPath string) []exportEntry {
+	folderPaths := make(map[uuid.UUID]string, len(folders))
+	for _, folder := range folders {
+		relative := strings.TrimPrefix(folder.Path, rootPath)
+		folderPaths[folder.ID] = strings.Trim(relative, "/")
+	}
+
+	now := time.Now()
+	used := map[string]bool{exportManifestName: true, exportChecksumsName: true}
+	entries := make([]exportEntry, 0, len(files))
+	for _, file := range files {
+		if file.AccessExpired(now) || file.Quarantined() {
+			continue
+		}
+
+		dir := ""
+		if file.FolderID != nil {
+			dir = folderPaths[*file.FolderID]
+		}
+		filename := strings.NewReplacer("/", "_", "\\", "_").Replace(file.OriginalFilename)
+		if filename == "" || filename == "." || filename == ".." {
+			filename = file.ID.String()
+		}
+
+		name := path.Join(dir, filename)
+		ext := path.Ext(name)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(path.Join(dir, filename), ext), i, ext)
+		}
+		used[name] = true
+
+		entries = append(entries, exportEntry{file: file, name: name})
+	}
+	return entries
+}
+
+// streamExport writes entries as a ZIP archive straight to the response.
+// Every blob is located before anything is sent so missing content is still
+// reported as an error. Entries are stored uncompressed with their sizes
+// taken from the file records; if the stream fails part way the central
+// directory is left out, so the truncated archive can't be mistaken for a
+// complete one, and the X-Export-Status trailer says "failed".
+func (h *FileHandler) streamExport(c *gin.Context, archiveName string, entries []exportEntry) {
+	// Remote blobs aren't checked up front; a missing one fails the stream
+	var missing []string
+	for i := range entries {
+		if !h.localBlobs() {
+			break
+		}
+		if _, err := h.exportBlobPath(&entries[i].file); err != nil {
+			missing = append(missing, entries[i].name)
+		}
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":         "Some files are missing their content and can't be exported",
+			"missing_files": missing,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", strings.ReplaceAll(archiveName, "\"", "_")))
+	c.Header("Trailer", exportStatusTrailer)
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	var manifest, checksums strings.Builder
+	for _, entry := range entries {
+		if err := h.writeExportEntry(archive, entry); err != nil {
+			log.Printf("Export of %s failed at %s: %v", archiveName, entry.name, err)
+			c.Writer.Header().Set(exportStatusTrailer, "failed")
+			return
+		}
+		hash := ""
+		if entry.file.FileHash != nil {
+			hash = entry.file.FileHash.Hash
+		}
+		fmt.Fprintf(&manifest, "%s  %d  %s\n", hash, entry.file.Size, entry.name)
+		// Legacy files have no stored hash and are left out rather than
+		// listed with one that can never match
+		if hash != "" {
+			fmt.Fprintf(&checksums, "%s  %s\n", hash, entry.name)
+		}
+	}
+
+	var err error
+	if h.cfg.ExportManifest {
+		err = writeExportManifest(archive, exportManifestName, manifest.String())
+		if err == nil {
+			err = writeExportManifest(archive, exportChecksumsName, checksums.String())
+		}
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("Export of %s failed while finishing the archive: %v", archiveName, err)
+		c.Writer.Header().Set(exportStatusTrailer, "failed")
+		return
+	}
+
+	c.Writer.Header().Set(exportStatusTrailer, "complete")
+}
+
+// writeExportManifest adds a manifest to the end of the archive
+func writeExportManifest(archive *zip.Writer, name, content string) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, content)
+	return err
+}
+
+// writeExportEntry copies one file into the archive, failing if its content
+// doesn't match the size on record
+func (h *FileHandler) writeExportEntry(archive *zip.Writer, entry exportEntry) error {
+	blob, err := h.openBlob(&entry.file)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	header := &zip.FileHeader{
+		Name:               entry.name,
+		Method:             zip.Store,
+		Modified:           entry.file.UpdatedAt,
+		UncompressedSize64: uint64(entry.file.Size),
+	}
+	writer, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(writer, io.LimitReader(blob, entry.file.Size+1))
+	if err != nil {
+		return err
+	}
+	if written != entry.file.Size {
+		return fmt.Errorf("content is %d bytes, expected %d", written, entry.file.Size)
+	}
+	return nil
+}
+
+// localBlobs reports whether blobs are kept on local disk
+func (h *FileHandler) localBlobs() bool {
+	_, ok := h.storage.(services.LocalBlobs)
+	return ok
+}
+
+// openBlob opens a file's content, from local disk with the legacy fallback
+// or from remote storage. The file's FileHash must be loaded.
+func (h *FileHandler) openBlob(file *models.File) (io.ReadCloser, error) {
+	if !h.localBlobs() {
+		if file.FileHash == nil {
+			return nil, services.ErrBlobNotFound
+		}
+		return h.storage.Get(file.FileHash.Hash)
+	}
+
+	blobPath, err := h.exportBlobPath(file)
+	if err != nil {
+		return nil, services.ErrBlobNotFound
+	}
+	return os.Open(blobPath)
+}
+
+// exportBlobPath finds a file's content on disk, falling back to the legacy
+// per-file location unless that fallback is disabled
+func (h *FileHandler) exportBlobPath(file *models.File) (string, error) {
+	if file.FileHash != nil {
+		blobPath := filepath.Join(h.cfg.StoragePath, file.FileHash.StoragePath)
+		if _, err := os.Stat(blobPath); err == nil {
+			return blobPath, nil
+		}
+	}
+	if !h.cfg.LegacyStorageFallback {
+		return "", os.ErrNotExist
+	}
+
+	legacyPath := filepath.Join(h.cfg.StoragePath, file.ID.String())
+	if _, err := os.Stat(legacyPath); err != nil {
+		return "", err
+	}
+	return legacyPath, nil
+}
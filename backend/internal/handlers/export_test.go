@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+func TestStreamExportWritesCompleteArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storagePath := t.TempDir()
+	storage := services.NewLocalStorage(storagePath)
+	h := &FileHandler{cfg: &config.Config{StoragePath: storagePath, ExportManifest: true}, storage: storage}
+
+	folder := models.Folder{BaseModel: models.BaseModel{ID: uuid.New()}, Name: "reports", Path: "/reports"}
+
+	// Many files, some sharing content and some sharing a name
+	const fileCount = 250
+	want := make(map[string][]byte, fileCount)
+	files := make([]models.File, 0, fileCount)
+	for i := 0; i < fileCount; i++ {
+		content := []byte(strings.Repeat(fmt.Sprintf("content %d\n", i%40), i%7+1))
+		file := models.File{
+			BaseModel:        models.BaseModel{ID: uuid.New()},
+			OriginalFilename: fmt.Sprintf("file-%d.txt", i%200),
+			Size:             int64(len(content)),
+			FileHash:         storedContent(t, storage, content),
+		}
+		if i%2 == 0 {
+			file.FolderID = &folder.ID
+		}
+		files = append(files, file)
+	}
+
+	entries := exportEntries(files, []models.Folder{folder}, "")
+	if len(entries) != fileCount {
+		t.Fatalf("exportEntries() returned %d entries, want %d", len(entries), fileCount)
+	}
+	for _, entry := range entries {
+		blob, err := storage.Get(entry.file.FileHash.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(blob)
+		blob.Close()
+		want[entry.name] = content
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	h.streamExport(c, "export", entries)
+
+	if status := recorder.Header().Get(exportStatusTrailer); status != "complete" {
+		t.Fatalf("%s = %q, want complete", exportStatusTrailer, status)
+	}
+
+	body := recorder.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("export isn't a valid ZIP archive: %v", err)
+	}
+
+	got := make(map[string]bool, len(archive.File))
+	var checksums string
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", entry.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", entry.Name, err)
+		}
+
+		switch entry.Name {
+		case exportChecksumsName:
+			checksums = string(content)
+		case exportManifestName:
+		default:
+			if !bytes.Equal(content, want[entry.Name]) {
+				t.Errorf("%s holds %q, want %q", entry.Name, content, want[entry.Name])
+			}
+			got[entry.Name] = true
+		}
+	}
+
+	if len(got) != fileCount {
+		t.Errorf("archive holds %d files, want %d", len(got), fileCount)
+	}
+	if lines := strings.Count(checksums, "\n"); lines != fileCount {
+		t.Errorf("%s lists %d files, want %d", exportChecksumsName, lines, fileCount)
+	}
+}
+
+func TestStreamExportFailureLeavesInvalidArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	storagePath := t.TempDir()
+	storage := services.NewLocalStorage(storagePath)
+	h := &FileHandler{cfg: &config.Config{StoragePath: storagePath, ExportManifest: true}, storage: storage}
+
+	content := []byte("shorter than the record says")
+	files := []models.File{{
+		BaseModel:        models.BaseModel{ID: uuid.New()},
+		OriginalFilename: "truncated.txt",
+		Size:             int64(len(content)) + 10,
+		FileHash:         storedContent(t, storage, content),
+	}}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	h.streamExport(c, "export", exportEntries(files, nil, ""))
+
+	if status := recorder.Header().Get(exportStatusTrailer); status != "failed" {
+		t.Errorf("%s = %q, want failed", exportStatusTrailer, status)
+	}
+	body := recorder.Body.Bytes()
+	if _, err := zip.NewReader(bytes.NewReader(body), int64(len(body))); err == nil {
+		t.Error("a failed export reads as a valid ZIP archive")
+	}
+}
+
+func TestFolderDownloadRedirectsToExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &FileHandler{}
+	router := gin.New()
+	router.GET("/api/v1/folders/:id/download", h.RedirectFolderDownload)
+
+	folderID := uuid.NewString()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/folders/"+folderID+"/download?manifest=false", nil))
+
+	if recorder.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusPermanentRedirect)
+	}
+	want := "/api/v1/folders/" + folderID + "/export?manifest=false"
+	if location := recorder.Header().Get("Location"); location != want {
+		t.Errorf("Location = %q, want %q", location, want)
+	}
+}
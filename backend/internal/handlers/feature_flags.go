@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/services"
+)
+
+type FeatureFlagHandler struct {
+	featureFlags *services.FeatureFlagService
+}
+
+func NewFeatureFlagHandler(featureFlags *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		featureFlags: featureFlags,
+	}
+}
+
+// ListFeatureFlags returns all feature flags (admin only)
+func (h *FeatureFlagHandler) ListFeatureFlags(c *gin.Context) {
+	flags, err := h.featureFlags.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feature flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feature_flags": flags,
+	})
+}
+
+// UpdateFeatureFlag enables or disables a feature flag (admin only)
+func (h *FeatureFlagHandler) UpdateFeatureFlag(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	flag, err := h.featureFlags.Set(c.Param("key"), *req.Enabled, userID.(uuid.UUID))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownFeatureFlag) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Feature flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Feature flag updated successfully",
+		"feature_flag": flag,
+	})
+}
@@ -1,22 +1,28 @@
 package handlers
 
 import (
-	"crypto/sha256"
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/middleware"
 	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
 	"file-vault-system/backend/pkg/utils"
 )
 
@@ -24,25 +30,56 @@ import (
 type FileUploadInfo struct {
 	Header   *multipart.FileHeader
 	Content  []byte
+	TempPath string // Absolute path of content already streamed to disk, used instead of Content
 	Size     int64
 	Hash     string
 	MimeType string
 	IsValid  bool
 	Warning  string
+
+	DeclaredMimeType string // Type the client sent, kept for upload provenance
+	MalwareSignature string // Set when the scanner flagged the content for quarantine
 }
 
 type FileHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db         *gorm.DB
+	cfg        *config.Config
+	thumbnails *services.ThumbnailService
+	backends   *services.StorageBackends
+	storage    services.Storage
+	progress   *services.UploadProgressHub
+	scanner    services.MalwareScanner // nil when malware scanning is off
+	chunks     *services.ChunkVerifier
+
+	mimeOverrides []utils.MimeOverride
+	mimePolicy    *services.MimePolicyService
+	notifications *services.NotificationHub
 }
 
-func NewFileHandler(db *gorm.DB, cfg *config.Config) *FileHandler {
+func NewFileHandler(db *gorm.DB, cfg *config.Config, thumbnails *services.ThumbnailService, backends *services.StorageBackends, progress *services.UploadProgressHub) *FileHandler {
 	return &FileHandler{
-		db:  db,
-		cfg: cfg,
+		db:         db,
+		cfg:        cfg,
+		thumbnails: thumbnails,
+		backends:   backends,
+		storage:    services.NewLocalStorage(cfg.StoragePath),
+		progress:   progress,
+		scanner:    services.NewMalwareScanner(cfg),
+		chunks:     services.NewChunkVerifier(db, cfg),
 	}
 }
 
+// SetMimeOverrides sets the MIME types forced on formats detection gets wrong
+func (h *FileHandler) SetMimeOverrides(overrides []utils.MimeOverride) {
+	h.mimeOverrides = overrides
+}
+
+// SetMimePolicy sets the runtime MIME type policy uploads are checked
+// against, in place of the static ALLOWED_MIME_TYPES
+func (h *FileHandler) SetMimePolicy(policy *services.MimePolicyService) {
+	h.mimePolicy = policy
+}
+
 // GetUserStats returns storage statistics for the authenticated user
 func (h *FileHandler) GetUserStats(c *gin.Context) {
 	// Get user from context (set by auth middleware)
@@ -65,8 +102,8 @@ func (h *FileHandler) GetUserStats(c *gin.Context) {
 
 	// Calculate storage efficiency
 	storageEfficiency := float64(0)
-	if user.TotalUploadedBytes > 0 {
-		storageEfficiency = (float64(user.SavedBytes) / float64(user.TotalUploadedBytes)) * 100
+	if user.StorageUsed > 0 {
+		storageEfficiency = (float64(user.SavedBytes) / float64(user.StorageUsed)) * 100
 	}
 
 	// Calculate remaining storage
@@ -84,10 +121,13 @@ func (h *FileHandler) GetUserStats(c *gin.Context) {
 		"remaining_storage":    remainingStorage,
 		"file_count":           fileCount,
 		"storage_efficiency":   storageEfficiency,
+		"quota":                h.quotaStatus(&user),
 	})
 }
 
-// UploadFile handles single and multiple file uploads with deduplication and MIME validation
+// UploadFile handles single and multiple file uploads with deduplication and MIME validation.
+// A batch is all or nothing unless ?partial=true, which keeps the files that
+// succeed and reports each file's outcome.
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	// Get user from context (set by auth middleware)
 	userID, exists := c.Get("user_id")
@@ -110,9 +150,9 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			return
 		}
 
-		// Verify folder exists and user owns it
+		// Verify folder exists and user owns it or has edit access through a share
 		var folder models.Folder
-		if err := h.db.Where("id = ? AND owner_id = ?", parsedFolderID, userID).First(&folder).Error; err != nil {
+		if err := h.db.Where("id = ?", parsedFolderID).First(&folder).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
 				return
@@ -120,11 +160,26 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify folder"})
 			return
 		}
+		level, err := services.FolderAccessLevel(h.db, &folder, userID.(uuid.UUID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify folder"})
+			return
+		}
+		if !level.Allows(services.AccessWrite) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+			return
+		}
 		folderID = &parsedFolderID
 	}
 
+	// Conditional upload: link existing content by hash without a body
+	if c.GetHeader("If-None-Match") != "" {
+		h.conditionalUpload(c, userID.(uuid.UUID), folderID)
+		return
+	}
+
 	// Initialize MIME type validator
-	validator := utils.NewMimeTypeValidator()
+	validator := utils.NewMimeTypeValidatorWithOverrides(h.mimeOverrides)
 
 	// Parse multipart form with max memory (32MB)
 	err := c.Request.ParseMultipartForm(32 << 20)
@@ -140,21 +195,25 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// Get files from both 'file' (single) and 'files' (multiple) fields
-	var allFiles []*multipart.FileHeader
-
-	// Single file upload (field name: "file")
-	if files, exists := form.File["file"]; exists {
-		allFiles = append(allFiles, files...)
-	}
-
-	// Multiple file upload (field name: "files")
-	if files, exists := form.File["files"]; exists {
-		allFiles = append(allFiles, files...)
+	// Get files from the accepted multipart fields
+	allFiles := multipartUploadFiles(form, h.cfg.UploadFieldNames)
+	if len(allFiles) == 0 {
+		received := make([]string, 0, len(form.File))
+		for field := range form.File {
+			received = append(received, field)
+		}
+		sort.Strings(received)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           fmt.Sprintf("No files found in upload, expected a file in one of these fields: %s", strings.Join(h.cfg.UploadFieldNames, ", ")),
+			"expected_fields": h.cfg.UploadFieldNames,
+			"received_fields": received,
+		})
+		return
 	}
 
-	if len(allFiles) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No files found in upload"})
+	// Checksums the client claims, verified once each file is received
+	checksums, ok := uploadChecksums(c, form, allFiles)
+	if !ok {
 		return
 	}
 
@@ -165,115 +224,72 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	// Validate each file and calculate total size
+	// Validate each file and calculate total size. Files are streamed to temp
+	// files rather than held in memory; whatever isn't moved into storage is
+	// removed once the request is done.
 	var uploadFiles []FileUploadInfo
 	var totalSize int64
+	var tempPaths []string
+	defer func() {
+		removeUploadTempFiles(tempPaths)
+	}()
 
-	for _, fileHeader := range allFiles {
-		// Open file
-		file, err := fileHeader.Open()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("Failed to open file %s", fileHeader.Filename),
-			})
-			return
-		}
-
-		// Read file content
-		content, err := io.ReadAll(file)
-		file.Close()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("Failed to read file %s", fileHeader.Filename),
-			})
-			return
-		}
-
-		fileSize := int64(len(content))
-
-		// Validate file size
-		if fileSize > h.cfg.MaxFileSize {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":     fmt.Sprintf("File %s exceeds size limit", fileHeader.Filename),
-				"max_size":  h.cfg.MaxFileSize,
-				"file_size": fileSize,
-			})
-			return
-		}
+	// With ?partial=true each file is committed on its own, so one bad file
+	// doesn't lose the rest of the batch
+	if c.Query("partial") == "true" {
+		h.uploadFilesPartially(c, validator, &user, folderID, allFiles, checksums, &tempPaths)
+		return
+	}
 
-		// Validate MIME type
-		declaredMimeType := fileHeader.Header.Get("Content-Type")
-		if declaredMimeType == "" {
-			declaredMimeType = "application/octet-stream"
+	for i, fileHeader := range allFiles {
+		uploadFile, tempPath, ok := h.validateUploadFile(c, validator, fileHeader, checksums[i])
+		if tempPath != "" {
+			tempPaths = append(tempPaths, tempPath)
 		}
-
-		isValid, actualMimeType, warning := validator.ValidateMimeType(content, declaredMimeType, fileHeader.Filename)
-
-		if !isValid {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":             fmt.Sprintf("Invalid file type for %s", fileHeader.Filename),
-				"filename":          fileHeader.Filename,
-				"declared_mimetype": declaredMimeType,
-				"actual_mimetype":   actualMimeType,
-				"warning":           warning,
-			})
+		if !ok {
 			return
 		}
 
-		// Check if MIME type is allowed (if configured)
-		if len(h.cfg.AllowedMimeTypes) > 0 && !validator.IsAllowedMimeType(actualMimeType, h.cfg.AllowedMimeTypes) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":         fmt.Sprintf("File type not allowed for %s", fileHeader.Filename),
-				"filename":      fileHeader.Filename,
-				"mimetype":      actualMimeType,
-				"allowed_types": h.cfg.AllowedMimeTypes,
-			})
-			return
-		}
+		uploadFiles = append(uploadFiles, uploadFile)
+		totalSize += uploadFile.Size
+	}
 
-		uploadFiles = append(uploadFiles, FileUploadInfo{
-			Header:   fileHeader,
-			Content:  content,
-			Size:     fileSize,
-			Hash:     h.calculateContentHash(content),
-			MimeType: actualMimeType,
-			IsValid:  isValid,
-			Warning:  warning,
-		})
+	// The request transaction commits once a successful response is written.
+	// The user's row stays locked until then, so the quota is checked against
+	// what concurrent uploads have committed.
+	tx := middleware.Tx(c)
+	if err := lockUserForQuota(tx, &user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
 
-		totalSize += fileSize
+	// Check upload budget for the rolling window
+	budget, err := h.loadUploadBudget(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload budget"})
+		return
+	}
+	if !budget.allows(totalSize, len(uploadFiles)) {
+		rejectOverBudget(c, budget, totalSize, len(uploadFiles))
+		return
 	}
 
-	// Check total storage quota
-	if user.StorageUsed+totalSize > user.StorageQuota {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":         "Total upload size exceeds storage quota",
-			"total_size":    totalSize,
-			"storage_used":  user.StorageUsed,
-			"storage_quota": user.StorageQuota,
-			"available":     user.StorageQuota - user.StorageUsed,
-		})
+	// Check total storage quota, allowing a one-off grace overrun
+	quota := h.checkQuota(&user, totalSize)
+	if !quota.allowed {
+		rejectOverQuota(c, &user, totalSize, quota)
 		return
 	}
 
 	// Process each file upload
 	var results []map[string]interface{}
 	var totalSavedBytes int64
-	var totalActualStorage int64
 	var totalUploadedBytes int64
-
-	// Start transaction for atomic operation
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	var orphaned []*models.FileHash
 
 	for _, uploadFile := range uploadFiles {
-		result, savedBytes, actualStorageUsed, err := h.processFileUpload(tx, uploadFile, userID.(uuid.UUID), folderID)
+		result, savedBytes, pruned, err := h.processFileUpload(tx, uploadFile, userID.(uuid.UUID), folderID, apiKeyIDFromContext(c))
 		if err != nil {
-			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":    "Failed to process file upload",
 				"filename": uploadFile.Header.Filename,
@@ -281,24 +297,47 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 			})
 			return
 		}
+		h.recordUploadProvenance(tx, c, uploadFile, apiKeyIDFromContext(c), result)
 
 		results = append(results, result)
+		orphaned = append(orphaned, pruned...)
 		totalSavedBytes += savedBytes
-		totalActualStorage += actualStorageUsed
 		totalUploadedBytes += uploadFile.Size
 	}
 
 	// Update user storage statistics
-	if err := h.updateUserStorageStats(tx, userID.(uuid.UUID), totalUploadedBytes, totalActualStorage, totalSavedBytes); err != nil {
-		tx.Rollback()
+	if err := h.updateUserStorageStats(tx, userID.(uuid.UUID), totalUploadedBytes); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
 		return
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit upload transaction"})
-		return
+	if quota.usesGrace {
+		if err := h.markQuotaGraceUsed(tx, userID.(uuid.UUID)); err != nil {
+			if errors.Is(err, errQuotaGraceUsed) {
+				quota.graceAvailable = false
+				rejectOverQuota(c, &user, totalSize, quota)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+			return
+		}
+	}
+
+	setUploadBudgetHeaders(c, budget, totalUploadedBytes, len(results))
+
+	middleware.AfterCommit(c, func() {
+		h.notifyUploadCompleted(userID.(uuid.UUID), results)
+		h.removeContent(orphaned)
+	})
+
+	// Queue thumbnails once the content is committed
+	for _, uploadFile := range uploadFiles {
+		if h.thumbnails.Supports(uploadFile.MimeType) && uploadFile.MalwareSignature == "" {
+			hash, mimeType := uploadFile.Hash, uploadFile.MimeType
+			middleware.AfterCommit(c, func() {
+				h.thumbnails.Enqueue(hash, mimeType)
+			})
+		}
 	}
 
 	// Return results
@@ -324,51 +363,255 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// processFileUpload handles the upload of a single file within a transaction
-func (h *FileHandler) processFileUpload(tx *gorm.DB, uploadFile FileUploadInfo, userID uuid.UUID, folderID *uuid.UUID) (map[string]interface{}, int64, int64, error) {
-	// Check if file hash already exists (deduplication)
+// validateUploadFile streams one uploaded file to a temp file and checks its
+// size, checksum, content and type, responding and returning false when it is
+// rejected. The temp path is returned whenever the file was streamed, so the
+// caller can remove it.
+func (h *FileHandler) validateUploadFile(c *gin.Context, validator *utils.MimeTypeValidator, fileHeader *multipart.FileHeader, checksum uploadChecksum) (FileUploadInfo, string, bool) {
+	// Open file
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to open file %s", fileHeader.Filename),
+		})
+		return FileUploadInfo{}, "", false
+	}
+
+	// Stream file content to disk
+	spooled, err := h.spoolUpload(file, h.cfg.MaxFileSize)
+	file.Close()
+	if err != nil {
+		log.Printf("Failed to spool upload %q: %v", fileHeader.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to read file %s", fileHeader.Filename),
+		})
+		return FileUploadInfo{}, "", false
+	}
+	tempPath := spooled.path
+
+	fileSize := spooled.size
+
+	// Validate file size
+	if fileSize > h.cfg.MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("File %s exceeds size limit", fileHeader.Filename),
+			"max_size":  h.cfg.MaxFileSize,
+			"file_size": fileSize,
+		})
+		return FileUploadInfo{}, tempPath, false
+	}
+
+	if rejectChecksumMismatch(c, fileHeader.Filename, spooled, checksum) {
+		return FileUploadInfo{}, tempPath, false
+	}
+
+	if h.rejectExecutable(c, validator, fileHeader.Filename, spooled.head) {
+		return FileUploadInfo{}, tempPath, false
+	}
+
+	var malwareSignature string
+	if h.scanner != nil {
+		content, err := os.Open(spooled.path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Failed to read file %s", fileHeader.Filename),
+			})
+			return FileUploadInfo{}, tempPath, false
+		}
+		signature, rejected := h.scanUpload(c, fileHeader.Filename, content)
+		content.Close()
+		if rejected {
+			return FileUploadInfo{}, tempPath, false
+		}
+		malwareSignature = signature
+	}
+
+	// Validate MIME type
+	declaredMimeType := fileHeader.Header.Get("Content-Type")
+	if declaredMimeType == "" {
+		declaredMimeType = "application/octet-stream"
+	}
+
+	isValid, actualMimeType, warning := validator.ValidateMimeType(spooled.head, declaredMimeType, fileHeader.Filename)
+
+	if !isValid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             fmt.Sprintf("Invalid file type for %s", fileHeader.Filename),
+			"filename":          fileHeader.Filename,
+			"declared_mimetype": declaredMimeType,
+			"actual_mimetype":   actualMimeType,
+			"warning":           warning,
+		})
+		return FileUploadInfo{}, tempPath, false
+	}
+
+	// Check the MIME type against the allowlist and blocklist
+	if h.rejectDisallowedMimeType(c, fileHeader.Filename, actualMimeType) {
+		return FileUploadInfo{}, tempPath, false
+	}
+
+	return FileUploadInfo{
+		Header:   fileHeader,
+		TempPath: spooled.path,
+		Size:     fileSize,
+		Hash:     spooled.hash,
+		MimeType: actualMimeType,
+		IsValid:  isValid,
+		Warning:  warning,
+
+		DeclaredMimeType: declaredMimeType,
+		MalwareSignature: malwareSignature,
+	}, tempPath, true
+
+}
+
+// removeUploadTempFiles removes the temp files of an upload that weren't
+// moved into storage
+func removeUploadTempFiles(tempPaths []string) {
+	for _, tempPath := range tempPaths {
+		if err := os.Remove(tempPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove upload temp file %s: %v", tempPath, err)
+		}
+	}
+}
+
+// processFileUpload handles the upload of a single file within a transaction.
+// Content records deleted by pruning old versions are returned so their blobs
+// can be removed once the transaction commits.
+func (h *FileHandler) processFileUpload(tx *gorm.DB, uploadFile FileUploadInfo, userID uuid.UUID, folderID *uuid.UUID, apiKeyID *uuid.UUID) (map[string]interface{}, int64, []*models.FileHash, error) {
+	// Check if file hash already exists (deduplication). The lookup runs on
+	// tx for every file, so content stored by an earlier file of the same
+	// batch, not yet committed, is found and counted as a duplicate.
 	var existingHash models.FileHash
 	isNewContent := false
 	err := tx.Where("hash = ?", uploadFile.Hash).First(&existingHash).Error
 
 	if err == gorm.ErrRecordNotFound {
 		// Content doesn't exist, create new hash record
-		isNewContent = true
+		if uploadFile.Content == nil && uploadFile.TempPath == "" {
+			return nil, 0, nil, errContentRequired
+		}
+
+		newHash, createErr := h.createContentHash(tx, uploadFile)
+		if createErr != nil {
+			return nil, 0, nil, createErr
+		}
+		if newHash != nil {
+			existingHash = *newHash
+			isNewContent = true
+			err = nil
+		} else {
+			// A concurrent upload of the same content got there first; treat
+			// this one as a duplicate of it
+			err = tx.Where("hash = ?", uploadFile.Hash).First(&existingHash).Error
+		}
+	}
+
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("database error: %v", err)
+	} else if !isNewContent {
+		// Content is shared by whoever references it, regardless of who first
+		// uploaded it. If the blob has gone missing, this upload restores it.
+		matches, reason, err := h.verifyDuplicate(uploadFile, &existingHash)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		if !matches {
+			// Same hash, different content: keep both rather than corrupt either
+			if uploadFile.Content == nil && uploadFile.TempPath == "" {
+				return nil, 0, nil, errContentRequired
+			}
+			h.reportDedupCollision(uploadFile, &existingHash, reason, userID)
 
-		// Store file physically only if it's new content
-		storagePath := fmt.Sprintf("storage/%s", uploadFile.Hash)
+			collisionHash, err := h.storeCollidingContent(tx, uploadFile)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			existingHash = collisionHash
+			isNewContent = true
+		} else {
+			exists, err := blobExists(h.storage, existingHash.Hash)
+			if err != nil {
+				h.backends.RecordOperation(h.storage.Name(), err)
+				return nil, 0, nil, fmt.Errorf("failed to check stored content: %v", err)
+			}
+			if !exists {
+				if uploadFile.Content == nil && uploadFile.TempPath == "" {
+					return nil, 0, nil, errContentRequired
+				}
+				if err := h.storeBlob(uploadFile, existingHash.Hash); err != nil {
+					return nil, 0, nil, err
+				}
+				log.Printf("Restored missing blob %s from a new upload", existingHash.Hash)
+			}
 
-		// Create storage directory if it doesn't exist
-		fullStoragePath := filepath.Join(h.cfg.StoragePath, storagePath)
-		storageDir := filepath.Dir(fullStoragePath)
-		if err := os.MkdirAll(storageDir, 0755); err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to create storage directory: %v", err)
+			// Content already exists, increment reference count
+			if err := tx.Model(&existingHash).Update("reference_count", gorm.Expr("reference_count + 1")).Error; err != nil {
+				return nil, 0, nil, fmt.Errorf("failed to update reference count: %v", err)
+			}
 		}
+	}
 
-		// Write file content to disk
-		if err := os.WriteFile(fullStoragePath, uploadFile.Content, 0644); err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to write file to storage: %v", err)
+	// Flagged content is held for review along with every file already
+	// holding it, as are new references to content that is already held
+	quarantineReason := uploadFile.MalwareSignature
+	if quarantineReason != "" {
+		if err := quarantineContent(tx, existingHash.ID, quarantineReason); err != nil {
+			return nil, 0, nil, err
+		}
+	} else if !isNewContent {
+		reason, err := quarantinedContent(tx, existingHash.ID)
+		if err != nil {
+			return nil, 0, nil, err
 		}
+		quarantineReason = reason
+	}
+	status := models.FileStatusActive
+	if quarantineReason != "" {
+		status = models.FileStatusQuarantined
+	}
 
-		newHash := models.FileHash{
-			ID:             uuid.New(),
-			Hash:           uploadFile.Hash,
-			Size:           uploadFile.Size,
-			StoragePath:    storagePath,
-			ReferenceCount: 1,
+	// Normalize the name so equivalent spellings search and export alike,
+	// keeping the name as sent when that changes it
+	originalFilename := uploadFile.Header.Filename
+	var rawFilename []byte
+	if h.cfg.NormalizeFilenames {
+		if normalized := utils.NormalizeFilename(originalFilename); normalized != originalFilename {
+			rawFilename = []byte(originalFilename)
+			originalFilename = normalized
 		}
+	}
 
-		if err := tx.Create(&newHash).Error; err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to save file hash: %v", err)
+	// With versioning on, re-uploading a name in the same folder adds a
+	// version to the file already there rather than creating another
+	if h.cfg.FileVersioning {
+		versioned, err := findVersionedFile(tx, userID, folderID, originalFilename)
+		if err != nil {
+			return nil, 0, nil, err
 		}
-		existingHash = newHash
-	} else if err != nil {
-		return nil, 0, 0, fmt.Errorf("database error: %v", err)
-	} else {
-		// Content already exists, increment reference count
-		if err := tx.Model(&existingHash).Update("reference_count", gorm.Expr("reference_count + 1")).Error; err != nil {
-			return nil, 0, 0, fmt.Errorf("failed to update reference count: %v", err)
+		if versioned != nil && versioned.FileHashID == existingHash.ID {
+			// Same content as the current version; nothing to record
+			if err := tx.Model(&existingHash).Update("reference_count", gorm.Expr("reference_count - 1")).Error; err != nil {
+				return nil, 0, nil, fmt.Errorf("failed to update reference count: %v", err)
+			}
+			return uploadResult(versioned, uploadFile, false, 0), uploadFile.Size, nil, nil
 		}
+		if versioned != nil {
+			actualStorageUsed, orphaned, err := addFileVersion(tx, versioned, &existingHash, uploadFile.Size, uploadFile.MimeType,
+				quarantineReason, nil, h.cfg.FileVersionLimit)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			savedBytes := uploadFile.Size - actualStorageUsed
+			return uploadResult(versioned, uploadFile, isNewContent, actualStorageUsed), savedBytes, orphaned, nil
+		}
+	}
+
+	// Files pick up the default tags of the folder they are uploaded into
+	tags, err := folderDefaultTags(tx, folderID)
+	if err != nil {
+		return nil, 0, nil, err
 	}
 
 	// Create file record
@@ -376,75 +619,168 @@ func (h *FileHandler) processFileUpload(tx *gorm.DB, uploadFile FileUploadInfo,
 		BaseModel: models.BaseModel{
 			ID: uuid.New(),
 		},
-		Filename:         generateUniqueFilename(uploadFile.Header.Filename),
-		OriginalFilename: uploadFile.Header.Filename,
+		Filename:         generateUniqueFilename(originalFilename),
+		OriginalFilename: originalFilename,
+		RawFilename:      rawFilename,
 		MimeType:         uploadFile.MimeType,
 		Size:             uploadFile.Size,
 		FileHashID:       existingHash.ID,
 		OwnerID:          userID,
 		FolderID:         folderID,
+		Tags:             tags,
+		APIKeyID:         apiKeyID,
+		Status:           status,
+		QuarantineReason: quarantineReason,
+		Version:          1,
 	}
 
 	if err := tx.Create(&fileRecord).Error; err != nil {
 		// If file record creation fails and this was new content, decrement reference count
 		if isNewContent {
-			tx.Model(&models.FileHash{}).Where("hash = ?", uploadFile.Hash).Update("reference_count", gorm.Expr("reference_count - 1"))
+			tx.Model(&existingHash).Update("reference_count", gorm.Expr("reference_count - 1"))
 		}
-		return nil, 0, 0, fmt.Errorf("failed to create file record: %v", err)
+		return nil, 0, nil, fmt.Errorf("failed to create file record: %v", err)
 	}
 
-	// Calculate savings and storage
-	savedBytes := int64(0)
-	actualStorageUsed := int64(0)
-
-	if !isNewContent {
-		savedBytes = uploadFile.Size // User saved the full file size due to deduplication
-	} else {
-		actualStorageUsed = uploadFile.Size // New storage used
+	// Charge the file to its owner; content the owner already holds adds no
+	// physical usage
+	actualStorageUsed, err := accountFileAdded(tx, &fileRecord)
+	if err != nil {
+		return nil, 0, nil, err
 	}
+	savedBytes := uploadFile.Size - actualStorageUsed
+
+	return uploadResult(&fileRecord, uploadFile, isNewContent, actualStorageUsed), savedBytes, nil, nil
+}
 
+// uploadResult describes an uploaded file in the upload response
+func uploadResult(fileRecord *models.File, uploadFile FileUploadInfo, isNewContent bool, actualStorageUsed int64) map[string]interface{} {
 	result := map[string]interface{}{
-		"file_id":       fileRecord.ID,
-		"filename":      fileRecord.Filename,
-		"original_name": fileRecord.OriginalFilename,
-		"size":          fileRecord.Size,
-		"mime_type":     fileRecord.MimeType,
-		"content_hash":  uploadFile.Hash,
-		"is_duplicate":  !isNewContent,
-		"saved_bytes":   savedBytes,
+		"file_id":              fileRecord.ID,
+		"filename":             fileRecord.Filename,
+		"original_name":        fileRecord.OriginalFilename,
+		"size":                 fileRecord.Size,
+		"mime_type":            fileRecord.MimeType,
+		"tags":                 fileRecord.Tags,
+		"content_hash":         uploadFile.Hash,
+		"is_duplicate":         !isNewContent,
+		"saved_bytes":          uploadFile.Size - actualStorageUsed,
+		"actual_storage_bytes": actualStorageUsed,
+		"status":               fileRecord.Status,
+		"version":              fileRecord.Version,
+	}
+
+	if fileRecord.Quarantined() {
+		result["notice"] = quarantineNotice
 	}
 
 	if uploadFile.Warning != "" {
 		result["warning"] = uploadFile.Warning
 	}
 
-	return result, savedBytes, actualStorageUsed, nil
+	return result
 }
 
-// updateUserStorageStats updates user storage statistics within a transaction
-func (h *FileHandler) updateUserStorageStats(tx *gorm.DB, userID uuid.UUID, totalUploadedBytes, totalActualStorage, totalSavedBytes int64) error {
-	var user models.User
-	if err := tx.First(&user, userID).Error; err != nil {
-		return fmt.Errorf("failed to find user: %v", err)
+// createContentHash claims the hash of new content and stores its blob. When
+// a concurrent upload of the same content has already claimed the hash it
+// returns nil, once that upload has committed, and writes nothing.
+func (h *FileHandler) createContentHash(tx *gorm.DB, uploadFile FileUploadInfo) (*models.FileHash, error) {
+	storagePath := services.BlobStoragePath(uploadFile.Hash)
+	newHash := models.FileHash{
+		ID:             uuid.New(),
+		Hash:           uploadFile.Hash,
+		Size:           uploadFile.Size,
+		StoragePath:    storagePath,
+		ReferenceCount: 1,
 	}
 
-	// Update user storage statistics
-	user.TotalUploadedBytes += totalUploadedBytes
-	user.ActualStorageBytes += totalActualStorage
-	user.StorageUsed += totalActualStorage
-	user.SavedBytes += totalSavedBytes
+	// Postgres waits on a conflicting insert from another open transaction,
+	// so losing here means the other upload's row and blob are in place
+	result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "hash"}}, DoNothing: true}).Create(&newHash)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to save file hash: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
 
-	if err := tx.Save(&user).Error; err != nil {
-		return fmt.Errorf("failed to update user storage stats: %v", err)
+	// Store file physically only if it's new content
+	if err := h.storeBlob(uploadFile, uploadFile.Hash); err != nil {
+		return nil, err
 	}
 
+	if chunkSize, checksums := h.chunkChecksums(uploadFile.Hash); checksums != nil {
+		if err := tx.Model(&newHash).Updates(map[string]interface{}{
+			"chunk_size":      chunkSize,
+			"chunk_checksums": checksums,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to save chunk checksums: %v", err)
+		}
+	}
+	return &newHash, nil
+}
+
+// chunkChecksums computes the chunk checksums of a stored blob when they are
+// enabled and blobs are on local disk. A failure is logged and leaves the
+// blob without them, so its ranges are served unchecked.
+func (h *FileHandler) chunkChecksums(hash string) (int64, []byte) {
+	if !h.cfg.ChunkChecksums {
+		return 0, nil
+	}
+	blobPath, ok := localBlobPath(h.storage, hash)
+	if !ok {
+		return 0, nil
+	}
+
+	checksums, err := services.ComputeChunkChecksums(blobPath, h.cfg.ChunkChecksumSize)
+	if err != nil {
+		log.Printf("Failed to compute chunk checksums for %s: %v", blobPath, err)
+		return 0, nil
+	}
+	return h.cfg.ChunkChecksumSize, checksums
+}
+
+// storeBlob stores uploaded content as the blob for hash. Streamed content
+// is moved into place when blobs are on local disk, and uploaded from its
+// spool file otherwise.
+func (h *FileHandler) storeBlob(uploadFile FileUploadInfo, hash string) error {
+	if uploadFile.TempPath != "" {
+		if local, ok := h.storage.(*services.LocalStorage); ok {
+			err := local.Adopt(hash, uploadFile.TempPath)
+			h.backends.RecordOperation(h.storage.Name(), err)
+			return err
+		}
+
+		tempFile, err := os.Open(uploadFile.TempPath)
+		if err != nil {
+			return fmt.Errorf("failed to open uploaded content: %v", err)
+		}
+		defer tempFile.Close()
+		err = h.storage.Put(hash, tempFile)
+		h.backends.RecordOperation(h.storage.Name(), err)
+		if err != nil {
+			return fmt.Errorf("failed to store file: %v", err)
+		}
+		return nil
+	}
+
+	err := h.storage.Put(hash, bytes.NewReader(uploadFile.Content))
+	h.backends.RecordOperation(h.storage.Name(), err)
+	if err != nil {
+		return fmt.Errorf("failed to write file to storage: %v", err)
+	}
 	return nil
 }
 
-// calculateContentHash calculates SHA-256 hash of file content
-func (h *FileHandler) calculateContentHash(data []byte) string {
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash[:])
+// updateUserStorageStats records uploaded bytes in the user's lifetime total.
+// Logical and physical usage are charged per file by processFileUpload.
+func (h *FileHandler) updateUserStorageStats(tx *gorm.DB, userID uuid.UUID, totalUploadedBytes int64) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("total_uploaded_bytes", gorm.Expr("total_uploaded_bytes + ?", totalUploadedBytes)).Error; err != nil {
+		return fmt.Errorf("failed to update user storage stats: %v", err)
+	}
+
+	return nil
 }
 
 // ListFiles handles listing user files
@@ -455,37 +791,86 @@ func (h *FileHandler) ListFiles(c *gin.Context) {
 		return
 	}
 
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
 	// Get folder filter from query parameter
 	folderIDStr := c.Query("folder_id")
 
 	var files []models.File
-	query := h.db.Where("owner_id = ? AND is_deleted = false", userID)
+	query := h.db.Where("is_deleted = false")
 
 	// Apply folder filter
-	if folderIDStr != "" {
-		if folderIDStr == "root" || folderIDStr == "null" {
-			// Show files in root folder (no folder assigned)
-			query = query.Where("folder_id IS NULL")
-		} else {
-			// Show files in specific folder
-			folderUUID, err := uuid.Parse(folderIDStr)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID format"})
+	if folderIDStr != "" && folderIDStr != "root" && folderIDStr != "null" {
+		// Show files in specific folder
+		folderUUID, err := uuid.Parse(folderIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID format"})
+			return
+		}
+
+		// Folders shared with the caller list everyone's files in them
+		var folder models.Folder
+		if err := h.db.Where("id = ?", folderUUID).First(&folder).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
 				return
 			}
-			query = query.Where("folder_id = ?", folderUUID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get folder"})
+			return
+		}
+
+		level, err := services.FolderAccessLevel(h.db, &folder, userID.(uuid.UUID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check folder access"})
+			return
+		}
+		if !level.Allows(services.AccessRead) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+
+		if folder.OwnerID == userID.(uuid.UUID) {
+			query = query.Where("owner_id = ?", userID)
+		}
+		query = query.Where("folder_id = ?", folderUUID)
+	} else {
+		query = query.Where("owner_id = ?", userID)
+		if folderIDStr != "" {
+			// Show files in root folder (no folder assigned)
+			query = query.Where("folder_id IS NULL")
+		}
+	}
+
+	// Apply API key filter
+	if apiKeyIDStr := c.Query("api_key_id"); apiKeyIDStr != "" {
+		apiKeyUUID, err := uuid.Parse(apiKeyIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID format"})
+			return
 		}
+		query = query.Where("api_key_id = ?", apiKeyUUID)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(&models.File{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count files"})
+		return
 	}
 
 	// Load files with folder relationship
-	if err := query.Preload("Folder").Order("original_filename ASC").Find(&files).Error; err != nil {
+	if err := query.Preload("Folder").Order("original_filename ASC").Order("id ASC").
+		Offset(paging.offset()).Limit(paging.pageSize).Find(&files).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"files": files,
-		"count": len(files),
+		"files":      files,
+		"count":      len(files),
+		"pagination": paging.envelope(total),
 	})
 }
 
@@ -499,8 +884,9 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 
 	fileID := c.Param("id")
 
-	var file models.File
-	if err := h.db.Where("id = ? AND owner_id = ? AND is_deleted = false", fileID, userID).First(&file).Error; err != nil {
+	// Owners and users the containing folder is shared with can see the file
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessRead)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 			return
@@ -509,110 +895,222 @@ func (h *FileHandler) GetFile(c *gin.Context) {
 		return
 	}
 
+	if rejectExpiredAccess(c, file) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"file": file,
 	})
 }
 
 // ViewFile serves file content for preview/viewing
+// GET /api/files/:id/view
+// HEAD /api/files/:id/view
 func (h *FileHandler) ViewFile(c *gin.Context) {
-	fmt.Printf("DEBUG ViewFile: Starting ViewFile function\n")
+	h.serveFile(c, "inline")
+}
 
+// DownloadFile serves file content as an attachment, so browsers save it
+// rather than display it. HEAD answers with the same access checks and
+// headers (Content-Length, Content-Type, ETag, Last-Modified, Accept-Ranges)
+// but no body. Large files can be fetched as several ranges at once: HEAD
+// gives the size and recommended range size, then each range is a GET with a
+// Range header (If-Range with the ETag guards against the content changing
+// in between). Every request reads through its own file handle, so
+// concurrent ranges of the same blob don't interfere.
+// GET /api/files/:id/download
+// HEAD /api/files/:id/download
+// HEAD /api/files/:id
+func (h *FileHandler) DownloadFile(c *gin.Context) {
+	h.serveFile(c, "attachment")
+}
+
+// serveFile streams a file the user can read with the given
+// Content-Disposition and records the download
+func (h *FileHandler) serveFile(c *gin.Context, disposition string) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		fmt.Printf("DEBUG ViewFile: User not authenticated - user_id not found in context\n")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	fmt.Printf("DEBUG ViewFile: User ID from context: %v\n", userID)
-
 	fileID := c.Param("id")
-	fmt.Printf("DEBUG ViewFile: File ID from URL: %s\n", fileID)
 
 	// Get file with its file hash information
-	var file models.File
 	var fileHash models.FileHash
 
-	if err := h.db.Where("id = ? AND owner_id = ? AND is_deleted = false", fileID, userID).First(&file).Error; err != nil {
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessRead)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			fmt.Printf("DEBUG ViewFile: File not found in database: %s\n", fileID)
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 			return
 		}
-		fmt.Printf("DEBUG ViewFile: Database error getting file: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
 		return
 	}
 
-	fmt.Printf("DEBUG ViewFile: Found file: %s, FileHashID: %s\n", file.ID, file.FileHashID)
+	if rejectExpiredAccess(c, file) {
+		return
+	}
+	if rejectQuarantined(c, file) {
+		return
+	}
 
 	// Get the file hash record to find the storage path
-	fmt.Printf("DEBUG ViewFile: Looking up file hash with ID: %s\n", file.FileHashID)
 	if err := h.db.Where("id = ?", file.FileHashID).First(&fileHash).Error; err != nil {
-		fmt.Printf("DEBUG ViewFile: Failed to get file hash: %v\n", err)
-		if err == gorm.ErrRecordNotFound {
-			fmt.Printf("DEBUG ViewFile: File hash record not found for ID: %s\n", file.FileHashID)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get file storage information",
-			"debug": fmt.Sprintf("FileHashID: %s, Error: %v", file.FileHashID, err),
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file storage information"})
 		return
 	}
 
-	fmt.Printf("DEBUG ViewFile: Found file hash: %s, StoragePath: %s\n", fileHash.ID, fileHash.StoragePath)
+	if _, local := localBlobPath(h.storage, fileHash.Hash); !local {
+		h.serveRemoteBlob(c, file, &fileHash, disposition, userID.(uuid.UUID))
+		return
+	}
 
-	// First try the new storage path structure (storage/{hash})
+	// First try content-hash storage, sharded or still flat
 	filePath := filepath.Join(h.cfg.StoragePath, fileHash.StoragePath)
 
-	// Debug logging
-	fmt.Printf("DEBUG ViewFile: StoragePath=%s, fileHash.StoragePath=%s, filePath=%s\n",
-		h.cfg.StoragePath, fileHash.StoragePath, filePath)
-
 	// Check if file exists at new location
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		fmt.Printf("DEBUG ViewFile: File does not exist at new path: %s\n", filePath)
-
+	if _, err := os.Stat(filePath); os.IsNotExist(err) && !h.cfg.LegacyStorageFallback {
+		log.Printf("Content %s of file %s is missing from storage at %s", fileHash.Hash, file.ID, filePath)
+		h.backends.RecordOperation(services.LocalStorageBackend, fmt.Errorf("blob %s missing", fileHash.Hash))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "File content is missing from storage",
+			"code":  "content_missing",
+		})
+		return
+	} else if os.IsNotExist(err) {
 		// Try legacy storage pattern (direct UUID filename)
 		legacyFilePath := filepath.Join(h.cfg.StoragePath, file.ID.String())
-		fmt.Printf("DEBUG ViewFile: Trying legacy path: %s\n", legacyFilePath)
 
 		if _, err := os.Stat(legacyFilePath); os.IsNotExist(err) {
-			fmt.Printf("DEBUG ViewFile: File does not exist at legacy path either: %s\n", legacyFilePath)
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "File not found on disk",
-				"debug": fmt.Sprintf("StoragePath: %s, FileHashPath: %s, FullPath: %s, LegacyPath: %s", h.cfg.StoragePath, fileHash.StoragePath, filePath, legacyFilePath),
-			})
+			h.backends.RecordOperation(services.LocalStorageBackend, fmt.Errorf("blob %s missing", fileHash.Hash))
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
 			return
 		}
 
 		// Use legacy path
 		filePath = legacyFilePath
-		fmt.Printf("DEBUG ViewFile: Using legacy file path: %s\n", filePath)
 	}
+	h.backends.RecordOperation(services.LocalStorageBackend, nil)
+
+	// Check the chunks a range request reads; legacy blobs have no checksums,
+	// and HEAD requests read no content
+	isBlob := filePath == filepath.Join(h.cfg.StoragePath, fileHash.StoragePath)
+	if isBlob && c.Request.Method != http.MethodHead && rejectCorruptRange(c, h.chunks.VerifyRequest(&fileHash, filePath, c.GetHeader("Range"))) {
+		return
+	}
+	if isBlob && rejectCorruptBlob(c, h.db, h.cfg, h.storage, &fileHash) {
+		return
+	}
+
+	blob, err := os.Open(filePath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer blob.Close()
+	info, err := blob.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+
+	// The content hash identifies the bytes served, so it makes a strong ETag
+	// that stays valid across renames and duplicate copies
+	etag := fmt.Sprintf("%q", fileHash.Hash)
+	if c.Request.Method != http.MethodHead && !etagMatches(c.GetHeader("If-None-Match"), etag) {
+		h.recordDownload(c, file, userID.(uuid.UUID))
+	}
+
+	h.setDownloadHeaders(c, file, disposition, etag)
+
+	// ServeContent answers Range requests so media players can seek, and
+	// If-None-Match / If-Modified-Since with 304 Not Modified. HEAD requests
+	// get the headers, Content-Length included, without the body
+	http.ServeContent(c.Writer, c.Request, file.OriginalFilename, info.ModTime(), blob)
+}
+
+// serveRemoteBlob streams a file whose content is kept in remote storage.
+// Ranges can't be served or checked against chunk checksums there, so the
+// whole blob is sent.
+func (h *FileHandler) serveRemoteBlob(c *gin.Context, file *models.File, fileHash *models.FileHash, disposition string, userID uuid.UUID) {
+	etag := fmt.Sprintf("%q", fileHash.Hash)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if rejectCorruptBlob(c, h.db, h.cfg, h.storage, fileHash) {
+		return
+	}
+
+	blob, err := h.storage.Get(fileHash.Hash)
+	if errors.Is(err, services.ErrBlobNotFound) {
+		log.Printf("Content %s of file %s is missing from %s storage", fileHash.Hash, file.ID, h.storage.Name())
+		h.backends.RecordOperation(h.storage.Name(), fmt.Errorf("blob %s missing", fileHash.Hash))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "File content is missing from storage",
+			"code":  "content_missing",
+		})
+		return
+	}
+	h.backends.RecordOperation(h.storage.Name(), err)
+	if err != nil {
+		log.Printf("Failed to open content %s of file %s: %v", fileHash.Hash, file.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer blob.Close()
+
+	if c.Request.Method != http.MethodHead {
+		h.recordDownload(c, file, userID)
+	}
+	h.setDownloadHeaders(c, file, disposition, etag)
+	streamBlob(c, blob, file.OriginalFilename, fileHash.Size)
+}
 
-	// Set appropriate headers for inline viewing
+// setDownloadHeaders describes the file being served
+func (h *FileHandler) setDownloadHeaders(c *gin.Context, file *models.File, disposition, etag string) {
 	c.Header("Content-Type", file.MimeType)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", file.OriginalFilename))
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, file.OriginalFilename))
 	c.Header("Cache-Control", "max-age=3600") // Cache for 1 hour
+	c.Header("ETag", etag)
+	if h.cfg.DownloadChunkSize > 0 {
+		c.Header("X-Recommended-Chunk-Size", strconv.FormatInt(h.cfg.DownloadChunkSize, 10))
+	}
+}
 
-	// Serve the file
-	c.File(filePath)
+// etagMatches reports whether an If-None-Match header names etag, so a
+// revalidation that ends in 304 Not Modified isn't counted as a download
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
-// DeleteFile handles file deletion with deduplication cleanup
-func (h *FileHandler) DeleteFile(c *gin.Context) {
+// GetThumbnail serves the generated thumbnail for a file, or a generic icon
+// when it has none
+func (h *FileHandler) GetThumbnail(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	fileID := c.Param("id")
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
 
-	var file models.File
-	if err := h.db.Where("id = ? AND owner_id = ? AND is_deleted = false", fileID, userID).First(&file).Error; err != nil {
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessRead, "FileHash")
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 			return
@@ -621,84 +1119,233 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	// Start transaction for consistent deduplication cleanup
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Mark file as deleted
-	if err := tx.Model(&file).Updates(map[string]interface{}{
-		"is_deleted": true,
-		"deleted_at": time.Now(),
-		"updated_at": time.Now(),
-	}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+	if rejectExpiredAccess(c, file) {
 		return
 	}
-
-	// Decrease reference count for the file hash
-	var fileHash models.FileHash
-	if err := tx.Where("id = ?", file.FileHashID).First(&fileHash).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find file hash"})
+	if rejectQuarantined(c, file) {
 		return
 	}
 
-	// Decrement reference count
-	newRefCount := fileHash.ReferenceCount - 1
-	if err := tx.Model(&fileHash).Update("reference_count", newRefCount).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reference count"})
+	if file.FileHash == nil {
+		serveGenericThumbnail(c)
 		return
 	}
 
-	// If no more references, delete the hash record
-	actualStorageFreed := int64(0)
-	if newRefCount <= 0 {
-		if err := tx.Delete(&fileHash).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file hash"})
+	thumbnailPath := h.thumbnails.ThumbnailFilePath(file.FileHash)
+	if thumbnailPath == "" {
+		// Thumbnails skipped while the queue was full are generated on demand
+		if !file.FileHash.ThumbnailFailed && h.thumbnails.Enqueue(file.FileHash.Hash, file.MimeType) {
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusAccepted, gin.H{"message": "Thumbnail is being generated"})
 			return
 		}
-		actualStorageFreed = file.Size
+		serveGenericThumbnail(c)
+		return
 	}
 
-	// Update user storage statistics
-	var user models.User
-	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+	if _, err := os.Stat(thumbnailPath); err != nil {
+		serveGenericThumbnail(c)
 		return
 	}
 
-	updates := map[string]interface{}{
-		"storage_used":         gorm.Expr("storage_used - ?", file.Size),
-		"actual_storage_bytes": gorm.Expr("actual_storage_bytes - ?", actualStorageFreed),
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("Cache-Control", "max-age=86400") // Thumbnails are immutable per content hash
+	c.File(thumbnailPath)
+}
+
+// serveGenericThumbnail sends the icon shown for files without a thumbnail:
+// unsupported types, failed generation, generation dropped from a full queue,
+// or no workers to generate it. It isn't cached for long, as a thumbnail may still be generated.
+func serveGenericThumbnail(c *gin.Context) {
+	c.Header("Cache-Control", "max-age=300")
+	c.Data(http.StatusOK, "image/png", services.GenericThumbnail())
+}
+
+// DeleteFile handles file deletion with deduplication cleanup
+func (h *FileHandler) DeleteFile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
 	}
 
-	if err := tx.Model(&user).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+	fileID := c.Param("id")
+
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
 		return
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+	// Delete within the request transaction for consistent deduplication cleanup
+	tx := middleware.Tx(c)
+
+	actualStorageFreed, revoked, err := trashFile(tx, file, blocksSharedFileDeletion(h.cfg))
+	var shared *fileSharedError
+	if errors.As(err, &shared) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "File has active shares, revoke them before deleting it",
+			"share_links": shared.shares.links,
+			"user_shares": shared.shares.users,
+			"acl_entries": shared.shares.aclEntries,
+		})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file", "details": err.Error()})
 		return
 	}
+	recordAudit(tx, c, "file_delete", "file", &file.ID, map[string]interface{}{
+		"filename":            file.OriginalFilename,
+		"size":                file.Size,
+		"revoked_share_links": revoked.links,
+		"revoked_user_shares": revoked.users,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":               "File deleted successfully",
 		"actual_storage_freed":  actualStorageFreed,
 		"logical_storage_freed": file.Size,
+		"revoked_share_links":   revoked.links,
+		"revoked_user_shares":   revoked.users,
 	})
 }
 
-// MoveFile moves a file to a different folder
+// fileSharedError is returned by trashFile when a file with active shares is
+// kept from being deleted
+type fileSharedError struct {
+	fileID uuid.UUID
+	shares fileShareCounts
+}
+
+func (e *fileSharedError) Error() string {
+	return fmt.Sprintf("file %s has active shares", e.fileID)
+}
+
+// blocksSharedFileDeletion reports whether files with active shares are kept
+// from being deleted rather than having their shares revoked
+func blocksSharedFileDeletion(cfg *config.Config) bool {
+	return cfg.SharedFileDeletion == "block"
+}
+
+// trashFile moves a file to trash within a transaction along with its
+// shares, which are revoked so nobody reaches the file once it is deleted.
+// With block set a file that still has active shares is left alone and a
+// *fileSharedError returned instead. It returns the physical storage freed
+// from the owner's usage and the shares revoked.
+//
+// Every path that deletes files on a user's behalf goes through here rather
+// than calling softDeleteFile directly.
+func trashFile(tx *gorm.DB, file *models.File, block bool) (int64, fileShareCounts, error) {
+	if block {
+		shares, err := countActiveFileShares(tx, file.ID)
+		if err != nil {
+			return 0, fileShareCounts{}, fmt.Errorf("failed to check file shares: %v", err)
+		}
+		if shares.total() > 0 {
+			return 0, fileShareCounts{}, &fileSharedError{fileID: file.ID, shares: shares}
+		}
+	}
+
+	revoked, err := revokeFileShares(tx, file.ID)
+	if err != nil {
+		return 0, revoked, err
+	}
+	freed, err := softDeleteFile(tx, file)
+	return freed, revoked, err
+}
+
+// softDeleteFile marks a file as deleted within a transaction, releases its
+// reference on the underlying content and updates the owner's storage stats.
+// It returns the physical storage freed from the owner's usage. Content rows
+// are kept at zero references so the file can still be restored.
+func softDeleteFile(tx *gorm.DB, file *models.File) (int64, error) {
+	now := time.Now()
+
+	// Mark file as deleted
+	if err := tx.Model(file).Updates(map[string]interface{}{
+		"is_deleted": true,
+		"deleted_at": now,
+		"updated_at": now,
+	}).Error; err != nil {
+		return 0, fmt.Errorf("failed to mark file as deleted: %v", err)
+	}
+
+	// Decrease reference count for the file hash
+	if err := tx.Model(&models.FileHash{}).Where("id = ? AND reference_count > 0", file.FileHashID).
+		Update("reference_count", gorm.Expr("reference_count - 1")).Error; err != nil {
+		return 0, fmt.Errorf("failed to update reference count: %v", err)
+	}
+
+	return accountFileRemoved(tx, file)
+}
+
+// fileShareCounts counts the ways a file is shared
+type fileShareCounts struct {
+	links      int64 // public share links
+	users      int64 // direct shares with users
+	aclEntries int64 // access list entries
+}
+
+func (s fileShareCounts) total() int64 {
+	return s.links + s.users + s.aclEntries
+}
+
+// countActiveFileShares counts the share links, user shares and access list
+// entries that still give someone access to a file
+func countActiveFileShares(tx *gorm.DB, fileID uuid.UUID) (fileShareCounts, error) {
+	var counts fileShareCounts
+	now := time.Now()
+
+	if err := tx.Model(&models.ShareLink{}).
+		Where("file_id = ? AND is_active = true AND (expires_at IS NULL OR expires_at >= ?)", fileID, now).
+		Count(&counts.links).Error; err != nil {
+		return counts, err
+	}
+	if err := tx.Model(&models.FileShare{}).
+		Where("file_id = ? AND is_active = true AND (expires_at IS NULL OR expires_at > ?)", fileID, now).
+		Count(&counts.users).Error; err != nil {
+		return counts, err
+	}
+	if err := tx.Model(&models.FileACLEntry{}).
+		Where("file_id = ? AND (expires_at IS NULL OR expires_at > ?)", fileID, now).
+		Count(&counts.aclEntries).Error; err != nil {
+		return counts, err
+	}
+	return counts, nil
+}
+
+// revokeFileShares deactivates a file's share links and direct user shares
+// and clears its access list so nobody reaches it once it is deleted
+func revokeFileShares(tx *gorm.DB, fileID uuid.UUID) (fileShareCounts, error) {
+	var revoked fileShareCounts
+
+	result := tx.Model(&models.ShareLink{}).Where("file_id = ? AND is_active = true", fileID).Update("is_active", false)
+	if result.Error != nil {
+		return revoked, fmt.Errorf("failed to revoke share links: %v", result.Error)
+	}
+	revoked.links = result.RowsAffected
+
+	result = tx.Model(&models.FileShare{}).Where("file_id = ? AND is_active = true", fileID).Update("is_active", false)
+	if result.Error != nil {
+		return revoked, fmt.Errorf("failed to revoke user shares: %v", result.Error)
+	}
+	revoked.users = result.RowsAffected
+
+	result = tx.Where("file_id = ?", fileID).Delete(&models.FileACLEntry{})
+	if result.Error != nil {
+		return revoked, fmt.Errorf("failed to clear access list: %v", result.Error)
+	}
+	revoked.aclEntries = result.RowsAffected
+
+	return revoked, nil
+}
+
+// MoveFile moves a file to a different folder. Users the file is shared
+// with for editing can move it between folders of its owner.
 func (h *FileHandler) MoveFile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -722,9 +1369,9 @@ func (h *FileHandler) MoveFile(c *gin.Context) {
 		return
 	}
 
-	// Get the file
-	var file models.File
-	if err := h.db.Where("id = ? AND owner_id = ? AND is_deleted = false", fileUUID, userID).First(&file).Error; err != nil {
+	// Get the file; users it is shared with for editing can move it too
+	file, err := services.FindFileWithAccess(h.db, fileUUID, userID.(uuid.UUID), services.AccessWrite)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 			return
@@ -732,11 +1379,13 @@ func (h *FileHandler) MoveFile(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
 		return
 	}
+	isOwner := file.OwnerID == userID.(uuid.UUID)
 
 	// Validate target folder if provided
 	if req.FolderID != nil {
-		var targetFolder models.Folder
-		if err := h.db.Where("id = ? AND owner_id = ?", req.FolderID, userID).First(&targetFolder).Error; err != nil {
+		// Moving a file into a folder is adding to it, so edit access is enough
+		target, err := services.FindFolderWithAccess(h.db, req.FolderID, userID.(uuid.UUID), services.AccessWrite)
+		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
 				return
@@ -744,16 +1393,28 @@ func (h *FileHandler) MoveFile(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify target folder"})
 			return
 		}
+		// Others only rearrange the file within its owner's folders
+		if !isOwner && target.OwnerID != file.OwnerID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Files shared with you can only be moved between their owner's folders"})
+			return
+		}
+	} else if !isOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can move a file to their root folder"})
+		return
 	}
 
 	// Update file folder
-	if err := h.db.Model(&file).Update("folder_id", req.FolderID).Error; err != nil {
+	previousFolderID := file.FolderID
+	if err := h.db.Model(file).Update("folder_id", req.FolderID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move file"})
 		return
 	}
+	recordAuditChange(h.db, c, "file_move", "file", &file.ID,
+		map[string]interface{}{"folder_id": previousFolderID},
+		map[string]interface{}{"folder_id": req.FolderID})
 
 	// Reload file with folder information
-	h.db.Preload("Folder").First(&file, fileUUID)
+	h.db.Preload("Folder").First(file, fileUUID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "File moved successfully",
@@ -776,18 +1437,77 @@ func (h *FileHandler) GetStorageSavings(c *gin.Context) {
 	}
 
 	savingsPercent := float64(0)
-	if user.TotalUploadedBytes > 0 {
-		savingsPercent = (float64(user.SavedBytes) / float64(user.TotalUploadedBytes)) * 100
+	if user.StorageUsed > 0 {
+		savingsPercent = (float64(user.SavedBytes) / float64(user.StorageUsed)) * 100
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"total_uploaded_bytes": user.TotalUploadedBytes,
+		"storage_used":         user.StorageUsed,
 		"actual_storage_bytes": user.ActualStorageBytes,
 		"saved_bytes":          user.SavedBytes,
 		"savings_percent":      savingsPercent,
 	})
 }
 
+// rejectExecutable refuses content that starts with an executable or script
+// signature when executables are blocked, whatever type it was declared as
+func (h *FileHandler) rejectExecutable(c *gin.Context, validator *utils.MimeTypeValidator, filename string, content []byte) bool {
+	if !h.cfg.BlockExecutables {
+		return false
+	}
+
+	format := validator.DetectExecutable(content)
+	if format == "" {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":           fmt.Sprintf("Executable content is not allowed for %s", filename),
+		"code":            "executable_content",
+		"filename":        filename,
+		"detected_format": format,
+	})
+	return true
+}
+
+// multipartUploadFiles collects the files sent in the accepted fields, in the
+// order the fields are listed. A "*" field accepts every file field, in name
+// order.
+func multipartUploadFiles(form *multipart.Form, fields []string) []*multipart.FileHeader {
+	var files []*multipart.FileHeader
+	for _, field := range fields {
+		if field == "*" {
+			names := make([]string, 0, len(form.File))
+			for name := range form.File {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			files = files[:0]
+			for _, name := range names {
+				files = append(files, form.File[name]...)
+			}
+			return files
+		}
+		files = append(files, form.File[field]...)
+	}
+	return files
+}
+
+// apiKeyIDFromContext returns the API key used to authenticate the request, if any
+func apiKeyIDFromContext(c *gin.Context) *uuid.UUID {
+	value, exists := c.Get("api_key_id")
+	if !exists {
+		return nil
+	}
+	apiKeyID, ok := value.(uuid.UUID)
+	if !ok {
+		return nil
+	}
+	return &apiKeyID
+}
+
 // Helper function to generate unique filename
 func generateUniqueFilename(originalFilename string) string {
 	ext := filepath.Ext(originalFilename)
@@ -795,3 +1515,63 @@ func generateUniqueFilename(originalFilename string) string {
 	timestamp := time.Now().Unix()
 	return fmt.Sprintf("%s_%d%s", name, timestamp, ext)
 }
+
+// CheckFileAccess reports the effective access a user has to a file. Admins
+// may ask about any user; everyone else may only ask about themselves.
+func (h *FileHandler) CheckFileAccess(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	targetUserID := userID.(uuid.UUID)
+	if rawUserID := c.Query("user_id"); rawUserID != "" {
+		parsed, err := uuid.Parse(rawUserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id format"})
+			return
+		}
+		targetUserID = parsed
+	}
+
+	if targetUserID != userID.(uuid.UUID) && !isAdminRequest(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can check access for other users"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var file models.File
+	if err := h.db.Where("id = ? AND is_deleted = false", fileID).First(&file).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	level, err := services.FileAccessLevel(h.db, &file, targetUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve file access"})
+		return
+	}
+
+	// Users asking about themselves can't tell a file they can't reach from
+	// one that doesn't exist
+	if level == services.AccessNone && !isAdminRequest(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file_id": file.ID,
+		"user_id": targetUserID,
+		"access":  level,
+	})
+}
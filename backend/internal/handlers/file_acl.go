@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// GetFileACL lists the access control entries on a file
+// GET /api/files/:id/acl
+func (h *SharingHandler) GetFileACL(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	entries, err := h.sharingService.ListFileACL(fileID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
+// AddFileACLEntries grants several users, or everyone signed in, access to a
+// file in one request
+// POST /api/files/:id/acl
+func (h *SharingHandler) AddFileACLEntries(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Entries []services.FileACLGrant `json:"entries" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	entries, err := h.sharingService.GrantFileACL(fileID, userID.(uuid.UUID), req.Entries)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(h.db, c, "file_acl_grant", "file", &fileID, map[string]interface{}{
+		"entries": entries,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Access updated successfully",
+		"entries": entries,
+	})
+}
+
+// RemoveFileACLEntry revokes one access control entry on a file
+// DELETE /api/files/:id/acl/:entryId
+func (h *SharingHandler) RemoveFileACLEntry(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	entryID, err := uuid.Parse(c.Param("entryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entry ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.sharingService.RevokeFileACL(fileID, entryID, userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(h.db, c, "file_acl_revoke", "file", &fileID, map[string]interface{}{
+		"entry_id": entryID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access revoked successfully",
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// GetFilesByHash returns the files with the given SHA-256 content hash that
+// the caller owns or can read, so clients tracking content by hash can find
+// their files without knowing the IDs
+func (h *FileHandler) GetFilesByHash(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	hash := strings.ToLower(strings.TrimPrefix(c.Param("hash"), "sha256:"))
+	if !contentHashPattern.MatchString(hash) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Hash must be a 64 character hex SHA-256 digest"})
+		return
+	}
+
+	var candidates []models.File
+	if err := h.db.Joins("JOIN file_hashes ON file_hashes.id = files.file_hash_id").
+		Where("file_hashes.hash = ? AND files.is_deleted = false", hash).
+		Order("files.created_at ASC").
+		Find(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up files"})
+		return
+	}
+
+	files := make([]models.File, 0, len(candidates))
+	for i := range candidates {
+		level, err := services.FileAccessLevel(h.db, &candidates[i], userID.(uuid.UUID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve file access"})
+			return
+		}
+		if level.Allows(services.AccessRead) {
+			files = append(files, candidates[i])
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hash":  hash,
+		"files": files,
+		"count": len(files),
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// isAdminRequest reports whether the authenticated user is an admin
+func isAdminRequest(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == "admin"
+}
+
+// rejectExpiredAccess responds with 403 when the file's access period has
+// ended. Admins are never blocked so they can review expired content.
+func rejectExpiredAccess(c *gin.Context, file *models.File) bool {
+	if !file.AccessExpired(time.Now()) || isAdminRequest(c) {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":             "Access to this file has expired",
+		"access_expires_at": file.AccessExpiresAt,
+	})
+	return true
+}
+
+// SetFileAccessExpiry sets or clears the date after which a file's content is
+// no longer served. Owners may change it until it passes; after that only an
+// admin can extend or clear it.
+func (h *FileHandler) SetFileAccessExpiry(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		AccessExpiresAt *time.Time `json:"access_expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	admin := isAdminRequest(c)
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var file *models.File
+	if admin {
+		file = &models.File{}
+		err = h.db.Where("id = ? AND is_deleted = false", fileID).First(file).Error
+	} else {
+		file, err = services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessOwner)
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	now := time.Now()
+	if !admin {
+		if file.AccessExpired(now) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to this file has expired; only an admin can change it"})
+			return
+		}
+		if req.AccessExpiresAt != nil && !req.AccessExpiresAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "access_expires_at must be in the future"})
+			return
+		}
+	}
+
+	if err := h.db.Model(file).Update("access_expires_at", req.AccessExpiresAt).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update access expiry"})
+		return
+	}
+	file.AccessExpiresAt = req.AccessExpiresAt
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access expiry updated",
+		"file":    file,
+	})
+}
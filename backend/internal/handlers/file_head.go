@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// The head of a file defaults to defaultFileHeadBytes bytes and can't exceed
+// maxFileHeadBytes
+const (
+	defaultFileHeadBytes = 512
+	maxFileHeadBytes     = 4096
+)
+
+// GetFileHead returns the first bytes of a file as a hex dump or text, to
+// identify unknown files or check their header without downloading them
+// GET /api/files/:id/head?bytes=512&format=hex|text
+func (h *FileHandler) GetFileHead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	n := defaultFileHeadBytes
+	if value := c.Query("bytes"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxFileHeadBytes {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bytes must be between 1 and " + strconv.Itoa(maxFileHeadBytes)})
+			return
+		}
+		n = parsed
+	}
+
+	format := c.DefaultQuery("format", "hex")
+	if format != "hex" && format != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be hex or text"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessRead, "FileHash")
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	if rejectExpiredAccess(c, file) {
+		return
+	}
+	if rejectQuarantined(c, file) {
+		return
+	}
+
+	blob, err := h.openBlob(file)
+	if errors.Is(err, services.ErrBlobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found in storage"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+		return
+	}
+	defer blob.Close()
+
+	head := make([]byte, n)
+	read, err := io.ReadFull(blob, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	head = head[:read]
+
+	content := hex.Dump(head)
+	if format == "text" {
+		// Bytes that aren't valid UTF-8 show as the replacement character
+		content = strings.ToValidUTF8(string(head), "�")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":    file.ID,
+		"filename":   file.OriginalFilename,
+		"mime_type":  file.MimeType,
+		"size":       file.Size,
+		"bytes_read": read,
+		"truncated":  int64(read) < file.Size,
+		"format":     format,
+		"content":    content,
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// A file carries at most maxFileTags tags
+const maxFileTags = 50
+
+// fileTagCount is one entry of the user's tag cloud
+type fileTagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// validateFileTags normalizes a file's tags and checks their limits
+func validateFileTags(tags []string) ([]string, error) {
+	tags = normalizeTags(tags)
+	if len(tags) > maxFileTags {
+		return nil, fmt.Errorf("a file can have at most %d tags", maxFileTags)
+	}
+	for _, tag := range tags {
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+	}
+	return tags, nil
+}
+
+// updateFileTags applies change to the tags of a file the user can edit,
+// holding the file so concurrent changes apply in turn, and responds with the
+// resulting tags
+func (h *FileHandler) updateFileTags(c *gin.Context, change func(tags []string) []string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var tags []string
+	var invalid error
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		file, err := services.FindFileWithAccess(tx, fileID, userID.(uuid.UUID), services.AccessWrite)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, tags").
+			Where("id = ? AND is_deleted = ?", file.ID, false).First(file).Error; err != nil {
+			return err
+		}
+
+		tags, invalid = validateFileTags(change(normalizeTags(file.Tags)))
+		if invalid != nil {
+			return invalid
+		}
+		return tx.Model(file).Update("tags", tags).Error
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	case invalid != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags updated successfully",
+		"file_id": fileID,
+		"tags":    tags,
+	})
+}
+
+// bindTags reads the tags list from the request body
+func bindTags(c *gin.Context) ([]string, bool) {
+	var req struct {
+		Tags *[]string `json:"tags" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return nil, false
+	}
+	return *req.Tags, true
+}
+
+// SetFileTags replaces a file's tags. Tags are trimmed, lowercased and
+// deduplicated.
+// PUT /api/files/:id/tags
+func (h *FileHandler) SetFileTags(c *gin.Context) {
+	tags, ok := bindTags(c)
+	if !ok {
+		return
+	}
+	h.updateFileTags(c, func([]string) []string {
+		return tags
+	})
+}
+
+// AddFileTags adds tags to a file, keeping the ones it already has
+// POST /api/files/:id/tags
+func (h *FileHandler) AddFileTags(c *gin.Context) {
+	tags, ok := bindTags(c)
+	if !ok {
+		return
+	}
+	h.updateFileTags(c, func(current []string) []string {
+		return append(current, tags...)
+	})
+}
+
+// RemoveFileTag removes one tag from a file. Removing a tag the file doesn't
+// have is not an error.
+// DELETE /api/files/:id/tags/:tag
+func (h *FileHandler) RemoveFileTag(c *gin.Context) {
+	removed := normalizeTags([]string{c.Param("tag")})
+	h.updateFileTags(c, func(current []string) []string {
+		kept := current[:0]
+		for _, tag := range current {
+			if len(removed) == 0 || tag != removed[0] {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// ListFileTags returns the distinct tags on the user's files with how many
+// files carry each, most used first
+// GET /api/files/tags
+func (h *FileHandler) ListFileTags(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tags := []fileTagCount{}
+	if err := h.db.Model(&models.File{}).
+		Select("tag, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL unnest(files.tags) AS tag").
+		Where("files.owner_id = ? AND files.is_deleted = ?", userID, false).
+		Group("tag").
+		Order("count DESC").Order("tag ASC").
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":  tags,
+		"total": len(tags),
+	})
+}
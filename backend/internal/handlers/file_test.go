@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/middleware"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/internal/testdb"
+)
+
+func TestSameContentTwiceInOneUpload(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	user := createTestUser(t, db, 10000)
+
+	const size = 1000
+	content := uniqueContent(size)
+	response := decodeUpload(t, uploadAs(t, h, user.ID,
+		testUpload{"first.bin", content},
+		testUpload{"second.bin", content},
+	))
+
+	if len(response.Files) != 2 {
+		t.Fatalf("uploaded %d files, want 2", len(response.Files))
+	}
+	first, second := response.Files[0], response.Files[1]
+	if first.IsDuplicate || first.SavedBytes != 0 || first.ActualStorageBytes != size {
+		t.Errorf("first file: duplicate %v, saved %d, actual %d; want new content stored in full", first.IsDuplicate, first.SavedBytes, first.ActualStorageBytes)
+	}
+	if !second.IsDuplicate || second.SavedBytes != size || second.ActualStorageBytes != 0 {
+		t.Errorf("second file: duplicate %v, saved %d, actual %d; want a duplicate saving %d bytes", second.IsDuplicate, second.SavedBytes, second.ActualStorageBytes, size)
+	}
+	if response.TotalSavedBytes != size {
+		t.Errorf("total saved bytes = %d, want %d", response.TotalSavedBytes, size)
+	}
+
+	if fileHash := loadContent(t, db, content); fileHash.ReferenceCount != 2 {
+		t.Errorf("reference count = %d, want 2", fileHash.ReferenceCount)
+	}
+	stored := loadUser(t, db, user.ID)
+	if stored.StorageUsed != 2*size || stored.ActualStorageBytes != size || stored.SavedBytes != size {
+		t.Errorf("storage used %d, actual %d, saved %d; want %d, %d, %d", stored.StorageUsed, stored.ActualStorageBytes, stored.SavedBytes, 2*size, size, size)
+	}
+}
+
+func TestConcurrentUploadsOfSameNewContent(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	users := []uuid.UUID{createTestUser(t, db, 10000).ID, createTestUser(t, db, 10000).ID}
+
+	content := uniqueContent(1000)
+	requests := []*http.Request{
+		newUploadRequest(t, testUpload{"first.bin", content}),
+		newUploadRequest(t, testUpload{"second.bin", content}),
+	}
+
+	recorders := make([]*httptest.ResponseRecorder, len(requests))
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range requests {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			recorders[i] = serveUpload(h, users[i], requests[i])
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+
+	duplicates := 0
+	for _, recorder := range recorders {
+		if decodeUpload(t, recorder).Files[0].IsDuplicate {
+			duplicates++
+		}
+	}
+	if duplicates != 1 {
+		t.Errorf("%d uploads were duplicates, want 1", duplicates)
+	}
+
+	fileHash := loadContent(t, db, content)
+	if fileHash.ReferenceCount != 2 {
+		t.Errorf("reference count = %d, want 2", fileHash.ReferenceCount)
+	}
+	var rows int64
+	if err := db.Model(&models.FileHash{}).Where("hash = ?", fileHash.Hash).Count(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Errorf("%d content records, want 1", rows)
+	}
+
+	blobPath := filepath.Join(h.cfg.StoragePath, filepath.FromSlash(services.BlobStoragePath(fileHash.Hash)))
+	entries, err := os.ReadDir(filepath.Dir(blobPath))
+	if err != nil {
+		t.Fatalf("failed to read blob directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(blobPath) {
+		t.Errorf("blob directory holds %d entries, want just the blob", len(entries))
+	}
+}
+
+// deleteFileAs deletes a file as the user through the delete route's
+// middleware
+func deleteFileAs(h *FileHandler, userID, fileID uuid.UUID) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.DELETE("/files/:id", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, middleware.Transaction(h.db), h.DeleteFile)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/files/"+fileID.String(), nil))
+	return recorder
+}
+
+// shareTestFile shares the file with the recipient and through a link
+func shareTestFile(t *testing.T, h *FileHandler, ownerID, fileID uuid.UUID, recipient models.User) models.ShareLink {
+	t.Helper()
+	if _, err := services.NewSharingService(h.db, h.cfg).ShareFileWithUser(services.ShareFileRequest{
+		FileID:     fileID,
+		SharedBy:   ownerID,
+		Email:      recipient.Email,
+		Permission: models.PermissionView,
+	}); err != nil {
+		t.Fatalf("ShareFileWithUser: %v", err)
+	}
+
+	link := models.ShareLink{
+		FileID:     &fileID,
+		CreatedBy:  ownerID,
+		ShareToken: uuid.NewString(),
+		Permission: models.PermissionDownload,
+		IsActive:   true,
+	}
+	if err := h.db.Create(&link).Error; err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+	return link
+}
+
+func TestDeleteFileRevokesShares(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	h.cfg.SharedFileDeletion = "revoke"
+	owner := createTestUser(t, db, 10000)
+	recipient := createTestUser(t, db, 10000)
+
+	fileID := decodeUpload(t, uploadAs(t, h, owner.ID, testUpload{"shared.bin", uniqueContent(100)})).Files[0].FileID
+	link := shareTestFile(t, h, owner.ID, fileID, recipient)
+
+	if recorder := deleteFileAs(h, owner.ID, fileID); recorder.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+
+	if err := db.First(&link, "id = ?", link.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if link.IsActive {
+		t.Error("share link is still active after the file was deleted")
+	}
+	var activeShares, aclEntries int64
+	if err := db.Model(&models.FileShare{}).Where("file_id = ? AND is_active = true", fileID).Count(&activeShares).Error; err != nil {
+		t.Fatal(err)
+	}
+	if activeShares != 0 {
+		t.Errorf("%d direct shares still active, want 0", activeShares)
+	}
+	if err := db.Model(&models.FileACLEntry{}).Where("file_id = ?", fileID).Count(&aclEntries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if aclEntries != 0 {
+		t.Errorf("%d access list entries left, want 0", aclEntries)
+	}
+}
+
+func TestDeleteFileBlockedByShares(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	h.cfg.SharedFileDeletion = "block"
+	owner := createTestUser(t, db, 10000)
+	recipient := createTestUser(t, db, 10000)
+
+	fileID := decodeUpload(t, uploadAs(t, h, owner.ID, testUpload{"shared.bin", uniqueContent(100)})).Files[0].FileID
+	link := shareTestFile(t, h, owner.ID, fileID, recipient)
+
+	if recorder := deleteFileAs(h, owner.ID, fileID); recorder.Code != http.StatusConflict {
+		t.Fatalf("delete status = %d, want %d: %s", recorder.Code, http.StatusConflict, recorder.Body.String())
+	}
+
+	var file models.File
+	if err := db.First(&file, "id = ?", fileID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if file.IsDeleted {
+		t.Error("file was deleted despite its active shares")
+	}
+	if err := db.First(&link, "id = ?", link.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !link.IsActive {
+		t.Error("share link was revoked though deletion was blocked")
+	}
+}
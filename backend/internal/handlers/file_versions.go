@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+var (
+	errVersionCurrent     = errors.New("version is already the current one")
+	errVersionContentGone = errors.New("version content is no longer in storage")
+	errVersionOverQuota   = errors.New("restoring the version would exceed the storage quota")
+)
+
+// findVersionedFile returns the owner's live file with the given name in the
+// folder, locked for update, or nil when there is none. With FILE_VERSIONING
+// on, an upload matching it becomes its next version.
+func findVersionedFile(tx *gorm.DB, ownerID uuid.UUID, folderID *uuid.UUID, name string) (*models.File, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("owner_id = ? AND original_filename = ? AND is_deleted = ?", ownerID, name, false)
+	if folderID != nil {
+		query = query.Where("folder_id = ?", *folderID)
+	} else {
+		query = query.Where("folder_id IS NULL")
+	}
+
+	var file models.File
+	err := query.Order("updated_at DESC").First(&file).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find existing file: %v", err)
+	}
+	return &file, nil
+}
+
+// ensureFirstVersion records a file's current content as a version when it
+// has none yet, which is the case for files uploaded before their first
+// re-upload or before versioning was turned on
+func ensureFirstVersion(tx *gorm.DB, file *models.File) error {
+	version := models.FileVersion{
+		ID:         uuid.New(),
+		FileID:     file.ID,
+		Version:    file.Version,
+		FileHashID: file.FileHashID,
+		Size:       file.Size,
+		MimeType:   file.MimeType,
+		CreatedAt:  file.CreatedAt,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&version).Error; err != nil {
+		return fmt.Errorf("failed to record file version: %v", err)
+	}
+	return nil
+}
+
+// addFileVersion makes fileHash the file's content as its next version. The
+// caller must already hold a reference on fileHash; the file's reference on
+// its previous content is released, though the version keeps that content.
+// Versions beyond limit are pruned. It returns the physical bytes the new
+// content adds to the owner's usage and the content records pruning deleted,
+// whose blobs are to be removed once the transaction commits.
+func addFileVersion(tx *gorm.DB, file *models.File, fileHash *models.FileHash, size int64, mimeType string, quarantineReason string, restoredFrom *int, limit int) (int64, []*models.FileHash, error) {
+	if err := ensureFirstVersion(tx, file); err != nil {
+		return 0, nil, err
+	}
+
+	if _, err := accountFileRemoved(tx, file); err != nil {
+		return 0, nil, err
+	}
+	if err := tx.Model(&models.FileHash{}).Where("id = ? AND reference_count > 0", file.FileHashID).
+		Update("reference_count", gorm.Expr("reference_count - 1")).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to update reference count: %v", err)
+	}
+
+	status := models.FileStatusActive
+	if quarantineReason != "" {
+		status = models.FileStatusQuarantined
+	}
+	if err := tx.Model(file).Updates(map[string]interface{}{
+		"file_hash_id":      fileHash.ID,
+		"size":              size,
+		"mime_type":         mimeType,
+		"version":           file.Version + 1,
+		"status":            status,
+		"quarantine_reason": quarantineReason,
+	}).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to update file: %v", err)
+	}
+	file.FileHashID = fileHash.ID
+	file.FileHash = fileHash
+	file.Size = size
+	file.MimeType = mimeType
+	file.Version++
+	file.Status = status
+	file.QuarantineReason = quarantineReason
+
+	version := models.FileVersion{
+		ID:           uuid.New(),
+		FileID:       file.ID,
+		Version:      file.Version,
+		FileHashID:   fileHash.ID,
+		Size:         size,
+		MimeType:     mimeType,
+		RestoredFrom: restoredFrom,
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to record file version: %v", err)
+	}
+
+	physical, err := accountFileAdded(tx, file)
+	if err != nil {
+		return 0, nil, err
+	}
+	orphaned, err := services.PruneFileVersions(tx, file.ID, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	return physical, orphaned, nil
+}
+
+// removeContent deletes the blobs of content records a committed transaction
+// deleted
+func (h *FileHandler) removeContent(orphaned []*models.FileHash) {
+	for _, fileHash := range orphaned {
+		services.RemoveContent(h.storage, h.cfg.StoragePath, fileHash)
+	}
+}
+
+// ListFileVersions lists a file's versions, newest first. A file that was
+// never re-uploaded lists its current content as its only version.
+// GET /api/files/:id/versions
+func (h *FileHandler) ListFileVersions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessRead)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+	if rejectExpiredAccess(c, file) {
+		return
+	}
+
+	versions := []models.FileVersion{}
+	if err := h.db.Preload("FileHash").Where("file_id = ?", file.ID).
+		Order("version DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list versions"})
+		return
+	}
+	if len(versions) == 0 {
+		versions = append(versions, models.FileVersion{
+			FileID:     file.ID,
+			Version:    file.Version,
+			FileHashID: file.FileHashID,
+			Size:       file.Size,
+			MimeType:   file.MimeType,
+			CreatedAt:  file.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":         file.ID,
+		"current_version": file.Version,
+		"versions":        versions,
+	})
+}
+
+// RestoreFileVersion makes an earlier version's content current again. The
+// restore is recorded as a new version, so the version it replaces can be
+// restored in turn. (owner only)
+// POST /api/files/:id/versions/:version/restore
+func (h *FileHandler) RestoreFileVersion(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	var file *models.File
+	var orphaned []*models.FileHash
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		file, err = services.FindFileWithAccess(tx, fileID, userID.(uuid.UUID), services.AccessOwner)
+		if err != nil {
+			return err
+		}
+		// Hold the file so concurrent uploads and restores number versions in turn
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND is_deleted = ?", file.ID, false).First(file).Error; err != nil {
+			return err
+		}
+		if file.Version == number {
+			return errVersionCurrent
+		}
+
+		var version models.FileVersion
+		if err := tx.Preload("FileHash").Where("file_id = ? AND version = ?", file.ID, number).
+			First(&version).Error; err != nil {
+			return err
+		}
+		if version.FileHash == nil {
+			return errVersionContentGone
+		}
+		if exists, err := blobExists(h.storage, version.FileHash.Hash); err != nil {
+			return fmt.Errorf("failed to check stored content: %v", err)
+		} else if !exists {
+			return errVersionContentGone
+		}
+
+		var user models.User
+		if err := tx.Select("storage_used, storage_quota").Where("id = ?", file.OwnerID).First(&user).Error; err != nil {
+			return fmt.Errorf("failed to get user: %v", err)
+		}
+		if user.StorageUsed-file.Size+version.Size > user.StorageQuota {
+			return errVersionOverQuota
+		}
+
+		if err := tx.Model(version.FileHash).Update("reference_count", gorm.Expr("reference_count + 1")).Error; err != nil {
+			return fmt.Errorf("failed to update reference count: %v", err)
+		}
+		quarantineReason, err := quarantinedContent(tx, version.FileHashID)
+		if err != nil {
+			return err
+		}
+		if _, orphaned, err = addFileVersion(tx, file, version.FileHash, version.Size, version.MimeType,
+			quarantineReason, &number, h.cfg.FileVersionLimit); err != nil {
+			return err
+		}
+		recordAudit(tx, c, "file_version_restore", "file", &file.ID, map[string]interface{}{
+			"restored_from": number,
+			"version":       file.Version,
+		})
+		return nil
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File or version not found"})
+		return
+	case errors.Is(err, errVersionCurrent):
+		c.JSON(http.StatusConflict, gin.H{"error": "That version is already the current one"})
+		return
+	case errors.Is(err, errVersionContentGone):
+		c.JSON(http.StatusGone, gin.H{"error": "That version's content is no longer in storage"})
+		return
+	case errors.Is(err, errVersionOverQuota):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Restoring the version would exceed your storage quota"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore version"})
+		return
+	}
+
+	h.removeContent(orphaned)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Version restored",
+		"file":    file,
+	})
+}
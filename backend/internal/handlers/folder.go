@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -10,6 +13,7 @@ import (
 
 	"file-vault-system/backend/internal/config"
 	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
 )
 
 type FolderHandler struct {
@@ -60,7 +64,9 @@ func (h *FolderHandler) CreateFolder(c *gin.Context) {
 
 	// If parent ID is provided, validate it exists and user owns it
 	if req.ParentID != nil {
-		if err := h.db.Where("id = ? AND owner_id = ?", req.ParentID, userID).First(&parentFolder).Error; err != nil {
+		var err error
+		parentFolder, err = services.FindFolderWithAccess(h.db, req.ParentID, userID.(uuid.UUID), services.AccessOwner)
+		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Parent folder not found"})
 				return
@@ -141,6 +147,19 @@ func (h *FolderHandler) ListFolders(c *gin.Context) {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent_id format"})
 				return
 			}
+
+			// Subfolders of a folder shared with the caller belong to its owner
+			var parent models.Folder
+			if err := h.db.Where("id = ?", parentUUID).First(&parent).Error; err == nil && parent.OwnerID != userID.(uuid.UUID) {
+				level, err := services.FolderAccessLevel(h.db, &parent, userID.(uuid.UUID))
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check folder access"})
+					return
+				}
+				if level.Allows(services.AccessRead) {
+					query = h.db.Where("owner_id = ?", parent.OwnerID)
+				}
+			}
 			query = query.Where("parent_id = ?", parentUUID)
 		}
 	}
@@ -181,7 +200,7 @@ func (h *FolderHandler) GetFolder(c *gin.Context) {
 	includeChildren := c.Query("include_children") == "true"
 
 	var folder models.Folder
-	query := h.db.Where("id = ? AND owner_id = ?", folderUUID, userID)
+	query := h.db.Where("id = ?", folderUUID)
 
 	// Load relationships
 	query = query.Preload("Parent").Preload("Owner")
@@ -201,10 +220,23 @@ func (h *FolderHandler) GetFolder(c *gin.Context) {
 		return
 	}
 
+	// Folders shared with the caller, directly or through an ancestor, are visible too
+	level, err := services.FolderAccessLevel(h.db, &folder, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check folder access"})
+		return
+	}
+	if !level.Allows(services.AccessRead) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"folder": folder})
 }
 
-// UpdateFolder updates a folder's name
+// UpdateFolder updates a folder's name and the default tags given to files
+// uploaded into it
+// PUT /api/folders/:id
 func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -220,24 +252,23 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 	}
 
 	var req struct {
-		Name string `json:"name" binding:"required"`
+		Name        *string   `json:"name"`
+		DefaultTags *[]string `json:"default_tags"`
+		InheritTags *bool     `json:"inherit_tags"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
-
-	// Sanitize folder name
-	sanitizedName := sanitizeFolderName(req.Name)
-	if sanitizedName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder name"})
+	if req.Name == nil && req.DefaultTags == nil && req.InheritTags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update, send name, default_tags or inherit_tags"})
 		return
 	}
 
 	// Get the folder
-	var folder models.Folder
-	if err := h.db.Where("id = ? AND owner_id = ?", folderUUID, userID).First(&folder).Error; err != nil {
+	folder, err := services.FindFolderWithAccess(h.db, folderUUID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
 			return
@@ -246,60 +277,92 @@ func (h *FolderHandler) UpdateFolder(c *gin.Context) {
 		return
 	}
 
-	// Check if folder with same name already exists in the same parent
-	var existingFolder models.Folder
-	err = h.db.Where("name = ? AND parent_id = ? AND owner_id = ? AND id != ?", sanitizedName, folder.ParentID, userID, folderUUID).First(&existingFolder).Error
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Folder with this name already exists in the same location"})
-		return
-	} else if err != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing folders"})
-		return
-	}
+	updates := map[string]interface{}{}
+	oldPath := folder.Path
+	newPath := folder.Path
 
-	// Start transaction to update folder and all children paths
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	if req.Name != nil {
+		// Sanitize folder name
+		sanitizedName := sanitizeFolderName(*req.Name)
+		if sanitizedName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder name"})
+			return
 		}
-	}()
 
-	// Update the folder path
-	oldPath := folder.Path
-	var newPath string
-	if folder.ParentID == nil {
-		newPath = "/" + sanitizedName
-	} else {
-		parentPath := strings.TrimSuffix(oldPath, "/"+folder.Name)
-		newPath = parentPath + "/" + sanitizedName
-	}
+		if sanitizedName != folder.Name {
+			// Check if folder with same name already exists in the same parent
+			var existingFolder models.Folder
+			err = h.db.Where("name = ? AND parent_id = ? AND owner_id = ? AND id != ?", sanitizedName, folder.ParentID, userID, folderUUID).First(&existingFolder).Error
+			if err == nil {
+				c.JSON(http.StatusConflict, gin.H{"error": "Folder with this name already exists in the same location"})
+				return
+			} else if err != gorm.ErrRecordNotFound {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing folders"})
+				return
+			}
 
-	// Update the folder
-	if err := tx.Model(&folder).Updates(map[string]interface{}{
-		"name": sanitizedName,
-		"path": newPath,
-	}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
-		return
+			if folder.ParentID == nil {
+				newPath = "/" + sanitizedName
+			} else {
+				parentPath := strings.TrimSuffix(oldPath, "/"+folder.Name)
+				newPath = parentPath + "/" + sanitizedName
+			}
+			updates["name"] = sanitizedName
+			updates["path"] = newPath
+		}
 	}
 
-	// Update all children paths recursively
-	if err := h.updateChildrenPaths(tx, folderUUID, oldPath, newPath); err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update children paths"})
-		return
-	}
+	if req.DefaultTags != nil {
+		tags, err := validateFolderTags(*req.DefaultTags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["default_tags"] = tags
+	}
+	if req.InheritTags != nil {
+		updates["inherit_tags"] = *req.InheritTags
+	}
+
+	if len(updates) > 0 {
+		previous := map[string]interface{}{}
+		for field := range updates {
+			switch field {
+			case "name":
+				previous[field] = folder.Name
+			case "path":
+				previous[field] = oldPath
+			case "default_tags":
+				previous[field] = folder.DefaultTags
+			case "inherit_tags":
+				previous[field] = folder.InheritTags
+			}
+		}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
-		return
+		// Start transaction to update folder and all children paths
+		err = h.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(folder).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update folder: %v", err)
+			}
+
+			// Swap the old path prefix for the new one on every descendant
+			if newPath != oldPath {
+				if err := updateDescendantPaths(tx, folder.OwnerID, oldPath, newPath); err != nil {
+					return fmt.Errorf("failed to update children paths: %v", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to update folder %s: %v", folder.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
+			return
+		}
+		recordAuditChange(h.db, c, "folder_update", "folder", &folder.ID, previous, updates)
 	}
 
 	// Reload the updated folder
-	h.db.Preload("Parent").Preload("Owner").First(&folder, folderUUID)
+	h.db.Preload("Parent").Preload("Owner").First(folder, folderUUID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Folder updated successfully",
@@ -332,8 +395,8 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 	}
 
 	// Get the folder to move
-	var folder models.Folder
-	if err := h.db.Where("id = ? AND owner_id = ?", folderUUID, userID).First(&folder).Error; err != nil {
+	folder, err := services.FindFolderWithAccess(h.db, folderUUID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
 			return
@@ -352,8 +415,8 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 		}
 
 		// Check if new parent exists and is owned by user
-		var parentFolder models.Folder
-		if err := h.db.Where("id = ? AND owner_id = ?", req.ParentID, userID).First(&parentFolder).Error; err != nil {
+		parentFolder, err := services.FindFolderWithAccess(h.db, req.ParentID, userID.(uuid.UUID), services.AccessOwner)
+		if err != nil {
 			if err == gorm.ErrRecordNotFound {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Target parent folder not found"})
 				return
@@ -394,6 +457,7 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 
 	// Calculate new path
 	oldPath := folder.Path
+	previousParentID := folder.ParentID
 	var newPath string
 	if newParentPath == "/" {
 		newPath = "/" + folder.Name
@@ -402,7 +466,7 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 	}
 
 	// Update the folder
-	if err := tx.Model(&folder).Updates(map[string]interface{}{
+	if err := tx.Model(folder).Updates(map[string]interface{}{
 		"parent_id": req.ParentID,
 		"path":      newPath,
 	}).Error; err != nil {
@@ -411,8 +475,8 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 		return
 	}
 
-	// Update all children paths recursively
-	if err := h.updateChildrenPaths(tx, folderUUID, oldPath, newPath); err != nil {
+	// Swap the old path prefix for the new one on every descendant
+	if err := updateDescendantPaths(tx, folder.OwnerID, oldPath, newPath); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update children paths"})
 		return
@@ -423,9 +487,12 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
 		return
 	}
+	recordAuditChange(h.db, c, "folder_move", "folder", &folder.ID,
+		map[string]interface{}{"parent_id": previousParentID, "path": oldPath},
+		map[string]interface{}{"parent_id": req.ParentID, "path": newPath})
 
 	// Reload the moved folder
-	h.db.Preload("Parent").Preload("Owner").First(&folder, folderUUID)
+	h.db.Preload("Parent").Preload("Owner").First(folder, folderUUID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Folder moved successfully",
@@ -433,7 +500,9 @@ func (h *FolderHandler) MoveFolder(c *gin.Context) {
 	})
 }
 
-// DeleteFolder deletes a folder and optionally its contents
+// DeleteFolder deletes an empty folder, or with recursive=true the folder and
+// everything in it
+// DELETE /api/folders/:id?recursive=true
 func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -448,12 +517,13 @@ func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	// Check if force delete is requested (deletes all contents)
-	forceDelete := c.Query("force") == "true"
+	// Recursive delete removes the folder with everything in it; force is
+	// the older name for it
+	recursive := c.Query("recursive") == "true" || c.Query("force") == "true"
 
 	// Get the folder
-	var folder models.Folder
-	if err := h.db.Where("id = ? AND owner_id = ?", folderUUID, userID).First(&folder).Error; err != nil {
+	folder, err := services.FindFolderWithAccess(h.db, folderUUID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
 			return
@@ -462,76 +532,72 @@ func (h *FolderHandler) DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	// Check if folder has children or files
-	var childCount int64
-	var fileCount int64
-	h.db.Model(&models.Folder{}).Where("parent_id = ?", folderUUID).Count(&childCount)
-	h.db.Model(&models.File{}).Where("folder_id = ? AND is_deleted = false", folderUUID).Count(&fileCount)
-
-	if (childCount > 0 || fileCount > 0) && !forceDelete {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":       "Folder is not empty",
-			"child_count": childCount,
-			"file_count":  fileCount,
-			"suggestion":  "Use force=true to delete folder and all its contents",
-		})
-		return
-	}
-
-	// Start transaction
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+	if !recursive {
+		// Check if folder has children or files
+		var childCount int64
+		var fileCount int64
+		h.db.Model(&models.Folder{}).Where("parent_id = ?", folderUUID).Count(&childCount)
+		h.db.Model(&models.File{}).Where("folder_id = ? AND is_deleted = false", folderUUID).Count(&fileCount)
 
-	if forceDelete {
-		// Delete all files in folder and subfolders recursively
-		if err := h.deleteAllFolderContents(tx, folderUUID); err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder contents"})
+		if childCount > 0 || fileCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":       "Folder is not empty",
+				"child_count": childCount,
+				"file_count":  fileCount,
+				"suggestion":  "Use recursive=true to delete folder and all its contents",
+			})
 			return
 		}
 	}
 
-	// Delete the folder
-	if err := tx.Delete(&folder).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
-		return
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit deletion"})
-		return
-	}
+	var deleted folderDeletion
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if recursive {
+			// Delete all files in folder and subfolders
+			var err error
+			if deleted, err = h.deleteAllFolderContents(tx, folder); err != nil {
+				return err
+			}
+		}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Folder deleted successfully",
+		// Delete the folder
+		if err := tx.Delete(folder).Error; err != nil {
+			return fmt.Errorf("failed to delete folder: %v", err)
+		}
+		return nil
 	})
-}
-
-// GetFolderTree gets the complete folder tree for the user
-func (h *FolderHandler) GetFolderTree(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	var shared *fileSharedError
+	if errors.As(err, &shared) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Folder holds a file with active shares, revoke them before deleting it",
+			"file_id":     shared.fileID,
+			"share_links": shared.shares.links,
+			"user_shares": shared.shares.users,
+			"acl_entries": shared.shares.aclEntries,
+		})
 		return
-	}
-
-	var folders []models.Folder
-	if err := h.db.Where("owner_id = ?", userID).Order("path ASC").Find(&folders).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder tree"})
+	} else if err != nil {
+		log.Printf("Failed to delete folder %s: %v", folder.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
 		return
 	}
-
-	// Build tree structure
-	tree := buildFolderTree(folders)
+	recordAudit(h.db, c, "folder_delete", "folder", &folder.ID, map[string]interface{}{
+		"path":                folder.Path,
+		"recursive":           recursive,
+		"deleted_folders":     deleted.folders,
+		"deleted_files":       deleted.files,
+		"revoked_share_links": deleted.revoked.links,
+		"revoked_user_shares": deleted.revoked.users,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
-		"tree": tree,
+		"message":               "Folder deleted successfully",
+		"deleted_folders":       deleted.folders,
+		"deleted_files":         deleted.files,
+		"actual_storage_freed":  deleted.actualStorageFreed,
+		"logical_storage_freed": deleted.logicalStorageFreed,
+		"revoked_share_links":   deleted.revoked.links,
+		"revoked_user_shares":   deleted.revoked.users,
 	})
 }
 
@@ -555,85 +621,65 @@ func sanitizeFolderName(name string) string {
 	return name
 }
 
-func (h *FolderHandler) updateChildrenPaths(tx *gorm.DB, parentID uuid.UUID, oldParentPath, newParentPath string) error {
-	var children []models.Folder
-	if err := tx.Where("parent_id = ?", parentID).Find(&children).Error; err != nil {
-		return err
-	}
-
-	for _, child := range children {
-		newChildPath := strings.Replace(child.Path, oldParentPath, newParentPath, 1)
-		if err := tx.Model(&child).Update("path", newChildPath).Error; err != nil {
-			return err
-		}
-
-		// Recursively update grandchildren
-		if err := h.updateChildrenPaths(tx, child.ID, child.Path, newChildPath); err != nil {
-			return err
-		}
-	}
-
-	return nil
+// updateDescendantPaths rewrites the stored path of every folder below
+// oldPath to sit below newPath instead, in one statement. The trailing
+// separator keeps "/Documents" from matching "/Documents2".
+func updateDescendantPaths(tx *gorm.DB, ownerID uuid.UUID, oldPath, newPath string) error {
+	return tx.Model(&models.Folder{}).
+		Where("owner_id = ? AND path LIKE ?", ownerID, services.EscapeLike(oldPath)+"/%").
+		Update("path", gorm.Expr("? || substr(path, char_length(?) + 1)", newPath, oldPath)).Error
 }
 
-func (h *FolderHandler) deleteAllFolderContents(tx *gorm.DB, folderID uuid.UUID) error {
-	// Get all subfolders
-	var subfolders []models.Folder
-	if err := tx.Where("parent_id = ?", folderID).Find(&subfolders).Error; err != nil {
-		return err
-	}
-
-	// Recursively delete subfolder contents
-	for _, subfolder := range subfolders {
-		if err := h.deleteAllFolderContents(tx, subfolder.ID); err != nil {
-			return err
-		}
-	}
-
-	// Mark all files in this folder as deleted
-	if err := tx.Model(&models.File{}).Where("folder_id = ?", folderID).Update("is_deleted", true).Error; err != nil {
-		return err
-	}
-
-	// Delete all subfolders
-	if err := tx.Where("parent_id = ?", folderID).Delete(&models.Folder{}).Error; err != nil {
-		return err
-	}
-
-	return nil
-}
-
-type FolderTreeNode struct {
-	models.Folder
-	Children []FolderTreeNode `json:"children"`
+// folderDeletion tallies what a recursive folder delete removed
+type folderDeletion struct {
+	folders             int64 // descendant folders, not counting the folder itself
+	files               int64
+	actualStorageFreed  int64
+	logicalStorageFreed int64
+	revoked             fileShareCounts // shares of the deleted files
 }
 
-func buildFolderTree(folders []models.Folder) []FolderTreeNode {
-	folderMap := make(map[uuid.UUID]*FolderTreeNode)
-	var roots []FolderTreeNode
-
-	// First pass: create all nodes
-	for _, folder := range folders {
-		node := FolderTreeNode{
-			Folder:   folder,
-			Children: []FolderTreeNode{},
+// deleteAllFolderContents moves every file under a folder to trash, the way
+// DeleteFile does, revoking their shares or refusing while they have any,
+// and deletes its descendant folders. Descendants are found
+// by path; the trailing separator keeps "/Documents" from matching
+// "/Documents2".
+func (h *FolderHandler) deleteAllFolderContents(tx *gorm.DB, folder *models.Folder) (folderDeletion, error) {
+	var deleted folderDeletion
+
+	var descendantIDs []uuid.UUID
+	if err := tx.Model(&models.Folder{}).
+		Where("owner_id = ? AND path LIKE ?", folder.OwnerID, services.EscapeLike(folder.Path)+"/%").
+		Pluck("id", &descendantIDs).Error; err != nil {
+		return deleted, fmt.Errorf("failed to find subfolders: %v", err)
+	}
+
+	// Mark all files in the tree as deleted, releasing their storage
+	folderIDs := append([]uuid.UUID{folder.ID}, descendantIDs...)
+	var files []models.File
+	if err := tx.Where("folder_id IN ? AND is_deleted = ?", folderIDs, false).Find(&files).Error; err != nil {
+		return deleted, fmt.Errorf("failed to find folder files: %v", err)
+	}
+	for i := range files {
+		freed, revoked, err := trashFile(tx, &files[i], blocksSharedFileDeletion(h.cfg))
+		if err != nil {
+			return deleted, err
 		}
-		folderMap[folder.ID] = &node
+		deleted.files++
+		deleted.actualStorageFreed += freed
+		deleted.logicalStorageFreed += files[i].Size
+		deleted.revoked.links += revoked.links
+		deleted.revoked.users += revoked.users
+		deleted.revoked.aclEntries += revoked.aclEntries
 	}
 
-	// Second pass: build relationships
-	for _, folder := range folders {
-		node := folderMap[folder.ID]
-		if folder.ParentID == nil {
-			// Root folder
-			roots = append(roots, *node)
-		} else {
-			// Child folder
-			if parent, exists := folderMap[*folder.ParentID]; exists {
-				parent.Children = append(parent.Children, *node)
-			}
+	// Delete all subfolders
+	if len(descendantIDs) > 0 {
+		if err := tx.Where("id IN ?", descendantIDs).Delete(&models.Folder{}).Error; err != nil {
+			return deleted, fmt.Errorf("failed to delete subfolders: %v", err)
 		}
 	}
+	deleted.folders = int64(len(descendantIDs))
 
-	return roots
+	return deleted, nil
 }
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/services"
+)
+
+// folderDownloadTopFiles is how many of the most downloaded files are listed
+const folderDownloadTopFiles = 10
+
+// folderFileDownloads is the download count for one file in a folder stats
+// response
+type folderFileDownloads struct {
+	FileID           uuid.UUID `json:"file_id"`
+	OriginalFilename string    `json:"original_filename"`
+	Downloads        int64     `json:"downloads"`
+	Bytes            int64     `json:"bytes"`
+}
+
+// parseStatsTime reads a date range bound given as RFC3339 or YYYY-MM-DD
+func parseStatsTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// GetFolderDownloadStats totals the downloads of files in a folder and its
+// subfolders, optionally within a date range (owner only)
+// GET /api/folders/:id/download-stats?from=&to=
+func (h *FolderHandler) GetFolderDownloadStats(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	folder, err := services.FindFolderWithAccess(h.db, folderID, userID.(uuid.UUID), services.AccessOwner)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder"})
+		return
+	}
+
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		from = &parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseStatsTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		to = &parsed
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	// Downloads of files anywhere in the folder's subtree, in one query
+	subtree := h.db.Table("download_stats AS ds").
+		Joins("JOIN files f ON f.id = ds.file_id").
+		Joins("JOIN folders fo ON fo.id = f.folder_id").
+		Where("fo.owner_id = ? AND (fo.id = ? OR fo.path LIKE ?)", folder.OwnerID, folder.ID, services.EscapeLike(folder.Path)+"/%")
+	if from != nil {
+		subtree = subtree.Where("ds.downloaded_at >= ?", *from)
+	}
+	if to != nil {
+		subtree = subtree.Where("ds.downloaded_at < ?", *to)
+	}
+
+	var totals struct {
+		Downloads int64
+		Bytes     int64
+		Files     int64
+	}
+	if err := subtree.Session(&gorm.Session{}).
+		Select("COUNT(ds.id) AS downloads, COALESCE(SUM(ds.download_size), 0) AS bytes, COUNT(DISTINCT ds.file_id) AS files").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download stats"})
+		return
+	}
+
+	topFiles := []folderFileDownloads{}
+	if err := subtree.Session(&gorm.Session{}).
+		Select("f.id AS file_id, f.original_filename, COUNT(ds.id) AS downloads, COALESCE(SUM(ds.download_size), 0) AS bytes").
+		Group("f.id, f.original_filename").
+		Order("downloads DESC, f.original_filename ASC").
+		Limit(folderDownloadTopFiles).
+		Scan(&topFiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"folder_id":        folder.ID,
+		"from":             from,
+		"to":               to,
+		"total_downloads":  totals.Downloads,
+		"total_bytes":      totals.Bytes,
+		"files_downloaded": totals.Files,
+		"top_files":        topFiles,
+	})
+}
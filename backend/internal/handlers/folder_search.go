@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// Folder searches return at most limit matches, defaulting to
+// defaultFolderSearchLimit and capped at maxFolderSearchLimit
+const (
+	defaultFolderSearchLimit = 100
+	maxFolderSearchLimit     = 500
+)
+
+// folderSearchMatch is a folder matching a search, with where the query
+// appears in its name so the UI can highlight it
+type folderSearchMatch struct {
+	FolderID    uuid.UUID   `json:"folder_id"`
+	Name        string      `json:"name"`
+	Path        string      `json:"path"`
+	Highlights  [][2]int    `json:"highlights"` // [start, end) character offsets in the name
+	AncestorIDs []uuid.UUID `json:"ancestor_ids"`
+}
+
+// SearchFolders finds the user's folders whose name contains q, ignoring
+// case, and optionally those whose path does. Alongside the matches it
+// returns a tree holding just the matches and their ancestors, so the
+// navigator can expand straight to them; child counts in that tree only
+// count folders in the results.
+// GET /api/folders/search?q=&include_path=&limit=
+func (h *FolderHandler) SearchFolders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if len(q) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must be at most 255 characters"})
+		return
+	}
+
+	limit := defaultFolderSearchLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxFolderSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxFolderSearchLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	pattern := "%" + services.EscapeLike(q) + "%"
+	query := h.db.Where("owner_id = ?", userID)
+	if c.Query("include_path") == "true" {
+		query = query.Where("name ILIKE ? OR path ILIKE ?", pattern, pattern)
+	} else {
+		query = query.Where("name ILIKE ?", pattern)
+	}
+
+	// One extra row tells whether there were more matches than returned
+	var matched []models.Folder
+	if err := query.Order("path ASC").Limit(limit + 1).Find(&matched).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search folders"})
+		return
+	}
+	truncated := len(matched) > limit
+	if truncated {
+		matched = matched[:limit]
+	}
+
+	// Ancestors are the folders at each prefix of a match's path
+	inResults := make(map[string]bool, len(matched))
+	for _, folder := range matched {
+		inResults[folder.Path] = true
+	}
+	var ancestorPaths []string
+	for _, folder := range matched {
+		for i := 1; i < len(folder.Path); i++ {
+			if folder.Path[i] != '/' {
+				continue
+			}
+			prefix := folder.Path[:i]
+			if !inResults[prefix] {
+				inResults[prefix] = true
+				ancestorPaths = append(ancestorPaths, prefix)
+			}
+		}
+	}
+
+	folders := matched
+	if len(ancestorPaths) > 0 {
+		var ancestors []models.Folder
+		if err := h.db.Where("owner_id = ? AND path IN ?", userID, ancestorPaths).Find(&ancestors).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get parent folders"})
+			return
+		}
+		folders = append(append([]models.Folder{}, matched...), ancestors...)
+	}
+
+	byID := make(map[uuid.UUID]*models.Folder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	matches := make([]folderSearchMatch, 0, len(matched))
+	for _, folder := range matched {
+		// Walk up to the root, then list the ancestors from the top down
+		var ancestorIDs []uuid.UUID
+		for parentID := folder.ParentID; parentID != nil; {
+			parent, ok := byID[*parentID]
+			if !ok {
+				break
+			}
+			ancestorIDs = append([]uuid.UUID{parent.ID}, ancestorIDs...)
+			parentID = parent.ParentID
+		}
+
+		matches = append(matches, folderSearchMatch{
+			FolderID:    folder.ID,
+			Name:        folder.Name,
+			Path:        folder.Path,
+			Highlights:  matchHighlights(folder.Name, q),
+			AncestorIDs: ancestorIDs,
+		})
+	}
+
+	tree, _ := buildFolderTree(folders, nil, 0, 0)
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":     q,
+		"matches":   matches,
+		"count":     len(matches),
+		"truncated": truncated,
+		"tree":      tree,
+	})
+}
+
+// matchHighlights finds every occurrence of q in name, ignoring case, as
+// [start, end) character offsets
+func matchHighlights(name, q string) [][2]int {
+	lower := func(s string) []rune {
+		runes := []rune(s)
+		for i, r := range runes {
+			runes[i] = unicode.ToLower(r)
+		}
+		return runes
+	}
+	haystack, needle := lower(name), lower(q)
+
+	highlights := [][2]int{}
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			highlights = append(highlights, [2]int{i, i + len(needle)})
+			i += len(needle) - 1
+		}
+	}
+	return highlights
+}
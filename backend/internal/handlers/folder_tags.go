@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// A folder holds at most maxFolderTags default tags of up to maxTagLength
+// characters each
+const (
+	maxFolderTags = 20
+	maxTagLength  = 50
+)
+
+// normalizeTags trims and lowercases tags, dropping empty ones and repeats
+// while keeping the order they were first given in
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// validateFolderTags normalizes a folder's default tags and checks their limits
+func validateFolderTags(tags []string) ([]string, error) {
+	tags = normalizeTags(tags)
+	if len(tags) > maxFolderTags {
+		return nil, fmt.Errorf("a folder can have at most %d default tags", maxFolderTags)
+	}
+	for _, tag := range tags {
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+	}
+	return tags, nil
+}
+
+// folderDefaultTags returns the tags files uploaded into a folder get: the
+// folder's own defaults, then those of each ancestor for as long as folders
+// up the tree inherit from their parent
+func folderDefaultTags(db *gorm.DB, folderID *uuid.UUID) ([]string, error) {
+	if folderID == nil {
+		return nil, nil
+	}
+
+	var folder models.Folder
+	if err := db.Select("id, owner_id, path, default_tags, inherit_tags").First(&folder, "id = ?", *folderID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get folder tags: %v", err)
+	}
+	tags := folder.DefaultTags
+	if !folder.InheritTags {
+		return normalizeTags(tags), nil
+	}
+
+	// Every proper prefix of the path is an ancestor
+	var ancestorPaths []string
+	for i := len(folder.Path) - 1; i > 0; i-- {
+		if folder.Path[i] == '/' {
+			ancestorPaths = append(ancestorPaths, folder.Path[:i])
+		}
+	}
+	if len(ancestorPaths) == 0 {
+		return normalizeTags(tags), nil
+	}
+
+	var ancestors []models.Folder
+	if err := db.Select("id, path, default_tags, inherit_tags").
+		Where("owner_id = ? AND path IN ?", folder.OwnerID, ancestorPaths).
+		Order("LENGTH(path) DESC").Find(&ancestors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ancestor folder tags: %v", err)
+	}
+	for _, ancestor := range ancestors {
+		tags = append(tags, ancestor.DefaultTags...)
+		if !ancestor.InheritTags {
+			break
+		}
+	}
+	return normalizeTags(tags), nil
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/testdb"
+)
+
+// createTestFolder creates a folder of the user's under parent, or at the
+// root when parent is nil
+func createTestFolder(t *testing.T, db *gorm.DB, ownerID uuid.UUID, parent *models.Folder, name string) models.Folder {
+	t.Helper()
+	folder := models.Folder{Name: name, OwnerID: ownerID, Path: "/" + name}
+	if parent != nil {
+		folder.ParentID = &parent.ID
+		folder.Path = parent.Path + "/" + name
+	}
+	if err := db.Create(&folder).Error; err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	return folder
+}
+
+// serveFolderRequest sends a JSON request to a folder route as the user
+func serveFolderRequest(handler gin.HandlerFunc, userID uuid.UUID, method, route, target, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, handler)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMoveAndRenameFolderTree(t *testing.T) {
+	db := testdb.Open(t)
+	gin.SetMode(gin.TestMode)
+	h := NewFolderHandler(db, config.Load())
+	user := createTestUser(t, db, 10000)
+
+	top := createTestFolder(t, db, user.ID, nil, "top")
+	mid := createTestFolder(t, db, user.ID, &top, "mid")
+	leaf := createTestFolder(t, db, user.ID, &mid, "leaf")
+	lookalike := createTestFolder(t, db, user.ID, &top, "middle") // shares mid's path as a prefix
+	target := createTestFolder(t, db, user.ID, nil, "target")
+
+	move := func(folder models.Folder, parent models.Folder) *httptest.ResponseRecorder {
+		return serveFolderRequest(h.MoveFolder, user.ID, http.MethodPost, "/folders/:id/move",
+			"/folders/"+folder.ID.String()+"/move", `{"parent_id":"`+parent.ID.String()+`"}`)
+	}
+	checkPaths := func(step string, want map[uuid.UUID]string) {
+		t.Helper()
+		for id, path := range want {
+			var folder models.Folder
+			if err := db.First(&folder, "id = ?", id).Error; err != nil {
+				t.Fatal(err)
+			}
+			if folder.Path != path {
+				t.Errorf("after %s: %s has path %q, want %q", step, folder.Name, folder.Path, path)
+			}
+		}
+	}
+
+	// Moving a folder under its own child or grandchild would make a cycle
+	for _, descendant := range []models.Folder{mid, leaf} {
+		if recorder := move(top, descendant); recorder.Code != http.StatusBadRequest {
+			t.Errorf("moving top into %s: status = %d, want %d", descendant.Name, recorder.Code, http.StatusBadRequest)
+		}
+	}
+	checkPaths("blocked moves", map[uuid.UUID]string{
+		top.ID:  "/top",
+		mid.ID:  "/top/mid",
+		leaf.ID: "/top/mid/leaf",
+	})
+
+	if recorder := move(mid, target); recorder.Code != http.StatusOK {
+		t.Fatalf("moving mid: status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	checkPaths("moving mid", map[uuid.UUID]string{
+		top.ID:       "/top",
+		mid.ID:       "/target/mid",
+		leaf.ID:      "/target/mid/leaf",
+		lookalike.ID: "/top/middle",
+	})
+
+	recorder := serveFolderRequest(h.UpdateFolder, user.ID, http.MethodPut, "/folders/:id",
+		"/folders/"+target.ID.String(), `{"name":"renamed"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("renaming target: status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	checkPaths("renaming target", map[uuid.UUID]string{
+		target.ID:    "/renamed",
+		mid.ID:       "/renamed/mid",
+		leaf.ID:      "/renamed/mid/leaf",
+		lookalike.ID: "/top/middle",
+	})
+}
+
+// sharedFileInFolder uploads a file into a subfolder of a new folder and
+// shares it, returning the top folder, the file and its share link
+func sharedFileInFolder(t *testing.T, h *FileHandler, owner, recipient models.User) (models.Folder, uuid.UUID, models.ShareLink) {
+	t.Helper()
+	top := createTestFolder(t, h.db, owner.ID, nil, "top")
+	inner := createTestFolder(t, h.db, owner.ID, &top, "inner")
+
+	fileID := decodeUpload(t, uploadAs(t, h, owner.ID, testUpload{"shared.bin", uniqueContent(100)})).Files[0].FileID
+	if err := h.db.Model(&models.File{}).Where("id = ?", fileID).Update("folder_id", inner.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return top, fileID, shareTestFile(t, h, owner.ID, fileID, recipient)
+}
+
+// deleteFolderAs deletes a folder and everything in it as the user
+func deleteFolderAs(h *FolderHandler, userID, folderID uuid.UUID) *httptest.ResponseRecorder {
+	return serveFolderRequest(h.DeleteFolder, userID, http.MethodDelete, "/folders/:id",
+		"/folders/"+folderID.String()+"?recursive=true", "")
+}
+
+func TestDeleteFolderRevokesSharesOfItsFiles(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	h.cfg.SharedFileDeletion = "revoke"
+	owner := createTestUser(t, db, 10000)
+	recipient := createTestUser(t, db, 10000)
+	folder, fileID, link := sharedFileInFolder(t, h, owner, recipient)
+
+	recorder := deleteFolderAs(NewFolderHandler(db, h.cfg), owner.ID, folder.ID)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+
+	if err := db.First(&link, "id = ?", link.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if link.IsActive {
+		t.Error("share link of a file in the deleted folder is still active")
+	}
+	var activeShares, aclEntries int64
+	if err := db.Model(&models.FileShare{}).Where("file_id = ? AND is_active = true", fileID).Count(&activeShares).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.FileACLEntry{}).Where("file_id = ?", fileID).Count(&aclEntries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if activeShares != 0 || aclEntries != 0 {
+		t.Errorf("%d direct shares active and %d access list entries left, want none", activeShares, aclEntries)
+	}
+}
+
+func TestDeleteFolderBlockedBySharesOfItsFiles(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	h.cfg.SharedFileDeletion = "block"
+	owner := createTestUser(t, db, 10000)
+	recipient := createTestUser(t, db, 10000)
+	folder, fileID, link := sharedFileInFolder(t, h, owner, recipient)
+
+	recorder := deleteFolderAs(NewFolderHandler(db, h.cfg), owner.ID, folder.ID)
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("delete status = %d, want %d: %s", recorder.Code, http.StatusConflict, recorder.Body.String())
+	}
+
+	var file models.File
+	if err := db.First(&file, "id = ?", fileID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if file.IsDeleted {
+		t.Error("shared file was deleted with its folder despite the block policy")
+	}
+	if err := db.First(&link, "id = ?", link.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !link.IsActive {
+		t.Error("share link was revoked though deletion was blocked")
+	}
+	var remaining models.Folder
+	if err := db.First(&remaining, "id = ?", folder.ID).Error; err != nil {
+		t.Errorf("folder is gone though deletion was blocked: %v", err)
+	}
+}
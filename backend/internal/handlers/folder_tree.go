@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+type FolderTreeNode struct {
+	models.Folder
+	Children          []FolderTreeNode `json:"children"`
+	ChildCount        int              `json:"child_count"`
+	ChildrenTruncated bool             `json:"children_truncated,omitempty"` // Some children were left out; load them with GET /folders/:id/tree
+}
+
+// folderTreeBuild is a node while the tree is assembled
+type folderTreeBuild struct {
+	folder    *models.Folder
+	children  []*folderTreeBuild
+	total     int
+	truncated bool
+}
+
+// buildFolderTree assembles the folders below parentID (nil for the root)
+// in memory, breadth first so a limit keeps the top of the tree. Depth 1 is
+// the top level; limits of 0 or less don't apply. It reports whether
+// anything was left out.
+func buildFolderTree(folders []models.Folder, parentID *uuid.UUID, maxDepth, maxNodes int) ([]FolderTreeNode, bool) {
+	children := make(map[uuid.UUID][]*models.Folder)
+	var tops []*models.Folder
+	for i := range folders {
+		folder := &folders[i]
+		switch {
+		case parentID == nil && folder.ParentID == nil,
+			parentID != nil && folder.ParentID != nil && *folder.ParentID == *parentID:
+			tops = append(tops, folder)
+		case folder.ParentID != nil:
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder)
+		}
+	}
+
+	type queued struct {
+		node  *folderTreeBuild
+		depth int
+	}
+	count, truncated := 0, false
+	full := func() bool { return maxNodes > 0 && count >= maxNodes }
+
+	var roots []*folderTreeBuild
+	var queue []queued
+	for _, folder := range tops {
+		if full() {
+			truncated = true
+			break
+		}
+		node := &folderTreeBuild{folder: folder, total: len(children[folder.ID])}
+		roots = append(roots, node)
+		queue = append(queue, queued{node: node, depth: 1})
+		count++
+	}
+
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		kids := children[item.node.folder.ID]
+		if len(kids) == 0 {
+			continue
+		}
+		if maxDepth > 0 && item.depth >= maxDepth {
+			item.node.truncated = true
+			truncated = true
+			continue
+		}
+		for _, folder := range kids {
+			if full() {
+				item.node.truncated = true
+				truncated = true
+				break
+			}
+			node := &folderTreeBuild{folder: folder, total: len(children[folder.ID])}
+			item.node.children = append(item.node.children, node)
+			queue = append(queue, queued{node: node, depth: item.depth + 1})
+			count++
+		}
+	}
+
+	return folderTreeNodes(roots), truncated
+}
+
+// folderTreeNodes converts assembled nodes into the response shape
+func folderTreeNodes(built []*folderTreeBuild) []FolderTreeNode {
+	nodes := make([]FolderTreeNode, 0, len(built))
+	for _, node := range built {
+		nodes = append(nodes, FolderTreeNode{
+			Folder:            *node.folder,
+			Children:          folderTreeNodes(node.children),
+			ChildCount:        node.total,
+			ChildrenTruncated: node.truncated,
+		})
+	}
+	return nodes
+}
+
+// folderTreeLimits reads max_depth and max_nodes, which may lower but never
+// raise the configured limits
+func (h *FolderHandler) folderTreeLimits(c *gin.Context) (int, int, bool) {
+	limits := []struct {
+		param string
+		value int
+	}{
+		{"max_depth", h.cfg.FolderTreeMaxDepth},
+		{"max_nodes", h.cfg.FolderTreeMaxNodes},
+	}
+	for i, limit := range limits {
+		value := c.Query(limit.param)
+		if value == "" {
+			continue
+		}
+		requested, err := strconv.Atoi(value)
+		if err != nil || requested < 1 || (limit.value > 0 && requested > limit.value) {
+			message := fmt.Sprintf("%s must be a positive number", limit.param)
+			if limit.value > 0 {
+				message = fmt.Sprintf("%s must be between 1 and %d", limit.param, limit.value)
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return 0, 0, false
+		}
+		limits[i].value = requested
+	}
+	return limits[0].value, limits[1].value, true
+}
+
+// GetFolderTree gets the user's folder tree, up to the configured depth and
+// node limits. Folders are loaded in one query and assembled in memory.
+// GET /api/folders/tree?max_depth=&max_nodes=
+func (h *FolderHandler) GetFolderTree(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	maxDepth, maxNodes, ok := h.folderTreeLimits(c)
+	if !ok {
+		return
+	}
+
+	var folders []models.Folder
+	if err := h.db.Where("owner_id = ?", userID).Order("path ASC").Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder tree"})
+		return
+	}
+
+	tree, truncated := buildFolderTree(folders, nil, maxDepth, maxNodes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"tree":          tree,
+		"truncated":     truncated,
+		"total_folders": len(folders),
+	})
+}
+
+// GetFolderSubtree gets the tree below one folder, for expanding a truncated
+// tree
+// GET /api/folders/:id/tree?max_depth=&max_nodes=
+func (h *FolderHandler) GetFolderSubtree(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	maxDepth, maxNodes, ok := h.folderTreeLimits(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	folder, err := services.FindFolderWithAccess(h.db, folderID, userID.(uuid.UUID), services.AccessRead)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder"})
+		return
+	}
+
+	var folders []models.Folder
+	if err := h.db.Where("owner_id = ? AND path LIKE ?", folder.OwnerID, services.EscapeLike(folder.Path)+"/%").
+		Order("path ASC").Find(&folders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder tree"})
+		return
+	}
+
+	tree, truncated := buildFolderTree(folders, &folder.ID, maxDepth, maxNodes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"folder":        folder,
+		"tree":          tree,
+		"truncated":     truncated,
+		"total_folders": len(folders),
+	})
+}
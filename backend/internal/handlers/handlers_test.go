@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/middleware"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// newTestFileHandler returns a file handler over db keeping blobs in a
+// temporary directory, with quota grace, thumbnails, scanning and MIME type
+// restrictions out of the way
+func newTestFileHandler(t *testing.T, db *gorm.DB) *FileHandler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Load()
+	cfg.StoragePath = t.TempDir()
+	cfg.AllowedMimeTypes = nil
+	cfg.QuotaGracePercent = 0
+	cfg.ThumbnailsEnabled = false
+	cfg.MalwareScanMode = "off"
+	cfg.FileVersioning = false
+	return NewFileHandler(db, cfg, services.NewThumbnailService(db, cfg), services.NewStorageBackends(db, cfg), services.NewUploadProgressHub())
+}
+
+// createTestUser creates a user of its own for a test
+func createTestUser(t *testing.T, db *gorm.DB, quota int64) models.User {
+	t.Helper()
+	id := uuid.New()
+	user := models.User{
+		BaseModel:    models.BaseModel{ID: id},
+		Username:     "test-" + id.String(),
+		Email:        id.String() + "@example.com",
+		PasswordHash: "not a password hash",
+		StorageQuota: quota,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// uniqueContent returns content no earlier test run has stored, so it is new
+// to the shared database
+func uniqueContent(size int) []byte {
+	content := bytes.Repeat([]byte{'x'}, size)
+	copy(content, uuid.NewString())
+	return content
+}
+
+// loadContent loads the content record for the bytes
+func loadContent(t *testing.T, db *gorm.DB, content []byte) models.FileHash {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	var fileHash models.FileHash
+	if err := db.Where("hash = ?", hex.EncodeToString(sum[:])).First(&fileHash).Error; err != nil {
+		t.Fatalf("failed to load content: %v", err)
+	}
+	return fileHash
+}
+
+// loadUser loads the user's current record
+func loadUser(t *testing.T, db *gorm.DB, userID uuid.UUID) models.User {
+	t.Helper()
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	return user
+}
+
+// testUpload is one file of a multipart upload request
+type testUpload struct {
+	filename string
+	content  []byte
+}
+
+// uploadAs sends the files as one multipart upload by the user
+func uploadAs(t *testing.T, h *FileHandler, userID uuid.UUID, files ...testUpload) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveUpload(h, userID, newUploadRequest(t, files...))
+}
+
+// newUploadRequest builds a multipart upload request for the files
+func newUploadRequest(t *testing.T, files ...testUpload) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := writer.CreateFormFile("files", file.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file.content)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// serveUpload handles an upload request by the user through the same
+// middleware the upload route uses. It is safe to call from any goroutine.
+func serveUpload(h *FileHandler, userID uuid.UUID, req *http.Request) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/upload", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, middleware.Transaction(h.db), h.UploadFile)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// uploadResponse is the part of a successful upload response tests check
+type uploadResponse struct {
+	Files []struct {
+		FileID             uuid.UUID `json:"file_id"`
+		IsDuplicate        bool      `json:"is_duplicate"`
+		SavedBytes         int64     `json:"saved_bytes"`
+		ActualStorageBytes int64     `json:"actual_storage_bytes"`
+	} `json:"files"`
+	TotalSavedBytes int64 `json:"total_saved_bytes"`
+}
+
+// decodeUpload reads a successful upload response
+func decodeUpload(t *testing.T, recorder *httptest.ResponseRecorder) uploadResponse {
+	t.Helper()
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	var response uploadResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode upload response: %v", err)
+	}
+	return response
+}
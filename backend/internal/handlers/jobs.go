@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/services"
+)
+
+type JobHandler struct {
+	scheduler *services.JobScheduler
+}
+
+func NewJobHandler(scheduler *services.JobScheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// ListJobs returns every background job with its schedule, limits and last
+// run (admin only)
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"jobs": h.scheduler.List()})
+}
+
+// TriggerJob starts a job immediately, outside its window if necessary
+// (admin only)
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	name := c.Param("name")
+
+	if err := h.scheduler.Trigger(name); err != nil {
+		h.respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Job started", "job": name})
+}
+
+// PauseJob stops scheduled runs of a job until it is resumed (admin only)
+func (h *JobHandler) PauseJob(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// ResumeJob re-enables scheduled runs of a paused job (admin only)
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+func (h *JobHandler) setPaused(c *gin.Context, paused bool) {
+	name := c.Param("name")
+
+	if err := h.scheduler.SetPaused(name, paused); err != nil {
+		h.respondJobError(c, err)
+		return
+	}
+
+	status, err := h.scheduler.Status(name)
+	if err != nil {
+		h.respondJobError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": status})
+}
+
+func (h *JobHandler) respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownJob):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, services.ErrJobRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already running"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update job"})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/services"
+)
+
+type MaintenanceHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewMaintenanceHandler(db *gorm.DB, cfg *config.Config) *MaintenanceHandler {
+	return &MaintenanceHandler{db: db, cfg: cfg}
+}
+
+// RebuildReferenceCounts recomputes every FileHash reference count and
+// reports the ones that had drifted. Pass dry_run=true to only report.
+// (admin only)
+func (h *MaintenanceHandler) RebuildReferenceCounts(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := services.RebuildReferenceCounts(h.db, dryRun)
+	if err != nil {
+		log.Printf("Reference count rebuild failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild reference counts"})
+		return
+	}
+
+	if !dryRun && report.Corrected > 0 {
+		log.Printf("Reference count rebuild corrected %d of %d file hashes", report.Corrected, report.Checked)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReclaimableStorage reports how much physical storage purging expired
+// trash, collecting unreferenced content and removing orphaned blobs would
+// free, without deleting anything (admin only)
+// GET /api/admin/maintenance/reclaimable
+func (h *MaintenanceHandler) GetReclaimableStorage(c *gin.Context) {
+	report, err := services.EstimateReclaimableStorage(c.Request.Context(), h.db, h.cfg)
+	if err != nil {
+		log.Printf("Reclaimable storage estimate failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate reclaimable storage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CollectOrphanedBlobs deletes blobs and thumbnails on disk that no content
+// record points at, such as those left behind by an interrupted purge, and
+// reports how many were removed and the bytes reclaimed. Files written in
+// the last hour are left alone, as uploads in progress may be about to
+// record them. (admin only)
+// POST /api/admin/storage/gc
+func (h *MaintenanceHandler) CollectOrphanedBlobs(c *gin.Context) {
+	collected, err := services.CollectOrphanedBlobs(c.Request.Context(), h.db, h.cfg)
+	if err != nil {
+		log.Printf("Orphaned blob collection failed after removing %d blobs: %v", collected.Blobs, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":           "Failed to collect orphaned blobs",
+			"removed":         collected.Blobs,
+			"bytes_reclaimed": collected.Bytes,
+		})
+		return
+	}
+
+	recordAudit(h.db, c, "storage_gc", "storage", nil, map[string]interface{}{
+		"removed":         collected.Blobs,
+		"bytes_reclaimed": collected.Bytes,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"removed":         collected.Blobs,
+		"bytes_reclaimed": collected.Bytes,
+	})
+}
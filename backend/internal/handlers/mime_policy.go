@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/utils"
+)
+
+type MimePolicyHandler struct {
+	db     *gorm.DB
+	policy *services.MimePolicyService
+}
+
+func NewMimePolicyHandler(db *gorm.DB, policy *services.MimePolicyService) *MimePolicyHandler {
+	return &MimePolicyHandler{
+		db:     db,
+		policy: policy,
+	}
+}
+
+// GetMimePolicy returns the MIME type allowlist and blocklist uploads are
+// checked against (admin only)
+// GET /api/admin/mime-policy
+func (h *MimePolicyHandler) GetMimePolicy(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"mime_policy": h.policy.Get(),
+	})
+}
+
+// UpdateMimePolicy replaces the MIME type allowlist and blocklist. An empty
+// allowlist allows any type that isn't blocked; blocked types are rejected
+// either way. Takes effect for the next upload. (admin only)
+// PUT /api/admin/mime-policy
+func (h *MimePolicyHandler) UpdateMimePolicy(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		AllowedTypes *[]string `json:"allowed_types" binding:"required"`
+		BlockedTypes *[]string `json:"blocked_types" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	for _, pattern := range append(append([]string{}, *req.AllowedTypes...), *req.BlockedTypes...) {
+		if err := services.ValidateMimePattern(pattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	previous := h.policy.Get()
+	policy, err := h.policy.Set(*req.AllowedTypes, *req.BlockedTypes, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update MIME policy"})
+		return
+	}
+	recordAuditChange(h.db, c, "mime_policy_update", "mime_policy", nil,
+		map[string]interface{}{"allowed_types": previous.AllowedTypes, "blocked_types": previous.BlockedTypes},
+		map[string]interface{}{"allowed_types": policy.AllowedTypes, "blocked_types": policy.BlockedTypes})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "MIME policy updated successfully",
+		"mime_policy": policy,
+	})
+}
+
+// rejectDisallowedMimeType checks an upload's type against the MIME policy,
+// responding and returning true when it isn't accepted
+func (h *FileHandler) rejectDisallowedMimeType(c *gin.Context, filename, mimeType string) bool {
+	decision, allowedTypes := services.MimeAllowed, h.cfg.AllowedMimeTypes
+	if h.mimePolicy != nil {
+		decision, allowedTypes = h.mimePolicy.Check(mimeType), h.mimePolicy.Get().AllowedTypes
+	} else if len(allowedTypes) > 0 && !utils.MatchesMimeType(mimeType, allowedTypes) {
+		decision = services.MimeNotAllowed
+	}
+
+	switch decision {
+	case services.MimeBlocked:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    fmt.Sprintf("File type is blocked for %s", filename),
+			"filename": filename,
+			"mimetype": mimeType,
+		})
+		return true
+	case services.MimeNotAllowed:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         fmt.Sprintf("File type not allowed for %s", filename),
+			"filename":      filename,
+			"mimetype":      mimeType,
+			"allowed_types": allowedTypes,
+		})
+		return true
+	}
+	return false
+}
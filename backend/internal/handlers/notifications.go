@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/middleware"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/utils"
+)
+
+const (
+	// notificationKeepAlive is how often an idle connection is pinged so
+	// proxies don't close it and dead peers are noticed
+	notificationKeepAlive = 30 * time.Second
+	// notificationWriteTimeout bounds each write to a connection
+	notificationWriteTimeout = 10 * time.Second
+)
+
+type NotificationHandler struct {
+	hub *services.NotificationHub
+	cfg *config.Config
+}
+
+func NewNotificationHandler(hub *services.NotificationHub, cfg *config.Config) *NotificationHandler {
+	return &NotificationHandler{
+		hub: hub,
+		cfg: cfg,
+	}
+}
+
+// SetNotifications sets the hub completed uploads are announced on
+func (h *FileHandler) SetNotifications(hub *services.NotificationHub) {
+	h.notifications = hub
+}
+
+// SetNotifications sets the hub new shares and share link downloads are
+// announced on
+func (h *SharingHandler) SetNotifications(hub *services.NotificationHub) {
+	h.notifications = hub
+}
+
+// SetNotifications sets the hub whose connections are closed on logout and
+// session revocation
+func (h *AuthHandler) SetNotifications(hub *services.NotificationHub) {
+	h.notifications = hub
+}
+
+// IssueTicket returns a single-use ticket, valid for a few seconds, that
+// opens a notification connection. Browsers can't set headers on WebSockets,
+// and the ticket keeps the access token out of the URL.
+// POST /api/v1/notifications/ticket
+func (h *NotificationHandler) IssueTicket(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	token := middleware.BearerToken(c)
+	claims, err := middleware.ValidateJWTToken(token)
+	if err != nil || claims.ExpiresAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "An access token is required"})
+		return
+	}
+
+	ticket, err := h.hub.IssueTicket(services.NotificationSession{
+		UserID:    userID.(uuid.UUID),
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket})
+}
+
+// Connect redeems a ticket from IssueTicket and upgrades to a WebSocket that
+// pushes the user's notifications as JSON messages: files shared with them,
+// downloads of their share links and their completed uploads. The
+// connection closes when its access token expires or is logged out.
+// GET /ws?ticket=
+func (h *NotificationHandler) Connect(c *gin.Context) {
+	session, ok := h.hub.RedeemTicket(c.Query("ticket"))
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired ticket"})
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: h.checkOrigin,
+		Handler: func(conn *websocket.Conn) {
+			h.serve(conn, session)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin refuses connections from browser pages on origins that aren't
+// allowed. Clients outside a browser send no Origin.
+func (h *NotificationHandler) checkOrigin(_ *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range h.cfg.AllowedOrigins {
+		if origin == allowed {
+			return nil
+		}
+	}
+	return errors.New("origin not allowed")
+}
+
+// serve pushes notifications to conn until the client disconnects, a write
+// fails or the session ends, then releases its subscription
+func (h *NotificationHandler) serve(conn *websocket.Conn, session services.NotificationSession) {
+	defer conn.Close()
+
+	notifications, loggedOut, unsubscribe := h.hub.Subscribe(session)
+	defer unsubscribe()
+
+	// Clients don't send anything; reading only notices when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard string
+		for websocket.Message.Receive(conn, &discard) == nil {
+		}
+	}()
+
+	keepAlive := time.NewTicker(notificationKeepAlive)
+	defer keepAlive.Stop()
+
+	expired := time.NewTimer(time.Until(session.ExpiresAt))
+	defer expired.Stop()
+
+	send := func(message interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(notificationWriteTimeout))
+		return websocket.JSON.Send(conn, message) == nil
+	}
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-loggedOut:
+			return
+		case <-expired.C:
+			return
+		case notification := <-notifications:
+			if !send(notification) {
+				return
+			}
+		case now := <-keepAlive.C:
+			if !send(services.Notification{Type: "ping", CreatedAt: now}) {
+				return
+			}
+		}
+	}
+}
+
+// notifyUploadCompleted tells the uploader's connections which files were
+// stored. Call it once the upload is committed.
+func (h *FileHandler) notifyUploadCompleted(userID uuid.UUID, files []map[string]interface{}) {
+	h.notifications.Publish(userID, services.NotificationUploadCompleted, gin.H{
+		"uploaded_files_count": len(files),
+		"files":                files,
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Listings wrapped in a pagination envelope default to defaultPageSize items
+// per page and allow at most maxPageSize
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// pagination is the page of a listing a request asked for
+type pagination struct {
+	page     int
+	pageSize int
+}
+
+// parsePagination reads page and page_size, responding with 400 when they
+// are out of range
+func parsePagination(c *gin.Context) (pagination, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return pagination{}, false
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxPageSize)})
+		return pagination{}, false
+	}
+	return pagination{page: page, pageSize: pageSize}, true
+}
+
+func (p pagination) offset() int {
+	return (p.page - 1) * p.pageSize
+}
+
+// envelope describes the page within a listing of total items
+func (p pagination) envelope(total int64) gin.H {
+	totalPages := (total + int64(p.pageSize) - 1) / int64(p.pageSize)
+	return gin.H{
+		"total_count": total,
+		"page":        p.page,
+		"page_size":   p.pageSize,
+		"total_pages": totalPages,
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// quarantineNotice tells the uploader why their file isn't available yet
+const quarantineNotice = "This file was flagged by the malware scanner and is under review by an administrator"
+
+// scanUpload runs content past the malware scanner. In reject mode infected
+// content is refused with 422; in quarantine mode the matched signature is
+// returned so the file can be stored for review. A scan that can't complete
+// fails the upload rather than letting content through unchecked.
+func (h *FileHandler) scanUpload(c *gin.Context, filename string, content io.Reader) (string, bool) {
+	if h.scanner == nil {
+		return "", false
+	}
+
+	signature, err := h.scanner.Scan(c.Request.Context(), content)
+	if err != nil {
+		log.Printf("Malware scan of %q failed: %v", filename, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":    fmt.Sprintf("Malware scan unavailable for %s, try again later", filename),
+			"filename": filename,
+		})
+		return "", true
+	}
+	if signature == "" {
+		return "", false
+	}
+
+	log.Printf("Malware scanner flagged upload %q: %s", filename, signature)
+	if h.cfg.MalwareScanMode != "quarantine" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     fmt.Sprintf("Malware detected in %s", filename),
+			"code":      "malware_detected",
+			"filename":  filename,
+			"signature": signature,
+		})
+		return "", true
+	}
+	return signature, false
+}
+
+// quarantinedContent returns the reason content is quarantined, or "" when it
+// isn't, so uploads and restores that reuse stored content without sending it
+// can't slip past a quarantine
+func quarantinedContent(tx *gorm.DB, fileHashID uuid.UUID) (string, error) {
+	var fileHash models.FileHash
+	if err := tx.Select("quarantine_reason").Where("id = ?", fileHashID).First(&fileHash).Error; err != nil {
+		return "", fmt.Errorf("failed to check content quarantine: %v", err)
+	}
+	return fileHash.QuarantineReason, nil
+}
+
+// quarantineContent holds flagged content for review. Quarantine applies to
+// the content, so every file holding it is held too, whoever uploaded it.
+func quarantineContent(tx *gorm.DB, fileHashID uuid.UUID, reason string) error {
+	if err := tx.Model(&models.FileHash{}).Where("id = ?", fileHashID).
+		Update("quarantine_reason", reason).Error; err != nil {
+		return fmt.Errorf("failed to quarantine content: %v", err)
+	}
+	if err := tx.Model(&models.File{}).Where("file_hash_id = ?", fileHashID).Updates(map[string]interface{}{
+		"status":            models.FileStatusQuarantined,
+		"quarantine_reason": reason,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to quarantine files: %v", err)
+	}
+	return nil
+}
+
+// rejectQuarantined responds with 403 when the file is held for review.
+// Quarantined content is never served, not even to admins.
+func rejectQuarantined(c *gin.Context, file *models.File) bool {
+	if !file.Quarantined() {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  quarantineNotice,
+		"status": file.Status,
+	})
+	return true
+}
+
+// ListQuarantinedFiles lists the files held for review, oldest first (admin only)
+// GET /api/admin/quarantine
+func (h *AdminHandler) ListQuarantinedFiles(c *gin.Context) {
+	var files []models.File
+	if err := h.db.Preload("Owner", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, username, email, first_name, last_name")
+	}).Where("status = ? AND is_deleted = false", models.FileStatusQuarantined).
+		Order("created_at ASC").Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get quarantined files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files": files,
+		"count": len(files),
+	})
+}
+
+// ReleaseQuarantinedFile makes a quarantined file's content available again
+// after it was judged a false positive. Quarantine applies to the content, so
+// every file holding it is released (admin only).
+// POST /api/admin/quarantine/:id/release
+func (h *AdminHandler) ReleaseQuarantinedFile(c *gin.Context) {
+	file, ok := h.findQuarantinedFile(c)
+	if !ok {
+		return
+	}
+
+	var released int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.FileHash{}).Where("id = ?", file.FileHashID).
+			Update("quarantine_reason", "").Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.File{}).
+			Where("file_hash_id = ? AND status = ?", file.FileHashID, models.FileStatusQuarantined).
+			Updates(map[string]interface{}{
+				"status":            models.FileStatusActive,
+				"quarantine_reason": "",
+			})
+		released = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release file"})
+		return
+	}
+
+	recordAudit(h.db, c, "quarantine_release", "file", &file.ID, map[string]interface{}{
+		"owner_id":       file.OwnerID,
+		"signature":      file.QuarantineReason,
+		"file_hash_id":   file.FileHashID,
+		"files_released": released,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "File released from quarantine",
+		"file_id":        file.ID,
+		"files_released": released,
+	})
+}
+
+// DeleteQuarantinedFile permanently deletes a quarantined file. Its content is
+// removed from storage too once nothing else references it (admin only).
+// DELETE /api/admin/quarantine/:id
+func (h *AdminHandler) DeleteQuarantinedFile(c *gin.Context) {
+	file, ok := h.findQuarantinedFile(c)
+	if !ok {
+		return
+	}
+
+	var orphaned []*models.FileHash
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if _, _, err := trashFile(tx, file, false); err != nil {
+			return err
+		}
+		var err error
+		orphaned, err = services.PurgeFileRecord(tx, file)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file", "details": err.Error()})
+		return
+	}
+
+	for _, fileHash := range orphaned {
+		services.RemoveContent(h.storage, h.cfg.StoragePath, fileHash)
+	}
+
+	recordAudit(h.db, c, "quarantine_delete", "file", &file.ID, map[string]interface{}{
+		"owner_id":        file.OwnerID,
+		"filename":        file.OriginalFilename,
+		"signature":       file.QuarantineReason,
+		"content_removed": len(orphaned) > 0,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Quarantined file deleted",
+		"file_id":         file.ID,
+		"content_removed": len(orphaned) > 0,
+	})
+}
+
+// findQuarantinedFile loads the quarantined file named in the URL
+func (h *AdminHandler) findQuarantinedFile(c *gin.Context) (*models.File, bool) {
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID format"})
+		return nil, false
+	}
+
+	var file models.File
+	if err := h.db.Where("id = ? AND status = ? AND is_deleted = false", fileID, models.FileStatusQuarantined).
+		First(&file).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined file not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return nil, false
+	}
+	return &file, true
+}
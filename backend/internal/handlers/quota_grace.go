@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// errQuotaGraceUsed is returned when grace was claimed by a concurrent upload
+var errQuotaGraceUsed = errors.New("quota grace has already been used")
+
+// quotaCheck is the outcome of checking an upload against a user's quota
+type quotaCheck struct {
+	allowed        bool
+	usesGrace      bool  // the upload only fits within the grace allowance
+	overQuota      bool  // the user is already over quota from an earlier grace upload
+	graceAvailable bool  // grace can still be used
+	graceLimit     int64 // the most the user may store when using grace
+}
+
+// lockUserForQuota reloads the user and holds their row until tx ends, so
+// concurrent uploads check the quota in turn and each sees the storage the
+// one before it used
+func lockUserForQuota(tx *gorm.DB, user *models.User) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, "id = ?", user.ID).Error
+}
+
+// quotaGraceLimit is the storage a user may reach by using grace
+func (h *FileHandler) quotaGraceLimit(user *models.User) int64 {
+	if h.cfg.QuotaGracePercent <= 0 {
+		return user.StorageQuota
+	}
+	return user.StorageQuota + user.StorageQuota*int64(h.cfg.QuotaGracePercent)/100
+}
+
+// quotaGraceAvailable reports whether the user may go over quota on grace:
+// it must be enabled and not used within the cooldown
+func (h *FileHandler) quotaGraceAvailable(user *models.User, now time.Time) bool {
+	if h.cfg.QuotaGracePercent <= 0 {
+		return false
+	}
+	if user.QuotaGraceUsedAt == nil {
+		return true
+	}
+	if h.cfg.QuotaGraceCooldown <= 0 {
+		return false
+	}
+	return now.Sub(*user.QuotaGraceUsedAt) >= time.Duration(h.cfg.QuotaGraceCooldown)*time.Hour
+}
+
+// checkQuota decides whether an upload of size bytes fits the user's quota,
+// falling back to the one-off grace allowance. Users already over quota are
+// blocked until they clean up.
+func (h *FileHandler) checkQuota(user *models.User, size int64) quotaCheck {
+	check := quotaCheck{
+		overQuota:      user.StorageUsed > user.StorageQuota,
+		graceAvailable: h.quotaGraceAvailable(user, time.Now()),
+		graceLimit:     h.quotaGraceLimit(user),
+	}
+
+	switch {
+	case check.overQuota:
+	case user.StorageUsed+size <= user.StorageQuota:
+		check.allowed = true
+	case check.graceAvailable && user.StorageUsed+size <= check.graceLimit:
+		check.allowed = true
+		check.usesGrace = true
+	}
+	return check
+}
+
+// rejectOverQuota writes the response for an upload the quota doesn't allow
+func rejectOverQuota(c *gin.Context, user *models.User, size int64, check quotaCheck) {
+	message := "Total upload size exceeds storage quota"
+	if check.overQuota {
+		message = "Storage quota exceeded, delete files before uploading more"
+	}
+
+	available := user.StorageQuota - user.StorageUsed
+	if available < 0 {
+		available = 0
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":           message,
+		"total_size":      size,
+		"storage_used":    user.StorageUsed,
+		"storage_quota":   user.StorageQuota,
+		"available":       available,
+		"over_quota":      check.overQuota,
+		"grace_available": check.graceAvailable,
+	})
+}
+
+// markQuotaGraceUsed records that the user went over quota on grace. The
+// update only succeeds while grace is still available so two concurrent
+// uploads can't both claim it.
+func (h *FileHandler) markQuotaGraceUsed(tx *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+	query := tx.Model(&models.User{}).Where("id = ?", userID)
+	if h.cfg.QuotaGraceCooldown > 0 {
+		cutoff := now.Add(-time.Duration(h.cfg.QuotaGraceCooldown) * time.Hour)
+		query = query.Where("quota_grace_used_at IS NULL OR quota_grace_used_at <= ?", cutoff)
+	} else {
+		query = query.Where("quota_grace_used_at IS NULL")
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"quota_grace_used_at": now,
+		"quota_grace_count":   gorm.Expr("quota_grace_count + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errQuotaGraceUsed
+	}
+	return nil
+}
+
+// quotaStatus summarizes the user's quota and grace state for stats responses
+func (h *FileHandler) quotaStatus(user *models.User) gin.H {
+	status := gin.H{
+		"over_quota":      user.StorageUsed > user.StorageQuota,
+		"grace_percent":   h.cfg.QuotaGracePercent,
+		"grace_available": h.quotaGraceAvailable(user, time.Now()),
+		"grace_limit":     h.quotaGraceLimit(user),
+		"grace_count":     user.QuotaGraceCount,
+	}
+	if user.QuotaGraceUsedAt != nil {
+		status["grace_used_at"] = user.QuotaGraceUsedAt
+	}
+	return status
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/testdb"
+)
+
+func TestConcurrentUploadsCannotExceedQuota(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	user := createTestUser(t, db, 1000)
+
+	// Each upload fits the quota on its own, both together don't
+	const size = 600
+	requests := []*http.Request{
+		newUploadRequest(t, testUpload{"first.bin", uniqueContent(size)}),
+		newUploadRequest(t, testUpload{"second.bin", uniqueContent(size)}),
+	}
+
+	statuses := make([]int, len(requests))
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range requests {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			statuses[i] = serveUpload(h, user.ID, requests[i]).Code
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+
+	succeeded, rejected := 0, 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusBadRequest:
+			rejected++
+		}
+	}
+	if succeeded != 1 || rejected != 1 {
+		t.Fatalf("upload statuses = %v, want exactly one %d and one %d", statuses, http.StatusOK, http.StatusBadRequest)
+	}
+
+	var stored models.User
+	if err := db.First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StorageUsed != size {
+		t.Errorf("storage used = %d, want %d", stored.StorageUsed, size)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// quotaImpactPageSize is how many files are read per query while looking
+// for removal candidates
+const quotaImpactPageSize = 100
+
+// quotaImpactCandidate is a file that could be removed to get under a quota
+type quotaImpactCandidate struct {
+	ID               uuid.UUID  `json:"id"`
+	OriginalFilename string     `json:"original_filename"`
+	Size             int64      `json:"size"`
+	FolderID         *uuid.UUID `json:"folder_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	Redacted         bool       `json:"redacted,omitempty"`
+}
+
+// GetQuotaImpact previews a quota reduction: how far over the new quota the
+// user would be and which of their largest files would need to go to get
+// back under it. Nothing is changed. (admin only)
+func (h *AdminHandler) GetQuotaImpact(c *gin.Context) {
+	uid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	newQuota, err := strconv.ParseInt(c.Query("new_quota"), 10, 64)
+	if err != nil || newQuota < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new_quota must be a non-negative number of bytes"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Select("id, storage_used, storage_quota").First(&user, "id = ?", uid).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	overBy := user.StorageUsed - newQuota
+	if overBy < 0 {
+		overBy = 0
+	}
+
+	hiddenFolders := map[uuid.UUID]bool{}
+	if h.cfg.AdminFilePrivacy && overBy > 0 {
+		if hiddenFolders, err = adminHiddenFolderIDs(h.db); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve hidden folders"})
+			return
+		}
+	}
+
+	// Walk the user's files largest first until removing them would cover the
+	// overage; storage_used is the sum of live file sizes, so each removal
+	// frees exactly its size
+	candidates := []quotaImpactCandidate{}
+	var bytesFreed int64
+	for offset := 0; bytesFreed < overBy; offset += quotaImpactPageSize {
+		var files []models.File
+		if err := h.db.Select("id, original_filename, size, folder_id, created_at, hidden_from_admin").
+			Where("owner_id = ? AND is_deleted = false", uid).
+			Order("size DESC, id ASC").Offset(offset).Limit(quotaImpactPageSize).
+			Find(&files).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get files"})
+			return
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			if bytesFreed >= overBy {
+				break
+			}
+			candidate := quotaImpactCandidate{
+				ID:               file.ID,
+				OriginalFilename: file.OriginalFilename,
+				Size:             file.Size,
+				FolderID:         file.FolderID,
+				CreatedAt:        file.CreatedAt,
+			}
+			if h.cfg.AdminFilePrivacy && (file.HiddenFromAdmin || (file.FolderID != nil && hiddenFolders[*file.FolderID])) {
+				candidate.OriginalFilename = redactedName
+				candidate.Redacted = true
+			}
+			candidates = append(candidates, candidate)
+			bytesFreed += file.Size
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":        user.ID,
+		"current_quota":  user.StorageQuota,
+		"new_quota":      newQuota,
+		"storage_used":   user.StorageUsed,
+		"over_by":        overBy,
+		"within_quota":   overBy == 0,
+		"files_to_clear": len(candidates),
+		"bytes_freed":    bytesFreed,
+		"covers_overage": bytesFreed >= overBy,
+		"candidates":     candidates,
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// rejectCorruptRange aborts a download whose requested byte ranges failed
+// chunk verification, rather than serving corrupt content
+func rejectCorruptRange(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var mismatch *services.ChunkMismatchError
+	if errors.As(err, &mismatch) {
+		log.Printf("ALERT: refusing range %q: %v", c.GetHeader("Range"), mismatch)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Stored content is corrupt",
+			"chunk": mismatch.Chunk,
+		})
+		return true
+	}
+
+	log.Printf("Failed to verify range %q: %v", c.GetHeader("Range"), err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify stored content"})
+	return true
+}
+
+// rejectCorruptBlob re-hashes a blob before it is served whole when
+// VERIFY_ON_SERVE is enabled, refusing the download with 500 and flagging the
+// blob in the integrity report when it no longer matches its hash. Range
+// requests are left to chunk checksums, and HEAD and 304 responses send no
+// content. A missing blob is left for the caller to report.
+func rejectCorruptBlob(c *gin.Context, db *gorm.DB, cfg *config.Config, storage services.Storage, fileHash *models.FileHash) bool {
+	if !cfg.VerifyOnServe || c.Request.Method == http.MethodHead || c.GetHeader("Range") != "" ||
+		etagMatches(c.GetHeader("If-None-Match"), fmt.Sprintf("%q", fileHash.Hash)) {
+		return false
+	}
+
+	status, err := services.VerifyBlob(storage, fileHash)
+	if status != models.IntegrityCorrupt {
+		return false
+	}
+
+	log.Printf("ALERT: refusing to serve content %s, it no longer matches its hash (read error: %v)", fileHash.Hash, err)
+	if err := services.RecordVerification(db, fileHash, status); err != nil {
+		log.Printf("Failed to flag corrupt content: %v", err)
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Stored content is corrupt",
+		"code":  "content_corrupt",
+	})
+	return true
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+type SavedSearchHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewSavedSearchHandler(db *gorm.DB, cfg *config.Config) *SavedSearchHandler {
+	return &SavedSearchHandler{db: db, cfg: cfg}
+}
+
+// savedSearchRequest is the body for creating or replacing a saved search
+type savedSearchRequest struct {
+	Name     string                `json:"name" binding:"required"`
+	Criteria models.SearchCriteria `json:"criteria"`
+}
+
+// bindSavedSearch reads and validates a saved search from the request body
+func bindSavedSearch(c *gin.Context) (savedSearchRequest, bool) {
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Saved search name must be between 1 and 100 characters"})
+		return req, false
+	}
+	req.Criteria.Query = strings.TrimSpace(req.Criteria.Query)
+	if err := services.ValidateSearchCriteria(&req.Criteria); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// CreateSavedSearch saves a named search
+// POST /api/saved-searches
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	req, ok := bindSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	savedSearch := models.SavedSearch{
+		BaseModel: models.BaseModel{
+			ID: uuid.New(),
+		},
+		UserID:   userID.(uuid.UUID),
+		Name:     req.Name,
+		Criteria: req.Criteria,
+	}
+	if err := h.db.Create(&savedSearch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create saved search"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Saved search created successfully",
+		"saved_search": savedSearch,
+	})
+}
+
+// ListSavedSearches lists the user's saved searches
+// GET /api/saved-searches
+func (h *SavedSearchHandler) ListSavedSearches(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var savedSearches []models.SavedSearch
+	if err := h.db.Where("user_id = ?", userID).Order("name ASC").Find(&savedSearches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saved_searches": savedSearches,
+		"count":          len(savedSearches),
+	})
+}
+
+// GetSavedSearch returns one saved search
+// GET /api/saved-searches/:id
+func (h *SavedSearchHandler) GetSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, ok := h.findOwnedSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"saved_search": savedSearch})
+}
+
+// UpdateSavedSearch replaces a saved search's name and criteria
+// PUT /api/saved-searches/:id
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, ok := h.findOwnedSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	req, ok := bindSavedSearch(c)
+	if !ok {
+		return
+	}
+
+	savedSearch.Name = req.Name
+	savedSearch.Criteria = req.Criteria
+	// Select all columns so criteria cleared in the request are cleared here too
+	if err := h.db.Select("*").Omit("created_at").Save(savedSearch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Saved search updated successfully",
+		"saved_search": savedSearch,
+	})
+}
+
+// DeleteSavedSearch deletes a saved search. The files it matched are untouched.
+// DELETE /api/saved-searches/:id
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, ok := h.findOwnedSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(savedSearch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// ListSavedSearchFiles runs a saved search against the user's current files
+// GET /api/saved-searches/:id/files
+func (h *SavedSearchHandler) ListSavedSearchFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	savedSearch, ok := h.findOwnedSavedSearch(c, userID)
+	if !ok {
+		return
+	}
+
+	files, err := searchUserFiles(h.db, h.cfg, userID, &savedSearch.Criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saved_search": savedSearch,
+		"files":        files,
+		"count":        len(files),
+	})
+}
+
+// findOwnedSavedSearch loads the saved search named in the URL, responding
+// with an error when it doesn't exist or belongs to someone else
+func (h *SavedSearchHandler) findOwnedSavedSearch(c *gin.Context, userID interface{}) (*models.SavedSearch, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search ID format"})
+		return nil, false
+	}
+
+	var savedSearch models.SavedSearch
+	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&savedSearch).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved search"})
+		return nil, false
+	}
+	return &savedSearch, true
+}
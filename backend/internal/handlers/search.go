@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// searchCriteriaFromQuery reads search criteria from query parameters: q,
+// fuzzy, category, tags (comma separated or repeated), min_size, max_size,
+// from and to
+func searchCriteriaFromQuery(c *gin.Context) (models.SearchCriteria, error) {
+	criteria := models.SearchCriteria{
+		Query:        strings.TrimSpace(c.Query("q")),
+		MimeCategory: c.Query("category"),
+	}
+
+	if value := c.Query("fuzzy"); value != "" {
+		fuzzy, err := strconv.ParseBool(value)
+		if err != nil {
+			return criteria, fmt.Errorf("fuzzy must be true or false")
+		}
+		criteria.Fuzzy = fuzzy
+	}
+
+	for _, value := range c.QueryArray("tags") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				criteria.Tags = append(criteria.Tags, tag)
+			}
+		}
+	}
+
+	for param, target := range map[string]**int64{"min_size": &criteria.MinSize, "max_size": &criteria.MaxSize} {
+		if value := c.Query(param); value != "" {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return criteria, fmt.Errorf("%s must be a number of bytes", param)
+			}
+			*target = &size
+		}
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseStatsTime(value)
+		if err != nil {
+			return criteria, fmt.Errorf("from must be an RFC3339 time or a YYYY-MM-DD date")
+		}
+		criteria.CreatedAfter = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseStatsTime(value)
+		if err != nil {
+			return criteria, fmt.Errorf("to must be an RFC3339 time or a YYYY-MM-DD date")
+		}
+		criteria.CreatedBefore = &to
+	}
+
+	return criteria, services.ValidateSearchCriteria(&criteria)
+}
+
+// searchUserFiles runs criteria against a user's own non-deleted files. Fuzzy
+// queries run in a transaction so the similarity threshold applies to them
+// alone.
+func searchUserFiles(db *gorm.DB, cfg *config.Config, userID interface{}, criteria *models.SearchCriteria) ([]models.File, error) {
+	var files []models.File
+	search := func(tx *gorm.DB) error {
+		query := tx.Where("owner_id = ? AND is_deleted = false", userID)
+		query = services.ApplySearchCriteria(query, criteria)
+		return query.Preload("Folder").Order("original_filename ASC").Find(&files).Error
+	}
+
+	if !criteria.Fuzzy || criteria.Query == "" {
+		return files, search(db)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := services.SetFuzzyThreshold(tx, cfg.SearchFuzzyThreshold); err != nil {
+			return err
+		}
+		return search(tx)
+	})
+	return files, err
+}
+
+// SearchFiles searches the user's files by name, type, tags, size and upload
+// date. With fuzzy=true names similar to q match too, best matches first.
+// GET /api/files/search?q=&fuzzy=&category=&tags=&min_size=&max_size=&from=&to=
+func (h *FileHandler) SearchFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	criteria, err := searchCriteriaFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	files, err := searchUserFiles(h.db, h.cfg, userID, &criteria)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"criteria": criteria,
+		"files":    files,
+		"count":    len(files),
+	})
+}
+
+// mimeTypeCount is how many of a user's files have a MIME type
+type mimeTypeCount struct {
+	MimeType string `json:"mime_type"`
+	Count    int64  `json:"count"`
+}
+
+// ListMimeTypes lists the distinct MIME types among the user's files with a
+// count for each, most common first, for building type filters
+// GET /api/files/mimetypes
+func (h *FileHandler) ListMimeTypes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	mimeTypes := []mimeTypeCount{}
+	if err := h.db.Model(&models.File{}).
+		Select("mime_type, COUNT(*) AS count").
+		Where("owner_id = ? AND is_deleted = false", userID).
+		Group("mime_type").
+		Order("count DESC, mime_type ASC").
+		Scan(&mimeTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get MIME types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mime_types": mimeTypes,
+		"count":      len(mimeTypes),
+	})
+}
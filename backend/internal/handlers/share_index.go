@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// shareIndexTemplate renders the browsable page for a folder share link
+var shareIndexTemplate = template.Must(template.New("share-index").Funcs(template.FuncMap{
+	"size": formatShareSize,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Folder}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+table { width: 100%; border-collapse: collapse; }
+th, td { text-align: left; padding: .4rem; border-bottom: 1px solid #ddd; }
+td.size { text-align: right; white-space: nowrap; }
+p.note { color: #666; font-size: .9rem; }
+</style>
+</head>
+<body>
+<h1>{{.Folder}}</h1>
+{{if .Parent}}<p><a href="{{.Parent}}">Up</a></p>{{end}}
+{{if or .Folders .Files}}
+<table>
+<tr><th>Name</th><th>Size</th></tr>
+{{range .Folders}}<tr><td><a href="{{.URL}}">{{.Name}}/</a></td><td class="size"></td></tr>
+{{end}}{{range .Files}}<tr><td>{{if $.Downloadable}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td class="size">{{size .Size}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>This folder is empty.</p>
+{{end}}
+{{if not .Downloadable}}<p class="note">Files in this folder can be viewed but not downloaded.</p>{{end}}
+{{if .ExpiresAt}}<p class="note">This link expires {{.ExpiresAt.Format "2 Jan 2006 15:04 MST"}}.</p>{{end}}
+{{if .DownloadsLeft}}<p class="note">{{.DownloadsLeft}} download(s) left on this link.</p>{{end}}
+</body>
+</html>
+`))
+
+// shareIndexFile is one row of the folder share page
+type shareIndexFile struct {
+	Name string
+	Size int64
+	URL  string
+}
+
+// formatShareSize renders a byte count for the folder share page
+func formatShareSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
+
+// accessSharedFolder answers a folder share link with a listing of the
+// folder's files and subfolders: an HTML index when the link asks for one and
+// the deployment allows it, JSON otherwise. ?format=json always returns JSON,
+// and ?folder= lists a subfolder instead.
+func (h *SharingHandler) accessSharedFolder(c *gin.Context, shareLink *models.ShareLink, password string) {
+	var folderID *uuid.UUID
+	if value := c.Query("folder"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+			return
+		}
+		folderID = &parsed
+	}
+	folder, err := h.sharingService.SharedFolder(shareLink, folderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	subfolders, err := h.sharingService.SharedSubfolders(folder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	files, err := h.sharingService.SharedFolderFiles(folder)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.sharingService.RecordShareLinkAccess(shareLink, c.ClientIP(), c.GetHeader("User-Agent"), "view")
+
+	if !h.cfg.ShareHTMLIndex || !shareLink.HTMLIndex || c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"folder":     shareLink.Folder,
+			"current":    folder,
+			"subfolders": subfolders,
+			"files":      files,
+			"permission": shareLink.Permission,
+			"share_info": gin.H{
+				"created_at":     shareLink.CreatedAt,
+				"expires_at":     shareLink.ExpiresAt,
+				"download_count": shareLink.DownloadCount,
+				"max_downloads":  shareLink.MaxDownloads,
+			},
+		})
+		return
+	}
+
+	base := "/share/" + url.PathEscape(shareLink.ShareToken)
+	link := func(path string, query url.Values) string {
+		if password != "" {
+			query.Set("password", password)
+		}
+		if len(query) == 0 {
+			return base + path
+		}
+		return base + path + "?" + query.Encode()
+	}
+
+	folderRows := make([]shareIndexFile, 0, len(subfolders))
+	for _, subfolder := range subfolders {
+		folderRows = append(folderRows, shareIndexFile{
+			Name: subfolder.Name,
+			URL:  link("", url.Values{"folder": {subfolder.ID.String()}}),
+		})
+	}
+	rows := make([]shareIndexFile, 0, len(files))
+	for _, file := range files {
+		rows = append(rows, shareIndexFile{
+			Name: file.OriginalFilename,
+			Size: file.Size,
+			URL:  link("/download/"+file.ID.String(), url.Values{}),
+		})
+	}
+
+	page := struct {
+		Folder        string
+		Parent        string
+		Folders       []shareIndexFile
+		Files         []shareIndexFile
+		Downloadable  bool
+		ExpiresAt     *time.Time
+		DownloadsLeft int
+	}{
+		Folder:       folder.Name,
+		Folders:      folderRows,
+		Files:        rows,
+		Downloadable: shareLink.Permission == models.PermissionDownload,
+		ExpiresAt:    shareLink.ExpiresAt,
+	}
+	if folder.ID != shareLink.Folder.ID && folder.ParentID != nil {
+		query := url.Values{}
+		if *folder.ParentID != shareLink.Folder.ID {
+			query.Set("folder", folder.ParentID.String())
+		}
+		page.Parent = link("", query)
+	}
+	if shareLink.MaxDownloads != nil {
+		page.DownloadsLeft = *shareLink.MaxDownloads - shareLink.DownloadCount
+	}
+
+	var body bytes.Buffer
+	if err := shareIndexTemplate.Execute(&body, page); err != nil {
+		log.Printf("Failed to render share index for link %s: %v", shareLink.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render folder index"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// RedirectSharedFolderFile sends links using the old path for a file in a
+// folder share to its download path
+// GET /share/:token/files/:fileId
+func (h *SharingHandler) RedirectSharedFolderFile(c *gin.Context) {
+	target := "/share/" + url.PathEscape(c.Param("token")) + "/download/" + url.PathEscape(c.Param("fileId"))
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, target)
+}
+
+// DownloadSharedFolderFile downloads one file from a folder share link, from
+// the folder or any of its subfolders
+// GET /share/:token/download/:fileId
+func (h *SharingHandler) DownloadSharedFolderFile(c *gin.Context) {
+	fileID, err := uuid.Parse(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	shareLink, err := h.sharingService.ValidateShareLink(c.Param("token"), c.Query("password"))
+	if err != nil {
+		rejectShareLink(c, err)
+		return
+	}
+
+	if shareLink.FolderID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This link shares a single file, use its download link"})
+		return
+	}
+
+	file, err := h.sharingService.SharedFolderFile(shareLink, fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.serveSharedFile(c, shareLink, file)
+}
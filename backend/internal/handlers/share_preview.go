@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// sharePreviewMaxImageSize is the largest image served as its own preview
+// when no thumbnail has been generated for it
+const sharePreviewMaxImageSize = 5 * 1024 * 1024
+
+// sharePreviewAgents are the link unfurlers of common chat apps. Some of them
+// don't ask for HTML explicitly.
+var sharePreviewAgents = []string{
+	"slackbot", "twitterbot", "facebookexternalhit", "discordbot", "linkedinbot",
+	"telegrambot", "whatsapp", "skypeuripreview", "microsoftpreview", "mattermost",
+}
+
+// sharePreviewTemplate renders the landing page of a file share link. The
+// Open Graph and Twitter card tags are only included when Meta is set.
+var sharePreviewTemplate = template.Must(template.New("share-preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+{{if .Meta}}<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{if .ImageURL}}<meta property="og:image" content="{{.ImageURL}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="{{.ImageURL}}">
+{{else}}<meta name="twitter:card" content="summary">
+{{end}}<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+{{end}}<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+p.note { color: #666; font-size: .9rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Description}}</p>
+{{if .DownloadURL}}<p><a href="{{.DownloadURL}}">Download</a></p>{{else}}<p class="note">This file can be viewed but not downloaded.</p>{{end}}
+{{if .ExpiresAt}}<p class="note">This link expires {{.ExpiresAt.Format "2 Jan 2006 15:04 MST"}}.</p>{{end}}
+</body>
+</html>
+`))
+
+// wantsSharePreview reports whether the client is a browser or link unfurler
+// rather than an API client
+func wantsSharePreview(c *gin.Context) bool {
+	if strings.Contains(c.GetHeader("Accept"), "text/html") {
+		return true
+	}
+	agent := strings.ToLower(c.GetHeader("User-Agent"))
+	for _, unfurler := range sharePreviewAgents {
+		if strings.Contains(agent, unfurler) {
+			return true
+		}
+	}
+	return false
+}
+
+// sharePreviewImage finds the image shown when a link unfurls, the generated
+// thumbnail or a small enough image file itself, and its content type
+func (h *SharingHandler) sharePreviewImage(file *models.File) (string, string) {
+	if file.FileHash == nil || file.Quarantined() {
+		return "", ""
+	}
+	if file.FileHash.ThumbnailPath != "" {
+		thumbnailPath := filepath.Join(h.cfg.StoragePath, file.FileHash.ThumbnailPath)
+		if _, err := os.Stat(thumbnailPath); err == nil {
+			return thumbnailPath, "image/jpeg"
+		}
+	}
+	if strings.HasPrefix(file.MimeType, "image/") && file.Size <= sharePreviewMaxImageSize {
+		imagePath := filepath.Join(h.cfg.StoragePath, file.FileHash.StoragePath)
+		if _, err := os.Stat(imagePath); err == nil {
+			return imagePath, file.MimeType
+		}
+	}
+	return "", ""
+}
+
+// renderSharePreview serves the HTML landing page of a file share link.
+// Password-protected links never carry preview metadata.
+func (h *SharingHandler) renderSharePreview(c *gin.Context, shareLink *models.ShareLink) {
+	file := shareLink.File
+	// Unfurls are cached, so links come from configuration rather than the
+	// request's Host header, which the client controls
+	baseURL := strings.TrimSuffix(h.cfg.PublicURL, "/")
+	linkPath := "/share/" + url.PathEscape(shareLink.ShareToken)
+
+	page := struct {
+		Meta        bool
+		Title       string
+		Description string
+		URL         string
+		ImageURL    string
+		DownloadURL string
+		ExpiresAt   *time.Time
+	}{
+		Meta:        shareLink.PasswordHash == "",
+		Title:       file.OriginalFilename,
+		Description: fmt.Sprintf("Shared file, %s", formatShareSize(file.Size)),
+		URL:         baseURL + linkPath,
+		ExpiresAt:   shareLink.ExpiresAt,
+	}
+	if file.Description != "" {
+		page.Description = file.Description
+	}
+	if imagePath, _ := h.sharePreviewImage(file); page.Meta && imagePath != "" {
+		page.ImageURL = baseURL + linkPath + "/preview"
+	}
+	if shareLink.Permission == models.PermissionDownload {
+		page.DownloadURL = linkPath + "/download"
+		if password := c.Query("password"); password != "" {
+			page.DownloadURL += "?password=" + url.QueryEscape(password)
+		}
+	}
+
+	var body bytes.Buffer
+	if err := sharePreviewTemplate.Execute(&body, page); err != nil {
+		log.Printf("Failed to render share preview for link %s: %v", shareLink.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render share page"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// SharedFilePreview serves the unfurl image of a file share link. It doesn't
+// count as a download, and isn't available for password-protected links.
+// GET /share/:token/preview
+func (h *SharingHandler) SharedFilePreview(c *gin.Context) {
+	if !h.cfg.ShareLinkPreviews {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link previews are disabled"})
+		return
+	}
+
+	shareLink, err := h.sharingService.ValidateShareLink(c.Param("token"), "")
+	// A preview would show a burn-after-reading file without using up the link
+	if err != nil || shareLink.File == nil || shareLink.BurnAfterReading {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preview not available"})
+		return
+	}
+	if shareLink.File.AccessExpired(time.Now()) || shareLink.File.Quarantined() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preview not available"})
+		return
+	}
+
+	imagePath, contentType := h.sharePreviewImage(shareLink.File)
+	if imagePath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preview not available"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "max-age=3600")
+	c.File(imagePath)
+}
@@ -1,23 +1,47 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"file-vault-system/backend/internal/config"
 	"file-vault-system/backend/internal/models"
 	"file-vault-system/backend/internal/services"
 )
 
+// Share link listings are paginated, page_size defaults to
+// defaultShareLinkPageSize and can't exceed maxShareLinkPageSize
+const (
+	defaultShareLinkPageSize = 50
+	maxShareLinkPageSize     = 200
+)
+
 type SharingHandler struct {
+	db             *gorm.DB
 	sharingService *services.SharingService
+	cfg            *config.Config
+	storage        services.Storage
+	notifications  *services.NotificationHub
 }
 
-func NewSharingHandler(sharingService *services.SharingService) *SharingHandler {
+func NewSharingHandler(db *gorm.DB, sharingService *services.SharingService, cfg *config.Config) *SharingHandler {
 	return &SharingHandler{
+		db:             db,
 		sharingService: sharingService,
+		cfg:            cfg,
+		storage:        services.NewLocalStorage(cfg.StoragePath),
 	}
 }
 
@@ -68,8 +92,15 @@ func (h *SharingHandler) ShareFileWithUser(c *gin.Context) {
 
 	// Set default permission
 	permission := models.PermissionView
-	if req.Permission == "download" {
+	switch req.Permission {
+	case "", "view":
+	case "download":
 		permission = models.PermissionDownload
+	case "edit":
+		permission = models.PermissionEdit
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permission must be one of view, download or edit"})
+		return
 	}
 
 	shareReq := services.ShareFileRequest{
@@ -87,6 +118,23 @@ func (h *SharingHandler) ShareFileWithUser(c *gin.Context) {
 		return
 	}
 
+	recordAudit(h.db, c, "file_share", "file", &fileID, map[string]interface{}{
+		"share_id":    fileShare.ID,
+		"shared_with": fileShare.SharedWith,
+		"permission":  fileShare.Permission,
+		"expires_at":  fileShare.ExpiresAt,
+	})
+	username, _ := c.Get("username")
+	h.notifications.Publish(fileShare.SharedWith, services.NotificationFileShared, gin.H{
+		"share_id":           fileShare.ID,
+		"file_id":            fileShare.FileID,
+		"shared_by":          fileShare.SharedBy,
+		"shared_by_username": username,
+		"permission":         fileShare.Permission,
+		"message":            fileShare.Message,
+		"expires_at":         fileShare.ExpiresAt,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "File shared successfully",
 		"share":   fileShare,
@@ -103,6 +151,24 @@ func (h *SharingHandler) CreateShareLink(c *gin.Context) {
 		return
 	}
 
+	h.createShareLink(c, services.CreateShareLinkRequest{FileID: fileID})
+}
+
+// CreateFolderShareLink creates a shareable link for a folder
+// POST /api/folders/:id/share-link
+func (h *SharingHandler) CreateFolderShareLink(c *gin.Context) {
+	folderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	h.createShareLink(c, services.CreateShareLinkRequest{FolderID: folderID})
+}
+
+// createShareLink completes shareReq, which names the file or folder to
+// share, from the request body and creates the link
+func (h *SharingHandler) createShareLink(c *gin.Context, shareReq services.CreateShareLinkRequest) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -116,10 +182,14 @@ func (h *SharingHandler) CreateShareLink(c *gin.Context) {
 	}
 
 	var req struct {
-		Password     string  `json:"password"`
-		MaxDownloads *int    `json:"max_downloads"`
-		ExpiresAt    *string `json:"expires_at"`
-		Permission   string  `json:"permission"`
+		Password         string  `json:"password"`
+		MaxDownloads     *int    `json:"max_downloads"`
+		ExpiresAt        *string `json:"expires_at"`
+		NeverExpires     bool    `json:"never_expires"`
+		Permission       string  `json:"permission"`
+		HTMLIndex        bool    `json:"html_index"`
+		BurnAfterReading bool    `json:"burn_after_reading"`
+		BurnDeletesFile  bool    `json:"burn_deletes_file"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -144,25 +214,46 @@ func (h *SharingHandler) CreateShareLink(c *gin.Context) {
 		permission = models.PermissionDownload
 	}
 
-	shareReq := services.CreateShareLinkRequest{
-		FileID:       fileID,
-		CreatedBy:    createdBy,
-		Password:     req.Password,
-		MaxDownloads: req.MaxDownloads,
-		ExpiresAt:    expiresAt,
-		Permission:   permission,
-	}
+	shareReq.CreatedBy = createdBy
+	shareReq.Password = req.Password
+	shareReq.MaxDownloads = req.MaxDownloads
+	shareReq.ExpiresAt = expiresAt
+	shareReq.NeverExpires = req.NeverExpires
+	shareReq.Permission = permission
+	shareReq.HTMLIndex = req.HTMLIndex
+	shareReq.BurnAfterReading = req.BurnAfterReading
+	shareReq.BurnDeletesFile = req.BurnDeletesFile
+	shareReq.IsAdmin = isAdminRequest(c)
 
 	shareLink, err := h.sharingService.CreateShareLink(shareReq)
 	if err != nil {
+		if errors.Is(err, services.ErrNeverExpiringLink) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	resourceType, resourceID := "file", shareLink.FileID
+	if shareLink.FolderID != nil {
+		resourceType, resourceID = "folder", shareLink.FolderID
+	}
+	recordAudit(h.db, c, "share_link_create", resourceType, resourceID, map[string]interface{}{
+		"share_link_id":      shareLink.ID,
+		"permission":         shareLink.Permission,
+		"expires_at":         shareLink.ExpiresAt,
+		"max_downloads":      shareLink.MaxDownloads,
+		"password_protected": shareLink.PasswordHash != "",
+		"burn_after_reading": shareLink.BurnAfterReading,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message":    "Share link created successfully",
-		"share_link": shareLink,
-		"url":        "/share/" + shareLink.ShareToken,
+		"message":       "Share link created successfully",
+		"share_link":    shareLink,
+		"url":           "/share/" + shareLink.ShareToken,
+		"expires_at":    shareLink.ExpiresAt,
+		"expiry_capped": expiresAt != nil && shareLink.ExpiresAt != nil && shareLink.ExpiresAt.Before(*expiresAt),
 	})
 }
 
@@ -192,6 +283,89 @@ func (h *SharingHandler) GetSharedFiles(c *gin.Context) {
 	})
 }
 
+// sharedWithMeFile is a file shared directly with the user, with what the
+// share allows
+type sharedWithMeFile struct {
+	ShareID    uuid.UUID              `json:"share_id"`
+	File       models.File            `json:"file"`
+	Permission models.SharePermission `json:"permission"`
+	Access     services.AccessLevel   `json:"access"`
+	ExpiresAt  *time.Time             `json:"expires_at,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	SharedBy   gin.H                  `json:"shared_by"`
+	SharedAt   time.Time              `json:"shared_at"`
+}
+
+// GetFilesSharedWithMe lists the files shared directly with the user whose
+// shares are active and unexpired, most recently shared first, each with its
+// permission and expiry. Files reached through shared folders are listed by
+// the folder instead.
+// GET /api/files/shared-with-me?page=&page_size=
+func (h *SharingHandler) GetFilesSharedWithMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.FileShare{}).
+		Joins("JOIN files ON files.id = file_shares.file_id AND files.is_deleted = false").
+		Where("file_shares.shared_with = ? AND file_shares.is_active = true", userID).
+		Where("file_shares.expires_at IS NULL OR file_shares.expires_at > ?", time.Now())
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count shared files"})
+		return
+	}
+
+	var shares []models.FileShare
+	if err := query.Preload("File").
+		Preload("SharedByUser", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username, email, first_name, last_name")
+		}).
+		Order("file_shares.updated_at DESC").Order("file_shares.id DESC").
+		Offset(paging.offset()).Limit(paging.pageSize).
+		Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get shared files"})
+		return
+	}
+
+	files := make([]sharedWithMeFile, 0, len(shares))
+	for _, share := range shares {
+		access := services.AccessRead
+		if share.Permission == models.PermissionEdit {
+			access = services.AccessWrite
+		}
+		files = append(files, sharedWithMeFile{
+			ShareID:    share.ID,
+			File:       share.File,
+			Permission: share.Permission,
+			Access:     access,
+			ExpiresAt:  share.ExpiresAt,
+			Message:    share.Message,
+			SharedBy: gin.H{
+				"id":         share.SharedByUser.ID,
+				"username":   share.SharedByUser.Username,
+				"email":      share.SharedByUser.Email,
+				"first_name": share.SharedByUser.FirstName,
+				"last_name":  share.SharedByUser.LastName,
+			},
+			SharedAt: share.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":      files,
+		"pagination": paging.envelope(total),
+	})
+}
+
 // GetFileShares returns all shares for a specific file
 // GET /api/files/:id/shares
 func (h *SharingHandler) GetFileShares(c *gin.Context) {
@@ -225,8 +399,9 @@ func (h *SharingHandler) GetFileShares(c *gin.Context) {
 	})
 }
 
-// GetShareLinks returns all share links for the current user's files
-// GET /api/share-links
+// GetShareLinks returns the current user's share links, filtered by status
+// and target type, sorted and paginated
+// GET /api/share-links?status=&type=&sort=&order=&page=&page_size=
 func (h *SharingHandler) GetShareLinks(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -240,7 +415,53 @@ func (h *SharingHandler) GetShareLinks(c *gin.Context) {
 		return
 	}
 
-	shareLinks, err := h.sharingService.GetShareLinks(userUUID)
+	filter := services.ShareLinkFilter{
+		TargetType: c.Query("type"),
+		SortBy:     c.DefaultQuery("sort", "created_at"),
+	}
+
+	switch status := c.Query("status"); status {
+	case "":
+	case "all":
+		filter.AllStatus = true
+	case string(models.ShareLinkActive), string(models.ShareLinkExpired), string(models.ShareLinkExhausted), string(models.ShareLinkRevoked):
+		filter.Status = models.ShareLinkStatus(status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of active, expired, exhausted, revoked or all"})
+		return
+	}
+
+	if filter.TargetType != "" && filter.TargetType != "file" && filter.TargetType != "folder" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be file or folder"})
+		return
+	}
+	if filter.SortBy != "created_at" && filter.SortBy != "expires_at" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be created_at or expires_at"})
+		return
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultShareLinkPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxShareLinkPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxShareLinkPageSize)})
+		return
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	shareLinks, total, err := h.sharingService.GetShareLinks(userUUID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -248,10 +469,32 @@ func (h *SharingHandler) GetShareLinks(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"share_links": shareLinks,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
 	})
 }
 
-// AccessSharedFile handles access to files via share links
+// rejectShareLink responds to a share link that can't be used: 404 when it
+// doesn't exist, 410 once expired or revoked, 403 when its download limit is
+// reached and 401 when the password is missing or wrong
+func rejectShareLink(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShareLinkExpired), errors.Is(err, services.ErrShareLinkRevoked):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShareLinkExhausted):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShareLinkPasswordRequired), errors.Is(err, services.ErrShareLinkInvalidPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Failed to use share link: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access share link"})
+	}
+}
+
+// AccessSharedFile handles access to files and folders via share links
 // GET /share/:token
 func (h *SharingHandler) AccessSharedFile(c *gin.Context) {
 	token := c.Param("token")
@@ -259,7 +502,19 @@ func (h *SharingHandler) AccessSharedFile(c *gin.Context) {
 
 	shareLink, err := h.sharingService.ValidateShareLink(token, password)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		rejectShareLink(c, err)
+		return
+	}
+
+	if shareLink.FolderID != nil {
+		h.accessSharedFolder(c, shareLink, password)
+		return
+	}
+
+	if rejectExpiredAccess(c, shareLink.File) {
+		return
+	}
+	if rejectQuarantined(c, shareLink.File) {
 		return
 	}
 
@@ -268,6 +523,11 @@ func (h *SharingHandler) AccessSharedFile(c *gin.Context) {
 	userAgent := c.GetHeader("User-Agent")
 	h.sharingService.RecordShareLinkAccess(shareLink, ipAddress, userAgent, "view")
 
+	if h.cfg.ShareLinkPreviews && c.Query("format") != "json" && wantsSharePreview(c) {
+		h.renderSharePreview(c, shareLink)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"file":       shareLink.File,
 		"permission": shareLink.Permission,
@@ -288,7 +548,25 @@ func (h *SharingHandler) DownloadSharedFile(c *gin.Context) {
 
 	shareLink, err := h.sharingService.ValidateShareLink(token, password)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		rejectShareLink(c, err)
+		return
+	}
+
+	if shareLink.FolderID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This link shares a folder, download its files individually"})
+		return
+	}
+
+	h.serveSharedFile(c, shareLink, shareLink.File)
+}
+
+// serveSharedFile sends file as a download through shareLink, counting it
+// against the link's download limit
+func (h *SharingHandler) serveSharedFile(c *gin.Context, shareLink *models.ShareLink, file *models.File) {
+	if rejectExpiredAccess(c, file) {
+		return
+	}
+	if rejectQuarantined(c, file) {
 		return
 	}
 
@@ -298,24 +576,102 @@ func (h *SharingHandler) DownloadSharedFile(c *gin.Context) {
 		return
 	}
 
-	// Record download
-	ipAddress := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-	h.sharingService.RecordShareLinkAccess(shareLink, ipAddress, userAgent, "download")
-
 	// Get file path from FileHash
-	if shareLink.File.FileHash == nil {
+	if file.FileHash == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "File not found"})
 		return
 	}
 
-	filePath := shareLink.File.FileHash.StoragePath
-	c.Header("Content-Disposition", "attachment; filename=\""+shareLink.File.OriginalFilename+"\"")
-	c.Header("Content-Type", shareLink.File.MimeType)
+	// Each request through a link with a download limit is counted, so it
+	// gets the whole file. Serving ranges would let a player reading in
+	// chunks use up the link on its first chunk.
+	if shareLinkLimited(shareLink) {
+		c.Request.Header.Del("Range")
+		c.Request.Header.Del("If-Range")
+	}
+
+	filePath, local := localBlobPath(h.storage, file.FileHash.Hash)
+	var blob io.ReadCloser
+	if local {
+		if _, err := os.Stat(filePath); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
+			return
+		}
+		if rejectCorruptRange(c, h.sharingService.VerifyServedRange(file.FileHash, filePath, c.GetHeader("Range"))) {
+			return
+		}
+	} else {
+		// Open remote content up front so a missing blob isn't counted
+		var err error
+		blob, err = h.storage.Get(file.FileHash.Hash)
+		if errors.Is(err, services.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found in storage"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
+			return
+		}
+		defer blob.Close()
+	}
+
+	if rejectCorruptBlob(c, h.db, h.cfg, h.storage, file.FileHash) {
+		return
+	}
+
+	// Count the download before serving it, so the limit holds. Range
+	// requests past the first byte continue a download already counted.
+	if startsDownload(c.GetHeader("Range")) {
+		var onCounted func(tx *gorm.DB) error
+		if shareLink.BurnDeletesFile {
+			// Trashed in the transaction that burns the link. The content
+			// stays on disk until the trash is purged, so the download below
+			// is still served.
+			onCounted = func(tx *gorm.DB) error {
+				return burnSharedFile(tx, file.ID, blocksSharedFileDeletion(h.cfg))
+			}
+		}
+
+		ipAddress := c.ClientIP()
+		userAgent := c.GetHeader("User-Agent")
+		if err := h.sharingService.RecordShareDownload(shareLink, file, ipAddress, userAgent, onCounted); err != nil {
+			rejectShareLink(c, err)
+			return
+		}
+		h.sharingService.RecordShareLinkAccess(shareLink, ipAddress, userAgent, "download")
+		h.notifications.Publish(shareLink.CreatedBy, services.NotificationShareLinkDownloaded, gin.H{
+			"share_link_id": shareLink.ID,
+			"file_id":       file.ID,
+			"filename":      file.OriginalFilename,
+		})
+		if shareLink.BurnDeletesFile {
+			recordAudit(h.db, c, "share_link_burn", "file", &file.ID, map[string]interface{}{
+				"share_link_id": shareLink.ID,
+			})
+		}
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+file.OriginalFilename+"\"")
+	c.Header("Content-Type", file.MimeType)
+	if !local {
+		streamBlob(c, blob, file.OriginalFilename, file.FileHash.Size)
+		return
+	}
 	c.File(filePath)
 }
 
-// RevokeFileShare revokes a file share
+// RedirectUserShareRevoke sends revocations of a direct file share at its
+// user share path to the route that revokes it
+// DELETE /api/shares/user/:id
+func (h *SharingHandler) RedirectUserShareRevoke(c *gin.Context) {
+	target := strings.Replace(c.Request.URL.EscapedPath(), "/shares/user/", "/shares/", 1)
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusPermanentRedirect, target)
+}
+
+// RevokeFileShare revokes a direct file share; only the user who shared the
+// file can revoke it
 // DELETE /api/shares/:id
 func (h *SharingHandler) RevokeFileShare(c *gin.Context) {
 	shareIDStr := c.Param("id")
@@ -342,6 +698,7 @@ func (h *SharingHandler) RevokeFileShare(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(h.db, c, "file_share_revoke", "share", &shareID, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "File share revoked successfully",
@@ -375,8 +732,225 @@ func (h *SharingHandler) RevokeShareLink(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	recordAudit(h.db, c, "share_link_revoke", "share_link", &linkID, nil)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Share link revoked successfully",
 	})
 }
+
+// ShareFolderWithUser shares a folder, including everything inside it, with another user by email
+// POST /api/folders/:id/share
+func (h *SharingHandler) ShareFolderWithUser(c *gin.Context) {
+	folderIDStr := c.Param("id")
+	folderID, err := uuid.Parse(folderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sharedBy, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Email      string  `json:"email" binding:"required,email"`
+		Message    string  `json:"message"`
+		ExpiresAt  *string `json:"expires_at"`
+		Permission string  `json:"permission"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Parse expiration date if provided
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil && *req.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiration date format"})
+			return
+		}
+		expiresAt = &parsed
+	}
+
+	// Set default permission
+	permission := models.PermissionView
+	switch req.Permission {
+	case "", "view":
+	case "download":
+		permission = models.PermissionDownload
+	case "edit":
+		permission = models.PermissionEdit
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permission must be one of view, download or edit"})
+		return
+	}
+
+	shareReq := services.ShareFolderRequest{
+		FolderID:   folderID,
+		SharedBy:   sharedBy,
+		Email:      req.Email,
+		Message:    req.Message,
+		ExpiresAt:  expiresAt,
+		Permission: permission,
+	}
+
+	folderShare, err := h.sharingService.ShareFolderWithUser(shareReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(h.db, c, "folder_share", "folder", &folderID, map[string]interface{}{
+		"share_id":    folderShare.ID,
+		"shared_with": folderShare.SharedWith,
+		"permission":  folderShare.Permission,
+		"expires_at":  folderShare.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Folder shared successfully",
+		"share":   folderShare,
+	})
+}
+
+// GetSharedFolders returns folders shared with the current user
+// GET /api/shared-folders
+func (h *SharingHandler) GetSharedFolders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	folderShares, err := h.sharingService.GetSharedFolders(userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shared_folders": folderShares,
+	})
+}
+
+// GetFolderShares returns all shares for a specific folder
+// GET /api/folders/:id/shares
+func (h *SharingHandler) GetFolderShares(c *gin.Context) {
+	folderIDStr := c.Param("id")
+	folderID, err := uuid.Parse(folderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ownerID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	folderShares, err := h.sharingService.GetFolderShares(folderID, ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shares": folderShares,
+	})
+}
+
+// RevokeFolderShare revokes a folder share
+// DELETE /api/folder-shares/:id
+func (h *SharingHandler) RevokeFolderShare(c *gin.Context) {
+	shareIDStr := c.Param("id")
+	shareID, err := uuid.Parse(shareIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ownerID, ok := userID.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = h.sharingService.RevokeFolderShare(shareID, ownerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordAudit(h.db, c, "folder_share_revoke", "folder_share", &shareID, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder share revoked successfully",
+	})
+}
+
+// burnSharedFile moves a file to trash within the transaction that used up
+// its burn-after-reading link, as deleting it would. A file the owner already
+// deleted is left alone, as is one kept from deletion by its other shares.
+func burnSharedFile(tx *gorm.DB, fileID uuid.UUID, block bool) error {
+	var file models.File
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND is_deleted = false", fileID).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	_, _, err = trashFile(tx, &file, block)
+	var shared *fileSharedError
+	if errors.As(err, &shared) {
+		// The link is still used up; only the file stays
+		log.Printf("Kept file %s after its burn-after-reading link was used: it has other active shares", file.ID)
+		return nil
+	}
+	return err
+}
+
+// shareLinkLimited reports whether a share link allows a limited number of
+// downloads
+func shareLinkLimited(shareLink *models.ShareLink) bool {
+	return shareLink.BurnAfterReading || shareLink.MaxDownloads != nil
+}
+
+// startsDownload reports whether a request with the given Range header reads
+// a file from its first byte, as a new download does, rather than resuming
+// one or reading a later part
+func startsDownload(rangeHeader string) bool {
+	rangeHeader = strings.TrimSpace(rangeHeader)
+	return rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/internal/testdb"
+)
+
+// downloadShared downloads through a share link with the given Range header
+func downloadShared(h *SharingHandler, token, rangeHeader string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/share/:token/download", h.DownloadSharedFile)
+
+	req := httptest.NewRequest(http.MethodGet, "/share/"+token+"/download", nil)
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// sharedTestFile uploads content for a new user and links to it
+func sharedTestFile(t *testing.T, h *FileHandler, content []byte, link models.ShareLink) (*SharingHandler, models.ShareLink) {
+	t.Helper()
+	owner := createTestUser(t, h.db, 10000)
+	fileID := decodeUpload(t, uploadAs(t, h, owner.ID, testUpload{"shared.bin", content})).Files[0].FileID
+
+	link.FileID = &fileID
+	link.CreatedBy = owner.ID
+	link.ShareToken = uuid.NewString()
+	link.Permission = models.PermissionDownload
+	link.IsActive = true
+	if err := h.db.Create(&link).Error; err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+	return NewSharingHandler(h.db, services.NewSharingService(h.db, h.cfg), h.cfg), link
+}
+
+func TestSharedDownloadCountsOnlyFromFirstByte(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	sharing, link := sharedTestFile(t, h, uniqueContent(1000), models.ShareLink{})
+
+	requests := []struct {
+		rangeHeader string
+		wantStatus  int
+		wantCount   int
+	}{
+		{"bytes=500-599", http.StatusPartialContent, 0},
+		{"bytes=0-99", http.StatusPartialContent, 1},
+		{"bytes=100-", http.StatusPartialContent, 1},
+		{"", http.StatusOK, 2},
+	}
+	for _, request := range requests {
+		recorder := downloadShared(sharing, link.ShareToken, request.rangeHeader)
+		if recorder.Code != request.wantStatus {
+			t.Fatalf("range %q: status = %d, want %d", request.rangeHeader, recorder.Code, request.wantStatus)
+		}
+		var stored models.ShareLink
+		if err := db.First(&stored, "id = ?", link.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.DownloadCount != request.wantCount {
+			t.Errorf("after range %q: download count = %d, want %d", request.rangeHeader, stored.DownloadCount, request.wantCount)
+		}
+	}
+}
+
+func TestBurnLinkServesWholeFileOnce(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	content := uniqueContent(1000)
+	sharing, link := sharedTestFile(t, h, content, models.ShareLink{BurnAfterReading: true, BurnDeletesFile: true})
+
+	// A player asking for a later chunk first still gets the whole file, as
+	// this is the only download the link allows
+	recorder := downloadShared(sharing, link.ShareToken, "bytes=500-599")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("first download status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), content) {
+		t.Errorf("first download returned %d bytes, want the whole %d byte file", recorder.Body.Len(), len(content))
+	}
+
+	var file models.File
+	if err := db.First(&file, "id = ?", *link.FileID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !file.IsDeleted {
+		t.Error("file was not moved to trash by its burn-after-reading link")
+	}
+
+	if recorder := downloadShared(sharing, link.ShareToken, ""); recorder.Code == http.StatusOK {
+		t.Errorf("second download status = %d, want it refused", recorder.Code)
+	}
+}
+
+func TestUserShareRevokeRedirects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &SharingHandler{}
+	router := gin.New()
+	router.DELETE("/api/v1/shares/user/:id", h.RedirectUserShareRevoke)
+
+	shareID := uuid.NewString()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/v1/shares/user/"+shareID, nil))
+
+	if recorder.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusPermanentRedirect)
+	}
+	if location := recorder.Header().Get("Location"); location != "/api/v1/shares/"+shareID {
+		t.Errorf("Location = %q, want %q", location, "/api/v1/shares/"+shareID)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/middleware"
+)
+
+type SlowRequestHandler struct {
+	log *middleware.SlowRequestLog
+}
+
+func NewSlowRequestHandler(log *middleware.SlowRequestLog) *SlowRequestHandler {
+	return &SlowRequestHandler{log: log}
+}
+
+// GetSlowRequests summarizes recent requests that exceeded the latency
+// threshold, grouped by endpoint (admin only)
+func (h *SlowRequestHandler) GetSlowRequests(c *gin.Context) {
+	recent := h.log.Recent()
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold_ms": h.log.Threshold().Milliseconds(),
+		"endpoints":    h.log.Summary(),
+		"recent":       recent,
+		"total":        len(recent),
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// Storage accounting
+//
+// Every change to a user's storage stats goes through accountFileAdded and
+// accountFileRemoved so the counters stay consistent:
+//
+//   - StorageUsed is logical usage: the full size of every live file the user
+//     owns, duplicates included. Quotas are enforced against it.
+//   - ActualStorageBytes is physical usage after deduplication: each distinct
+//     content among the user's live files counts once.
+//   - SavedBytes is always StorageUsed - ActualStorageBytes.
+//   - TotalUploadedBytes is a lifetime total and is never reduced.
+
+// ownerHoldsContent reports whether the owner has another live file with the
+// given content
+func ownerHoldsContent(tx *gorm.DB, ownerID, fileHashID, excludeFileID uuid.UUID) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.File{}).
+		Where("owner_id = ? AND file_hash_id = ? AND id <> ? AND is_deleted = ?", ownerID, fileHashID, excludeFileID, false).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check existing content: %v", err)
+	}
+	return count > 0, nil
+}
+
+// adjustStorageStats applies a logical and physical delta to a user's stats
+func adjustStorageStats(tx *gorm.DB, userID uuid.UUID, logical, physical int64) error {
+	updates := map[string]interface{}{
+		"storage_used":         gorm.Expr("storage_used + ?", logical),
+		"actual_storage_bytes": gorm.Expr("actual_storage_bytes + ?", physical),
+		"saved_bytes":          gorm.Expr("saved_bytes + ?", logical-physical),
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update user storage stats: %v", err)
+	}
+	return nil
+}
+
+// accountFileAdded charges a live file to its owner. It must run after the
+// file record has been created or restored, and returns the physical bytes
+// added to the owner's usage.
+func accountFileAdded(tx *gorm.DB, file *models.File) (int64, error) {
+	held, err := ownerHoldsContent(tx, file.OwnerID, file.FileHashID, file.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	physical := file.Size
+	if held {
+		physical = 0
+	}
+
+	if err := adjustStorageStats(tx, file.OwnerID, file.Size, physical); err != nil {
+		return 0, err
+	}
+	return physical, nil
+}
+
+// accountFileRemoved releases a file from its owner's usage. It must run after
+// the file has been marked deleted, and returns the physical bytes freed from
+// the owner's usage.
+func accountFileRemoved(tx *gorm.DB, file *models.File) (int64, error) {
+	held, err := ownerHoldsContent(tx, file.OwnerID, file.FileHashID, file.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	physical := file.Size
+	if held {
+		physical = 0
+	}
+
+	if err := adjustStorageStats(tx, file.OwnerID, -file.Size, -physical); err != nil {
+		return 0, err
+	}
+	return physical, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/testdb"
+)
+
+// checkStorage checks the user's logical and physical usage, and that saved
+// bytes are the difference between them
+func checkStorage(t *testing.T, db *gorm.DB, userID uuid.UUID, step string, used, actual int64) {
+	t.Helper()
+	user := loadUser(t, db, userID)
+	if user.StorageUsed != used || user.ActualStorageBytes != actual || user.SavedBytes != used-actual {
+		t.Errorf("after %s: storage used %d, actual %d, saved %d; want %d, %d, %d",
+			step, user.StorageUsed, user.ActualStorageBytes, user.SavedBytes, used, actual, used-actual)
+	}
+}
+
+func TestStorageAccountingConservedWithDuplicates(t *testing.T) {
+	db := testdb.Open(t)
+	h := newTestFileHandler(t, db)
+	user := createTestUser(t, db, 100000)
+	other := createTestUser(t, db, 100000)
+
+	const sharedSize, otherSize = 1000, 300
+	shared, distinct := uniqueContent(sharedSize), uniqueContent(otherSize)
+
+	// Another user holding the same content doesn't change this user's usage
+	decodeUpload(t, uploadAs(t, h, other.ID, testUpload{"theirs.bin", shared}))
+
+	files := decodeUpload(t, uploadAs(t, h, user.ID,
+		testUpload{"first.bin", shared},
+		testUpload{"second.bin", shared},
+		testUpload{"other.bin", distinct},
+	)).Files
+	checkStorage(t, db, user.ID, "upload", 2*sharedSize+otherSize, sharedSize+otherSize)
+
+	// The second copy still holds the content, so only logical usage drops
+	if recorder := deleteFileAs(h, user.ID, files[0].FileID); recorder.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+	checkStorage(t, db, user.ID, "deleting one copy", sharedSize+otherSize, sharedSize+otherSize)
+
+	if recorder := deleteFileAs(h, user.ID, files[1].FileID); recorder.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+	checkStorage(t, db, user.ID, "deleting both copies", otherSize, otherSize)
+
+	if status, _, err := h.restoreOwnedFile(files[0].FileID, user.ID); status != restoreStatusRestored {
+		t.Fatalf("restore status = %q: %v", status, err)
+	}
+	checkStorage(t, db, user.ID, "restoring one copy", sharedSize+otherSize, sharedSize+otherSize)
+
+	if status, _, err := h.restoreOwnedFile(files[1].FileID, user.ID); status != restoreStatusRestored {
+		t.Fatalf("restore status = %q: %v", status, err)
+	}
+	checkStorage(t, db, user.ID, "restoring both copies", 2*sharedSize+otherSize, sharedSize+otherSize)
+
+	for _, file := range files {
+		if recorder := deleteFileAs(h, user.ID, file.FileID); recorder.Code != http.StatusOK {
+			t.Fatalf("delete status = %d: %s", recorder.Code, recorder.Body.String())
+		}
+	}
+	checkStorage(t, db, user.ID, "deleting everything", 0, 0)
+	checkStorage(t, db, other.ID, "the other user's upload", sharedSize, sharedSize)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/services"
+)
+
+type StorageBackendHandler struct {
+	backends *services.StorageBackends
+}
+
+func NewStorageBackendHandler(backends *services.StorageBackends) *StorageBackendHandler {
+	return &StorageBackendHandler{backends: backends}
+}
+
+// ListStorageBackends probes each configured storage backend and reports its
+// reachability, capacity, blob counts and recent error rate (admin only)
+func (h *StorageBackendHandler) ListStorageBackends(c *gin.Context) {
+	backends, err := h.backends.Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage backend status"})
+		return
+	}
+
+	status := "healthy"
+	for _, backend := range backends {
+		if !backend.Reachable {
+			status = "degraded"
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   status,
+		"backends": backends,
+	})
+}
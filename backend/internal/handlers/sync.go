@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Change listings return defaultSyncChanges changes per request unless the
+// client asks for up to maxSyncChanges
+const (
+	defaultSyncChanges = 500
+	maxSyncChanges     = 1000
+)
+
+// syncSettleDelay holds back changes this recent. Timestamps are taken when
+// a row is written, not when its transaction commits, so a change made just
+// before another could become visible just after it; waiting until such
+// transactions have committed keeps a cursor from skipping past them.
+const syncSettleDelay = 5 * time.Second
+
+// syncChangesQuery lists a user's files and folders with the time each last
+// changed. Trashing a file and deleting a folder set deleted_at, moves and
+// renames set updated_at, so the later of the two orders every change.
+const syncChangesQuery = `
+	SELECT 'file' AS type, f.id, f.original_filename AS name, f.folder_id AS parent_id, '' AS path,
+		f.size, f.mime_type, COALESCE(fh.hash, '') AS hash, f.is_deleted AS deleted,
+		f.created_at, GREATEST(f.updated_at, f.deleted_at) AS changed_at
+	FROM files f
+	LEFT JOIN file_hashes fh ON fh.id = f.file_hash_id
+	WHERE f.owner_id = ?
+	UNION ALL
+	SELECT 'folder', fo.id, fo.name, fo.parent_id, fo.path,
+		0, '', '', fo.deleted_at IS NOT NULL,
+		fo.created_at, GREATEST(fo.updated_at, fo.deleted_at)
+	FROM folders fo
+	WHERE fo.owner_id = ?`
+
+type SyncHandler struct {
+	db *gorm.DB
+}
+
+func NewSyncHandler(db *gorm.DB) *SyncHandler {
+	return &SyncHandler{db: db}
+}
+
+// syncCursor is the position of the last change a client has seen: changes
+// are ordered by time, then type and ID to break ties
+type syncCursor struct {
+	changedAt time.Time
+	typ       string
+	id        uuid.UUID
+}
+
+// String encodes the cursor as an opaque token
+func (c syncCursor) String() string {
+	raw := c.changedAt.UTC().Format(time.RFC3339Nano) + "," + c.typ + "," + c.id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseSyncCursor decodes a cursor returned by an earlier request
+func parseSyncCursor(token string) (syncCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return syncCursor{}, err
+	}
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != 3 || (parts[1] != "file" && parts[1] != "folder") {
+		return syncCursor{}, fmt.Errorf("malformed cursor")
+	}
+	changedAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return syncCursor{}, err
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return syncCursor{}, err
+	}
+	return syncCursor{changedAt: changedAt, typ: parts[1], id: id}, nil
+}
+
+// syncChange is a file or folder that changed since the client's cursor
+type syncChange struct {
+	Type      string     `json:"type"`   // file or folder
+	Action    string     `json:"action"` // created, updated or deleted
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"` // containing folder, empty at the root
+	Path      string     `json:"path,omitempty"`      // folders only
+	Size      int64      `json:"size,omitempty"`      // files only
+	MimeType  string     `json:"mime_type,omitempty"` // files only
+	Hash      string     `json:"hash,omitempty"`      // SHA-256 of a file's content
+	Deleted   bool       `json:"-"`
+	CreatedAt time.Time  `json:"-"`
+	ChangedAt time.Time  `json:"changed_at"`
+}
+
+// GetChanges lists the files and folders created, updated, moved or deleted
+// since a cursor, oldest first, with the cursor to pass next time. Without
+// a cursor every current file and folder is listed as created, which is a
+// client's initial sync. Moves and renames come through as updates with the
+// new parent and name; trashed files and deleted folders as deletions, and a
+// file restored from trash as an update. Files purged from trash drop out
+// without another event, having been reported deleted when they were
+// trashed.
+// GET /api/sync/changes?since=<cursor>&limit=<n>
+func (h *SyncHandler) GetChanges(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userUUID := userID.(uuid.UUID)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSyncChanges)))
+	if err != nil || limit < 1 || limit > maxSyncChanges {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxSyncChanges)})
+		return
+	}
+
+	var since *syncCursor
+	if token := c.Query("since"); token != "" {
+		cursor, err := parseSyncCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		since = &cursor
+	}
+
+	query := "SELECT * FROM (" + syncChangesQuery + ") changes WHERE changed_at < ?"
+	args := []interface{}{userUUID, userUUID, time.Now().Add(-syncSettleDelay)}
+	if since != nil {
+		query += " AND (changed_at, type, id) > (?, ?, ?)"
+		args = append(args, since.changedAt, since.typ, since.id)
+	} else {
+		// Nothing to delete on a client that hasn't synced yet
+		query += " AND NOT deleted"
+	}
+	query += " ORDER BY changed_at, type, id LIMIT ?"
+	args = append(args, limit+1)
+
+	changes := []syncChange{}
+	if err := h.db.Raw(query, args...).Scan(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list changes"})
+		return
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+	for i := range changes {
+		change := &changes[i]
+		switch {
+		case change.Deleted:
+			change.Action = "deleted"
+		case since == nil || change.CreatedAt.After(since.changedAt):
+			change.Action = "created"
+		default:
+			change.Action = "updated"
+		}
+	}
+
+	// With nothing new the client keeps its cursor
+	cursor := c.Query("since")
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		cursor = syncCursor{changedAt: last.ChangedAt, typ: last.Type, id: last.ID}.String()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":  changes,
+		"cursor":   cursor,
+		"has_more": hasMore,
+	})
+}
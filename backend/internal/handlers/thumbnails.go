@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/services"
+)
+
+type ThumbnailHandler struct {
+	thumbnails *services.ThumbnailService
+}
+
+func NewThumbnailHandler(thumbnails *services.ThumbnailService) *ThumbnailHandler {
+	return &ThumbnailHandler{thumbnails: thumbnails}
+}
+
+// GetQueueStats reports the thumbnail worker pool's queue depth and
+// throughput (admin only)
+func (h *ThumbnailHandler) GetQueueStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"available": h.thumbnails.Available(),
+		"queue":     h.thumbnails.QueueStats(),
+	})
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// maxRestoreBatch bounds how many files a single restore request may name
+const maxRestoreBatch = 500
+
+// Per-file restore outcomes
+const (
+	restoreStatusRestored       = "restored"
+	restoreStatusNotFound       = "not_found"
+	restoreStatusNotTrashed     = "not_trashed"
+	restoreStatusContentMissing = "content_missing"
+	restoreStatusQuotaExceeded  = "quota_exceeded"
+	restoreStatusFailed         = "failed"
+)
+
+var (
+	errNotTrashed         = errors.New("file is not in trash")
+	errRestoreContentGone = errors.New("file content is no longer stored")
+	errRestoreOverQuota   = errors.New("restoring the file would exceed the storage quota")
+)
+
+// restoreFile brings a trashed file back within a transaction, taking a new
+// reference on its content and charging it to the owner's storage. Files
+// whose folder has since been deleted are restored to the root.
+func (h *FileHandler) restoreFile(tx *gorm.DB, file *models.File) error {
+	if !file.IsDeleted {
+		return errNotTrashed
+	}
+
+	// The content may have been purged or lost while the file was in trash
+	var fileHash models.FileHash
+	if err := tx.Where("id = ?", file.FileHashID).First(&fileHash).Error; err == gorm.ErrRecordNotFound {
+		return errRestoreContentGone
+	} else if err != nil {
+		return fmt.Errorf("failed to find file hash: %v", err)
+	}
+	if exists, err := blobExists(h.storage, fileHash.Hash); err != nil {
+		return fmt.Errorf("failed to check stored content: %v", err)
+	} else if !exists {
+		return errRestoreContentGone
+	}
+
+	var user models.User
+	if err := tx.Select("storage_used, storage_quota").Where("id = ?", file.OwnerID).First(&user).Error; err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	if user.StorageUsed+file.Size > user.StorageQuota {
+		return errRestoreOverQuota
+	}
+
+	updates := map[string]interface{}{
+		"is_deleted": false,
+		"deleted_at": nil,
+	}
+	if file.FolderID != nil {
+		var folder models.Folder
+		if err := tx.Select("id").Where("id = ?", *file.FolderID).First(&folder).Error; err == gorm.ErrRecordNotFound {
+			updates["folder_id"] = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to check folder: %v", err)
+		}
+	}
+
+	if err := tx.Model(file).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to restore file: %v", err)
+	}
+	file.IsDeleted = false
+	file.DeletedAt = nil
+	if _, cleared := updates["folder_id"]; cleared {
+		file.FolderID = nil
+	}
+
+	if err := tx.Model(&fileHash).Update("reference_count", gorm.Expr("reference_count + 1")).Error; err != nil {
+		return fmt.Errorf("failed to update reference count: %v", err)
+	}
+
+	_, err := accountFileAdded(tx, file)
+	return err
+}
+
+// RestoreTrashedFiles restores the given trashed files, reporting the outcome
+// for each ID. Each file is restored independently so one failure doesn't
+// block the rest.
+func (h *FileHandler) RestoreTrashedFiles(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		FileIDs []string `json:"file_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if len(req.FileIDs) > maxRestoreBatch {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("At most %d files can be restored per request", maxRestoreBatch),
+			"max_files": maxRestoreBatch,
+		})
+		return
+	}
+
+	results := make([]gin.H, 0, len(req.FileIDs))
+	restoredCount := 0
+	seen := make(map[uuid.UUID]bool, len(req.FileIDs))
+
+	for _, rawID := range req.FileIDs {
+		fileID, err := uuid.Parse(rawID)
+		if err != nil {
+			results = append(results, gin.H{"file_id": rawID, "status": restoreStatusNotFound})
+			continue
+		}
+		if seen[fileID] {
+			continue
+		}
+		seen[fileID] = true
+
+		status, file, err := h.restoreOwnedFile(fileID, userID.(uuid.UUID))
+		result := gin.H{"file_id": rawID, "status": status}
+		if status == restoreStatusRestored {
+			restoredCount++
+			result["file"] = file
+		} else if err != nil && status != restoreStatusNotFound {
+			result["error"] = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"restored_count": restoredCount,
+		"failed_count":   len(results) - restoredCount,
+		"results":        results,
+	})
+}
+
+// restoreOwnedFile restores one of the user's trashed files in its own
+// transaction and maps the outcome to a restore status
+func (h *FileHandler) restoreOwnedFile(fileID, userID uuid.UUID) (string, *models.File, error) {
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var file models.File
+	if err := tx.Where("id = ? AND owner_id = ?", fileID, userID).First(&file).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return restoreStatusNotFound, nil, err
+		}
+		return restoreStatusFailed, nil, err
+	}
+
+	if err := h.restoreFile(tx, &file); err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, errNotTrashed):
+			return restoreStatusNotTrashed, nil, err
+		case errors.Is(err, errRestoreContentGone):
+			return restoreStatusContentMissing, nil, err
+		case errors.Is(err, errRestoreOverQuota):
+			return restoreStatusQuotaExceeded, nil, err
+		default:
+			return restoreStatusFailed, nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return restoreStatusFailed, nil, fmt.Errorf("failed to commit restore: %v", err)
+	}
+
+	return restoreStatusRestored, &file, nil
+}
+
+// ListTrash lists the user's trashed files, most recently deleted first.
+// With a retention period set, each file says when it will be purged.
+// GET /api/files/trash?page=&page_size=
+func (h *FileHandler) ListTrash(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	paging, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	query := h.db.Model(&models.File{}).Where("owner_id = ? AND is_deleted = true", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count trashed files"})
+		return
+	}
+
+	var files []models.File
+	if err := query.Order("deleted_at DESC").Offset(paging.offset()).Limit(paging.pageSize).Find(&files).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get trashed files"})
+		return
+	}
+
+	entries := make([]gin.H, 0, len(files))
+	for i := range files {
+		entry := gin.H{"file": files[i]}
+		if h.cfg.TrashRetentionDays > 0 && files[i].DeletedAt != nil {
+			entry["purges_at"] = files[i].DeletedAt.AddDate(0, 0, h.cfg.TrashRetentionDays)
+		}
+		entries = append(entries, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":          entries,
+		"pagination":     paging.envelope(total),
+		"retention_days": h.cfg.TrashRetentionDays,
+	})
+}
+
+// RestoreTrashedFile restores one trashed file, charging it to the user's
+// storage again
+// POST /api/files/:id/restore
+func (h *FileHandler) RestoreTrashedFile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID format"})
+		return
+	}
+
+	status, file, err := h.restoreOwnedFile(fileID, userID.(uuid.UUID))
+	switch status {
+	case restoreStatusRestored:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "File restored successfully",
+			"file":    file,
+		})
+	case restoreStatusNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	case restoreStatusNotTrashed:
+		c.JSON(http.StatusConflict, gin.H{"error": "File is not in trash"})
+	case restoreStatusContentMissing:
+		c.JSON(http.StatusGone, gin.H{"error": "File content is no longer stored and can't be restored"})
+	case restoreStatusQuotaExceeded:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Restoring the file would exceed your storage quota"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore file", "details": err.Error()})
+	}
+}
+
+// PurgeFile permanently deletes a trashed file. Its content is removed from
+// storage too once no other file references it.
+// DELETE /api/files/:id/purge
+func (h *FileHandler) PurgeFile(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID format"})
+		return
+	}
+
+	var file models.File
+	if err := h.db.Where("id = ? AND owner_id = ?", fileID, userID).First(&file).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+	if !file.IsDeleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only files in trash can be purged, delete the file first"})
+		return
+	}
+
+	var orphaned []*models.FileHash
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		orphaned, err = services.PurgeFileRecord(tx, &file)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge file", "details": err.Error()})
+		return
+	}
+
+	for _, fileHash := range orphaned {
+		services.RemoveContent(h.storage, h.cfg.StoragePath, fileHash)
+	}
+	recordAudit(h.db, c, "file_purge", "file", &file.ID, map[string]interface{}{
+		"filename":        file.OriginalFilename,
+		"size":            file.Size,
+		"content_removed": len(orphaned) > 0,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "File permanently deleted",
+		"file_id":         file.ID,
+		"content_removed": len(orphaned) > 0,
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// Upload budget response headers
+const (
+	UploadBudgetRemainingHeader      = "X-Upload-Budget-Remaining"
+	UploadBudgetFilesRemainingHeader = "X-Upload-Budget-Files-Remaining"
+)
+
+// uploadBudget is a user's ingest allowance over the rolling window; a zero
+// limit means unlimited
+type uploadBudget struct {
+	maxBytes  int64
+	maxFiles  int
+	usedBytes int64
+	usedFiles int
+	resetsAt  time.Time
+}
+
+func (b *uploadBudget) remainingBytes() int64 {
+	if b.maxBytes <= 0 {
+		return -1
+	}
+	if remaining := b.maxBytes - b.usedBytes; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func (b *uploadBudget) remainingFiles() int {
+	if b.maxFiles <= 0 {
+		return -1
+	}
+	if remaining := b.maxFiles - b.usedFiles; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// allows reports whether an upload of the given size and file count fits
+func (b *uploadBudget) allows(size int64, files int) bool {
+	if b.maxBytes > 0 && b.usedBytes+size > b.maxBytes {
+		return false
+	}
+	if b.maxFiles > 0 && b.usedFiles+files > b.maxFiles {
+		return false
+	}
+	return true
+}
+
+// loadUploadBudget computes the user's upload budget from the files they
+// created within the rolling window. Deleted files still count so deleting
+// and re-uploading doesn't reset the budget.
+func (h *FileHandler) loadUploadBudget(user *models.User) (*uploadBudget, error) {
+	budget := &uploadBudget{
+		maxBytes: h.cfg.UploadBudgetBytes,
+		maxFiles: h.cfg.UploadBudgetFiles,
+	}
+	if user.Role == models.RoleAdmin {
+		budget.maxBytes = h.cfg.AdminUploadBudgetBytes
+		budget.maxFiles = h.cfg.AdminUploadBudgetFiles
+	}
+
+	if budget.maxBytes <= 0 && budget.maxFiles <= 0 {
+		return budget, nil
+	}
+
+	window := time.Duration(h.cfg.UploadBudgetWindow) * time.Second
+	since := time.Now().Add(-window)
+
+	var usage struct {
+		TotalBytes int64
+		TotalFiles int
+		Oldest     *time.Time
+	}
+	if err := h.db.Model(&models.File{}).
+		Select("COALESCE(SUM(size), 0) AS total_bytes, COUNT(*) AS total_files, MIN(created_at) AS oldest").
+		Where("owner_id = ? AND created_at > ?", user.ID, since).
+		Scan(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute upload usage: %w", err)
+	}
+
+	budget.usedBytes = usage.TotalBytes
+	budget.usedFiles = usage.TotalFiles
+	if usage.Oldest != nil {
+		budget.resetsAt = usage.Oldest.Add(window)
+	}
+
+	return budget, nil
+}
+
+// setUploadBudgetHeaders exposes the remaining budget after an upload of the
+// given size and file count; unlimited budgets are not advertised
+func setUploadBudgetHeaders(c *gin.Context, budget *uploadBudget, size int64, files int) {
+	if budget.maxBytes > 0 {
+		remaining := budget.maxBytes - budget.usedBytes - size
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Header(UploadBudgetRemainingHeader, strconv.FormatInt(remaining, 10))
+	}
+	if budget.maxFiles > 0 {
+		remaining := budget.maxFiles - budget.usedFiles - files
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Header(UploadBudgetFilesRemainingHeader, strconv.Itoa(remaining))
+	}
+}
+
+// rejectOverBudget writes the 429 response for an upload exceeding the budget
+func rejectOverBudget(c *gin.Context, budget *uploadBudget, size int64, files int) {
+	setUploadBudgetHeaders(c, budget, 0, 0)
+
+	retryAfter := int(time.Until(budget.resetsAt).Seconds()) + 1
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":                  "Upload budget exceeded, try again later",
+		"upload_size":            size,
+		"upload_files":           files,
+		"budget_bytes":           budget.maxBytes,
+		"budget_files":           budget.maxFiles,
+		"budget_remaining_bytes": budget.remainingBytes(),
+		"budget_remaining_files": budget.remainingFiles(),
+	})
+}
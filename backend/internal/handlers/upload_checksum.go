@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Checksums a client may claim for uploaded content. The headers cover a
+// single-file upload; multipart uploads of several files send one form field
+// value per file instead, in upload order.
+const (
+	contentMD5Header    = "Content-MD5"
+	contentSHA256Header = "X-Content-SHA256"
+	contentMD5Field     = "content_md5"
+	contentSHA256Field  = "content_sha256"
+)
+
+// uploadChecksum is what the client claims an uploaded file hashes to. Either
+// digest may be missing.
+type uploadChecksum struct {
+	md5    []byte
+	sha256 []byte
+}
+
+// decodeDigest accepts a digest as base64, the form Content-MD5 uses, or hex
+func decodeDigest(value string, size int) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if digest, err := hex.DecodeString(value); err == nil && len(digest) == size {
+		return digest, nil
+	}
+	if digest, err := base64.StdEncoding.DecodeString(value); err == nil && len(digest) == size {
+		return digest, nil
+	}
+	return nil, fmt.Errorf("expected a %d byte digest in base64 or hex", size)
+}
+
+// parseUploadChecksum decodes the claimed digests, either of which may be empty
+func parseUploadChecksum(md5Value, sha256Value string) (uploadChecksum, error) {
+	var checksum uploadChecksum
+	var err error
+	if md5Value != "" {
+		if checksum.md5, err = decodeDigest(md5Value, md5.Size); err != nil {
+			return checksum, fmt.Errorf("invalid MD5 checksum: %v", err)
+		}
+	}
+	if sha256Value != "" {
+		if checksum.sha256, err = decodeDigest(sha256Value, sha256.Size); err != nil {
+			return checksum, fmt.Errorf("invalid SHA-256 checksum: %v", err)
+		}
+	}
+	return checksum, nil
+}
+
+// uploadChecksums returns the claimed checksum of each uploaded file, in
+// upload order, from the request headers or the per-file form fields. Files
+// without a claim get an empty checksum. On a malformed or ambiguous claim it
+// responds with 400 and returns false.
+func uploadChecksums(c *gin.Context, form *multipart.Form, files []*multipart.FileHeader) ([]uploadChecksum, bool) {
+	checksums := make([]uploadChecksum, len(files))
+
+	md5Header := c.GetHeader(contentMD5Header)
+	sha256Header := c.GetHeader(contentSHA256Header)
+	if md5Header != "" || sha256Header != "" {
+		if len(files) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s and %s only apply to single-file uploads, send %s or %s per file instead",
+					contentMD5Header, contentSHA256Header, contentMD5Field, contentSHA256Field),
+			})
+			return nil, false
+		}
+		checksum, err := parseUploadChecksum(md5Header, sha256Header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		checksums[0] = checksum
+	}
+
+	md5Values := form.Value[contentMD5Field]
+	sha256Values := form.Value[contentSHA256Field]
+	for _, values := range [][]string{md5Values, sha256Values} {
+		if len(values) > 0 && len(values) != len(files) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Checksum fields must have one value per uploaded file, empty for files without one",
+				"file_count": len(files),
+			})
+			return nil, false
+		}
+	}
+	for i := range files {
+		var md5Value, sha256Value string
+		if len(md5Values) > 0 {
+			md5Value = md5Values[i]
+		}
+		if len(sha256Values) > 0 {
+			sha256Value = sha256Values[i]
+		}
+		if md5Value == "" && sha256Value == "" {
+			continue
+		}
+		checksum, err := parseUploadChecksum(md5Value, sha256Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":    fmt.Sprintf("%s for %s", err.Error(), files[i].Filename),
+				"filename": files[i].Filename,
+			})
+			return nil, false
+		}
+		if checksum.md5 != nil {
+			checksums[i].md5 = checksum.md5
+		}
+		if checksum.sha256 != nil {
+			checksums[i].sha256 = checksum.sha256
+		}
+	}
+
+	return checksums, true
+}
+
+// rejectChecksumMismatch responds with 400 when the spooled content doesn't
+// hash to what the client claimed, meaning it was corrupted on the way
+func rejectChecksumMismatch(c *gin.Context, filename string, spooled *spooledUpload, claimed uploadChecksum) bool {
+	algorithm, expected, actual := "", "", ""
+	if claimed.md5 != nil && !bytes.Equal(claimed.md5, spooled.md5) {
+		algorithm, expected, actual = "md5", hex.EncodeToString(claimed.md5), hex.EncodeToString(spooled.md5)
+	} else if claimed.sha256 != nil && hex.EncodeToString(claimed.sha256) != spooled.hash {
+		algorithm, expected, actual = "sha256", hex.EncodeToString(claimed.sha256), spooled.hash
+	}
+	if algorithm == "" {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":     fmt.Sprintf("Checksum mismatch for %s, the content was corrupted in transit", filename),
+		"code":      "checksum_mismatch",
+		"filename":  filename,
+		"algorithm": algorithm,
+		"expected":  expected,
+		"actual":    actual,
+	})
+	return true
+}
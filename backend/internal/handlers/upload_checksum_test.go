@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRejectChecksumMismatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	content := []byte("uploaded content")
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	spooled := &spooledUpload{hash: hex.EncodeToString(sha256Sum[:]), md5: md5Sum[:], size: int64(len(content))}
+
+	wrong := make([]byte, sha256.Size)
+	copy(wrong, sha256Sum[:])
+	wrong[0] ^= 0xFF
+
+	tests := []struct {
+		name          string
+		claimed       uploadChecksum
+		wantRejected  bool
+		wantAlgorithm string
+	}{
+		{"no checksum claimed", uploadChecksum{}, false, ""},
+		{"matching digests", uploadChecksum{md5: md5Sum[:], sha256: sha256Sum[:]}, false, ""},
+		{"wrong MD5", uploadChecksum{md5: wrong[:md5.Size]}, true, "md5"},
+		{"wrong SHA-256", uploadChecksum{sha256: wrong}, true, "sha256"},
+		{"right MD5, wrong SHA-256", uploadChecksum{md5: md5Sum[:], sha256: wrong}, true, "sha256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+
+			if got := rejectChecksumMismatch(c, "report.pdf", spooled, tt.claimed); got != tt.wantRejected {
+				t.Fatalf("rejectChecksumMismatch() = %v, want %v", got, tt.wantRejected)
+			}
+			if !tt.wantRejected {
+				return
+			}
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			var body struct {
+				Code      string `json:"code"`
+				Algorithm string `json:"algorithm"`
+				Actual    string `json:"actual"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body.Code != "checksum_mismatch" || body.Algorithm != tt.wantAlgorithm {
+				t.Errorf("response = %+v, want code checksum_mismatch and algorithm %s", body, tt.wantAlgorithm)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/middleware"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// rejectionRecorder stands in for the response writer while one file of a
+// partial upload is checked, so the rejection a check writes can be reported
+// for that file instead of ending the request
+type rejectionRecorder struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *rejectionRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *rejectionRecorder) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *rejectionRecorder) WriteHeaderNow() {}
+
+func (w *rejectionRecorder) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *rejectionRecorder) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *rejectionRecorder) Status() int {
+	return w.status
+}
+
+func (w *rejectionRecorder) Size() int {
+	return w.body.Len()
+}
+
+func (w *rejectionRecorder) Written() bool {
+	return w.status != 0
+}
+
+// captureRejection runs check with the response redirected and returns the
+// body it responded with, plus its status, or nil when it wrote nothing
+func captureRejection(c *gin.Context, check func()) gin.H {
+	recorder := &rejectionRecorder{ResponseWriter: c.Writer, header: http.Header{}}
+	c.Writer = recorder
+	check()
+	c.Writer = recorder.ResponseWriter
+
+	if recorder.status == 0 {
+		return nil
+	}
+	rejection := gin.H{}
+	if err := json.Unmarshal(recorder.body.Bytes(), &rejection); err != nil {
+		rejection = gin.H{"error": recorder.body.String()}
+	}
+	rejection["status"] = recorder.status
+	return rejection
+}
+
+// uploadFilesPartially stores each file of a multi-file upload in its own
+// savepoint. Files that fail a check or can't be stored are reported with
+// the error they would have failed the whole upload with, and the rest are
+// kept. Quota and upload budget are charged file by file, in upload order.
+func (h *FileHandler) uploadFilesPartially(c *gin.Context, validator *utils.MimeTypeValidator, user *models.User, folderID *uuid.UUID, allFiles []*multipart.FileHeader, checksums []uploadChecksum, tempPaths *[]string) {
+	// The request transaction commits once a successful response is written,
+	// holding the user's row so concurrent uploads are charged in turn
+	tx := middleware.Tx(c)
+	if err := lockUserForQuota(tx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	budget, err := h.loadUploadBudget(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload budget"})
+		return
+	}
+
+	apiKeyID := apiKeyIDFromContext(c)
+
+	var results []map[string]interface{}
+	var uploaded []FileUploadInfo
+	var orphaned []*models.FileHash
+	outcomes := make([]gin.H, 0, len(allFiles))
+	var totalSavedBytes, totalUploadedBytes int64
+
+	for i, fileHeader := range allFiles {
+		outcome := gin.H{"index": i, "filename": fileHeader.Filename}
+
+		var uploadFile FileUploadInfo
+		rejection := captureRejection(c, func() {
+			var tempPath string
+			var ok bool
+			uploadFile, tempPath, ok = h.validateUploadFile(c, validator, fileHeader, checksums[i])
+			if tempPath != "" {
+				*tempPaths = append(*tempPaths, tempPath)
+			}
+			if !ok {
+				return
+			}
+
+			// Budget and quota count the files kept so far
+			remaining := *budget
+			remaining.usedBytes += totalUploadedBytes
+			remaining.usedFiles += len(uploaded)
+			if !remaining.allows(uploadFile.Size, 1) {
+				rejectOverBudget(c, &remaining, uploadFile.Size, 1)
+				return
+			}
+			charged := *user
+			charged.StorageUsed += totalUploadedBytes
+			quota := h.checkQuota(&charged, uploadFile.Size)
+			if !quota.allowed {
+				rejectOverQuota(c, &charged, uploadFile.Size, quota)
+				return
+			}
+
+			var result map[string]interface{}
+			var savedBytes int64
+			var pruned []*models.FileHash
+			err := tx.Transaction(func(sub *gorm.DB) error {
+				if quota.usesGrace {
+					if err := h.markQuotaGraceUsed(sub, user.ID); err != nil {
+						return err
+					}
+				}
+				var err error
+				result, savedBytes, pruned, err = h.processFileUpload(sub, uploadFile, user.ID, folderID, apiKeyID)
+				if err != nil {
+					return err
+				}
+				h.recordUploadProvenance(sub, c, uploadFile, apiKeyID, result)
+				return nil
+			})
+			if errors.Is(err, errQuotaGraceUsed) {
+				quota.graceAvailable = false
+				rejectOverQuota(c, &charged, uploadFile.Size, quota)
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":    "Failed to process file upload",
+					"filename": fileHeader.Filename,
+					"details":  err.Error(),
+				})
+				return
+			}
+			if quota.usesGrace {
+				// Grace is spent for the rest of the batch
+				now := time.Now()
+				user.QuotaGraceUsedAt = &now
+			}
+
+			results = append(results, result)
+			uploaded = append(uploaded, uploadFile)
+			orphaned = append(orphaned, pruned...)
+			totalSavedBytes += savedBytes
+			totalUploadedBytes += uploadFile.Size
+			outcome["file"] = result
+		})
+
+		if rejection != nil {
+			for key, value := range rejection {
+				if key != "filename" {
+					outcome[key] = value
+				}
+			}
+			outcome["success"] = false
+		} else {
+			outcome["success"] = true
+			if uploadFile.Warning != "" {
+				outcome["warning"] = uploadFile.Warning
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	if totalUploadedBytes > 0 {
+		if err := h.updateUserStorageStats(tx, user.ID, totalUploadedBytes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+			return
+		}
+	}
+
+	setUploadBudgetHeaders(c, budget, totalUploadedBytes, len(results))
+
+	if len(results) > 0 {
+		middleware.AfterCommit(c, func() {
+			h.notifyUploadCompleted(user.ID, results)
+			h.removeContent(orphaned)
+		})
+	}
+
+	// Queue thumbnails once the content is committed
+	for _, uploadFile := range uploaded {
+		if h.thumbnails.Supports(uploadFile.MimeType) && uploadFile.MalwareSignature == "" {
+			hash, mimeType := uploadFile.Hash, uploadFile.MimeType
+			middleware.AfterCommit(c, func() {
+				h.thumbnails.Enqueue(hash, mimeType)
+			})
+		}
+	}
+
+	failed := len(allFiles) - len(uploaded)
+	status, message := http.StatusOK, "Files uploaded successfully"
+	switch {
+	case len(uploaded) == 0:
+		status, message = http.StatusBadRequest, "None of the files could be uploaded"
+	case failed > 0:
+		status, message = http.StatusMultiStatus, fmt.Sprintf("%d of %d files uploaded", len(uploaded), len(allFiles))
+	}
+
+	if results == nil {
+		results = []map[string]interface{}{}
+	}
+	c.JSON(status, gin.H{
+		"message":              message,
+		"uploaded_files_count": len(uploaded),
+		"failed_files_count":   failed,
+		"total_size":           totalUploadedBytes,
+		"total_saved_bytes":    totalSavedBytes,
+		"files":                results,
+		"results":              outcomes,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// progressKeepAlive is how often an idle progress stream sends a comment so
+// proxies don't close it
+const progressKeepAlive = 15 * time.Second
+
+// progressReader reports how many bytes have been read, at most once per
+// interval
+type progressReader struct {
+	reader   io.Reader
+	read     int64
+	interval time.Duration
+	last     time.Time
+	report   func(read int64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	p.read += int64(n)
+	if n > 0 && time.Since(p.last) >= p.interval {
+		p.last = time.Now()
+		p.report(p.read)
+	}
+	return n, err
+}
+
+// trackChunkProgress publishes progress while a chunk for session streams in.
+// The session itself is left untouched; the offset is only committed once the
+// chunk is stored.
+func (h *FileHandler) trackChunkProgress(body io.Reader, session *models.UploadSession) io.Reader {
+	base := *session
+	return &progressReader{
+		reader:   body,
+		interval: time.Duration(h.cfg.UploadProgressRate) * time.Millisecond,
+		last:     time.Now(),
+		report: func(read int64) {
+			current := base
+			current.BytesReceived = base.BytesReceived + read
+			h.progress.Publish(uploadProgressEvent(&current))
+		},
+	}
+}
+
+// StreamUploadProgress pushes progress for an upload session as Server-Sent
+// Events until it completes, is aborted, expires, or the client goes away
+func (h *FileHandler) StreamUploadProgress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if !h.cfg.UploadProgressEvents {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload progress events are disabled"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	// Subscribe before reading the current state so nothing in between is lost
+	events, unsubscribe := h.progress.Subscribe(session.ID)
+	defer unsubscribe()
+
+	current := uploadProgressEvent(session)
+	if session.Status == models.UploadSessionPending && time.Now().After(session.ExpiresAt) {
+		current.State = "expired"
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("progress", current)
+	c.Writer.Flush()
+	if current.Final() {
+		return
+	}
+
+	keepAlive := time.NewTicker(progressKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			current = event
+			c.SSEvent("progress", event)
+			return !event.Final()
+		case now := <-keepAlive.C:
+			if now.After(session.ExpiresAt) {
+				current.State = "expired"
+				c.SSEvent("progress", current)
+				return false
+			}
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+)
+
+// uploadAuditAction marks audit log entries recording who uploaded a file
+// and from where
+const uploadAuditAction = "file_upload"
+
+// uploadProvenance is one recorded upload of a file
+type uploadProvenance struct {
+	UserID     *uuid.UUID      `json:"user_id,omitempty"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	UploadedAt time.Time       `json:"uploaded_at"`
+}
+
+// recordUploadProvenance writes the client behind an upload to the audit log
+// when capture is turned on. It runs in the upload's transaction so the entry
+// only exists for files that were stored.
+func (h *FileHandler) recordUploadProvenance(tx *gorm.DB, c *gin.Context, uploadFile FileUploadInfo, apiKeyID *uuid.UUID, result map[string]interface{}) {
+	if !h.cfg.UploadClientMetadata {
+		return
+	}
+	fileID, ok := result["file_id"].(uuid.UUID)
+	if !ok {
+		return
+	}
+
+	values := map[string]interface{}{
+		"filename":           uploadFile.Header.Filename,
+		"size":               uploadFile.Size,
+		"content_hash":       uploadFile.Hash,
+		"declared_mime_type": uploadFile.DeclaredMimeType,
+		"detected_mime_type": uploadFile.MimeType,
+	}
+	if apiKeyID != nil {
+		values["api_key_id"] = apiKeyID
+	}
+	recordAudit(tx, c, uploadAuditAction, "file", &fileID, values)
+}
+
+// GetFileProvenance returns the client metadata recorded when a file was
+// uploaded: IP address, user agent, API key and declared vs detected MIME
+// type. Only the owner and admins can see it, and only uploads made while
+// capture was on have any.
+// GET /api/files/:id/provenance
+func (h *FileHandler) GetFileProvenance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var file *models.File
+	if isAdminRequest(c) {
+		file = &models.File{}
+		err = h.db.Where("id = ?", fileID).First(file).Error
+	} else {
+		file, err = services.FindFileWithAccess(h.db, fileID, userID.(uuid.UUID), services.AccessOwner)
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get file"})
+		return
+	}
+
+	var entries []struct {
+		UserID    *uuid.UUID
+		IPAddress string
+		UserAgent string
+		NewValues string
+		CreatedAt time.Time
+	}
+	if err := h.db.Model(&models.AuditLog{}).
+		Select("user_id, host(ip_address) AS ip_address, user_agent, COALESCE(new_values::text, '') AS new_values, created_at").
+		Where("action = ? AND resource_type = ? AND resource_id = ?", uploadAuditAction, "file", file.ID).
+		Order("created_at ASC").Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload metadata"})
+		return
+	}
+
+	uploads := make([]uploadProvenance, 0, len(entries))
+	for _, entry := range entries {
+		upload := uploadProvenance{
+			UserID:     entry.UserID,
+			IPAddress:  entry.IPAddress,
+			UserAgent:  entry.UserAgent,
+			UploadedAt: entry.CreatedAt,
+		}
+		if entry.NewValues != "" {
+			upload.Details = json.RawMessage(entry.NewValues)
+		}
+		uploads = append(uploads, upload)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file_id":         file.ID,
+		"capture_enabled": h.cfg.UploadClientMetadata,
+		"retention_days":  h.cfg.AuditRetentionDays,
+		"uploads":         uploads,
+	})
+}
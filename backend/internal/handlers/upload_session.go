@@ -0,0 +1,754 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/services"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// UploadOffsetHeader carries the byte offset a streamed chunk starts at
+const UploadOffsetHeader = "Upload-Offset"
+
+// uploadSessionLocks serializes chunk writes per upload session
+var uploadSessionLocks sync.Map
+
+func lockUploadSession(id uuid.UUID) func() {
+	value, _ := uploadSessionLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// uploadProgressEvent describes where a session's upload stands
+func uploadProgressEvent(session *models.UploadSession) services.UploadProgressEvent {
+	percent := float64(100)
+	if session.TotalSize > 0 {
+		percent = float64(session.BytesReceived) / float64(session.TotalSize) * 100
+	}
+
+	state := "uploading"
+	if session.Status != models.UploadSessionPending {
+		state = string(session.Status)
+	} else if session.BytesReceived == session.TotalSize {
+		state = "received"
+	}
+
+	return services.UploadProgressEvent{
+		UploadID:      session.ID,
+		BytesReceived: session.BytesReceived,
+		TotalSize:     session.TotalSize,
+		Percent:       percent,
+		State:         state,
+	}
+}
+
+// uploadProgress builds the progress acknowledgement for a session
+func uploadProgress(session *models.UploadSession, runningHash string) gin.H {
+	event := uploadProgressEvent(session)
+
+	progress := gin.H{
+		"upload_id":      event.UploadID,
+		"bytes_received": event.BytesReceived,
+		"total_size":     event.TotalSize,
+		"percent":        event.Percent,
+		"state":          event.State,
+	}
+	if session.ChunkSize > 0 {
+		progress["chunk_size"] = session.ChunkSize
+		progress["chunks_received"] = (session.BytesReceived + session.ChunkSize - 1) / session.ChunkSize
+		progress["total_chunks"] = (session.TotalSize + session.ChunkSize - 1) / session.ChunkSize
+	}
+	if runningHash != "" {
+		progress["running_hash"] = runningHash
+	}
+	return progress
+}
+
+// InitUpload starts a streaming upload session
+func (h *FileHandler) InitUpload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Filename string     `json:"filename" binding:"required"`
+		Size     int64      `json:"size"`
+		MimeType string     `json:"mime_type"`
+		SHA256   string     `json:"sha256"`
+		FolderID *uuid.UUID `json:"folder_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	if req.Size < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Size must not be negative"})
+		return
+	}
+
+	if req.Size > h.cfg.MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("File %s exceeds size limit", req.Filename),
+			"max_size":  h.cfg.MaxFileSize,
+			"file_size": req.Size,
+		})
+		return
+	}
+
+	declaredHash := strings.ToLower(strings.TrimSpace(req.SHA256))
+	if declaredHash != "" && !contentHashPattern.MatchString(declaredHash) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sha256 must be a hex-encoded SHA-256 digest"})
+		return
+	}
+
+	// Verify folder exists and user owns it or has edit access through a share
+	if req.FolderID != nil {
+		var folder models.Folder
+		if err := h.db.Where("id = ?", req.FolderID).First(&folder).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify folder"})
+			return
+		}
+		level, err := services.FolderAccessLevel(h.db, &folder, userID.(uuid.UUID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify folder"})
+			return
+		}
+		if !level.Allows(services.AccessWrite) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target folder not found"})
+			return
+		}
+	}
+
+	// Check quota and upload budget up front so clients don't stream for nothing
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	budget, err := h.loadUploadBudget(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check upload budget"})
+		return
+	}
+	if !budget.allows(req.Size, 1) {
+		rejectOverBudget(c, budget, req.Size, 1)
+		return
+	}
+
+	quota := h.checkQuota(&user, req.Size)
+	if !quota.allowed {
+		rejectOverQuota(c, &user, req.Size, quota)
+		return
+	}
+
+	hashState, err := utils.NewProgressHasher(nil).State()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize upload"})
+		return
+	}
+
+	sessionID := uuid.New()
+	tempPath := fmt.Sprintf("tmp/uploads/%s.part", sessionID)
+	fullTempPath := filepath.Join(h.cfg.StoragePath, tempPath)
+	if err := os.MkdirAll(filepath.Dir(fullTempPath), 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+		return
+	}
+	tempFile, err := os.OpenFile(fullTempPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload file"})
+		return
+	}
+	tempFile.Close()
+
+	session := models.UploadSession{
+		BaseModel: models.BaseModel{
+			ID: sessionID,
+		},
+		UserID:       userID.(uuid.UUID),
+		FolderID:     req.FolderID,
+		APIKeyID:     apiKeyIDFromContext(c),
+		Filename:     req.Filename,
+		MimeType:     req.MimeType,
+		TotalSize:    req.Size,
+		ChunkSize:    h.cfg.UploadChunkSize,
+		DeclaredHash: declaredHash,
+		HashState:    hashState,
+		TempPath:     tempPath,
+		Status:       models.UploadSessionPending,
+		ExpiresAt:    time.Now().Add(time.Duration(h.cfg.UploadSessionTTL) * time.Hour),
+	}
+
+	if err := h.db.Create(&session).Error; err != nil {
+		os.Remove(fullTempPath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload session"})
+		return
+	}
+
+	response := gin.H{
+		"message":   "Upload session created",
+		"upload_id": session.ID,
+		"session":   session,
+	}
+	if session.ChunkSize > 0 {
+		response["chunk_size"] = session.ChunkSize
+		response["total_chunks"] = (session.TotalSize + session.ChunkSize - 1) / session.ChunkSize
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetUploadStatus returns the progress of an upload session so interrupted
+// clients know where to resume
+func (h *FileHandler) GetUploadStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	c.Header(UploadOffsetHeader, strconv.FormatInt(session.BytesReceived, 10))
+	c.JSON(http.StatusOK, uploadProgress(session, ""))
+}
+
+// UploadChunk appends the request body to an upload session at the offset
+// given by the Upload-Offset header, which must match the bytes received
+func (h *FileHandler) UploadChunk(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	unlock := lockUploadSession(session.ID)
+	defer unlock()
+
+	// Reload under the lock in case a concurrent chunk moved the offset
+	if err := h.db.First(session, "id = ?", session.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload session"})
+		return
+	}
+
+	if !h.checkUploadSessionOpen(c, session) {
+		return
+	}
+
+	offsetStr := c.GetHeader(UploadOffsetHeader)
+	if offsetStr == "" {
+		offsetStr = c.Query("offset")
+	}
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset != session.BytesReceived {
+		c.Header(UploadOffsetHeader, strconv.FormatInt(session.BytesReceived, 10))
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Upload offset does not match bytes received",
+			"bytes_received": session.BytesReceived,
+		})
+		return
+	}
+
+	h.appendUploadChunk(c, session, session.TotalSize-session.BytesReceived, false)
+}
+
+// UploadIndexedChunk receives chunk number index of an upload session, each
+// chunk being the session's chunk size except the last. Chunks are hashed in
+// order, so only the next chunk is accepted; resending one already received
+// is acknowledged without rewriting it, letting clients retry blindly.
+// PUT /api/files/upload/:uploadId/chunk/:index
+func (h *FileHandler) UploadIndexedChunk(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	index, err := strconv.ParseInt(c.Param("index"), 10, 64)
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chunk index must be a non-negative number"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	unlock := lockUploadSession(session.ID)
+	defer unlock()
+
+	if err := h.db.First(session, "id = ?", session.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload session"})
+		return
+	}
+
+	if !h.checkUploadSessionOpen(c, session) {
+		return
+	}
+
+	if session.ChunkSize <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload session doesn't take indexed chunks, send chunks by offset"})
+		return
+	}
+
+	totalChunks := (session.TotalSize + session.ChunkSize - 1) / session.ChunkSize
+	if index >= totalChunks {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Chunk index is past the end of the upload",
+			"total_chunks": totalChunks,
+		})
+		return
+	}
+
+	offset := index * session.ChunkSize
+	size := session.ChunkSize
+	if offset+size > session.TotalSize {
+		size = session.TotalSize - offset
+	}
+
+	c.Header(UploadOffsetHeader, strconv.FormatInt(session.BytesReceived, 10))
+	if offset+size <= session.BytesReceived {
+		c.JSON(http.StatusOK, uploadProgress(session, ""))
+		return
+	}
+	if offset != session.BytesReceived {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Chunks must be sent in order",
+			"next_chunk":     session.BytesReceived / session.ChunkSize,
+			"bytes_received": session.BytesReceived,
+		})
+		return
+	}
+
+	h.appendUploadChunk(c, session, size, true)
+}
+
+// appendUploadChunk writes the request body to the end of the session's temp
+// file, reading at most limit bytes, or exactly limit bytes when exact is set.
+// The caller holds the session lock and has checked the offset.
+func (h *FileHandler) appendUploadChunk(c *gin.Context, session *models.UploadSession, limit int64, exact bool) {
+	hasher, err := utils.RestoreProgressHasher(session.HashState, session.BytesReceived, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore upload hash state"})
+		return
+	}
+
+	fullTempPath := filepath.Join(h.cfg.StoragePath, session.TempPath)
+	tempFile, err := os.OpenFile(fullTempPath, os.O_WRONLY, 0644)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open upload file"})
+		return
+	}
+	defer tempFile.Close()
+
+	// Drop anything left past the acknowledged offset by an interrupted chunk
+	if err := tempFile.Truncate(session.BytesReceived); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare upload file"})
+		return
+	}
+	if _, err := tempFile.Seek(session.BytesReceived, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare upload file"})
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	if h.cfg.UploadProgressEvents {
+		body = h.trackChunkProgress(body, session)
+	}
+	written, err := utils.CopyWithHash(tempFile, body, hasher, h.cfg.UploadHashBufferSize)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	if err != nil {
+		tempFile.Truncate(session.BytesReceived)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			message := "Chunk exceeds the declared upload size"
+			if exact {
+				message = fmt.Sprintf("Chunk must be %d bytes", limit)
+			}
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":     message,
+				"remaining": limit,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          "Failed to receive chunk",
+			"bytes_received": session.BytesReceived,
+		})
+		return
+	}
+	if exact && written != limit {
+		tempFile.Truncate(session.BytesReceived)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":          fmt.Sprintf("Chunk must be %d bytes, received %d", limit, written),
+			"bytes_received": session.BytesReceived,
+		})
+		return
+	}
+
+	hashState, err := hasher.State()
+	if err != nil {
+		tempFile.Truncate(session.BytesReceived)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save upload hash state"})
+		return
+	}
+
+	bytesReceived := session.BytesReceived + written
+	if err := h.db.Model(session).Updates(map[string]interface{}{
+		"bytes_received": bytesReceived,
+		"hash_state":     hashState,
+	}).Error; err != nil {
+		tempFile.Truncate(session.BytesReceived)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload session"})
+		return
+	}
+	session.BytesReceived = bytesReceived
+	session.HashState = hashState
+	h.progress.Publish(uploadProgressEvent(session))
+
+	c.Header(UploadOffsetHeader, strconv.FormatInt(session.BytesReceived, 10))
+	if !h.cfg.UploadChunkAcks {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, uploadProgress(session, hasher.Sum()))
+}
+
+// CompleteUpload finalizes a fully received upload session, verifying the
+// computed hash and running it through deduplication
+func (h *FileHandler) CompleteUpload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	unlock := lockUploadSession(session.ID)
+	defer unlock()
+
+	if err := h.db.First(session, "id = ?", session.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload session"})
+		return
+	}
+
+	if !h.checkUploadSessionOpen(c, session) {
+		return
+	}
+
+	if session.BytesReceived != session.TotalSize {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Upload is incomplete",
+			"bytes_received": session.BytesReceived,
+			"total_size":     session.TotalSize,
+		})
+		return
+	}
+
+	hasher, err := utils.RestoreProgressHasher(session.HashState, session.BytesReceived, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore upload hash state"})
+		return
+	}
+	contentHash := hasher.Sum()
+
+	if session.DeclaredHash != "" && session.DeclaredHash != contentHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         "Uploaded content does not match the declared hash",
+			"declared_hash": session.DeclaredHash,
+			"content_hash":  contentHash,
+		})
+		return
+	}
+
+	fullTempPath := filepath.Join(h.cfg.StoragePath, session.TempPath)
+
+	// Validate MIME type from the first bytes of the content
+	head, err := readFileHead(fullTempPath, 512)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded content"})
+		return
+	}
+
+	declaredMimeType := session.MimeType
+	if declaredMimeType == "" {
+		declaredMimeType = "application/octet-stream"
+	}
+
+	validator := utils.NewMimeTypeValidatorWithOverrides(h.mimeOverrides)
+	if h.rejectExecutable(c, validator, session.Filename, head) {
+		return
+	}
+
+	var malwareSignature string
+	if h.scanner != nil {
+		content, err := os.Open(fullTempPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded content"})
+			return
+		}
+		signature, rejected := h.scanUpload(c, session.Filename, content)
+		content.Close()
+		if rejected {
+			return
+		}
+		malwareSignature = signature
+	}
+
+	isValid, actualMimeType, warning := validator.ValidateMimeType(head, declaredMimeType, session.Filename)
+	if !isValid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             fmt.Sprintf("Invalid file type for %s", session.Filename),
+			"filename":          session.Filename,
+			"declared_mimetype": declaredMimeType,
+			"actual_mimetype":   actualMimeType,
+			"warning":           warning,
+		})
+		return
+	}
+
+	if h.rejectDisallowedMimeType(c, session.Filename, actualMimeType) {
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	quota := h.checkQuota(&user, session.TotalSize)
+	if !quota.allowed {
+		rejectOverQuota(c, &user, session.TotalSize, quota)
+		return
+	}
+
+	uploadFile := FileUploadInfo{
+		Header:   &multipart.FileHeader{Filename: session.Filename, Size: session.TotalSize},
+		TempPath: fullTempPath,
+		Size:     session.TotalSize,
+		Hash:     contentHash,
+		MimeType: actualMimeType,
+		IsValid:  isValid,
+		Warning:  warning,
+
+		DeclaredMimeType: declaredMimeType,
+		MalwareSignature: malwareSignature,
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Check again holding the user's row, so a concurrent upload that
+	// committed since can't take both over quota
+	if err := lockUserForQuota(tx, &user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	if quota = h.checkQuota(&user, session.TotalSize); !quota.allowed {
+		tx.Rollback()
+		rejectOverQuota(c, &user, session.TotalSize, quota)
+		return
+	}
+
+	result, savedBytes, orphaned, err := h.processFileUpload(tx, uploadFile, session.UserID, session.FolderID, session.APIKeyID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "Failed to process file upload",
+			"filename": session.Filename,
+			"details":  err.Error(),
+		})
+		return
+	}
+	h.recordUploadProvenance(tx, c, uploadFile, session.APIKeyID, result)
+
+	if err := h.updateUserStorageStats(tx, session.UserID, session.TotalSize); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+		return
+	}
+
+	if quota.usesGrace {
+		if err := h.markQuotaGraceUsed(tx, session.UserID); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errQuotaGraceUsed) {
+				quota.graceAvailable = false
+				rejectOverQuota(c, &user, session.TotalSize, quota)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user storage stats"})
+			return
+		}
+	}
+
+	fileID := result["file_id"].(uuid.UUID)
+	if err := tx.Model(session).Updates(map[string]interface{}{
+		"status":            models.UploadSessionCompleted,
+		"completed_file_id": fileID,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload session"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit upload transaction"})
+		return
+	}
+
+	h.removeContent(orphaned)
+
+	// Duplicate content leaves the streamed copy behind
+	os.Remove(fullTempPath)
+	session.Status = models.UploadSessionCompleted
+	h.progress.Publish(uploadProgressEvent(session))
+	h.notifyUploadCompleted(session.UserID, []map[string]interface{}{result})
+
+	if malwareSignature == "" {
+		h.thumbnails.Enqueue(contentHash, actualMimeType)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "File uploaded successfully",
+		"upload_id":    session.ID,
+		"content_hash": contentHash,
+		"is_duplicate": result["is_duplicate"],
+		"saved_bytes":  savedBytes,
+		"file":         result,
+	})
+}
+
+// AbortUpload cancels an upload session and discards the received content
+func (h *FileHandler) AbortUpload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findUploadSession(c, userID)
+	if !ok {
+		return
+	}
+
+	unlock := lockUploadSession(session.ID)
+	defer unlock()
+
+	if session.Status == models.UploadSessionCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is already complete"})
+		return
+	}
+
+	if err := h.db.Model(session).Update("status", models.UploadSessionAborted).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to abort upload"})
+		return
+	}
+
+	os.Remove(filepath.Join(h.cfg.StoragePath, session.TempPath))
+	uploadSessionLocks.Delete(session.ID)
+	session.Status = models.UploadSessionAborted
+	h.progress.Publish(uploadProgressEvent(session))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload aborted"})
+}
+
+// findUploadSession loads the upload session in the :uploadId param, writing
+// an error response and returning false when it isn't the caller's
+func (h *FileHandler) findUploadSession(c *gin.Context, userID interface{}) (*models.UploadSession, bool) {
+	sessionID, err := uuid.Parse(c.Param("uploadId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return nil, false
+	}
+
+	var session models.UploadSession
+	if err := h.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Upload session not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload session"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// checkUploadSessionOpen writes an error response and returns false when the
+// session can no longer accept data
+func (h *FileHandler) checkUploadSessionOpen(c *gin.Context, session *models.UploadSession) bool {
+	if session.Status != models.UploadSessionPending {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Upload session is no longer active",
+			"status": session.Status,
+		})
+		return false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload session has expired"})
+		return false
+	}
+	return true
+}
+
+// readFileHead reads up to n bytes from the start of a file
+func readFileHead(path string, n int) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, n)
+	read, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:read], nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// sniffLength is how much of the start of an upload is kept for MIME and
+// executable detection, the most http.DetectContentType looks at
+const sniffLength = 512
+
+// spooledUpload is a multipart file streamed to a temp file in storage
+type spooledUpload struct {
+	path string // absolute
+	size int64
+	hash string // SHA-256, hex encoded
+	md5  []byte // checked against a checksum the client claims
+	head []byte // the first sniffLength bytes
+}
+
+// headWriter keeps the first bytes written to it
+type headWriter struct {
+	head []byte
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := sniffLength - len(w.head); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		w.head = append(w.head, p[:room]...)
+	}
+	return len(p), nil
+}
+
+// spoolUpload streams content to a temp file under the storage root, hashing
+// it on the way, so memory use doesn't grow with the upload size. The temp
+// file sits on the same filesystem as the blobs so it can be renamed into
+// place. Content larger than maxSize is cut off at maxSize+1 bytes, enough
+// for the caller to see it is over the limit.
+func (h *FileHandler) spoolUpload(content io.Reader, maxSize int64) (*spooledUpload, error) {
+	tempPath := filepath.Join(h.cfg.StoragePath, "tmp", "uploads", uuid.New().String()+".upload")
+	if err := os.MkdirAll(filepath.Dir(tempPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	tempFile, err := os.OpenFile(tempPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+
+	hasher := sha256.New()
+	md5Hasher := md5.New()
+	head := &headWriter{}
+	size, err := io.Copy(io.MultiWriter(tempFile, hasher, md5Hasher, head), io.LimitReader(content, maxSize+1))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to write upload file: %v", err)
+	}
+
+	return &spooledUpload{
+		path: tempPath,
+		size: size,
+		hash: fmt.Sprintf("%x", hasher.Sum(nil)),
+		md5:  md5Hasher.Sum(nil),
+		head: head.head,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware authenticates requests carrying an X-API-Key header and sets
+// the same user context as AuthMiddleware. Requests without the header are left
+// for AuthMiddleware to handle.
+func APIKeyMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			c.Next()
+			return
+		}
+
+		var apiKey models.APIKey
+		if err := db.Preload("User").
+			Where("key_hash = ? AND revoked_at IS NULL", utils.HashAPIKey(rawKey)).
+			First(&apiKey).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid API key",
+			})
+			c.Abort()
+			return
+		}
+
+		if !apiKey.User.IsActive {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Account is disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		// Track usage without failing the request on error
+		db.Model(&apiKey).UpdateColumn("last_used_at", time.Now())
+
+		// Set user context
+		c.Set("user_id", apiKey.UserID)
+		c.Set("username", apiKey.User.Username)
+		c.Set("email", apiKey.User.Email)
+		c.Set("role", string(apiKey.User.Role))
+		c.Set("roles", []string{})
+		c.Set("api_key_id", apiKey.ID)
+
+		c.Next()
+	}
+}
@@ -21,8 +21,9 @@ type JWTClaims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
 	Email    string    `json:"email"`
-	Role     string    `json:"role"`  // Simple role field
-	Roles    []string  `json:"roles"` // Complex roles array (keeping for backward compatibility)
+	Role     string    `json:"role"`              // Simple role field
+	Roles    []string  `json:"roles"`             // Complex roles array (keeping for backward compatibility)
+	Purpose  string    `json:"purpose,omitempty"` // Set on special-purpose tokens (e.g. sudo) that must not grant access
 	jwt.RegisteredClaims
 }
 
@@ -35,6 +36,12 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Already authenticated by an earlier middleware (e.g. API key)
+		if _, exists := c.Get("user_id"); exists {
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -65,6 +72,15 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Special-purpose tokens are not access tokens
+		if claims.Purpose != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid token: not an access token",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set user context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
@@ -76,6 +92,17 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// BearerToken returns the access token sent in the Authorization header, or
+// "" when there is none
+func BearerToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return ""
+	}
+	return tokenString
+}
+
 // RequireRole middleware that ensures the user has the required role
 func RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -33,9 +33,9 @@ func CORS() gin.HandlerFunc {
 		}
 
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-None-Match, Range, If-Range, X-API-Key, X-Sudo-Token")
+		c.Header("Access-Control-Allow-Methods", "POST, GET, HEAD, OPTIONS, PUT, DELETE, PATCH")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type, Retry-After, X-Upload-Budget-Remaining, X-Upload-Budget-Files-Remaining, Accept-Ranges, Content-Range, ETag, X-Recommended-Chunk-Size")
 
 		// Handle preflight requests
 		if c.Request.Method == "OPTIONS" {
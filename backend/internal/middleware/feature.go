@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureChecker reports whether a feature flag is enabled
+type FeatureChecker interface {
+	IsEnabled(key string) bool
+}
+
+// RequireFeature middleware rejects requests with 403 while a feature is disabled
+func RequireFeature(checker FeatureChecker, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checker.IsEnabled(key) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   fmt.Sprintf("The %s feature is currently disabled", key),
+				"feature": key,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -14,45 +14,58 @@ import (
 	"gorm.io/gorm"
 )
 
+// limiterIdleTimeout is how long a key's limiter is kept after its last use
+const limiterIdleTimeout = time.Hour
+
+// trackedLimiter is a key's limiter and when it was last asked for
+type trackedLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
 // RateLimiter stores rate limiters for different users and endpoints
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
+	limiters map[string]*trackedLimiter
 	mu       sync.RWMutex
 	rate     rate.Limit
 	burst    int
+	now      func() time.Time // clock, replaceable so idle expiry can be simulated
 }
 
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
 	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
+		limiters: make(map[string]*trackedLimiter),
 		rate:     r,
 		burst:    b,
+		now:      time.Now,
 	}
 }
 
-// GetLimiter returns a rate limiter for a specific key
+// GetLimiter returns a rate limiter for a specific key, marking it as used
 func (rl *RateLimiter) GetLimiter(key string) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	limiter, exists := rl.limiters[key]
+	tracked, exists := rl.limiters[key]
 	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[key] = limiter
+		tracked = &trackedLimiter{limiter: rate.NewLimiter(rl.rate, rl.burst)}
+		rl.limiters[key] = tracked
 	}
+	tracked.lastSeen = rl.now()
 
-	return limiter
+	return tracked.limiter
 }
 
-// CleanupOldLimiters removes unused limiters to prevent memory leaks
+// CleanupOldLimiters removes limiters that haven't been used in the last hour
+// to prevent memory leaks
 func (rl *RateLimiter) CleanupOldLimiters() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	for key, limiter := range rl.limiters {
-		// Remove limiters that haven't been used in the last hour
-		if limiter.Tokens() == float64(rl.burst) {
+	cutoff := rl.now().Add(-limiterIdleTimeout)
+	for key, tracked := range rl.limiters {
+		if tracked.lastSeen.Before(cutoff) {
 			delete(rl.limiters, key)
 		}
 	}
@@ -115,8 +128,11 @@ func RateLimit() gin.HandlerFunc {
 	}
 }
 
-// DatabaseRateLimit middleware uses database to track rate limits
-func DatabaseRateLimit(db *gorm.DB) gin.HandlerFunc {
+// DatabaseRateLimit middleware uses database to track rate limits, with the
+// limit for each user resolved by policy. Requests are counted per matching
+// endpoint pattern, so every path a limit covers draws on the same count, and
+// unauthenticated requests are counted per client address.
+func DatabaseRateLimit(db *gorm.DB, policy *RateLimitPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip rate limiting for health check
 		if c.Request.URL.Path == "/health" {
@@ -124,37 +140,44 @@ func DatabaseRateLimit(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Get user ID from context
-		userIDInterface, exists := c.Get("user_id")
-		if !exists {
+		now := time.Now()
+		var endpoint string
+		var limit RateLimitSettings
+		var owner models.APIRateLimit
+		query := db
+
+		if userIDInterface, exists := c.Get("user_id"); exists {
+			userID, ok := userIDInterface.(uuid.UUID)
+			if !ok {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+				c.Abort()
+				return
+			}
+			endpoint, limit = policy.Limit(userID, c.Request.URL.Path)
+			owner.UserID = &userID
+			query = query.Where("user_id = ?", userID)
+		} else {
 			// For unauthenticated requests, use IP-based rate limiting
-			c.Next()
-			return
+			clientIP := c.ClientIP()
+			endpoint, limit = policy.EndpointLimit(c.Request.URL.Path)
+			owner.ClientIP = &clientIP
+			query = query.Where("client_ip = ?", clientIP)
 		}
 
-		userID, ok := userIDInterface.(uuid.UUID)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
-			c.Abort()
-			return
-		}
-
-		endpoint := c.Request.URL.Path
-		now := time.Now()
-
 		// Check current rate limit status
 		var rateLimit models.APIRateLimit
-		result := db.Where("user_id = ? AND endpoint = ?", userID, endpoint).First(&rateLimit)
+		result := query.Where("endpoint = ?", endpoint).First(&rateLimit)
 
 		if result.Error == gorm.ErrRecordNotFound {
 			// Create new rate limit record
 			rateLimit = models.APIRateLimit{
-				UserID:         userID,
+				UserID:         owner.UserID,
+				ClientIP:       owner.ClientIP,
 				Endpoint:       endpoint,
 				RequestCount:   1,
 				WindowStart:    now,
-				WindowDuration: time.Second,
-				MaxRequests:    2,
+				WindowDuration: limit.Window,
+				MaxRequests:    limit.MaxRequests,
 			}
 			db.Create(&rateLimit)
 			c.Next()
@@ -165,6 +188,10 @@ func DatabaseRateLimit(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Pick up changes to the user's limit
+		rateLimit.WindowDuration = limit.Window
+		rateLimit.MaxRequests = limit.MaxRequests
+
 		// Check if window has expired
 		windowEnd := rateLimit.WindowStart.Add(rateLimit.WindowDuration)
 		if now.After(windowEnd) {
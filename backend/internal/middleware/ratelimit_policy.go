@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// GlobalRateLimitPattern stands for the endpoints no configured pattern
+// matches, which are counted together against the global limit
+const GlobalRateLimitPattern = "*"
+
+// RateLimitSettings is the number of requests a user may make to one
+// endpoint per window
+type RateLimitSettings struct {
+	MaxRequests int
+	Window      time.Duration
+}
+
+// cachedRateLimit holds a user's overrides, nil where they use the endpoint's
+// limit
+type cachedRateLimit struct {
+	requests *int
+	window   *int
+	loadedAt time.Time
+}
+
+// endpointRateLimit is the limit for requests whose path starts with pattern
+type endpointRateLimit struct {
+	pattern  string
+	settings RateLimitSettings
+}
+
+// RateLimitPolicy resolves the rate limit for a request: the limit of the
+// longest endpoint pattern matching its path, or the global limit when none
+// does, with the user's own override applied on top where an admin set one.
+// Endpoint limits are loaded once and reloaded after an admin changes them;
+// user lookups are cached so the limiter doesn't read the user on every
+// request, and changing an override invalidates its entry.
+type RateLimitPolicy struct {
+	db       *gorm.DB
+	defaults RateLimitSettings
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedRateLimit
+
+	endpointsMu     sync.RWMutex
+	endpoints       []endpointRateLimit // longest pattern first
+	endpointsLoaded bool
+}
+
+// NewRateLimitPolicy creates a policy using the configured global limit and
+// loads the per-endpoint limits
+func NewRateLimitPolicy(db *gorm.DB, cfg *config.Config) *RateLimitPolicy {
+	p := &RateLimitPolicy{
+		db: db,
+		defaults: RateLimitSettings{
+			MaxRequests: cfg.RateLimit,
+			Window:      time.Duration(cfg.RateLimitWindow) * time.Second,
+		},
+		ttl:   time.Duration(cfg.RateLimitOverrideCacheTTL) * time.Second,
+		cache: make(map[uuid.UUID]cachedRateLimit),
+	}
+	if err := p.loadEndpoints(); err != nil {
+		log.Printf("Failed to load endpoint rate limits, using the global limit: %v", err)
+	}
+	return p
+}
+
+// Defaults returns the global limit
+func (p *RateLimitPolicy) Defaults() RateLimitSettings {
+	return p.defaults
+}
+
+// Limit returns the pattern matching an endpoint and the limit that applies
+// to a user calling it
+func (p *RateLimitPolicy) Limit(userID uuid.UUID, endpoint string) (string, RateLimitSettings) {
+	pattern, settings := p.EndpointLimit(endpoint)
+
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.cache[userID]
+	p.mu.Unlock()
+	if ok && now.Sub(cached.loadedAt) < p.ttl {
+		return pattern, applyOverride(settings, cached.requests, cached.window)
+	}
+
+	var user models.User
+	if err := p.db.Select("id, rate_limit_requests, rate_limit_window").First(&user, "id = ?", userID).Error; err != nil {
+		// Fall back to the endpoint limit without caching, so the override
+		// applies as soon as it can be read
+		log.Printf("Failed to load rate limit override for user %s: %v", userID, err)
+		return pattern, settings
+	}
+
+	p.mu.Lock()
+	p.cache[userID] = cachedRateLimit{requests: user.RateLimitRequests, window: user.RateLimitWindow, loadedAt: now}
+	p.mu.Unlock()
+	return pattern, applyOverride(settings, user.RateLimitRequests, user.RateLimitWindow)
+}
+
+// Resolve applies a user's overrides to the global limit. Either part of the
+// limit may be overridden on its own.
+func (p *RateLimitPolicy) Resolve(user *models.User) RateLimitSettings {
+	return applyOverride(p.defaults, user.RateLimitRequests, user.RateLimitWindow)
+}
+
+// Invalidate drops a user's cached limit after their override changed
+func (p *RateLimitPolicy) Invalidate(userID uuid.UUID) {
+	p.mu.Lock()
+	delete(p.cache, userID)
+	p.mu.Unlock()
+}
+
+// EndpointLimit returns the pattern matching an endpoint and its limit before
+// user overrides. Endpoints no pattern matches share GlobalRateLimitPattern.
+func (p *RateLimitPolicy) EndpointLimit(endpoint string) (string, RateLimitSettings) {
+	p.endpointsMu.RLock()
+	loaded := p.endpointsLoaded
+	p.endpointsMu.RUnlock()
+	if !loaded {
+		if err := p.loadEndpoints(); err != nil {
+			log.Printf("Failed to load endpoint rate limits, using the global limit: %v", err)
+			return GlobalRateLimitPattern, p.defaults
+		}
+	}
+
+	p.endpointsMu.RLock()
+	defer p.endpointsMu.RUnlock()
+	for _, limit := range p.endpoints {
+		if strings.HasPrefix(endpoint, limit.pattern) {
+			return limit.pattern, limit.settings
+		}
+	}
+	return GlobalRateLimitPattern, p.defaults
+}
+
+// InvalidateEndpoints drops the loaded endpoint limits after an admin changed
+// them, so the next request reloads them
+func (p *RateLimitPolicy) InvalidateEndpoints() {
+	p.endpointsMu.Lock()
+	p.endpoints = nil
+	p.endpointsLoaded = false
+	p.endpointsMu.Unlock()
+}
+
+// loadEndpoints reads the endpoint limits, ordering them so the most specific
+// pattern matches first
+func (p *RateLimitPolicy) loadEndpoints() error {
+	var configs []models.RateLimitConfig
+	if err := p.db.Find(&configs).Error; err != nil {
+		return fmt.Errorf("failed to load rate limit configs: %v", err)
+	}
+
+	endpoints := make([]endpointRateLimit, 0, len(configs))
+	for _, cfg := range configs {
+		endpoints = append(endpoints, endpointRateLimit{
+			pattern: cfg.EndpointPattern,
+			settings: RateLimitSettings{
+				MaxRequests: cfg.MaxRequests,
+				Window:      time.Duration(cfg.WindowSeconds) * time.Second,
+			},
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return len(endpoints[i].pattern) > len(endpoints[j].pattern)
+	})
+
+	p.endpointsMu.Lock()
+	p.endpoints = endpoints
+	p.endpointsLoaded = true
+	p.endpointsMu.Unlock()
+	return nil
+}
+
+// applyOverride replaces the parts of a limit a user has overridden
+func applyOverride(settings RateLimitSettings, requests, window *int) RateLimitSettings {
+	if requests != nil {
+		settings.MaxRequests = *requests
+	}
+	if window != nil {
+		settings.Window = time.Duration(*window) * time.Second
+	}
+	return settings
+}
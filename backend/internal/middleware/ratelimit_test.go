@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCleanupOldLimitersEvictsIdleKeys(t *testing.T) {
+	now := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(1, 1)
+	rl.now = func() time.Time { return now }
+
+	busy := rl.GetLimiter("busy")
+	rl.GetLimiter("idle")
+
+	// The busy key keeps being used, draining its limiter; the idle key is
+	// never asked for again
+	for i := 0; i < 6; i++ {
+		now = now.Add(15 * time.Minute)
+		if limiter := rl.GetLimiter("busy"); limiter != busy {
+			t.Fatalf("GetLimiter() replaced the limiter of a key in use")
+		}
+		busy.Allow()
+		rl.CleanupOldLimiters()
+	}
+
+	if _, ok := rl.limiters["busy"]; !ok {
+		t.Error("CleanupOldLimiters() evicted a key used within the last hour")
+	}
+	if _, ok := rl.limiters["idle"]; ok {
+		t.Error("CleanupOldLimiters() kept a key idle for over an hour")
+	}
+}
+
+func TestCleanupOldLimitersKeepsKeyUntilIdleTimeout(t *testing.T) {
+	now := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	rl := NewRateLimiter(1, 1)
+	rl.now = func() time.Time { return now }
+
+	rl.GetLimiter("key")
+
+	now = now.Add(limiterIdleTimeout)
+	rl.CleanupOldLimiters()
+	if _, ok := rl.limiters["key"]; !ok {
+		t.Fatal("CleanupOldLimiters() evicted a key at exactly the idle timeout")
+	}
+
+	now = now.Add(time.Second)
+	rl.CleanupOldLimiters()
+	if _, ok := rl.limiters["key"]; ok {
+		t.Error("CleanupOldLimiters() kept a key past the idle timeout")
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SudoTokenHeader carries a short-lived token issued after re-entering the password
+const SudoTokenHeader = "X-Sudo-Token"
+
+// SudoTokenPurpose marks JWTs issued for re-authentication
+const SudoTokenPurpose = "sudo"
+
+// Sensitive operations that may require re-authentication
+const (
+	OpCreateAPIKey   = "create_api_key"
+	OpRevokeAPIKey   = "revoke_api_key"
+	OpDeleteAccount  = "delete_account"
+	OpChangeRole     = "change_role"
+	OpRevokeSessions = "revoke_sessions"
+)
+
+// maxReauthBodySize bounds how much of the request body is read looking for current_password
+const maxReauthBodySize = 1 << 20
+
+// RequireReauth middleware requires a fresh password confirmation for
+// operations listed in cfg.SensitiveOperations, either as a current_password
+// field in the JSON body or a valid X-Sudo-Token header
+func RequireReauth(db *gorm.DB, cfg *config.Config, operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isSensitiveOperation(cfg, operation) {
+			c.Next()
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		if sudoToken := c.GetHeader(SudoTokenHeader); sudoToken != "" {
+			if validSudoToken(sudoToken, userID.(uuid.UUID)) {
+				c.Next()
+				return
+			}
+		} else if password := currentPasswordFromBody(c); password != "" {
+			var user models.User
+			if err := db.Select("id, password_hash").First(&user, "id = ?", userID).Error; err == nil &&
+				utils.CheckPassword(password, user.PasswordHash) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "This operation requires re-entering your password",
+			"operation": operation,
+		})
+		c.Abort()
+	}
+}
+
+// isSensitiveOperation reports whether the operation is configured to require re-authentication
+func isSensitiveOperation(cfg *config.Config, operation string) bool {
+	for _, op := range cfg.SensitiveOperations {
+		if strings.TrimSpace(op) == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// validSudoToken reports whether the token is an unexpired sudo token for the user
+func validSudoToken(tokenString string, userID uuid.UUID) bool {
+	claims, err := ValidateJWTToken(tokenString)
+	if err != nil {
+		return false
+	}
+	return claims.Purpose == SudoTokenPurpose && claims.UserID == userID
+}
+
+// currentPasswordFromBody reads current_password from a JSON body, restoring
+// the body so the handler can still bind it
+func currentPasswordFromBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxReauthBodySize))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.CurrentPassword
+}
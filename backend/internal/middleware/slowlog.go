@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SlowRequest records a single request that exceeded the latency threshold
+type SlowRequest struct {
+	Method     string     `json:"method"`
+	Route      string     `json:"route"`
+	Path       string     `json:"path"`
+	Status     int        `json:"status"`
+	Duration   float64    `json:"duration_ms"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+// SlowEndpointSummary aggregates the slow requests seen for one route
+type SlowEndpointSummary struct {
+	Method      string    `json:"method"`
+	Route       string    `json:"route"`
+	Count       int       `json:"count"`
+	AvgDuration float64   `json:"avg_duration_ms"`
+	MaxDuration float64   `json:"max_duration_ms"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// SlowRequestLog keeps the most recent slow requests in a fixed-size ring
+// buffer
+type SlowRequestLog struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	entries []SlowRequest
+	next    int
+	full    bool
+}
+
+// NewSlowRequestLog creates a log of requests slower than threshold, keeping
+// at most size entries
+func NewSlowRequestLog(threshold time.Duration, size int) *SlowRequestLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &SlowRequestLog{
+		threshold: threshold,
+		entries:   make([]SlowRequest, size),
+	}
+}
+
+// Threshold returns the latency above which requests are recorded
+func (l *SlowRequestLog) Threshold() time.Duration {
+	return l.threshold
+}
+
+// Middleware times each request and logs and records those over the
+// threshold. A zero threshold disables it.
+func (l *SlowRequestLog) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.threshold <= 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		duration := time.Since(start)
+
+		// Event streams and WebSockets stay open by design
+		if duration < l.threshold || strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") ||
+			strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			return
+		}
+
+		entry := SlowRequest{
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			Duration:   float64(duration.Microseconds()) / 1000,
+			OccurredAt: start,
+		}
+		if entry.Route == "" {
+			entry.Route = entry.Path
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			if id, ok := userID.(uuid.UUID); ok {
+				entry.UserID = &id
+			}
+		}
+
+		user := "anonymous"
+		if entry.UserID != nil {
+			user = entry.UserID.String()
+		}
+		log.Printf("Slow request: %s %s took %s (status %d, user %s)", entry.Method, entry.Path, duration, entry.Status, user)
+
+		l.add(entry)
+	}
+}
+
+// add stores entry, overwriting the oldest once the buffer is full
+func (l *SlowRequestLog) add(entry SlowRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Recent returns the recorded slow requests, newest first
+func (l *SlowRequestLog) Recent() []SlowRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+
+	recent := make([]SlowRequest, 0, count)
+	for i := 1; i <= count; i++ {
+		recent = append(recent, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return recent
+}
+
+// Summary groups the recorded slow requests by route, slowest on average first
+func (l *SlowRequestLog) Summary() []SlowEndpointSummary {
+	byRoute := make(map[string]*SlowEndpointSummary)
+	totals := make(map[string]float64)
+
+	for _, entry := range l.Recent() {
+		key := entry.Method + " " + entry.Route
+		summary, ok := byRoute[key]
+		if !ok {
+			summary = &SlowEndpointSummary{Method: entry.Method, Route: entry.Route}
+			byRoute[key] = summary
+		}
+		summary.Count++
+		totals[key] += entry.Duration
+		if entry.Duration > summary.MaxDuration {
+			summary.MaxDuration = entry.Duration
+		}
+		if entry.OccurredAt.After(summary.LastSeenAt) {
+			summary.LastSeenAt = entry.OccurredAt
+		}
+	}
+
+	summaries := make([]SlowEndpointSummary, 0, len(byRoute))
+	for key, summary := range byRoute {
+		summary.AvgDuration = totals[key] / float64(summary.Count)
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].AvgDuration > summaries[j].AvgDuration
+	})
+	return summaries
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// requestTxKey is the context key the request transaction is stored under
+const requestTxKey = "request_tx"
+
+// requestTx is the transaction shared by one request. It is only begun when
+// the handler first asks for it.
+type requestTx struct {
+	db          *gorm.DB
+	tx          *gorm.DB
+	afterCommit []func()
+}
+
+// txResponseWriter holds back the response until the transaction has been
+// settled, so a failed commit can still be reported to the client
+type txResponseWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *txResponseWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *txResponseWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *txResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *txResponseWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *txResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *txResponseWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *txResponseWriter) Written() bool {
+	return w.written
+}
+
+// flush sends the held back response
+func (w *txResponseWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Transaction gives the request a database transaction, available to the
+// handler through Tx. It is committed when the handler responds with a 2xx
+// status and rolled back on any other status or a panic.
+func Transaction(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := &requestTx{db: db}
+		c.Set(requestTxKey, state)
+
+		writer := &txResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+
+		defer func() {
+			if r := recover(); r != nil {
+				if state.tx != nil {
+					state.tx.Rollback()
+				}
+				c.Writer = writer.ResponseWriter
+				panic(r)
+			}
+		}()
+
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if state.tx == nil {
+			writer.flush()
+			return
+		}
+
+		if writer.status < http.StatusOK || writer.status >= http.StatusMultipleChoices {
+			state.tx.Rollback()
+			writer.flush()
+			return
+		}
+
+		if err := state.tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit transaction for %s %s: %v", c.Request.Method, c.FullPath(), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+			return
+		}
+		writer.flush()
+
+		for _, fn := range state.afterCommit {
+			fn()
+		}
+	}
+}
+
+// Tx returns the request's transaction, beginning it on first use. The route
+// must be wrapped in Transaction.
+func Tx(c *gin.Context) *gorm.DB {
+	value, exists := c.Get(requestTxKey)
+	if !exists {
+		panic("middleware.Tx called on a route without the Transaction middleware")
+	}
+
+	state := value.(*requestTx)
+	if state.tx == nil {
+		state.tx = state.db.Begin()
+	}
+	return state.tx
+}
+
+// AfterCommit runs fn once the request's transaction has been committed. It
+// is dropped if the transaction is rolled back.
+func AfterCommit(c *gin.Context, fn func()) {
+	value, exists := c.Get(requestTxKey)
+	if !exists {
+		panic("middleware.AfterCommit called on a route without the Transaction middleware")
+	}
+
+	state := value.(*requestTx)
+	state.afterCommit = append(state.afterCommit, fn)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadAdmission caps the upload bytes the server holds at once. Each upload
+// request reserves its Content-Length until it finishes, and new ones are
+// turned away with 503 while the total would go over the limit, so a burst of
+// large uploads can't exhaust memory.
+type UploadAdmission struct {
+	limit       int64 // 0 = unlimited
+	unknownSize int64 // reserved for requests without a Content-Length
+	retryAfter  int   // in seconds
+
+	mu       sync.Mutex
+	inFlight int64
+}
+
+// NewUploadAdmission creates admission control admitting up to limit bytes of
+// concurrent uploads. Requests of unknown length count as unknownSize bytes.
+func NewUploadAdmission(limit, unknownSize int64, retryAfter int) *UploadAdmission {
+	if retryAfter <= 0 {
+		retryAfter = 5
+	}
+	return &UploadAdmission{
+		limit:       limit,
+		unknownSize: unknownSize,
+		retryAfter:  retryAfter,
+	}
+}
+
+// InFlight returns the upload bytes currently reserved
+func (a *UploadAdmission) InFlight() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inFlight
+}
+
+// reserve claims size bytes, failing when that would exceed the limit. A
+// request is always admitted when nothing else is in flight so uploads larger
+// than the limit can still get through one at a time.
+func (a *UploadAdmission) reserve(size int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inFlight > 0 && a.inFlight+size > a.limit {
+		return false
+	}
+	a.inFlight += size
+	return true
+}
+
+func (a *UploadAdmission) release(size int64) {
+	a.mu.Lock()
+	a.inFlight -= size
+	a.mu.Unlock()
+}
+
+// Middleware rejects upload requests with 503 and Retry-After while the
+// server is holding too many upload bytes
+func (a *UploadAdmission) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.limit <= 0 {
+			c.Next()
+			return
+		}
+
+		size := c.Request.ContentLength
+		if size < 0 {
+			size = a.unknownSize
+		}
+		if !a.reserve(size) {
+			c.Header("Retry-After", strconv.Itoa(a.retryAfter))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":       "The server is handling too many uploads, try again shortly",
+				"code":        "upload_capacity_exceeded",
+				"retry_after": a.retryAfter,
+			})
+			c.Abort()
+			return
+		}
+		defer a.release(size)
+
+		c.Next()
+	}
+}
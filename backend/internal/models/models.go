@@ -40,12 +40,20 @@ type User struct {
 	LastName     string       `json:"lastName" gorm:"size:100"`
 	Role         UserRoleType `json:"role" gorm:"type:varchar(20);default:'user'"`
 	StorageQuota int64        `json:"storageQuota" gorm:"default:1073741824"` // 1GB default
-	StorageUsed  int64        `json:"storageUsed" gorm:"default:0"`
+	StorageUsed  int64        `json:"storageUsed" gorm:"default:0"`           // Logical size of live files, duplicates included
 
 	// Storage savings tracking for deduplication
-	TotalUploadedBytes int64 `json:"totalUploadedBytes" gorm:"default:0"` // Total bytes uploaded by user
-	ActualStorageBytes int64 `json:"actualStorageBytes" gorm:"default:0"` // Actual storage used (after deduplication)
-	SavedBytes         int64 `json:"savedBytes" gorm:"default:0"`         // Bytes saved through deduplication
+	TotalUploadedBytes int64 `json:"totalUploadedBytes" gorm:"default:0"` // Lifetime bytes uploaded, never reduced by deletes
+	ActualStorageBytes int64 `json:"actualStorageBytes" gorm:"default:0"` // Live content after deduplication, each distinct blob counted once
+	SavedBytes         int64 `json:"savedBytes" gorm:"default:0"`         // Bytes saved through deduplication (StorageUsed - ActualStorageBytes)
+
+	// Quota grace tracking, so going over quota once doesn't become a habit
+	QuotaGraceUsedAt *time.Time `json:"quotaGraceUsedAt,omitempty"`
+	QuotaGraceCount  int        `json:"quotaGraceCount" gorm:"default:0"`
+
+	// Rate limit overrides for trusted accounts; nil uses the global limit
+	RateLimitRequests *int `json:"rateLimitRequests,omitempty"` // requests per endpoint per window
+	RateLimitWindow   *int `json:"rateLimitWindow,omitempty"`   // in seconds
 
 	IsActive      bool       `json:"isActive" gorm:"default:true"`
 	EmailVerified bool       `json:"emailVerified" gorm:"default:false"`
@@ -72,23 +80,58 @@ type UserRole struct {
 	Role Role `json:"role" gorm:"foreignKey:RoleID"`
 }
 
-// FileHash stores unique file content for deduplication (original schema)
+// FileHash stores unique file content for deduplication (original schema).
+// Content belongs to no single user: it lives as long as it is stored, and
+// deleting the user who first uploaded it never removes it from under others.
 type FileHash struct {
-	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Hash           string    `json:"hash" gorm:"unique;not null;size:64;index"` // SHA-256 hash
-	Size           int64     `json:"size" gorm:"not null"`
-	StoragePath    string    `json:"storage_path" gorm:"not null;type:text"`
-	ReferenceCount int       `json:"reference_count" gorm:"default:0"`
-	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	ID               uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Hash             string          `json:"hash" gorm:"unique;not null;size:64;index"` // SHA-256 hash
+	Size             int64           `json:"size" gorm:"not null"`
+	StoragePath      string          `json:"storage_path" gorm:"not null;type:text"`
+	ReferenceCount   int             `json:"reference_count" gorm:"default:0"`
+	ThumbnailPath    string          `json:"thumbnail_path,omitempty" gorm:"type:text"`       // Relative to the storage root, empty when no thumbnail exists
+	ThumbnailFailed  bool            `json:"thumbnail_failed,omitempty" gorm:"default:false"` // Generation failed; the generic icon is shown instead
+	LastVerifiedAt   *time.Time      `json:"last_verified_at,omitempty"`
+	IntegrityStatus  IntegrityStatus `json:"integrity_status,omitempty" gorm:"size:20"`
+	ChunkSize        int64           `json:"chunk_size,omitempty"`                        // Bytes covered by each chunk checksum, 0 when none were recorded
+	ChunkChecksums   []byte          `json:"-" gorm:"type:bytea"`                         // Concatenated SHA-256 of each chunk
+	QuarantineReason string          `json:"quarantine_reason,omitempty" gorm:"size:255"` // Malware signature holding every file with this content for review
+	CreatedAt        time.Time       `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// IntegrityStatus is the outcome of the last integrity check of a stored blob
+type IntegrityStatus string
+
+const (
+	IntegrityOK       IntegrityStatus = "ok"
+	IntegrityCorrupt  IntegrityStatus = "corrupt"
+	IntegrityMissing  IntegrityStatus = "missing"
+	IntegrityRepaired IntegrityStatus = "repaired"
+)
+
+// IntegrityScrubRun records the results of one pass of the integrity scrubber
+type IntegrityScrubRun struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	StartedAt  time.Time  `json:"started_at" gorm:"not null"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Checked    int        `json:"checked" gorm:"default:0"`
+	Corrupt    int        `json:"corrupt" gorm:"default:0"`
+	Missing    int        `json:"missing" gorm:"default:0"`
+	Repaired   int        `json:"repaired" gorm:"default:0"`
+	Error      string     `json:"error,omitempty" gorm:"type:text"`
 }
 
 // Folder represents a folder for organizing files
 type Folder struct {
 	BaseModel
-	Name     string     `json:"name" gorm:"not null;size:255"`
-	ParentID *uuid.UUID `json:"parent_id,omitempty" gorm:"type:uuid"`
-	OwnerID  uuid.UUID  `json:"owner_id" gorm:"type:uuid;not null"`
-	Path     string     `json:"path" gorm:"not null"` // Full path for quick lookups
+	Name            string     `json:"name" gorm:"not null;size:255"`
+	ParentID        *uuid.UUID `json:"parent_id,omitempty" gorm:"type:uuid"`
+	OwnerID         uuid.UUID  `json:"owner_id" gorm:"type:uuid;not null"`
+	Path            string     `json:"path" gorm:"not null"`                        // Full path for quick lookups
+	HiddenFromAdmin bool       `json:"hidden_from_admin" gorm:"default:false"`      // Redacts files here and in subfolders from the admin listing
+	DefaultTags     []string   `json:"default_tags" gorm:"type:text[]"`             // Applied to files uploaded here
+	InheritTags     bool       `json:"inherit_tags" gorm:"default:false"`           // Also apply the parent folder's default tags
+	TransferredFrom *uuid.UUID `json:"transferred_from,omitempty" gorm:"type:uuid"` // User whose content was transferred into this folder
 
 	// Relationships
 	Parent   *Folder  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
@@ -102,6 +145,7 @@ type File struct {
 	BaseModel
 	Filename         string     `json:"filename" gorm:"not null;size:255"`
 	OriginalFilename string     `json:"original_filename" gorm:"not null;size:255"`
+	RawFilename      []byte     `json:"raw_filename,omitempty" gorm:"type:bytea"` // Name as uploaded, kept when normalization changed it
 	MimeType         string     `json:"mime_type" gorm:"not null;size:100"`
 	Size             int64      `json:"size" gorm:"not null"`
 	FileHashID       uuid.UUID  `json:"file_hash_id" gorm:"type:uuid;not null;index"` // Reference to FileHash
@@ -111,6 +155,13 @@ type File struct {
 	Description      string     `json:"description" gorm:"type:text"`
 	IsDeleted        bool       `json:"is_deleted" gorm:"default:false"`
 	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	APIKeyID         *uuid.UUID `json:"api_key_id,omitempty" gorm:"type:uuid;index"` // Set when uploaded with an API key
+	AccessExpiresAt  *time.Time `json:"access_expires_at,omitempty"`                 // Content becomes unavailable after this, even to the owner
+	HiddenFromAdmin  bool       `json:"hidden_from_admin" gorm:"default:false"`      // Redacted in the admin listing
+	Status           FileStatus `json:"status" gorm:"default:'active';size:20"`
+	QuarantineReason string     `json:"quarantine_reason,omitempty" gorm:"size:255"` // Malware signature that put the file in quarantine
+	Version          int        `json:"version" gorm:"default:1"`                    // Current version, counting re-uploads and restores
+	Redacted         bool       `json:"redacted,omitempty" gorm:"-"`                 // Set when metadata was withheld from the response
 
 	// Relationships
 	FileHash      *FileHash      `json:"file_hash,omitempty" gorm:"foreignKey:FileHashID"`
@@ -125,14 +176,63 @@ type File struct {
 	IsShared   bool `json:"is_shared" gorm:"default:false"`
 }
 
+// FileVersion is one content a versioned file has had. The file itself holds
+// its current version; these rows keep every version, the current one
+// included, so earlier ones can be listed and restored.
+type FileVersion struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	FileID       uuid.UUID `json:"file_id" gorm:"type:uuid;not null;uniqueIndex:idx_file_versions_file_version"`
+	Version      int       `json:"version" gorm:"not null;uniqueIndex:idx_file_versions_file_version"`
+	FileHashID   uuid.UUID `json:"file_hash_id" gorm:"type:uuid;not null;index"`
+	Size         int64     `json:"size" gorm:"not null"`
+	MimeType     string    `json:"mime_type" gorm:"not null;size:100"`
+	RestoredFrom *int      `json:"restored_from,omitempty"` // Version whose content a restore brought back
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	FileHash *FileHash `json:"file_hash,omitempty" gorm:"foreignKey:FileHashID"`
+}
+
+// FileStatus tracks whether a file's content may be served
+type FileStatus string
+
+const (
+	FileStatusActive      FileStatus = "active"
+	FileStatusQuarantined FileStatus = "quarantined" // Flagged by the malware scanner, held for admin review
+)
+
+// Quarantined reports whether the file is held for review and must not be served
+func (f *File) Quarantined() bool {
+	return f.Status == FileStatusQuarantined
+}
+
+// AccessExpired reports whether the file's content is no longer available at now
+func (f *File) AccessExpired(now time.Time) bool {
+	return f.AccessExpiresAt != nil && !now.Before(*f.AccessExpiresAt)
+}
+
 // SharePermission represents access permissions for sharing
 type SharePermission string
 
 const (
 	PermissionView     SharePermission = "view"
 	PermissionDownload SharePermission = "download"
+	PermissionEdit     SharePermission = "edit" // Folder shares only: read plus adding files
 )
 
+// Rank orders permissions so the strongest of several grants can be chosen
+func (p SharePermission) Rank() int {
+	switch p {
+	case PermissionView:
+		return 1
+	case PermissionDownload:
+		return 2
+	case PermissionEdit:
+		return 3
+	}
+	return 0
+}
+
 // FileShare represents internal sharing between users
 type FileShare struct {
 	BaseModel
@@ -150,26 +250,98 @@ type FileShare struct {
 	SharedWithUser User `json:"shared_with_user" gorm:"foreignKey:SharedWith"`
 }
 
-// ShareLink represents external shareable links
+// ACLGranteeType identifies who a file ACL entry applies to
+type ACLGranteeType string
+
+const (
+	ACLGranteeUser          ACLGranteeType = "user"          // A single user
+	ACLGranteeAuthenticated ACLGranteeType = "authenticated" // Anyone signed in
+)
+
+// FileACLEntry grants a user, or every authenticated user, access to a file.
+// File access checks are made against these entries; FileShare records the
+// sharing action itself and keeps a matching entry.
+type FileACLEntry struct {
+	BaseModel
+	FileID      uuid.UUID       `json:"file_id" gorm:"type:uuid;not null;index"`
+	GranteeType ACLGranteeType  `json:"grantee_type" gorm:"not null;size:20"`
+	UserID      *uuid.UUID      `json:"user_id,omitempty" gorm:"type:uuid;index"` // Set for user grantees
+	Permission  SharePermission `json:"permission" gorm:"default:'view';size:20"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	GrantedBy   uuid.UUID       `json:"granted_by" gorm:"type:uuid;not null"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// FolderShare shares a folder, and everything beneath it, with another user
+type FolderShare struct {
+	BaseModel
+	FolderID   uuid.UUID       `json:"folder_id" gorm:"type:uuid;not null;index"`
+	SharedBy   uuid.UUID       `json:"shared_by" gorm:"type:uuid;not null"`
+	SharedWith uuid.UUID       `json:"shared_with" gorm:"type:uuid;not null;index"`
+	Permission SharePermission `json:"permission" gorm:"default:'view';size:20"`
+	Message    string          `json:"message" gorm:"type:text"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	IsActive   bool            `json:"is_active" gorm:"default:true"`
+
+	// Relationships
+	Folder         Folder `json:"folder" gorm:"foreignKey:FolderID"`
+	SharedByUser   User   `json:"shared_by_user" gorm:"foreignKey:SharedBy"`
+	SharedWithUser User   `json:"shared_with_user" gorm:"foreignKey:SharedWith"`
+}
+
+// ShareLink represents external shareable links to a file or a folder
 type ShareLink struct {
 	BaseModel
-	FileID         uuid.UUID       `json:"file_id" gorm:"type:uuid;not null"`
-	CreatedBy      uuid.UUID       `json:"created_by" gorm:"type:uuid;not null"`
-	ShareToken     string          `json:"share_token" gorm:"unique;not null;size:128"`
-	Permission     SharePermission `json:"permission" gorm:"default:'view';size:20"`
-	PasswordHash   string          `json:"-" gorm:"size:255"`
-	MaxDownloads   *int            `json:"max_downloads,omitempty"`
-	DownloadCount  int             `json:"download_count" gorm:"default:0"`
-	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
-	IsActive       bool            `json:"is_active" gorm:"default:true"`
-	LastAccessedAt *time.Time      `json:"last_accessed_at,omitempty"`
-
-	// Relationships
-	File          File                 `json:"file" gorm:"foreignKey:FileID"`
+	FileID           *uuid.UUID      `json:"file_id,omitempty" gorm:"type:uuid"`
+	FolderID         *uuid.UUID      `json:"folder_id,omitempty" gorm:"type:uuid"`
+	CreatedBy        uuid.UUID       `json:"created_by" gorm:"type:uuid;not null"`
+	ShareToken       string          `json:"share_token" gorm:"unique;not null;size:128"`
+	Permission       SharePermission `json:"permission" gorm:"default:'view';size:20"`
+	PasswordHash     string          `json:"-" gorm:"size:255"`
+	MaxDownloads     *int            `json:"max_downloads,omitempty"`
+	DownloadCount    int             `json:"download_count" gorm:"default:0"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty"`
+	IsActive         bool            `json:"is_active" gorm:"default:true"`
+	LastAccessedAt   *time.Time      `json:"last_accessed_at,omitempty"`
+	HTMLIndex        bool            `json:"html_index" gorm:"default:false"`         // folder links serve a browsable page
+	BurnAfterReading bool            `json:"burn_after_reading" gorm:"default:false"` // revoked by the first download
+	BurnDeletesFile  bool            `json:"burn_deletes_file" gorm:"default:false"`  // the first download also moves the file to trash
+	Status           ShareLinkStatus `json:"status,omitempty" gorm:"-"`               // computed when listing links
+
+	// Relationships
+	File          *File                `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Folder        *Folder              `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
 	CreatedByUser User                 `json:"created_by_user" gorm:"foreignKey:CreatedBy"`
 	AccessLogs    []ShareLinkAccessLog `json:"access_logs" gorm:"foreignKey:ShareLinkID"`
 }
 
+// ShareLinkStatus is where a share link is in its lifetime
+type ShareLinkStatus string
+
+const (
+	ShareLinkActive    ShareLinkStatus = "active"
+	ShareLinkExpired   ShareLinkStatus = "expired"
+	ShareLinkExhausted ShareLinkStatus = "exhausted" // download limit reached
+	ShareLinkRevoked   ShareLinkStatus = "revoked"
+)
+
+// StatusAt works out the link's status at now. Revocation wins over expiry,
+// and expiry over an exhausted download limit.
+func (l *ShareLink) StatusAt(now time.Time) ShareLinkStatus {
+	switch {
+	case !l.IsActive:
+		return ShareLinkRevoked
+	case l.ExpiresAt != nil && l.ExpiresAt.Before(now):
+		return ShareLinkExpired
+	case l.MaxDownloads != nil && l.DownloadCount >= *l.MaxDownloads:
+		return ShareLinkExhausted
+	default:
+		return ShareLinkActive
+	}
+}
+
 // ShareLinkAccessLog tracks access to shared links
 type ShareLinkAccessLog struct {
 	BaseModel
@@ -189,6 +361,7 @@ type DownloadStat struct {
 	FileID       uuid.UUID  `json:"file_id" gorm:"type:uuid;not null"`
 	DownloadedBy *uuid.UUID `json:"downloaded_by,omitempty" gorm:"type:uuid"`
 	ShareLinkID  *uuid.UUID `json:"share_link_id,omitempty" gorm:"type:uuid"`
+	FileName     string     `json:"file_name,omitempty" gorm:"size:255"` // Name of the file when it was downloaded
 	IPAddress    string     `json:"ip_address" gorm:"type:inet"`
 	UserAgent    string     `json:"user_agent" gorm:"type:text"`
 	DownloadSize int64      `json:"download_size"`
@@ -217,11 +390,125 @@ type AuditLog struct {
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
-// APIRateLimit tracks API rate limiting per user
+// APIKey lets automated clients authenticate on behalf of a user without a JWT
+type APIKey struct {
+	BaseModel
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	KeyPrefix  string     `json:"key_prefix" gorm:"not null;size:16"` // First characters of the key, for identification
+	KeyHash    string     `json:"-" gorm:"unique;not null;size:64"`   // SHA-256 of the full key
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// RefreshToken lets a client get a new access token without credentials.
+// Each is used once: refreshing replaces it with a new one, and logging out
+// deletes it.
+type RefreshToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string    `json:"-" gorm:"unique;not null;size:64"` // SHA-256 of the token
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// EmailVerificationToken is sent to a user's email address to prove they can
+// read it. Only the latest one sent to a user is kept.
+type EmailVerificationToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash string    `json:"-" gorm:"unique;not null;size:64"` // SHA-256 of the token
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// UploadSessionStatus represents the state of a streaming upload session
+type UploadSessionStatus string
+
+const (
+	UploadSessionPending   UploadSessionStatus = "pending"
+	UploadSessionCompleted UploadSessionStatus = "completed"
+	UploadSessionAborted   UploadSessionStatus = "aborted"
+)
+
+// UploadSession tracks a streaming upload sent over several requests. The
+// running SHA-256 state is persisted so hashing resumes where it stopped.
+type UploadSession struct {
+	BaseModel
+	UserID          uuid.UUID           `json:"user_id" gorm:"type:uuid;not null;index"`
+	FolderID        *uuid.UUID          `json:"folder_id,omitempty" gorm:"type:uuid"`
+	APIKeyID        *uuid.UUID          `json:"api_key_id,omitempty" gorm:"type:uuid"`
+	Filename        string              `json:"filename" gorm:"not null;size:255"`
+	MimeType        string              `json:"mime_type" gorm:"size:100"` // As declared by the client
+	TotalSize       int64               `json:"total_size" gorm:"not null"`
+	BytesReceived   int64               `json:"bytes_received" gorm:"default:0"`
+	ChunkSize       int64               `json:"chunk_size" gorm:"default:0"`            // Size of chunks sent by index, fixed when the session starts
+	DeclaredHash    string              `json:"declared_hash,omitempty" gorm:"size:64"` // Optional SHA-256 to verify on completion
+	HashState       []byte              `json:"-" gorm:"type:bytea"`                    // Serialized running SHA-256 state
+	TempPath        string              `json:"-" gorm:"not null;type:text"`            // Relative to the storage root
+	Status          UploadSessionStatus `json:"status" gorm:"default:'pending';size:20"`
+	ExpiresAt       time.Time           `json:"expires_at" gorm:"not null"`
+	CompletedFileID *uuid.UUID          `json:"completed_file_id,omitempty" gorm:"type:uuid"`
+}
+
+// SearchCriteria filters a user's files. Empty fields don't filter.
+type SearchCriteria struct {
+	Query         string     `json:"query,omitempty" gorm:"size:255"`        // Part of the filename, case-insensitive
+	Fuzzy         bool       `json:"fuzzy,omitempty" gorm:"default:false"`   // Also match names similar to Query, tolerating typos
+	MimeCategory  string     `json:"mime_category,omitempty" gorm:"size:20"` // image, video, audio, text, document or archive
+	Tags          []string   `json:"tags,omitempty" gorm:"type:text[]"`      // Files must carry all of them
+	MinSize       *int64     `json:"min_size,omitempty"`
+	MaxSize       *int64     `json:"max_size,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+}
+
+// SavedSearch is a named search a user can rerun, a "smart folder" whose
+// contents follow the files matching its criteria
+type SavedSearch struct {
+	BaseModel
+	UserID   uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name     string         `json:"name" gorm:"not null;size:100"`
+	Criteria SearchCriteria `json:"criteria" gorm:"embedded"`
+}
+
+// FeatureFlag toggles a capability at runtime without redeploying
+type FeatureFlag struct {
+	Key         string     `json:"key" gorm:"primary_key;size:100"`
+	Enabled     bool       `json:"enabled" gorm:"not null;default:true"`
+	Description string     `json:"description" gorm:"type:text"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// MimePolicy is the MIME type allowlist and blocklist admins manage at
+// runtime. There is at most one row; without it ALLOWED_MIME_TYPES applies.
+type MimePolicy struct {
+	ID           int        `json:"-" gorm:"primary_key;default:1"`
+	AllowedTypes []string   `json:"allowed_types" gorm:"type:text[];not null"` // Empty allows any type not blocked
+	BlockedTypes []string   `json:"blocked_types" gorm:"type:text[];not null"` // Rejected even when allowed
+	UpdatedBy    *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// APIRateLimit tracks API rate limiting per user, or per client address for
+// unauthenticated requests
 type APIRateLimit struct {
 	ID             uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	UserID         uuid.UUID     `json:"user_id" gorm:"type:uuid;not null"`
-	Endpoint       string        `json:"endpoint" gorm:"not null;size:255"`
+	UserID         *uuid.UUID    `json:"user_id,omitempty" gorm:"type:uuid"` // set for authenticated requests
+	ClientIP       *string       `json:"client_ip,omitempty" gorm:"size:45"` // set for unauthenticated requests
+	Endpoint       string        `json:"endpoint" gorm:"not null;size:255"`  // matching rate limit pattern
 	RequestCount   int           `json:"request_count" gorm:"default:0"`
 	WindowStart    time.Time     `json:"window_start" gorm:"autoCreateTime"`
 	WindowDuration time.Duration `json:"window_duration" gorm:"default:1000000000"` // 1 second in nanoseconds
@@ -230,3 +517,15 @@ type APIRateLimit struct {
 	// Relationships
 	User User `json:"user" gorm:"foreignKey:UserID"`
 }
+
+// RateLimitConfig sets the rate limit for requests whose path starts with
+// EndpointPattern. The longest matching pattern wins; paths matching none use
+// the global limit.
+type RateLimitConfig struct {
+	EndpointPattern string     `json:"endpoint_pattern" gorm:"primary_key;size:255"`
+	MaxRequests     int        `json:"max_requests" gorm:"not null"`
+	WindowSeconds   int        `json:"window_seconds" gorm:"not null"`
+	UpdatedBy       *uuid.UUID `json:"updated_by,omitempty" gorm:"type:uuid"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// AccessLevel is the effective access a user has to a file or folder
+type AccessLevel string
+
+const (
+	AccessNone  AccessLevel = "none"
+	AccessRead  AccessLevel = "read"  // view and download
+	AccessWrite AccessLevel = "write" // read plus adding files to a folder
+	AccessOwner AccessLevel = "owner" // full control, including sharing and deleting
+)
+
+// Rank orders access levels so they can be compared
+func (a AccessLevel) Rank() int {
+	switch a {
+	case AccessRead:
+		return 1
+	case AccessWrite:
+		return 2
+	case AccessOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Allows reports whether a grants at least the minimum level
+func (a AccessLevel) Allows(minimum AccessLevel) bool {
+	return a.Rank() >= minimum.Rank()
+}
+
+// accessForPermission maps a share permission to the access it grants
+func accessForPermission(permission models.SharePermission) AccessLevel {
+	switch permission {
+	case models.PermissionView, models.PermissionDownload:
+		return AccessRead
+	case models.PermissionEdit:
+		return AccessWrite
+	default:
+		return AccessNone
+	}
+}
+
+// FolderAccessLevel resolves the user's access to a folder from ownership
+// and shares on the folder or any of its ancestors
+func FolderAccessLevel(db *gorm.DB, folder *models.Folder, userID uuid.UUID) (AccessLevel, error) {
+	if folder.OwnerID == userID {
+		return AccessOwner, nil
+	}
+
+	permission, err := FolderSharePermission(db, folder.ID, userID)
+	if err != nil {
+		return AccessNone, err
+	}
+	return accessForPermission(permission), nil
+}
+
+// FileAccessLevel resolves the user's access to a file from ownership, the
+// file's ACL, direct shares with the user and shares on the folders
+// containing it. This is the single place file access decisions are made.
+func FileAccessLevel(db *gorm.DB, file *models.File, userID uuid.UUID) (AccessLevel, error) {
+	if file.OwnerID == userID {
+		return AccessOwner, nil
+	}
+
+	level := AccessNone
+
+	var permissions []models.SharePermission
+	if err := db.Model(&models.FileACLEntry{}).
+		Where("file_id = ?", file.ID).
+		Where("(grantee_type = ? AND user_id = ?) OR grantee_type = ?", models.ACLGranteeUser, userID, models.ACLGranteeAuthenticated).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Pluck("permission", &permissions).Error; err != nil {
+		return AccessNone, fmt.Errorf("error resolving file access list: %w", err)
+	}
+	// Sharing a file writes a matching ACL entry; shares made before there
+	// were ACLs only have the share
+	var shared []models.SharePermission
+	if err := db.Model(&models.FileShare{}).
+		Where("file_id = ? AND shared_with = ? AND is_active = true", file.ID, userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Pluck("permission", &shared).Error; err != nil {
+		return AccessNone, fmt.Errorf("error resolving file shares: %w", err)
+	}
+	permissions = append(permissions, shared...)
+
+	for _, permission := range permissions {
+		if granted := accessForPermission(permission); granted.Rank() > level.Rank() {
+			level = granted
+		}
+	}
+
+	if file.FolderID != nil {
+		permission, err := FolderSharePermission(db, *file.FolderID, userID)
+		if err != nil {
+			return AccessNone, err
+		}
+		if granted := accessForPermission(permission); granted.Rank() > level.Rank() {
+			level = granted
+		}
+	}
+
+	return level, nil
+}
+
+// FindFileWithAccess loads a non-deleted file the user holds at least the
+// minimum access to, returning gorm.ErrRecordNotFound when they don't so
+// inaccessible files are indistinguishable from missing ones
+func FindFileWithAccess(db *gorm.DB, fileID interface{}, userID uuid.UUID, minimum AccessLevel, preloads ...string) (*models.File, error) {
+	query := db.Where("id = ? AND is_deleted = false", fileID)
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	var file models.File
+	if err := query.First(&file).Error; err != nil {
+		return nil, err
+	}
+
+	level, err := FileAccessLevel(db, &file, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !level.Allows(minimum) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &file, nil
+}
+
+// FindFolderWithAccess loads a folder the user holds at least the minimum
+// access to, returning gorm.ErrRecordNotFound when they don't
+func FindFolderWithAccess(db *gorm.DB, folderID interface{}, userID uuid.UUID, minimum AccessLevel) (*models.Folder, error) {
+	var folder models.Folder
+	if err := db.Where("id = ?", folderID).First(&folder).Error; err != nil {
+		return nil, err
+	}
+
+	level, err := FolderAccessLevel(db, &folder, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !level.Allows(minimum) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &folder, nil
+}
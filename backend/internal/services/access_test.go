@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/testdb"
+)
+
+func TestDirectShareExpires(t *testing.T) {
+	db := testdb.Open(t)
+	owner := createTestUser(t, db)
+	recipient := createTestUser(t, db)
+	file := createTestFile(t, db, owner.ID)
+
+	expiresAt := time.Now().Add(time.Hour)
+	share, err := NewSharingService(db, config.Load()).ShareFileWithUser(ShareFileRequest{
+		FileID:     file.ID,
+		SharedBy:   owner.ID,
+		Email:      recipient.Email,
+		ExpiresAt:  &expiresAt,
+		Permission: models.PermissionView,
+	})
+	if err != nil {
+		t.Fatalf("ShareFileWithUser: %v", err)
+	}
+
+	level, err := FileAccessLevel(db, &file, recipient.ID)
+	if err != nil {
+		t.Fatalf("FileAccessLevel: %v", err)
+	}
+	if level != AccessRead {
+		t.Fatalf("access before expiry = %q, want %q", level, AccessRead)
+	}
+
+	// Let the expiry pass for the share and the access list entry it wrote
+	expired := time.Now().Add(-time.Minute)
+	if err := db.Model(&models.FileShare{}).Where("id = ?", share.ID).Update("expires_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.FileACLEntry{}).Where("file_id = ? AND user_id = ?", file.ID, recipient.ID).Update("expires_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	level, err = FileAccessLevel(db, &file, recipient.ID)
+	if err != nil {
+		t.Fatalf("FileAccessLevel: %v", err)
+	}
+	if level != AccessNone {
+		t.Errorf("access after expiry = %q, want %q", level, AccessNone)
+	}
+	if _, err := FindFileWithAccess(db, file.ID, recipient.ID, AccessRead); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindFileWithAccess after expiry error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+}
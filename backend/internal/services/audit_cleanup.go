@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// AuditLogCleaner deletes audit log entries older than the retention period,
+// including the client IP addresses and user agents they hold
+type AuditLogCleaner struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewAuditLogCleaner creates an audit log cleaner
+func NewAuditLogCleaner(db *gorm.DB, cfg *config.Config) *AuditLogCleaner {
+	return &AuditLogCleaner{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// RunJob deletes expired entries in batches until none are left or the batch
+// budget is spent
+func (a *AuditLogCleaner) RunJob(ctx context.Context, limits JobLimits) error {
+	if a.cfg.AuditRetentionDays <= 0 {
+		return nil
+	}
+	batchSize := limits.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	cutoff := time.Now().AddDate(0, 0, -a.cfg.AuditRetentionDays)
+
+	for batch := 0; limits.MaxBatches <= 0 || batch < limits.MaxBatches; batch++ {
+		if batch > 0 {
+			if err := limits.Pause(ctx); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		expired := a.db.Model(&models.AuditLog{}).Select("id").
+			Where("created_at < ?", cutoff).
+			Limit(batchSize)
+		result := a.db.Where("id IN (?)", expired).Delete(&models.AuditLog{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete expired audit logs: %w", result.Error)
+		}
+		if result.RowsAffected < int64(batchSize) {
+			return nil
+		}
+	}
+
+	return nil
+}
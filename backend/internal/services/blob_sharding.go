@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// BlobStoragePath returns where the blob for hash is kept, relative to the
+// storage path. Blobs are sharded by the first two byte pairs of their hash,
+// as storage/ab/cd/abcd..., so no single directory grows to hundreds of
+// thousands of entries.
+func BlobStoragePath(hash string) string {
+	if len(hash) < 4 {
+		return flatBlobStoragePath(hash)
+	}
+	return path.Join("storage", hash[:2], hash[2:4], hash)
+}
+
+// flatBlobStoragePath is where blobs were kept before sharding
+func flatBlobStoragePath(hash string) string {
+	return path.Join("storage", hash)
+}
+
+// BlobShardingOptions controls a blob sharding run
+type BlobShardingOptions struct {
+	DryRun bool // report what would move without touching storage or the database
+	Limit  int  // stop after relocating this many blobs, 0 = no limit
+}
+
+// BlobShardingReport summarizes a blob sharding run
+type BlobShardingReport struct {
+	DryRun    bool `json:"dry_run"`
+	Scanned   int  `json:"scanned"`
+	Relocated int  `json:"relocated"`
+	Skipped   int  `json:"skipped"` // files in the flat directory that aren't blobs
+	Failed    int  `json:"failed"`
+}
+
+// ShardFlatBlobs relocates blobs from the flat storage/{hash} layout to their
+// sharded location and points their FileHash at it. Each blob is linked into
+// place before its record changes and only then removed from the flat
+// directory, so it stays readable throughout and an interrupted run can
+// simply be started again.
+func ShardFlatBlobs(ctx context.Context, db *gorm.DB, cfg *config.Config, opts BlobShardingOptions) (*BlobShardingReport, error) {
+	report := &BlobShardingReport{DryRun: opts.DryRun}
+	if cfg.StorageBackend == S3StorageBackend {
+		return report, fmt.Errorf("blobs are kept in object storage, there is nothing to shard")
+	}
+
+	dir, err := os.Open(filepath.Join(cfg.StoragePath, "storage"))
+	if os.IsNotExist(err) {
+		return report, nil
+	} else if err != nil {
+		return report, fmt.Errorf("failed to open storage directory: %w", err)
+	}
+	defer dir.Close()
+
+	for {
+		// Read in batches; a flat directory can hold more names than are
+		// worth loading at once
+		entries, err := dir.ReadDir(1000)
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			if opts.Limit > 0 && report.Relocated >= opts.Limit {
+				return report, nil
+			}
+			if entry.IsDir() {
+				continue
+			}
+
+			report.Scanned++
+			hash := entry.Name()
+			if !isBlobHash(hash) {
+				report.Skipped++
+				continue
+			}
+			if opts.DryRun {
+				report.Relocated++
+				continue
+			}
+			if err := shardBlob(db, cfg.StoragePath, hash); err != nil {
+				report.Failed++
+				log.Printf("Failed to shard blob %s: %v", hash, err)
+				continue
+			}
+			report.Relocated++
+		}
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read storage directory: %w", err)
+		}
+	}
+}
+
+// shardBlob moves one flat blob to its sharded location. Records are matched
+// by path rather than hash: colliding content is stored under a key of its
+// own.
+func shardBlob(db *gorm.DB, storagePath, hash string) error {
+	flat := filepath.Join(storagePath, filepath.FromSlash(flatBlobStoragePath(hash)))
+	sharded := filepath.Join(storagePath, filepath.FromSlash(BlobStoragePath(hash)))
+	if err := os.MkdirAll(filepath.Dir(sharded), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Content is keyed by its hash, so a blob already at the sharded
+	// location holds the same content and wins
+	if _, err := os.Stat(sharded); os.IsNotExist(err) {
+		if err := os.Link(flat, sharded); err != nil {
+			return fmt.Errorf("failed to link blob into place: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to check sharded blob: %w", err)
+	}
+
+	if err := db.Model(&models.FileHash{}).
+		Where("storage_path = ?", flatBlobStoragePath(hash)).
+		Update("storage_path", BlobStoragePath(hash)).Error; err != nil {
+		return fmt.Errorf("failed to update storage path: %w", err)
+	}
+
+	if err := os.Remove(flat); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove flat blob: %w", err)
+	}
+	return nil
+}
+
+// isBlobHash reports whether a name in the flat directory is a blob key: a
+// hex-encoded SHA-256. Temporary files from interrupted writes aren't.
+func isBlobHash(name string) bool {
+	if len(name) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
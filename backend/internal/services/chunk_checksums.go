@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// ChunkMismatchError reports a chunk of a blob whose content no longer
+// matches the checksum recorded when it was stored
+type ChunkMismatchError struct {
+	Hash  string
+	Chunk int64
+}
+
+func (e *ChunkMismatchError) Error() string {
+	return fmt.Sprintf("chunk %d of blob %s does not match its checksum", e.Chunk, e.Hash)
+}
+
+// ComputeChunkChecksums reads a blob and returns the SHA-256 of each
+// chunkSize bytes of it, concatenated
+func ComputeChunkChecksums(path string, chunkSize int64) ([]byte, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var checksums bytes.Buffer
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			checksums.Write(sum[:])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return checksums.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ParseByteRanges reads a Range header such as "bytes=0-99,-500" against a
+// blob of size bytes, returning inclusive [start, end] offsets. Headers it
+// can't satisfy return nil and are left for the file server to reject.
+func ParseByteRanges(header string, size int64) [][2]int64 {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || size <= 0 {
+		return nil
+	}
+
+	var ranges [][2]int64
+	for _, part := range strings.Split(spec, ",") {
+		startText, endText, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil
+		}
+
+		var start, end int64
+		if startText == "" {
+			// Suffix range: the last n bytes
+			n, err := strconv.ParseInt(endText, 10, 64)
+			if err != nil || n <= 0 {
+				return nil
+			}
+			start, end = size-n, size-1
+			if start < 0 {
+				start = 0
+			}
+		} else {
+			var err error
+			start, err = strconv.ParseInt(startText, 10, 64)
+			if err != nil || start < 0 || start >= size {
+				return nil
+			}
+			end = size - 1
+			if endText != "" {
+				end, err = strconv.ParseInt(endText, 10, 64)
+				if err != nil || end < start {
+					return nil
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+		}
+		ranges = append(ranges, [2]int64{start, end})
+	}
+	return ranges
+}
+
+// ChunkVerifier checks the chunks of a blob a range request is about to read
+// against their recorded checksums, so corruption inside a large blob is
+// caught without hashing all of it
+type ChunkVerifier struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewChunkVerifier creates a verifier for served byte ranges
+func NewChunkVerifier(db *gorm.DB, cfg *config.Config) *ChunkVerifier {
+	return &ChunkVerifier{db: db, cfg: cfg}
+}
+
+// VerifyRequest verifies the chunks covered by a Range header. Requests
+// without a range, and blobs stored without chunk checksums, pass unchecked.
+// A mismatch marks the blob corrupt so the scrubber and admins see it.
+func (v *ChunkVerifier) VerifyRequest(fileHash *models.FileHash, blobPath, rangeHeader string) error {
+	if !v.cfg.ChunkChecksums || rangeHeader == "" || fileHash == nil ||
+		fileHash.ChunkSize <= 0 || len(fileHash.ChunkChecksums) == 0 {
+		return nil
+	}
+
+	ranges := ParseByteRanges(rangeHeader, fileHash.Size)
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(blobPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	verified := make(map[int64]bool)
+	buf := make([]byte, fileHash.ChunkSize)
+	for _, byteRange := range ranges {
+		for chunk := byteRange[0] / fileHash.ChunkSize; chunk <= byteRange[1]/fileHash.ChunkSize; chunk++ {
+			if verified[chunk] {
+				continue
+			}
+			if err := v.verifyChunk(file, fileHash, chunk, buf); err != nil {
+				return err
+			}
+			verified[chunk] = true
+		}
+	}
+	return nil
+}
+
+// verifyChunk hashes one chunk of the blob and compares it to its checksum
+func (v *ChunkVerifier) verifyChunk(file *os.File, fileHash *models.FileHash, chunk int64, buf []byte) error {
+	offset := chunk * sha256.Size
+	if offset+sha256.Size > int64(len(fileHash.ChunkChecksums)) {
+		return v.markCorrupt(fileHash, chunk)
+	}
+
+	n, err := file.ReadAt(buf, chunk*fileHash.ChunkSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	sum := sha256.Sum256(buf[:n])
+	if !bytes.Equal(sum[:], fileHash.ChunkChecksums[offset:offset+sha256.Size]) {
+		return v.markCorrupt(fileHash, chunk)
+	}
+	return nil
+}
+
+// markCorrupt records the blob as corrupt and returns the mismatch
+func (v *ChunkVerifier) markCorrupt(fileHash *models.FileHash, chunk int64) error {
+	if err := v.db.Model(&models.FileHash{}).Where("id = ?", fileHash.ID).
+		Update("integrity_status", models.IntegrityCorrupt).Error; err != nil {
+		return fmt.Errorf("failed to mark blob %s corrupt: %w", fileHash.Hash, err)
+	}
+	return &ChunkMismatchError{Hash: fileHash.Hash, Chunk: chunk}
+}
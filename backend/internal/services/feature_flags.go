@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// Known feature flag keys
+const (
+	FeatureRegistration = "registration"
+	FeatureSharing      = "sharing"
+	FeaturePublicLinks  = "public_links"
+)
+
+// ErrUnknownFeatureFlag is returned when updating a flag that doesn't exist
+var ErrUnknownFeatureFlag = errors.New("unknown feature flag")
+
+// defaultFeatureFlags apply until the flags are loaded, and for keys missing
+// from the database
+var defaultFeatureFlags = map[string]bool{
+	FeatureRegistration: true,
+	FeatureSharing:      true,
+	FeaturePublicLinks:  true,
+}
+
+// FeatureFlagService caches feature flags in memory, refreshing them
+// periodically so changes made on other instances are picked up
+type FeatureFlagService struct {
+	db  *gorm.DB
+	cfg *config.Config
+
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// NewFeatureFlagService creates a feature flag service and loads the flags
+func NewFeatureFlagService(db *gorm.DB, cfg *config.Config) *FeatureFlagService {
+	s := &FeatureFlagService{
+		db:    db,
+		cfg:   cfg,
+		flags: make(map[string]bool),
+	}
+	if err := s.Refresh(); err != nil {
+		log.Printf("Failed to load feature flags, using defaults: %v", err)
+	}
+	return s
+}
+
+// Start refreshes the cached flags every configured interval until ctx is cancelled
+func (s *FeatureFlagService) Start(ctx context.Context) {
+	if s.cfg.FeatureFlagRefreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.cfg.FeatureFlagRefreshInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(); err != nil {
+					log.Printf("Failed to refresh feature flags: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh reloads all flags from the database
+func (s *FeatureFlagService) Refresh() error {
+	var flags []models.FeatureFlag
+	if err := s.db.Find(&flags).Error; err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	loaded := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		loaded[flag.Key] = flag.Enabled
+	}
+
+	s.mu.Lock()
+	s.flags = loaded
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsEnabled reports whether a feature is enabled. Unknown keys are enabled
+// unless a default says otherwise.
+func (s *FeatureFlagService) IsEnabled(key string) bool {
+	s.mu.RLock()
+	enabled, ok := s.flags[key]
+	s.mu.RUnlock()
+	if ok {
+		return enabled
+	}
+
+	if enabled, ok := defaultFeatureFlags[key]; ok {
+		return enabled
+	}
+	return true
+}
+
+// List returns all flags stored in the database
+func (s *FeatureFlagService) List() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	if err := s.db.Order("key ASC").Find(&flags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// Set enables or disables a known flag and updates the cache immediately
+func (s *FeatureFlagService) Set(key string, enabled bool, updatedBy uuid.UUID) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	err := s.db.Where("key = ?", key).First(&flag).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, known := defaultFeatureFlags[key]; !known {
+			return nil, ErrUnknownFeatureFlag
+		}
+		flag = models.FeatureFlag{Key: key}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+
+	flag.Enabled = enabled
+	flag.UpdatedBy = &updatedBy
+	if err := s.db.Save(&flag).Error; err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	s.mu.Lock()
+	s.flags[key] = enabled
+	s.mu.Unlock()
+
+	return &flag, nil
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// FileACLGrant describes one access grant to add to a file's ACL. User
+// grantees are identified by UserID or Email.
+type FileACLGrant struct {
+	GranteeType models.ACLGranteeType  `json:"grantee_type"`
+	UserID      *uuid.UUID             `json:"user_id"`
+	Email       string                 `json:"email"`
+	Permission  models.SharePermission `json:"permission"`
+	ExpiresAt   *time.Time             `json:"expires_at"`
+}
+
+// ListFileACL returns the ACL entries on a file the user owns
+func (s *SharingService) ListFileACL(fileID uuid.UUID, ownerID uuid.UUID) ([]models.FileACLEntry, error) {
+	if _, err := FindFileWithAccess(s.db, fileID, ownerID, AccessOwner); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("file not found or you don't have permission to view its access list")
+		}
+		return nil, fmt.Errorf("error finding file: %w", err)
+	}
+
+	var entries []models.FileACLEntry
+	err := s.db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, username, email, first_name, last_name")
+	}).Where("file_id = ?", fileID).Order("created_at ASC").Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting file access list: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GrantFileACL adds or updates several grants on a file the user owns in one
+// transaction. A grantee that already has an entry has it replaced.
+func (s *SharingService) GrantFileACL(fileID uuid.UUID, ownerID uuid.UUID, grants []FileACLGrant) ([]models.FileACLEntry, error) {
+	if _, err := FindFileWithAccess(s.db, fileID, ownerID, AccessOwner); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("file not found or you don't have permission to share it")
+		}
+		return nil, fmt.Errorf("error finding file: %w", err)
+	}
+
+	now := time.Now()
+	entries := make([]models.FileACLEntry, 0, len(grants))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, grant := range grants {
+			switch grant.Permission {
+			case models.PermissionView, models.PermissionDownload, models.PermissionEdit:
+			default:
+				return fmt.Errorf("entry %d: invalid permission %q", i, grant.Permission)
+			}
+			if grant.ExpiresAt != nil && !grant.ExpiresAt.After(now) {
+				return fmt.Errorf("entry %d: expiration date must be in the future", i)
+			}
+
+			entry := models.FileACLEntry{
+				FileID:      fileID,
+				GranteeType: grant.GranteeType,
+				Permission:  grant.Permission,
+				ExpiresAt:   grant.ExpiresAt,
+				GrantedBy:   ownerID,
+			}
+
+			switch grant.GranteeType {
+			case models.ACLGranteeAuthenticated:
+			case models.ACLGranteeUser:
+				var user models.User
+				query := tx.Select("id")
+				if grant.UserID != nil {
+					query = query.Where("id = ?", *grant.UserID)
+				} else if grant.Email != "" {
+					query = query.Where("email = ?", grant.Email)
+				} else {
+					return fmt.Errorf("entry %d: user_id or email is required", i)
+				}
+				if err := query.First(&user).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return fmt.Errorf("entry %d: user not found", i)
+					}
+					return fmt.Errorf("error finding user: %w", err)
+				}
+				if user.ID == ownerID {
+					return fmt.Errorf("entry %d: you already own this file", i)
+				}
+				entry.UserID = &user.ID
+			default:
+				return fmt.Errorf("entry %d: invalid grantee type %q", i, grant.GranteeType)
+			}
+
+			if err := upsertFileACLEntry(tx, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// RevokeFileACL removes an entry from a file the user owns. Removing a user
+// entry also deactivates that user's file share so the two stay in step.
+func (s *SharingService) RevokeFileACL(fileID uuid.UUID, entryID uuid.UUID, ownerID uuid.UUID) error {
+	if _, err := FindFileWithAccess(s.db, fileID, ownerID, AccessOwner); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("file not found or you don't have permission to change its access list")
+		}
+		return fmt.Errorf("error finding file: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var entry models.FileACLEntry
+		if err := tx.Where("id = ? AND file_id = ?", entryID, fileID).First(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("access entry not found")
+			}
+			return fmt.Errorf("error finding access entry: %w", err)
+		}
+
+		if err := tx.Delete(&entry).Error; err != nil {
+			return fmt.Errorf("error removing access entry: %w", err)
+		}
+
+		if entry.UserID != nil {
+			if err := tx.Model(&models.FileShare{}).
+				Where("file_id = ? AND shared_with = ?", fileID, *entry.UserID).
+				Update("is_active", false).Error; err != nil {
+				return fmt.Errorf("error revoking file share: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// upsertFileACLEntry stores entry, replacing the grantee's existing entry on
+// the same file if there is one
+func upsertFileACLEntry(tx *gorm.DB, entry *models.FileACLEntry) error {
+	query := tx.Where("file_id = ? AND grantee_type = ?", entry.FileID, entry.GranteeType)
+	if entry.UserID != nil {
+		query = query.Where("user_id = ?", *entry.UserID)
+	}
+
+	var existing models.FileACLEntry
+	err := query.First(&existing).Error
+	if err == nil {
+		if err := tx.Model(&existing).Updates(map[string]interface{}{
+			"permission": entry.Permission,
+			"expires_at": entry.ExpiresAt,
+			"granted_by": entry.GrantedBy,
+		}).Error; err != nil {
+			return fmt.Errorf("error updating access entry: %w", err)
+		}
+		entry.BaseModel = existing.BaseModel
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error finding access entry: %w", err)
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("error creating access entry: %w", err)
+	}
+	return nil
+}
+
+// revokeUserFileACL removes the user's entry on a file, if any
+func revokeUserFileACL(tx *gorm.DB, fileID uuid.UUID, userID uuid.UUID) error {
+	if err := tx.Where("file_id = ? AND grantee_type = ? AND user_id = ?", fileID, models.ACLGranteeUser, userID).
+		Delete(&models.FileACLEntry{}).Error; err != nil {
+		return fmt.Errorf("error removing access entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// MimeCategories groups MIME types for searching. Entries ending in "/" or
+// "." match as prefixes, the rest exactly.
+var MimeCategories = map[string][]string{
+	"image": {"image/"},
+	"video": {"video/"},
+	"audio": {"audio/"},
+	"text":  {"text/"},
+	"document": {
+		"application/pdf", "application/msword", "application/rtf",
+		"application/vnd.ms-excel", "application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.", "application/vnd.oasis.opendocument.",
+	},
+	"archive": {
+		"application/zip", "application/x-tar", "application/gzip", "application/x-gzip",
+		"application/x-7z-compressed", "application/x-rar-compressed", "application/x-bzip2",
+	},
+}
+
+// likeEscaper escapes LIKE wildcards so user input matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes a value for use in a LIKE pattern
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// ValidateSearchCriteria checks criteria before they are run or saved
+func ValidateSearchCriteria(criteria *models.SearchCriteria) error {
+	if len(criteria.Query) > 255 {
+		return fmt.Errorf("query must be at most 255 characters")
+	}
+	if criteria.MimeCategory != "" {
+		if _, ok := MimeCategories[criteria.MimeCategory]; !ok {
+			categories := make([]string, 0, len(MimeCategories))
+			for category := range MimeCategories {
+				categories = append(categories, category)
+			}
+			sort.Strings(categories)
+			return fmt.Errorf("mime category must be one of %s", strings.Join(categories, ", "))
+		}
+	}
+	if criteria.MinSize != nil && *criteria.MinSize < 0 || criteria.MaxSize != nil && *criteria.MaxSize < 0 {
+		return fmt.Errorf("sizes can't be negative")
+	}
+	if criteria.MinSize != nil && criteria.MaxSize != nil && *criteria.MinSize > *criteria.MaxSize {
+		return fmt.Errorf("min size must not exceed max size")
+	}
+	if criteria.CreatedAfter != nil && criteria.CreatedBefore != nil && !criteria.CreatedAfter.Before(*criteria.CreatedBefore) {
+		return fmt.Errorf("created after must be before created before")
+	}
+	return nil
+}
+
+// ApplySearchCriteria narrows a files query to those matching criteria. A
+// fuzzy query also matches names whose trigrams are similar enough to the
+// term, most similar first; both forms are served by the trigram index on
+// original_filename.
+func ApplySearchCriteria(query *gorm.DB, criteria *models.SearchCriteria) *gorm.DB {
+	if term := strings.TrimSpace(criteria.Query); term != "" {
+		pattern := "%" + EscapeLike(term) + "%"
+		if criteria.Fuzzy {
+			query = query.Where("(original_filename ILIKE ? OR ? <% original_filename)", pattern, term).
+				Order(clause.OrderBy{Expression: clause.Expr{SQL: "word_similarity(?, original_filename) DESC", Vars: []interface{}{term}}})
+		} else {
+			query = query.Where("original_filename ILIKE ?", pattern)
+		}
+	}
+
+	if patterns, ok := MimeCategories[criteria.MimeCategory]; ok {
+		conditions := make([]string, 0, len(patterns))
+		args := make([]interface{}, 0, len(patterns))
+		for _, pattern := range patterns {
+			if strings.HasSuffix(pattern, "/") || strings.HasSuffix(pattern, ".") {
+				conditions = append(conditions, "mime_type LIKE ?")
+				args = append(args, EscapeLike(pattern)+"%")
+			} else {
+				conditions = append(conditions, "mime_type = ?")
+				args = append(args, pattern)
+			}
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	if len(criteria.Tags) > 0 {
+		query = query.Where("tags @> ARRAY[?]::text[]", criteria.Tags)
+	}
+	if criteria.MinSize != nil {
+		query = query.Where("size >= ?", *criteria.MinSize)
+	}
+	if criteria.MaxSize != nil {
+		query = query.Where("size <= ?", *criteria.MaxSize)
+	}
+	if criteria.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *criteria.CreatedAfter)
+	}
+	if criteria.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *criteria.CreatedBefore)
+	}
+	return query
+}
+
+// SetFuzzyThreshold sets how similar, from 0 to 1, a name must be to match a
+// fuzzy query for the rest of the transaction
+func SetFuzzyThreshold(tx *gorm.DB, threshold float64) error {
+	if err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)",
+		strconv.FormatFloat(threshold, 'f', -1, 64)).Error; err != nil {
+		return fmt.Errorf("failed to set fuzzy search threshold: %w", err)
+	}
+	return nil
+}
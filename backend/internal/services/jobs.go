@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownJob is returned when addressing a job that isn't registered
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning is returned when triggering a job that is already running
+	ErrJobRunning = errors.New("job already running")
+)
+
+// JobLimits bounds how much work a single job pass may do
+type JobLimits struct {
+	BatchSize  int           // items processed per batch
+	MaxBatches int           // batches per pass, 0 = until there is no work left
+	BatchPause time.Duration // sleep between batches
+}
+
+// Pause sleeps between batches, returning early with ctx's error if it is
+// cancelled
+func (l JobLimits) Pause(ctx context.Context) error {
+	if l.BatchPause <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(l.BatchPause)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// JobFunc runs one pass of a background job within the given limits. It must
+// stop promptly when ctx is cancelled, which happens when the job's allowed
+// time window closes.
+type JobFunc func(ctx context.Context, limits JobLimits) error
+
+// JobConfig controls when and how hard a background job runs
+type JobConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	Window   TimeWindow
+	Limits   JobLimits
+}
+
+// JobStatus reports the configuration and state of a registered job
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Enabled        bool       `json:"enabled"`
+	Paused         bool       `json:"paused"`
+	Running        bool       `json:"running"`
+	Interval       string     `json:"interval"`
+	Window         string     `json:"window"`
+	InWindow       bool       `json:"in_window"`
+	BatchSize      int        `json:"batch_size"`
+	MaxBatches     int        `json:"max_batches"`
+	BatchPauseMs   int64      `json:"batch_pause_ms"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+type scheduledJob struct {
+	name   string
+	config JobConfig
+	run    JobFunc
+
+	paused         bool
+	running        bool
+	lastStartedAt  *time.Time
+	lastFinishedAt *time.Time
+	lastError      string
+}
+
+// JobScheduler runs registered background jobs on their intervals, only
+// within their allowed time windows and never overlapping with themselves
+type JobScheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*scheduledJob
+	ctx  context.Context
+}
+
+// NewJobScheduler creates an empty job scheduler
+func NewJobScheduler() *JobScheduler {
+	return &JobScheduler{
+		jobs: make(map[string]*scheduledJob),
+		ctx:  context.Background(),
+	}
+}
+
+// Register adds a job; jobs must be registered before Start
+func (s *JobScheduler) Register(name string, config JobConfig, run JobFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[name] = &scheduledJob{
+		name:   name,
+		config: config,
+		run:    run,
+	}
+}
+
+// Start schedules every enabled job until ctx is cancelled
+func (s *JobScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	jobs := make([]*scheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		if !job.config.Enabled || job.config.Interval <= 0 {
+			log.Printf("Background job %s disabled", job.name)
+			continue
+		}
+		go s.loop(ctx, job)
+	}
+}
+
+// loop runs job on every tick that falls inside its window
+func (s *JobScheduler) loop(ctx context.Context, job *scheduledJob) {
+	ticker := time.NewTicker(job.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			paused := job.paused
+			s.mu.Unlock()
+			if paused || !job.config.Window.Contains(now) {
+				continue
+			}
+
+			// Stop the pass when the window closes
+			var runCtx context.Context
+			var cancel context.CancelFunc
+			if job.config.Window.Restricted() {
+				runCtx, cancel = context.WithDeadline(ctx, job.config.Window.End(now))
+			} else {
+				runCtx, cancel = context.WithCancel(ctx)
+			}
+			if err := s.execute(runCtx, job); err != nil && !errors.Is(err, ErrJobRunning) {
+				log.Printf("Background job %s failed: %v", job.name, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// execute runs one pass of job unless it is already running
+func (s *JobScheduler) execute(ctx context.Context, job *scheduledJob) error {
+	s.mu.Lock()
+	if job.running {
+		s.mu.Unlock()
+		return ErrJobRunning
+	}
+	job.running = true
+	startedAt := time.Now()
+	job.lastStartedAt = &startedAt
+	s.mu.Unlock()
+
+	err := job.run(ctx, job.config.Limits)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The window closed; the next pass picks up where this one stopped
+		err = nil
+	}
+
+	s.mu.Lock()
+	finishedAt := time.Now()
+	job.running = false
+	job.lastFinishedAt = &finishedAt
+	job.lastError = ""
+	if err != nil {
+		job.lastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
+// Trigger starts a pass of the job in the background immediately, regardless
+// of its window or pause state
+func (s *JobScheduler) Trigger(name string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return ErrUnknownJob
+	}
+	running := job.running
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if running {
+		return ErrJobRunning
+	}
+
+	go func() {
+		if err := s.execute(ctx, job); err != nil && !errors.Is(err, ErrJobRunning) {
+			log.Printf("Background job %s failed: %v", name, err)
+		}
+	}()
+	return nil
+}
+
+// SetPaused pauses or resumes scheduled runs of a job. A pass already in
+// progress is not interrupted.
+func (s *JobScheduler) SetPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+	job.paused = paused
+	return nil
+}
+
+// Status returns the state of a single job
+func (s *JobScheduler) Status(name string) (*JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	status := job.status(time.Now())
+	return &status, nil
+}
+
+// List returns the state of every registered job ordered by name
+func (s *JobScheduler) List() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		statuses = append(statuses, job.status(now))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// status snapshots the job; the scheduler lock must be held
+func (j *scheduledJob) status(now time.Time) JobStatus {
+	return JobStatus{
+		Name:           j.name,
+		Enabled:        j.config.Enabled,
+		Paused:         j.paused,
+		Running:        j.running,
+		Interval:       j.config.Interval.String(),
+		Window:         j.config.Window.String(),
+		InWindow:       j.config.Window.Contains(now),
+		BatchSize:      j.config.Limits.BatchSize,
+		MaxBatches:     j.config.Limits.MaxBatches,
+		BatchPauseMs:   j.config.Limits.BatchPause.Milliseconds(),
+		LastStartedAt:  j.lastStartedAt,
+		LastFinishedAt: j.lastFinishedAt,
+		LastError:      j.lastError,
+	}
+}
+
+// TimeWindow is a daily window of local time, such as 22:00-06:00. The zero
+// value allows any time.
+type TimeWindow struct {
+	start, end int // minutes since midnight
+	set        bool
+}
+
+// ParseTimeWindow parses "HH:MM-HH:MM"; an empty string allows any time
+func ParseTimeWindow(value string) (TimeWindow, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return TimeWindow{}, nil
+	}
+
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", value)
+	}
+
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", value, err)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", value, err)
+	}
+	if start == end {
+		return TimeWindow{}, nil
+	}
+
+	return TimeWindow{start: start, end: end, set: true}, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(value string) (int, error) {
+	hm := strings.Split(strings.TrimSpace(value), ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	hours, err := strconv.Atoi(hm[0])
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", value)
+	}
+	minutes, err := strconv.Atoi(hm[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// Contains reports whether t falls inside the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	if !w.set {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	// The window wraps past midnight
+	return minute >= w.start || minute < w.end
+}
+
+// Restricted reports whether the window limits when jobs may run
+func (w TimeWindow) Restricted() bool {
+	return w.set
+}
+
+// End returns when the window containing t closes
+func (w TimeWindow) End(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := midnight.Add(time.Duration(w.end) * time.Minute)
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// String formats the window as HH:MM-HH:MM, or "always" when unrestricted
+func (w TimeWindow) String() string {
+	if !w.set {
+		return "always"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// LegacyBlobMigrationOptions controls a legacy blob migration run
+type LegacyBlobMigrationOptions struct {
+	DryRun    bool      // report what would change without touching storage or the database
+	BatchSize int       // files loaded per query
+	Limit     int       // stop after this many legacy blobs, 0 = no limit
+	After     uuid.UUID // resume after this file ID
+	OwnerID   uuid.UUID // only migrate this user's files, uuid.Nil = everyone's
+}
+
+// LegacyBlobMigrationReport summarizes a legacy blob migration run
+type LegacyBlobMigrationReport struct {
+	DryRun         bool      `json:"dry_run"`
+	Scanned        int       `json:"scanned"`
+	Migrated       int       `json:"migrated"`
+	Deduplicated   int       `json:"deduplicated"`
+	Failed         int       `json:"failed"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+	LastFileID     uuid.UUID `json:"last_file_id"`
+}
+
+// LegacyBlobMigrator moves blobs stored under their file ID to content-hash
+// storage, linking each file to the FileHash for its content so duplicates
+// share one blob
+type LegacyBlobMigrator struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewLegacyBlobMigrator creates a legacy blob migrator
+func NewLegacyBlobMigrator(db *gorm.DB, cfg *config.Config) *LegacyBlobMigrator {
+	return &LegacyBlobMigrator{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Run migrates legacy blobs in file ID order. Each file is migrated on its
+// own and the legacy blob is only removed once the database points at the
+// new location, so an interrupted run can simply be started again.
+func (m *LegacyBlobMigrator) Run(ctx context.Context, opts LegacyBlobMigrationOptions) (*LegacyBlobMigrationReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	report := &LegacyBlobMigrationReport{DryRun: opts.DryRun, LastFileID: opts.After}
+	affectedOwners := make(map[uuid.UUID]bool)
+	found := 0
+
+	for {
+		var files []models.File
+		query := m.db.Order("id ASC").Limit(opts.BatchSize)
+		if report.LastFileID != uuid.Nil {
+			query = query.Where("id > ?", report.LastFileID)
+		}
+		if opts.OwnerID != uuid.Nil {
+			query = query.Where("owner_id = ?", opts.OwnerID)
+		}
+		if err := query.Find(&files).Error; err != nil {
+			return report, fmt.Errorf("failed to load files: %w", err)
+		}
+		if len(files) == 0 {
+			break
+		}
+
+		for i := range files {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+
+			file := &files[i]
+			report.Scanned++
+			report.LastFileID = file.ID
+
+			legacyPath := filepath.Join(m.cfg.StoragePath, file.ID.String())
+			if _, err := os.Stat(legacyPath); err != nil {
+				continue
+			}
+
+			found++
+			deduplicated, reclaimed, err := m.migrateFile(file, legacyPath, opts.DryRun)
+			if err != nil {
+				report.Failed++
+				log.Printf("Failed to migrate legacy blob for file %s: %v", file.ID, err)
+			} else {
+				report.Migrated++
+				report.ReclaimedBytes += reclaimed
+				if deduplicated {
+					report.Deduplicated++
+				}
+				affectedOwners[file.OwnerID] = true
+			}
+
+			if opts.Limit > 0 && found >= opts.Limit {
+				return report, m.recalculateOwners(affectedOwners, opts.DryRun)
+			}
+		}
+	}
+
+	return report, m.recalculateOwners(affectedOwners, opts.DryRun)
+}
+
+// migrateFile relinks one file to hash-named storage. It reports whether the
+// content was already stored and how many bytes were reclaimed as a result.
+func (m *LegacyBlobMigrator) migrateFile(file *models.File, legacyPath string, dryRun bool) (bool, int64, error) {
+	hash, size, err := hashBlob(legacyPath)
+	if err != nil {
+		return false, 0, err
+	}
+	if size != file.Size {
+		log.Printf("Legacy blob for file %s is %d bytes, file records %d", file.ID, size, file.Size)
+	}
+
+	var existing models.FileHash
+	err = m.db.Where("hash = ?", hash).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, 0, fmt.Errorf("failed to look up content: %w", err)
+	}
+	isNewContent := err == gorm.ErrRecordNotFound
+
+	contentStored := false
+	if !isNewContent {
+		if _, err := os.Stat(filepath.Join(m.cfg.StoragePath, existing.StoragePath)); err == nil {
+			contentStored = true
+		}
+	}
+
+	if dryRun {
+		if contentStored {
+			return true, size, nil
+		}
+		return false, 0, nil
+	}
+
+	target := existing
+	if isNewContent {
+		target = models.FileHash{
+			ID:          uuid.New(),
+			Hash:        hash,
+			Size:        size,
+			StoragePath: BlobStoragePath(hash),
+		}
+	}
+
+	// Place the content before touching the database; the legacy blob stays
+	// until the file points at its new location
+	if !contentStored {
+		if err := linkOrCopy(legacyPath, filepath.Join(m.cfg.StoragePath, target.StoragePath)); err != nil {
+			return false, 0, err
+		}
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if isNewContent {
+			if err := tx.Create(&target).Error; err != nil {
+				return fmt.Errorf("failed to create file hash: %w", err)
+			}
+		}
+
+		if file.FileHashID == target.ID {
+			return nil
+		}
+
+		if err := tx.Model(&models.File{}).Where("id = ?", file.ID).Update("file_hash_id", target.ID).Error; err != nil {
+			return fmt.Errorf("failed to relink file: %w", err)
+		}
+
+		// Only live files hold a reference
+		if !file.IsDeleted {
+			if err := tx.Model(&models.FileHash{}).Where("id = ? AND reference_count > 0", file.FileHashID).
+				Update("reference_count", gorm.Expr("reference_count - 1")).Error; err != nil {
+				return fmt.Errorf("failed to release old content: %w", err)
+			}
+			if err := tx.Model(&models.FileHash{}).Where("id = ?", target.ID).
+				Update("reference_count", gorm.Expr("reference_count + 1")).Error; err != nil {
+				return fmt.Errorf("failed to reference content: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+
+	if err := os.Remove(legacyPath); err != nil {
+		return contentStored, 0, fmt.Errorf("failed to remove legacy blob: %w", err)
+	}
+
+	if contentStored {
+		return true, size, nil
+	}
+	return false, 0, nil
+}
+
+// recalculateOwners recomputes physical usage for owners whose files were
+// relinked, since content they already held no longer counts twice
+func (m *LegacyBlobMigrator) recalculateOwners(owners map[uuid.UUID]bool, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+
+	for ownerID := range owners {
+		if err := m.db.Exec(`
+			UPDATE users SET actual_storage_bytes = (
+				SELECT COALESCE(SUM(file_hashes.size), 0)
+				FROM file_hashes
+				WHERE file_hashes.id IN (
+					SELECT DISTINCT files.file_hash_id FROM files
+					WHERE files.owner_id = users.id AND files.is_deleted = false
+				)
+			)
+			WHERE id = ?`, ownerID).Error; err != nil {
+			return fmt.Errorf("failed to recalculate storage for user %s: %w", ownerID, err)
+		}
+		if err := m.db.Exec("UPDATE users SET saved_bytes = storage_used - actual_storage_bytes WHERE id = ?", ownerID).Error; err != nil {
+			return fmt.Errorf("failed to recalculate savings for user %s: %w", ownerID, err)
+		}
+	}
+	return nil
+}
+
+// hashBlob streams a blob from disk and returns its SHA-256 and size
+func hashBlob(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// linkOrCopy makes the content at src available at dst, hard linking when
+// possible and copying otherwise
+func linkOrCopy(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	// A blob left at dst by an earlier interrupted run is reused only if it
+	// holds the same content
+	if _, err := os.Stat(dst); err == nil {
+		srcHash, _, err := hashBlob(src)
+		if err != nil {
+			return err
+		}
+		if dstHash, _, err := hashBlob(dst); err == nil && dstHash == srcHash {
+			return nil
+		}
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("failed to replace blob: %w", err)
+		}
+	}
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to copy blob: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to move blob into place: %w", err)
+	}
+	return nil
+}
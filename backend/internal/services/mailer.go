@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"file-vault-system/backend/internal/config"
+)
+
+// Mailer sends plain text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer returns a mailer sending through the configured SMTP server, or
+// one that only logs messages when no server is configured, which is enough
+// for development
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.SMTPHost == "" {
+		return logMailer{}
+	}
+	return &smtpMailer{cfg: cfg}
+}
+
+type logMailer struct{}
+
+func (logMailer) Send(to, subject, body string) error {
+	log.Printf("Email to %s (SMTP not configured, not sent): %s\n%s", to, subject, body)
+	return nil
+}
+
+type smtpMailer struct {
+	cfg *config.Config
+}
+
+func (m *smtpMailer) Send(to, subject, body string) error {
+	from, err := mail.ParseAddress(m.cfg.MailFrom)
+	if err != nil {
+		return fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	// Header values can't carry line breaks
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid recipient or subject")
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+	}
+	addr := net.JoinHostPort(m.cfg.SMTPHost, strconv.Itoa(m.cfg.SMTPPort))
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to}, []byte(message.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"file-vault-system/backend/internal/config"
+)
+
+// clamdChunkSize is how much content is sent to clamd per INSTREAM chunk
+const clamdChunkSize = 64 * 1024
+
+// MalwareScanner checks content for malware
+type MalwareScanner interface {
+	// Scan reads content to the end and returns the name of the signature it
+	// matched, or "" when the content is clean
+	Scan(ctx context.Context, content io.Reader) (string, error)
+}
+
+// NewMalwareScanner returns the scanner for the configured mode, or nil when
+// scanning is off
+func NewMalwareScanner(cfg *config.Config) MalwareScanner {
+	if cfg.MalwareScanMode != "reject" && cfg.MalwareScanMode != "quarantine" {
+		return nil
+	}
+	return &ClamdScanner{
+		address: cfg.ClamdAddress,
+		timeout: time.Duration(cfg.MalwareScanTimeout) * time.Second,
+	}
+}
+
+// ClamdScanner streams content to a clamd daemon over its INSTREAM command
+type ClamdScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// Scan sends content to clamd in length-prefixed chunks and parses its verdict
+func (s *ClamdScanner) Scan(ctx context.Context, content io.Reader) (string, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("failed to start clamd scan: %w", err)
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return "", fmt.Errorf("failed to send content to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return "", fmt.Errorf("failed to send content to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read content for scanning: %w", readErr)
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return "", fmt.Errorf("failed to finish clamd scan: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamdReply reads a verdict such as "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND"
+func parseClamdReply(reply string) (string, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd scan failed: %s", result)
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// MimeDecision is the outcome of checking an upload's type against the policy
+type MimeDecision int
+
+const (
+	MimeAllowed    MimeDecision = iota
+	MimeBlocked                 // on the blocklist
+	MimeNotAllowed              // missing from a non-empty allowlist
+)
+
+// MimePolicyService caches the MIME type policy in memory, refreshing it
+// periodically so changes made on other instances are picked up. Until an
+// admin saves a policy, the configured ALLOWED_MIME_TYPES apply with nothing
+// blocked.
+type MimePolicyService struct {
+	db  *gorm.DB
+	cfg *config.Config
+
+	mu     sync.RWMutex
+	policy models.MimePolicy
+}
+
+// NewMimePolicyService creates a MIME policy service and loads the policy
+func NewMimePolicyService(db *gorm.DB, cfg *config.Config) *MimePolicyService {
+	s := &MimePolicyService{
+		db:     db,
+		cfg:    cfg,
+		policy: defaultMimePolicy(cfg),
+	}
+	if err := s.Refresh(); err != nil {
+		log.Printf("Failed to load MIME policy, using ALLOWED_MIME_TYPES: %v", err)
+	}
+	return s
+}
+
+// defaultMimePolicy is the policy in effect until one is saved
+func defaultMimePolicy(cfg *config.Config) models.MimePolicy {
+	return models.MimePolicy{
+		AllowedTypes: normalizeMimePatterns(cfg.AllowedMimeTypes),
+		BlockedTypes: []string{},
+	}
+}
+
+// Start refreshes the cached policy every configured interval until ctx is cancelled
+func (s *MimePolicyService) Start(ctx context.Context) {
+	if s.cfg.MimePolicyRefreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.cfg.MimePolicyRefreshInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(); err != nil {
+					log.Printf("Failed to refresh MIME policy: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh reloads the policy from the database
+func (s *MimePolicyService) Refresh() error {
+	var policy models.MimePolicy
+	err := s.db.First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = defaultMimePolicy(s.cfg)
+	} else if err != nil {
+		return fmt.Errorf("failed to load MIME policy: %w", err)
+	}
+
+	s.mu.Lock()
+	s.policy = policy
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Get returns the policy in effect
+func (s *MimePolicyService) Get() models.MimePolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// Check decides whether uploads of a MIME type are accepted. Blocked types
+// are rejected even when the allowlist is empty.
+func (s *MimePolicyService) Check(mimeType string) MimeDecision {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if utils.MatchesMimeType(mimeType, s.policy.BlockedTypes) {
+		return MimeBlocked
+	}
+	if len(s.policy.AllowedTypes) > 0 && !utils.MatchesMimeType(mimeType, s.policy.AllowedTypes) {
+		return MimeNotAllowed
+	}
+	return MimeAllowed
+}
+
+// Set saves the allowlist and blocklist and updates the cache immediately.
+// An empty allowlist allows any type that isn't blocked.
+func (s *MimePolicyService) Set(allowed, blocked []string, updatedBy uuid.UUID) (*models.MimePolicy, error) {
+	policy := models.MimePolicy{
+		ID:           1,
+		AllowedTypes: normalizeMimePatterns(allowed),
+		BlockedTypes: normalizeMimePatterns(blocked),
+		UpdatedBy:    &updatedBy,
+	}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"allowed_types", "blocked_types", "updated_by", "updated_at"}),
+	}).Create(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save MIME policy: %w", err)
+	}
+	if err := s.db.First(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to load MIME policy: %w", err)
+	}
+
+	s.mu.Lock()
+	s.policy = policy
+	s.mu.Unlock()
+
+	return &policy, nil
+}
+
+// ValidateMimePattern checks that a policy entry is a type/subtype or a
+// type/* wildcard
+func ValidateMimePattern(pattern string) error {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	major, minor, ok := strings.Cut(pattern, "/")
+	if !ok || !isMimeToken(major) || (minor != "*" && !isMimeToken(minor)) {
+		return fmt.Errorf("invalid MIME type %q, expected type/subtype or type/*", pattern)
+	}
+	return nil
+}
+
+// isMimeToken reports whether s is a non-empty MIME type or subtype name
+func isMimeToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$&-^_.+", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeMimePatterns lower-cases and trims entries, dropping blanks and
+// duplicates, so they match the detected types
+func normalizeMimePatterns(patterns []string) []string {
+	normalized := make([]string, 0, len(patterns))
+	seen := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		normalized = append(normalized, pattern)
+	}
+	return normalized
+}
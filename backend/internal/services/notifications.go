@@ -0,0 +1,191 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"file-vault-system/backend/pkg/utils"
+)
+
+// Notification types pushed to connected users
+const (
+	NotificationFileShared          = "file_shared"           // a file was shared with the user
+	NotificationShareLinkDownloaded = "share_link_downloaded" // a share link the user created was downloaded
+	NotificationUploadCompleted     = "upload_completed"      // the user's upload was stored
+)
+
+// notificationTicketTTL is how long a connection ticket can be redeemed
+const notificationTicketTTL = 30 * time.Second
+
+// Notification is an event pushed to a user's live connections
+type Notification struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NotificationSession is the access token a connection was opened with. The
+// connection lasts no longer than the token does.
+type NotificationSession struct {
+	UserID    uuid.UUID
+	TokenHash string    // hash of the access token, so logging out closes its connections
+	ExpiresAt time.Time // when the access token expires
+}
+
+// notificationTicket is a pending ticket and the session it opens
+type notificationTicket struct {
+	session   NotificationSession
+	expiresAt time.Time
+}
+
+// notificationSubscriber is one connection's subscription
+type notificationSubscriber struct {
+	tokenHash string
+	closed    chan struct{}
+	once      sync.Once
+}
+
+// close tells the connection to go away
+func (s *notificationSubscriber) close() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
+
+// NotificationHub fans notifications out to in-process subscribers keyed by
+// user ID, one channel per connection. Slow subscribers drop notifications
+// rather than holding up the request that triggered them.
+//
+// Connections are opened with single-use tickets rather than access tokens,
+// so a token never travels in a URL where it would be logged.
+type NotificationHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan Notification]*notificationSubscriber
+	tickets     map[string]notificationTicket
+}
+
+// NewNotificationHub creates an empty notification hub
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{
+		subscribers: make(map[uuid.UUID]map[chan Notification]*notificationSubscriber),
+		tickets:     make(map[string]notificationTicket),
+	}
+}
+
+// IssueTicket returns a short-lived, single-use ticket that opens a
+// connection for the session
+func (h *NotificationHub) IssueTicket(session NotificationSession) (string, error) {
+	ticket, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Tickets nobody redeemed are dropped as new ones are issued
+	for key, pending := range h.tickets {
+		if now.After(pending.expiresAt) {
+			delete(h.tickets, key)
+		}
+	}
+	h.tickets[utils.HashToken(ticket)] = notificationTicket{session: session, expiresAt: now.Add(notificationTicketTTL)}
+	return ticket, nil
+}
+
+// RedeemTicket returns the session a ticket was issued for, once. Expired
+// and unknown tickets are refused.
+func (h *NotificationHub) RedeemTicket(ticket string) (NotificationSession, bool) {
+	key := utils.HashToken(ticket)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pending, ok := h.tickets[key]
+	if !ok {
+		return NotificationSession{}, false
+	}
+	delete(h.tickets, key)
+
+	now := time.Now()
+	if now.After(pending.expiresAt) || now.After(pending.session.ExpiresAt) {
+		return NotificationSession{}, false
+	}
+	return pending.session, true
+}
+
+// Subscribe returns a channel of notifications for the session, a channel
+// closed when the session is logged out or revoked, and a function that must
+// be called to release the subscription once the connection closes
+func (h *NotificationHub) Subscribe(session NotificationSession) (<-chan Notification, <-chan struct{}, func()) {
+	ch := make(chan Notification, 32)
+	subscriber := &notificationSubscriber{tokenHash: session.TokenHash, closed: make(chan struct{})}
+	userID := session.UserID
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Notification]*notificationSubscriber)
+	}
+	h.subscribers[userID][ch] = subscriber
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, subscriber.closed, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.subscribers[userID], ch)
+			if len(h.subscribers[userID]) == 0 {
+				delete(h.subscribers, userID)
+			}
+		})
+	}
+}
+
+// Disconnect closes the user's connections opened with the access token
+// whose hash is given, or all of them when tokenHash is empty. It is safe to
+// call on a nil hub.
+func (h *NotificationHub) Disconnect(userID uuid.UUID, tokenHash string) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subscriber := range h.subscribers[userID] {
+		if tokenHash == "" || subscriber.tokenHash == tokenHash {
+			subscriber.close()
+		}
+	}
+	for key, pending := range h.tickets {
+		if pending.session.UserID == userID && (tokenHash == "" || pending.session.TokenHash == tokenHash) {
+			delete(h.tickets, key)
+		}
+	}
+}
+
+// Publish delivers a notification to every connection of the user without
+// blocking. It is safe to call on a nil hub.
+func (h *NotificationHub) Publish(userID uuid.UUID, notificationType string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	notification := Notification{Type: notificationType, Data: data, CreatedAt: time.Now()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- notification:
+		default:
+			// The connection is behind; it misses this one
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// RateLimitCleaner deletes rate limit rows whose window ended long ago. The
+// rate limiter keeps a row per user and endpoint, so without it the table
+// grows with every distinct path a user requests.
+type RateLimitCleaner struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewRateLimitCleaner creates a rate limit row cleaner
+func NewRateLimitCleaner(db *gorm.DB, cfg *config.Config) *RateLimitCleaner {
+	return &RateLimitCleaner{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// RunJob deletes stale rows in batches until none are left or the batch
+// budget is spent. A deleted row is simply recreated if its user comes back.
+func (r *RateLimitCleaner) RunJob(ctx context.Context, limits JobLimits) error {
+	batchSize := limits.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	cutoff := time.Now().Add(-time.Duration(r.cfg.RateLimitCleanupAge) * time.Second)
+
+	for batch := 0; limits.MaxBatches <= 0 || batch < limits.MaxBatches; batch++ {
+		if batch > 0 {
+			if err := limits.Pause(ctx); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// The window_duration column holds nanoseconds
+		stale := r.db.Model(&models.APIRateLimit{}).Select("id").
+			Where("window_start + (window_duration / 1000) * interval '1 microsecond' < ?", cutoff).
+			Limit(batchSize)
+		result := r.db.Where("id IN (?)", stale).Delete(&models.APIRateLimit{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete stale rate limits: %w", result.Error)
+		}
+		if result.RowsAffected < int64(batchSize) {
+			return nil
+		}
+	}
+
+	return nil
+}
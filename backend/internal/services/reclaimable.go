@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+)
+
+// ReclaimableCategory is storage one kind of cleanup would free
+type ReclaimableCategory struct {
+	Files int64 `json:"files,omitempty"` // file records removed along the way
+	Blobs int64 `json:"blobs"`
+	Bytes int64 `json:"bytes"`
+}
+
+// ReclaimableStorageReport breaks down the physical storage cleanup could
+// free. The categories don't overlap, so their bytes add up to the total.
+type ReclaimableStorageReport struct {
+	ExpiredTrash       ReclaimableCategory `json:"expired_trash"`       // content only trash past retention holds
+	UnreferencedHashes ReclaimableCategory `json:"unreferenced_hashes"` // content records no file points at
+	OrphanedBlobs      ReclaimableCategory `json:"orphaned_blobs"`      // blobs on disk without a content record
+	TotalBytes         int64               `json:"total_bytes"`
+	TrashRetentionDays int                 `json:"trash_retention_days"`
+	GeneratedAt        time.Time           `json:"generated_at"`
+}
+
+// EstimateReclaimableStorage reports what purging expired trash and
+// collecting unreferenced content would free, without deleting anything
+func EstimateReclaimableStorage(ctx context.Context, db *gorm.DB, cfg *config.Config) (*ReclaimableStorageReport, error) {
+	report := &ReclaimableStorageReport{
+		TrashRetentionDays: cfg.TrashRetentionDays,
+		GeneratedAt:        time.Now(),
+	}
+
+	var err error
+	if report.ExpiredTrash, err = NewTrashPurger(db, cfg).Reclaimable(ctx); err != nil {
+		return nil, err
+	}
+	if report.UnreferencedHashes, err = unreferencedHashes(ctx, db); err != nil {
+		return nil, err
+	}
+	if report.OrphanedBlobs, err = OrphanedBlobs(ctx, db, cfg); err != nil {
+		return nil, err
+	}
+
+	report.TotalBytes = report.ExpiredTrash.Bytes + report.UnreferencedHashes.Bytes + report.OrphanedBlobs.Bytes
+	return report, nil
+}
+
+// unreferencedHashes sums the content records left without any file, live or
+// trashed, or file version pointing at them
+func unreferencedHashes(ctx context.Context, db *gorm.DB) (ReclaimableCategory, error) {
+	var category ReclaimableCategory
+	if err := db.WithContext(ctx).Table("file_hashes").
+		Select("COUNT(*) AS blobs, COALESCE(SUM(size), 0) AS bytes").
+		Where("NOT EXISTS (SELECT 1 FROM files WHERE files.file_hash_id = file_hashes.id)").
+		Where("NOT EXISTS (SELECT 1 FROM file_versions WHERE file_versions.file_hash_id = file_hashes.id)").
+		Scan(&category).Error; err != nil {
+		return category, fmt.Errorf("failed to sum unreferenced content: %w", err)
+	}
+	return category, nil
+}
+
+// orphanGracePeriod is how long a blob on disk must have gone unmodified
+// before it can count as orphaned. An upload stores its blob before the
+// transaction creating its content record commits, so a newer blob may be
+// about to gain one.
+const orphanGracePeriod = time.Hour
+
+// OrphanedBlobs sums the files in the blob and thumbnail directories no
+// content record points at, such as blobs left behind by an interrupted purge
+func OrphanedBlobs(ctx context.Context, db *gorm.DB, cfg *config.Config) (ReclaimableCategory, error) {
+	var category ReclaimableCategory
+	err := walkOrphanedBlobs(ctx, db, cfg, func(path string, info fs.FileInfo) error {
+		category.Blobs++
+		category.Bytes += info.Size()
+		return nil
+	})
+	return category, err
+}
+
+// CollectOrphanedBlobs deletes the files in the blob and thumbnail
+// directories no content record points at and reports what was freed. A
+// file that can't be removed is logged and skipped.
+func CollectOrphanedBlobs(ctx context.Context, db *gorm.DB, cfg *config.Config) (ReclaimableCategory, error) {
+	var category ReclaimableCategory
+	err := walkOrphanedBlobs(ctx, db, cfg, func(path string, info fs.FileInfo) error {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove orphaned blob %s: %v", path, err)
+			return nil
+		}
+		category.Blobs++
+		category.Bytes += info.Size()
+		return nil
+	})
+	return category, err
+}
+
+// walkOrphanedBlobs calls visit for each file in the blob and thumbnail
+// directories that no content record points at and that is older than the
+// grace period
+func walkOrphanedBlobs(ctx context.Context, db *gorm.DB, cfg *config.Config, visit func(path string, info fs.FileInfo) error) error {
+	storagePath := cfg.StoragePath
+
+	var paths []struct {
+		Hash          string
+		StoragePath   string
+		ThumbnailPath string
+	}
+	if err := db.WithContext(ctx).Table("file_hashes").Select("hash, storage_path, thumbnail_path").Scan(&paths).Error; err != nil {
+		return fmt.Errorf("failed to load content paths: %w", err)
+	}
+	referenced := make(map[string]bool, len(paths)*3)
+	for _, p := range paths {
+		// A blob may be at its sharded location while the record still
+		// names its flat one, or the other way round mid-relocation
+		referenced[filepath.Clean(p.StoragePath)] = true
+		referenced[filepath.FromSlash(BlobStoragePath(p.Hash))] = true
+		if p.ThumbnailPath != "" {
+			referenced[filepath.Clean(p.ThumbnailPath)] = true
+		}
+	}
+	cutoff := time.Now().Add(-orphanGracePeriod)
+
+	// Blobs kept in object storage aren't on disk to be scanned
+	dirs := []string{"storage", "thumbnails"}
+	if cfg.StorageBackend == S3StorageBackend {
+		dirs = []string{"thumbnails"}
+	}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(storagePath, dir), func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			relative, err := filepath.Rel(storagePath, path)
+			if err != nil || referenced[relative] {
+				return err
+			}
+			info, err := entry.Info()
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if info.ModTime().After(cutoff) {
+				return nil
+			}
+			return visit(path, info)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReferenceCountCorrection records a FileHash whose reference count didn't
+// match the live files pointing at it
+type ReferenceCountCorrection struct {
+	FileHashID uuid.UUID `json:"file_hash_id"`
+	Hash       string    `json:"hash"`
+	Recorded   int       `json:"recorded"`
+	Actual     int       `json:"actual"`
+}
+
+// ReferenceCountReport summarizes a reference count rebuild
+type ReferenceCountReport struct {
+	DryRun      bool                       `json:"dry_run"`
+	Checked     int64                      `json:"checked"`
+	Corrected   int                        `json:"corrected"`
+	Corrections []ReferenceCountCorrection `json:"corrections"`
+}
+
+// RebuildReferenceCounts recomputes every FileHash reference count from the
+// non-deleted files referencing it, matching the accounting used on upload,
+// delete and restore. Writes to files are blocked for the duration so the
+// counts can't move underneath the rebuild. A dry run only reports.
+func RebuildReferenceCounts(db *gorm.DB, dryRun bool) (*ReferenceCountReport, error) {
+	report := &ReferenceCountReport{DryRun: dryRun, Corrections: []ReferenceCountCorrection{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !dryRun {
+			if err := tx.Exec("LOCK TABLE files IN SHARE MODE").Error; err != nil {
+				return fmt.Errorf("failed to lock files: %w", err)
+			}
+		}
+
+		if err := tx.Table("file_hashes").Count(&report.Checked).Error; err != nil {
+			return fmt.Errorf("failed to count file hashes: %w", err)
+		}
+
+		if err := tx.Raw(`
+			SELECT file_hashes.id AS file_hash_id, file_hashes.hash, file_hashes.reference_count AS recorded,
+				COUNT(files.id) AS actual
+			FROM file_hashes
+			LEFT JOIN files ON files.file_hash_id = file_hashes.id AND files.is_deleted = false
+			GROUP BY file_hashes.id, file_hashes.hash, file_hashes.reference_count
+			HAVING file_hashes.reference_count <> COUNT(files.id)
+			ORDER BY file_hashes.id`).Scan(&report.Corrections).Error; err != nil {
+			return fmt.Errorf("failed to compare reference counts: %w", err)
+		}
+		report.Corrected = len(report.Corrections)
+
+		if dryRun {
+			return nil
+		}
+
+		for _, correction := range report.Corrections {
+			if err := tx.Table("file_hashes").Where("id = ?", correction.FileHashID).
+				Update("reference_count", correction.Actual).Error; err != nil {
+				return fmt.Errorf("failed to correct reference count for %s: %w", correction.FileHashID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
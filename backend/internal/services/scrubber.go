@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// ErrScrubInProgress is returned when a scrub is requested while one is running
+var ErrScrubInProgress = errors.New("integrity scrub already in progress")
+
+// BlobRepairer restores a blob that failed verification, e.g. from a replica
+type BlobRepairer interface {
+	Repair(ctx context.Context, fileHash *models.FileHash) error
+}
+
+// IntegrityScrubber periodically verifies a rolling subset of stored blobs
+// against their recorded SHA-256, least recently verified first
+type IntegrityScrubber struct {
+	db       *gorm.DB
+	cfg      *config.Config
+	repairer BlobRepairer
+	backends *StorageBackends
+	storage  Storage
+
+	mu      sync.Mutex
+	running bool
+	lastRun *models.IntegrityScrubRun
+}
+
+// NewIntegrityScrubber creates an integrity scrubber
+func NewIntegrityScrubber(db *gorm.DB, cfg *config.Config) *IntegrityScrubber {
+	return &IntegrityScrubber{
+		db:      db,
+		cfg:     cfg,
+		storage: NewLocalStorage(cfg.StoragePath),
+	}
+}
+
+// SetStorage sets where the blobs being verified are read from
+func (s *IntegrityScrubber) SetStorage(storage Storage) {
+	s.storage = storage
+}
+
+// SetStorageBackends reports blob reads made while scrubbing to the backend
+// error rates
+func (s *IntegrityScrubber) SetStorageBackends(backends *StorageBackends) {
+	s.backends = backends
+}
+
+// SetRepairer configures how corrupt or missing blobs are repaired
+func (s *IntegrityScrubber) SetRepairer(repairer BlobRepairer) {
+	s.repairer = repairer
+}
+
+// Running reports whether a scrub pass is currently in progress
+func (s *IntegrityScrubber) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// LastRun returns the most recent scrub run, falling back to the database
+// when none has run since the server started
+func (s *IntegrityScrubber) LastRun() (*models.IntegrityScrubRun, error) {
+	s.mu.Lock()
+	lastRun := s.lastRun
+	s.mu.Unlock()
+	if lastRun != nil {
+		return lastRun, nil
+	}
+
+	var run models.IntegrityScrubRun
+	if err := s.db.Order("started_at DESC").First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last scrub run: %w", err)
+	}
+	return &run, nil
+}
+
+// RunOnce verifies the next batch of blobs and records the run
+func (s *IntegrityScrubber) RunOnce(ctx context.Context) (*models.IntegrityScrubRun, error) {
+	return s.run(ctx, JobLimits{BatchSize: s.cfg.ScrubBatchSize, MaxBatches: 1})
+}
+
+// RunJob verifies blobs within the scheduler's limits and records the run
+func (s *IntegrityScrubber) RunJob(ctx context.Context, limits JobLimits) error {
+	_, err := s.run(ctx, limits)
+	if errors.Is(err, ErrScrubInProgress) {
+		return ErrJobRunning
+	}
+	return err
+}
+
+// run verifies up to limits.MaxBatches batches of blobs as a single recorded run
+func (s *IntegrityScrubber) run(ctx context.Context, limits JobLimits) (*models.IntegrityScrubRun, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrScrubInProgress
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	run := &models.IntegrityScrubRun{
+		ID:        uuid.New(),
+		StartedAt: time.Now(),
+	}
+
+	// Every blob is re-verified eventually, so a pass always stops after its
+	// batch budget rather than when work runs out
+	maxBatches := limits.MaxBatches
+	if maxBatches <= 0 {
+		maxBatches = 1
+	}
+
+	var scrubErr error
+	for batch := 0; batch < maxBatches; batch++ {
+		if batch > 0 {
+			if scrubErr = limits.Pause(ctx); scrubErr != nil {
+				break
+			}
+		}
+		if scrubErr = s.scrubBatch(ctx, run, limits.BatchSize); scrubErr != nil {
+			break
+		}
+	}
+	if scrubErr != nil {
+		run.Error = scrubErr.Error()
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	if err := s.db.Create(run).Error; err != nil {
+		log.Printf("Failed to record integrity scrub run: %v", err)
+	}
+
+	s.mu.Lock()
+	s.lastRun = run
+	s.mu.Unlock()
+
+	if run.Corrupt > 0 || run.Missing > 0 {
+		log.Printf("Integrity scrub found %d corrupt and %d missing blobs (%d repaired)", run.Corrupt, run.Missing, run.Repaired)
+	}
+
+	return run, scrubErr
+}
+
+// scrubBatch verifies up to batchSize blobs, updating run counters
+func (s *IntegrityScrubber) scrubBatch(ctx context.Context, run *models.IntegrityScrubRun, batchSize int) error {
+	var fileHashes []models.FileHash
+	if err := s.db.Order("last_verified_at ASC NULLS FIRST").Limit(batchSize).Find(&fileHashes).Error; err != nil {
+		return fmt.Errorf("failed to select blobs to verify: %w", err)
+	}
+
+	for i := range fileHashes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fileHash := &fileHashes[i]
+		status := s.verify(fileHash)
+		run.Checked++
+
+		switch status {
+		case models.IntegrityCorrupt:
+			run.Corrupt++
+		case models.IntegrityMissing:
+			run.Missing++
+		}
+
+		if status != models.IntegrityOK && s.repairer != nil {
+			if err := s.repairer.Repair(ctx, fileHash); err != nil {
+				log.Printf("Failed to repair blob %s: %v", fileHash.Hash, err)
+			} else if s.verify(fileHash) == models.IntegrityOK {
+				status = models.IntegrityRepaired
+				run.Repaired++
+			}
+		}
+
+		if err := RecordVerification(s.db, fileHash, status); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check verifies one blob outside a scrub pass and records the outcome
+func (s *IntegrityScrubber) Check(fileHash *models.FileHash) (models.IntegrityStatus, error) {
+	status := s.verify(fileHash)
+	return status, RecordVerification(s.db, fileHash, status)
+}
+
+// verify checks a blob, reporting the read to the backend error rates
+func (s *IntegrityScrubber) verify(fileHash *models.FileHash) models.IntegrityStatus {
+	status, err := VerifyBlob(s.storage, fileHash)
+	s.backends.RecordOperation(s.storage.Name(), err)
+	return status
+}
+
+// VerifyBlob streams a blob from storage and compares its SHA-256 to the
+// recorded hash. The error is the storage failure behind a missing or
+// unreadable blob, if any.
+func VerifyBlob(storage Storage, fileHash *models.FileHash) (models.IntegrityStatus, error) {
+	file, err := storage.Get(fileHash.Hash)
+	if err != nil {
+		return models.IntegrityMissing, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return models.IntegrityCorrupt, err
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != fileHash.Hash {
+		return models.IntegrityCorrupt, nil
+	}
+	return models.IntegrityOK, nil
+}
+
+// RecordVerification stores the outcome of checking a blob, for the
+// integrity report
+func RecordVerification(db *gorm.DB, fileHash *models.FileHash, status models.IntegrityStatus) error {
+	if err := db.Model(fileHash).Updates(map[string]interface{}{
+		"last_verified_at": time.Now(),
+		"integrity_status": status,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record verification of %s: %w", fileHash.Hash, err)
+	}
+	return nil
+}
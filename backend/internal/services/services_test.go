@@ -0,0 +1,59 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/models"
+)
+
+// createTestUser creates a user of its own for a test
+func createTestUser(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	id := uuid.New()
+	user := models.User{
+		BaseModel:    models.BaseModel{ID: id},
+		Username:     "test-" + id.String(),
+		Email:        id.String() + "@example.com",
+		PasswordHash: "not a password hash",
+		StorageQuota: 10 * 1024 * 1024,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// createTestFile records a file owned by the user over content of its own.
+// No blob is stored for it.
+func createTestFile(t *testing.T, db *gorm.DB, ownerID uuid.UUID) models.File {
+	t.Helper()
+	sum := sha256.Sum256([]byte(uuid.NewString()))
+	hash := hex.EncodeToString(sum[:])
+	fileHash := models.FileHash{
+		Hash:           hash,
+		Size:           100,
+		StoragePath:    BlobStoragePath(hash),
+		ReferenceCount: 1,
+	}
+	if err := db.Create(&fileHash).Error; err != nil {
+		t.Fatalf("failed to create file hash: %v", err)
+	}
+
+	file := models.File{
+		Filename:         "test.txt",
+		OriginalFilename: "test.txt",
+		MimeType:         "text/plain",
+		Size:             fileHash.Size,
+		FileHashID:       fileHash.ID,
+		OwnerID:          ownerID,
+	}
+	if err := db.Create(&file).Error; err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	return file
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// ShareExpiryCleaner deletes direct file and folder shares, and file access
+// list entries, once they have expired. Access checks and listings already
+// ignore them; this keeps them from piling up.
+type ShareExpiryCleaner struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewShareExpiryCleaner creates an expired share cleaner
+func NewShareExpiryCleaner(db *gorm.DB, cfg *config.Config) *ShareExpiryCleaner {
+	return &ShareExpiryCleaner{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// RunJob deletes expired shares in batches, table by table, until none are
+// left or the batch budget is spent
+func (s *ShareExpiryCleaner) RunJob(ctx context.Context, limits JobLimits) error {
+	batchSize := limits.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	now := time.Now()
+
+	batch := 0
+	for _, model := range []interface{}{&models.FileShare{}, &models.FolderShare{}, &models.FileACLEntry{}} {
+		for ; limits.MaxBatches <= 0 || batch < limits.MaxBatches; batch++ {
+			if batch > 0 {
+				if err := limits.Pause(ctx); err != nil {
+					return err
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			expired := s.db.Unscoped().Model(model).Select("id").
+				Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+				Limit(batchSize)
+			result := s.db.Unscoped().Where("id IN (?)", expired).Delete(model)
+			if result.Error != nil {
+				return fmt.Errorf("failed to delete expired shares: %w", result.Error)
+			}
+			if result.RowsAffected < int64(batchSize) {
+				batch++
+				break
+			}
+		}
+	}
+
+	return nil
+}
@@ -12,15 +12,36 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"file-vault-system/backend/internal/config"
 	"file-vault-system/backend/internal/models"
 )
 
+// ErrNeverExpiringLink is returned when a non-admin asks for a share link
+// without an expiry
+var ErrNeverExpiringLink = errors.New("only admins can create share links that never expire")
+
+// Reasons a share link can't be used
+var (
+	ErrShareLinkNotFound         = errors.New("share link not found")
+	ErrShareLinkExpired          = errors.New("share link has expired")
+	ErrShareLinkRevoked          = errors.New("share link has been revoked")
+	ErrShareLinkExhausted        = errors.New("share link download limit exceeded")
+	ErrShareLinkPasswordRequired = errors.New("password required")
+	ErrShareLinkInvalidPassword  = errors.New("invalid password")
+)
+
 type SharingService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	cfg    *config.Config
+	chunks *ChunkVerifier
 }
 
-func NewSharingService(db *gorm.DB) *SharingService {
-	return &SharingService{db: db}
+func NewSharingService(db *gorm.DB, cfg *config.Config) *SharingService {
+	return &SharingService{
+		db:     db,
+		cfg:    cfg,
+		chunks: NewChunkVerifier(db, cfg),
+	}
 }
 
 // ShareFileRequest represents a request to share a file
@@ -33,14 +54,21 @@ type ShareFileRequest struct {
 	Permission models.SharePermission `json:"permission"`
 }
 
-// CreateShareLinkRequest represents a request to create a shareable link
+// CreateShareLinkRequest represents a request to create a shareable link to
+// a file, or to a folder when FolderID is set
 type CreateShareLinkRequest struct {
-	FileID       uuid.UUID              `json:"file_id" binding:"required"`
-	CreatedBy    uuid.UUID              `json:"created_by" binding:"required"`
-	Password     string                 `json:"password"`
-	MaxDownloads *int                   `json:"max_downloads"`
-	ExpiresAt    *time.Time             `json:"expires_at"`
-	Permission   models.SharePermission `json:"permission"`
+	FileID           uuid.UUID              `json:"file_id"`
+	FolderID         uuid.UUID              `json:"folder_id"`
+	CreatedBy        uuid.UUID              `json:"created_by" binding:"required"`
+	Password         string                 `json:"password"`
+	MaxDownloads     *int                   `json:"max_downloads"`
+	ExpiresAt        *time.Time             `json:"expires_at"`
+	NeverExpires     bool                   `json:"never_expires"`
+	Permission       models.SharePermission `json:"permission"`
+	HTMLIndex        bool                   `json:"html_index"`         // Folder links only
+	BurnAfterReading bool                   `json:"burn_after_reading"` // File links only
+	BurnDeletesFile  bool                   `json:"burn_deletes_file"`  // Requires BurnAfterReading
+	IsAdmin          bool                   `json:"-"`                  // Admins may exceed the maximum expiry or opt out of one
 }
 
 // ShareFileWithUser shares a file with another user by email
@@ -55,14 +83,23 @@ func (s *SharingService) ShareFileWithUser(req ShareFileRequest) (*models.FileSh
 	}
 
 	// Check if file exists and belongs to the sharer
-	var file models.File
-	if err := s.db.Where("id = ? AND owner_id = ?", req.FileID, req.SharedBy).First(&file).Error; err != nil {
+	if _, err := FindFileWithAccess(s.db, req.FileID, req.SharedBy, AccessOwner); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("file not found or you don't have permission to share it")
 		}
 		return nil, fmt.Errorf("error finding file: %w", err)
 	}
 
+	// Sharing also grants the user access through the file's ACL
+	aclEntry := models.FileACLEntry{
+		FileID:      req.FileID,
+		GranteeType: models.ACLGranteeUser,
+		UserID:      &user.ID,
+		Permission:  req.Permission,
+		ExpiresAt:   req.ExpiresAt,
+		GrantedBy:   req.SharedBy,
+	}
+
 	// Check if already shared with this user
 	var existingShare models.FileShare
 	err := s.db.Where("file_id = ? AND shared_by = ? AND shared_with = ?",
@@ -76,8 +113,14 @@ func (s *SharingService) ShareFileWithUser(req ShareFileRequest) (*models.FileSh
 		existingShare.IsActive = true
 		existingShare.UpdatedAt = time.Now()
 
-		if err := s.db.Save(&existingShare).Error; err != nil {
-			return nil, fmt.Errorf("error updating existing share: %w", err)
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&existingShare).Error; err != nil {
+				return fmt.Errorf("error updating existing share: %w", err)
+			}
+			return upsertFileACLEntry(tx, &aclEntry)
+		})
+		if err != nil {
+			return nil, err
 		}
 		return &existingShare, nil
 	}
@@ -93,22 +136,50 @@ func (s *SharingService) ShareFileWithUser(req ShareFileRequest) (*models.FileSh
 		IsActive:   true,
 	}
 
-	if err := s.db.Create(&fileShare).Error; err != nil {
-		return nil, fmt.Errorf("error creating file share: %w", err)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fileShare).Error; err != nil {
+			return fmt.Errorf("error creating file share: %w", err)
+		}
+		return upsertFileACLEntry(tx, &aclEntry)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &fileShare, nil
 }
 
-// CreateShareLink creates a shareable link for a file
+// CreateShareLink creates a shareable link for a file or folder
 func (s *SharingService) CreateShareLink(req CreateShareLinkRequest) (*models.ShareLink, error) {
-	// Check if file exists and belongs to the creator
-	var file models.File
-	if err := s.db.Where("id = ? AND owner_id = ?", req.FileID, req.CreatedBy).First(&file).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("file not found or you don't have permission to share it")
+	// Check if the file or folder exists and belongs to the creator
+	if req.FolderID != uuid.Nil {
+		if req.BurnAfterReading || req.BurnDeletesFile {
+			return nil, fmt.Errorf("burn after reading is only available for file links")
 		}
-		return nil, fmt.Errorf("error finding file: %w", err)
+		if _, err := FindFolderWithAccess(s.db, req.FolderID, req.CreatedBy, AccessOwner); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("folder not found or you don't have permission to share it")
+			}
+			return nil, fmt.Errorf("error finding folder: %w", err)
+		}
+	} else {
+		if req.HTMLIndex {
+			return nil, fmt.Errorf("an HTML index is only available for folder links")
+		}
+		if req.BurnDeletesFile && !req.BurnAfterReading {
+			return nil, fmt.Errorf("deleting the file on download requires burn after reading")
+		}
+		if _, err := FindFileWithAccess(s.db, req.FileID, req.CreatedBy, AccessOwner); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("file not found or you don't have permission to share it")
+			}
+			return nil, fmt.Errorf("error finding file: %w", err)
+		}
+	}
+
+	expiresAt, err := s.shareLinkExpiry(req, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate unique share token
@@ -129,15 +200,22 @@ func (s *SharingService) CreateShareLink(req CreateShareLinkRequest) (*models.Sh
 
 	// Create share link
 	shareLink := models.ShareLink{
-		FileID:        req.FileID,
-		CreatedBy:     req.CreatedBy,
-		ShareToken:    token,
-		Permission:    req.Permission,
-		PasswordHash:  passwordHash,
-		MaxDownloads:  req.MaxDownloads,
-		ExpiresAt:     req.ExpiresAt,
-		IsActive:      true,
-		DownloadCount: 0,
+		CreatedBy:        req.CreatedBy,
+		ShareToken:       token,
+		Permission:       req.Permission,
+		PasswordHash:     passwordHash,
+		MaxDownloads:     req.MaxDownloads,
+		ExpiresAt:        expiresAt,
+		IsActive:         true,
+		DownloadCount:    0,
+		HTMLIndex:        req.HTMLIndex,
+		BurnAfterReading: req.BurnAfterReading,
+		BurnDeletesFile:  req.BurnDeletesFile,
+	}
+	if req.FolderID != uuid.Nil {
+		shareLink.FolderID = &req.FolderID
+	} else {
+		shareLink.FileID = &req.FileID
 	}
 
 	if err := s.db.Create(&shareLink).Error; err != nil {
@@ -147,6 +225,35 @@ func (s *SharingService) CreateShareLink(req CreateShareLinkRequest) (*models.Sh
 	return &shareLink, nil
 }
 
+// shareLinkExpiry resolves the expiry a new link gets: the configured default
+// when none is given, capped at the configured maximum for non-admins
+func (s *SharingService) shareLinkExpiry(req CreateShareLinkRequest, now time.Time) (*time.Time, error) {
+	if req.NeverExpires {
+		if !req.IsAdmin {
+			return nil, ErrNeverExpiringLink
+		}
+		return nil, nil
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("expiration date must be in the future")
+	}
+	if expiresAt == nil && s.cfg.DefaultShareLinkExpiry > 0 {
+		defaultExpiry := now.Add(time.Duration(s.cfg.DefaultShareLinkExpiry) * time.Hour)
+		expiresAt = &defaultExpiry
+	}
+
+	if !req.IsAdmin && s.cfg.MaxShareLinkExpiry > 0 {
+		maxExpiry := now.Add(time.Duration(s.cfg.MaxShareLinkExpiry) * time.Hour)
+		if expiresAt == nil || expiresAt.After(maxExpiry) {
+			expiresAt = &maxExpiry
+		}
+	}
+
+	return expiresAt, nil
+}
+
 // GetSharedFiles returns files shared with a user
 func (s *SharingService) GetSharedFiles(userID uuid.UUID) ([]models.FileShare, error) {
 	var fileShares []models.FileShare
@@ -163,12 +270,13 @@ func (s *SharingService) GetSharedFiles(userID uuid.UUID) ([]models.FileShare, e
 	return fileShares, nil
 }
 
-// GetFileShares returns all shares for a specific file
+// GetFileShares returns the active, unexpired shares of a specific file
 func (s *SharingService) GetFileShares(fileID uuid.UUID, ownerID uuid.UUID) ([]models.FileShare, error) {
 	var fileShares []models.FileShare
 
 	err := s.db.Preload("SharedWithUser").
 		Where("file_id = ? AND shared_by = ? AND is_active = true", fileID, ownerID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
 		Find(&fileShares).Error
 
 	if err != nil {
@@ -178,96 +286,406 @@ func (s *SharingService) GetFileShares(fileID uuid.UUID, ownerID uuid.UUID) ([]m
 	return fileShares, nil
 }
 
-// GetShareLinks returns all share links for a user's files
-func (s *SharingService) GetShareLinks(userID uuid.UUID) ([]models.ShareLink, error) {
-	var shareLinks []models.ShareLink
+// ShareLinkFilter narrows and orders a user's share links. An empty Status
+// lists every link that hasn't been revoked.
+type ShareLinkFilter struct {
+	Status     models.ShareLinkStatus
+	AllStatus  bool   // include revoked links when no Status is given
+	TargetType string // "file", "folder" or empty for both
+	SortBy     string // "created_at" or "expires_at"
+	Ascending  bool
+	Limit      int
+	Offset     int
+}
 
-	err := s.db.Preload("File").
-		Where("created_by = ? AND is_active = true", userID).
-		Find(&shareLinks).Error
+// GetShareLinks returns a page of the share links for a user's files and
+// folders along with the total number matching the filter
+func (s *SharingService) GetShareLinks(userID uuid.UUID, filter ShareLinkFilter) ([]models.ShareLink, int64, error) {
+	now := time.Now()
+	query := s.db.Model(&models.ShareLink{}).Where("created_by = ?", userID)
+
+	// Mirrors ShareLink.StatusAt so filtering and the reported status agree
+	notExpired := "(expires_at IS NULL OR expires_at >= ?)"
+	underLimit := "(max_downloads IS NULL OR download_count < max_downloads)"
+	switch filter.Status {
+	case models.ShareLinkActive:
+		query = query.Where("is_active = true AND "+notExpired+" AND "+underLimit, now)
+	case models.ShareLinkExpired:
+		query = query.Where("is_active = true AND expires_at < ?", now)
+	case models.ShareLinkExhausted:
+		query = query.Where("is_active = true AND "+notExpired+" AND NOT "+underLimit, now)
+	case models.ShareLinkRevoked:
+		query = query.Where("is_active = false")
+	case "":
+		if !filter.AllStatus {
+			query = query.Where("is_active = true")
+		}
+	default:
+		return nil, 0, fmt.Errorf("invalid status %q", filter.Status)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("error getting share links: %w", err)
+	switch filter.TargetType {
+	case "file":
+		query = query.Where("file_id IS NOT NULL")
+	case "folder":
+		query = query.Where("folder_id IS NOT NULL")
+	case "":
+	default:
+		return nil, 0, fmt.Errorf("invalid target type %q", filter.TargetType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting share links: %w", err)
+	}
+
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+	switch filter.SortBy {
+	case "", "created_at":
+		query = query.Order("created_at " + direction)
+	case "expires_at":
+		query = query.Order("expires_at " + direction + " NULLS LAST").Order("created_at DESC")
+	default:
+		return nil, 0, fmt.Errorf("invalid sort %q", filter.SortBy)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+
+	var shareLinks []models.ShareLink
+	if err := query.Preload("File").Preload("Folder").Find(&shareLinks).Error; err != nil {
+		return nil, 0, fmt.Errorf("error getting share links: %w", err)
+	}
+
+	for i := range shareLinks {
+		shareLinks[i].Status = shareLinks[i].StatusAt(now)
 	}
 
-	return shareLinks, nil
+	return shareLinks, total, nil
 }
 
 // ValidateShareLink validates and returns a share link by token
 func (s *SharingService) ValidateShareLink(token string, password string) (*models.ShareLink, error) {
 	var shareLink models.ShareLink
 
-	err := s.db.Preload("File").Preload("File.FileHash").
-		Where("share_token = ? AND is_active = true", token).First(&shareLink).Error
+	err := s.db.Preload("File").Preload("File.FileHash").Preload("Folder").
+		Where("share_token = ?", token).First(&shareLink).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("share link not found or expired")
+			return nil, ErrShareLinkNotFound
 		}
 		return nil, fmt.Errorf("error finding share link: %w", err)
 	}
 
-	// Check if expired
-	if shareLink.ExpiresAt != nil && shareLink.ExpiresAt.Before(time.Now()) {
-		return nil, fmt.Errorf("share link has expired")
+	// The shared file or folder may have since been deleted
+	if (shareLink.File == nil || shareLink.File.IsDeleted) && shareLink.Folder == nil {
+		return nil, ErrShareLinkNotFound
 	}
 
-	// Check download limit
-	if shareLink.MaxDownloads != nil && shareLink.DownloadCount >= *shareLink.MaxDownloads {
-		return nil, fmt.Errorf("share link download limit exceeded")
+	switch shareLink.StatusAt(time.Now()) {
+	case models.ShareLinkRevoked:
+		return nil, ErrShareLinkRevoked
+	case models.ShareLinkExpired:
+		return nil, ErrShareLinkExpired
+	case models.ShareLinkExhausted:
+		return nil, ErrShareLinkExhausted
 	}
 
 	// Check password if required
 	if shareLink.PasswordHash != "" {
 		if password == "" {
-			return nil, fmt.Errorf("password required")
+			return nil, ErrShareLinkPasswordRequired
 		}
 		if err := bcrypt.CompareHashAndPassword([]byte(shareLink.PasswordHash), []byte(password)); err != nil {
-			return nil, fmt.Errorf("invalid password")
+			return nil, ErrShareLinkInvalidPassword
 		}
 	}
 
-	// Update last accessed time
-	shareLink.LastAccessedAt = &[]time.Time{time.Now()}[0]
-	s.db.Save(&shareLink)
+	// Update last accessed time. Only the timestamp is written: the rest of
+	// the row may be stale by now, and saving it would undo a download
+	// counted in the meantime.
+	now := time.Now()
+	shareLink.LastAccessedAt = &now
+	s.db.Model(&shareLink).UpdateColumn("last_accessed_at", now)
 
 	return &shareLink, nil
 }
 
-// RevokeFileShare revokes a file share
+// RevokeFileShare revokes a file share along with the access it granted
 func (s *SharingService) RevokeFileShare(shareID uuid.UUID, ownerID uuid.UUID) error {
-	result := s.db.Model(&models.FileShare{}).
-		Where("id = ? AND shared_by = ?", shareID, ownerID).
+	var fileShare models.FileShare
+	if err := s.db.Where("id = ? AND shared_by = ?", shareID, ownerID).First(&fileShare).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("file share not found or you don't have permission to revoke it")
+		}
+		return fmt.Errorf("error revoking file share: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&fileShare).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("error revoking file share: %w", err)
+		}
+		return revokeUserFileACL(tx, fileShare.FileID, fileShare.SharedWith)
+	})
+}
+
+// RevokeShareLink revokes a share link
+func (s *SharingService) RevokeShareLink(linkID uuid.UUID, ownerID uuid.UUID) error {
+	result := s.db.Model(&models.ShareLink{}).
+		Where("id = ? AND created_by = ?", linkID, ownerID).
 		Update("is_active", false)
 
 	if result.Error != nil {
-		return fmt.Errorf("error revoking file share: %w", result.Error)
+		return fmt.Errorf("error revoking share link: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("file share not found or you don't have permission to revoke it")
+		return fmt.Errorf("share link not found or you don't have permission to revoke it")
 	}
 
 	return nil
 }
 
-// RevokeShareLink revokes a share link
-func (s *SharingService) RevokeShareLink(linkID uuid.UUID, ownerID uuid.UUID) error {
-	result := s.db.Model(&models.ShareLink{}).
-		Where("id = ? AND created_by = ?", linkID, ownerID).
+// ShareFolderRequest represents a request to share a folder
+type ShareFolderRequest struct {
+	FolderID   uuid.UUID              `json:"folder_id" binding:"required"`
+	SharedBy   uuid.UUID              `json:"shared_by" binding:"required"`
+	Email      string                 `json:"email" binding:"required,email"`
+	Message    string                 `json:"message"`
+	ExpiresAt  *time.Time             `json:"expires_at"`
+	Permission models.SharePermission `json:"permission"`
+}
+
+// ShareFolderWithUser shares a folder and everything inside it with another user by email
+func (s *SharingService) ShareFolderWithUser(req ShareFolderRequest) (*models.FolderShare, error) {
+	// Find the user by email
+	var user models.User
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user with email %s not found", req.Email)
+		}
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+
+	if user.ID == req.SharedBy {
+		return nil, fmt.Errorf("you cannot share a folder with yourself")
+	}
+
+	// Check if folder exists and belongs to the sharer
+	if _, err := FindFolderWithAccess(s.db, req.FolderID, req.SharedBy, AccessOwner); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("folder not found or you don't have permission to share it")
+		}
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+
+	// Check if already shared with this user
+	var existingShare models.FolderShare
+	err := s.db.Where("folder_id = ? AND shared_with = ?", req.FolderID, user.ID).First(&existingShare).Error
+
+	if err == nil {
+		// Update existing share
+		existingShare.Permission = req.Permission
+		existingShare.Message = req.Message
+		existingShare.ExpiresAt = req.ExpiresAt
+		existingShare.IsActive = true
+		existingShare.UpdatedAt = time.Now()
+
+		if err := s.db.Save(&existingShare).Error; err != nil {
+			return nil, fmt.Errorf("error updating existing folder share: %w", err)
+		}
+		return &existingShare, nil
+	}
+
+	// Create new share
+	folderShare := models.FolderShare{
+		FolderID:   req.FolderID,
+		SharedBy:   req.SharedBy,
+		SharedWith: user.ID,
+		Permission: req.Permission,
+		Message:    req.Message,
+		ExpiresAt:  req.ExpiresAt,
+		IsActive:   true,
+	}
+
+	if err := s.db.Create(&folderShare).Error; err != nil {
+		return nil, fmt.Errorf("error creating folder share: %w", err)
+	}
+
+	return &folderShare, nil
+}
+
+// GetSharedFolders returns folders shared with a user
+func (s *SharingService) GetSharedFolders(userID uuid.UUID) ([]models.FolderShare, error) {
+	var folderShares []models.FolderShare
+
+	err := s.db.Preload("Folder").Preload("SharedByUser").
+		Where("shared_with = ? AND is_active = true", userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Find(&folderShares).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting shared folders: %w", err)
+	}
+
+	return folderShares, nil
+}
+
+// GetFolderShares returns the active, unexpired shares of a specific folder
+func (s *SharingService) GetFolderShares(folderID uuid.UUID, ownerID uuid.UUID) ([]models.FolderShare, error) {
+	var folderShares []models.FolderShare
+
+	err := s.db.Preload("SharedWithUser").
+		Where("folder_id = ? AND shared_by = ? AND is_active = true", folderID, ownerID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Find(&folderShares).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("error getting folder shares: %w", err)
+	}
+
+	return folderShares, nil
+}
+
+// RevokeFolderShare revokes a folder share
+func (s *SharingService) RevokeFolderShare(shareID uuid.UUID, ownerID uuid.UUID) error {
+	result := s.db.Model(&models.FolderShare{}).
+		Where("id = ? AND shared_by = ?", shareID, ownerID).
 		Update("is_active", false)
 
 	if result.Error != nil {
-		return fmt.Errorf("error revoking share link: %w", result.Error)
+		return fmt.Errorf("error revoking folder share: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("share link not found or you don't have permission to revoke it")
+		return fmt.Errorf("folder share not found or you don't have permission to revoke it")
 	}
 
 	return nil
 }
 
+// FolderSharePermission returns the strongest permission a user holds on a
+// folder through active shares of the folder or any of its ancestors, or an
+// empty permission when the folder isn't shared with them
+func FolderSharePermission(db *gorm.DB, folderID uuid.UUID, userID uuid.UUID) (models.SharePermission, error) {
+	var permissions []models.SharePermission
+
+	err := db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT f.id, f.parent_id FROM folders f
+			JOIN ancestors a ON f.id = a.parent_id
+			WHERE f.deleted_at IS NULL
+		)
+		SELECT permission FROM folder_shares
+		WHERE folder_id IN (SELECT id FROM ancestors)
+			AND shared_with = ?
+			AND is_active = true
+			AND deleted_at IS NULL
+			AND (expires_at IS NULL OR expires_at > ?)`,
+		folderID, userID, time.Now()).Scan(&permissions).Error
+	if err != nil {
+		return "", fmt.Errorf("error resolving folder share permission: %w", err)
+	}
+
+	var strongest models.SharePermission
+	for _, permission := range permissions {
+		if permission.Rank() > strongest.Rank() {
+			strongest = permission
+		}
+	}
+
+	return strongest, nil
+}
+
+// SharedFolder returns the folder a link shares, or with folderID one of its
+// subfolders at any depth. Subfolders are found by path; the trailing
+// separator keeps "/Documents" from matching "/Documents2".
+func (s *SharingService) SharedFolder(shareLink *models.ShareLink, folderID *uuid.UUID) (*models.Folder, error) {
+	if shareLink.FolderID == nil || shareLink.Folder == nil {
+		return nil, fmt.Errorf("share link is not for a folder")
+	}
+	if folderID == nil || *folderID == *shareLink.FolderID {
+		return shareLink.Folder, nil
+	}
+
+	var folder models.Folder
+	err := s.db.Where("id = ? AND owner_id = ? AND path LIKE ?",
+		*folderID, shareLink.Folder.OwnerID, EscapeLike(shareLink.Folder.Path)+"/%").
+		First(&folder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("folder not found in shared folder")
+		}
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	return &folder, nil
+}
+
+// SharedSubfolders returns the folders directly inside a shared folder
+func (s *SharingService) SharedSubfolders(folder *models.Folder) ([]models.Folder, error) {
+	var folders []models.Folder
+	if err := s.db.Where("parent_id = ?", folder.ID).Order("name ASC").Find(&folders).Error; err != nil {
+		return nil, fmt.Errorf("error getting subfolders: %w", err)
+	}
+	return folders, nil
+}
+
+// SharedFolderFiles returns the files directly inside a shared folder,
+// leaving out any whose access has expired or that are quarantined
+func (s *SharingService) SharedFolderFiles(folder *models.Folder) ([]models.File, error) {
+	var files []models.File
+	err := s.db.Where("folder_id = ? AND is_deleted = false", folder.ID).
+		Order("original_filename ASC").Find(&files).Error
+	if err != nil {
+		return nil, fmt.Errorf("error getting folder files: %w", err)
+	}
+
+	now := time.Now()
+	visible := files[:0]
+	for _, file := range files {
+		if !file.AccessExpired(now) && !file.Quarantined() {
+			visible = append(visible, file)
+		}
+	}
+	return visible, nil
+}
+
+// SharedFolderFile returns one file from the folder a link shares or any of
+// its subfolders
+func (s *SharingService) SharedFolderFile(shareLink *models.ShareLink, fileID uuid.UUID) (*models.File, error) {
+	if shareLink.FolderID == nil || shareLink.Folder == nil {
+		return nil, fmt.Errorf("share link is not for a folder")
+	}
+
+	var file models.File
+	err := s.db.Preload("FileHash").
+		Joins("JOIN folders ON folders.id = files.folder_id AND folders.deleted_at IS NULL").
+		Where("files.id = ? AND files.is_deleted = false", fileID).
+		Where("folders.id = ? OR (folders.owner_id = ? AND folders.path LIKE ?)",
+			*shareLink.FolderID, shareLink.Folder.OwnerID, EscapeLike(shareLink.Folder.Path)+"/%").
+		First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("file not found in shared folder")
+		}
+		return nil, fmt.Errorf("error finding file: %w", err)
+	}
+	return &file, nil
+}
+
+// VerifyServedRange checks the chunks of a shared file's blob that a Range
+// header asks for
+func (s *SharingService) VerifyServedRange(fileHash *models.FileHash, blobPath, rangeHeader string) error {
+	return s.chunks.VerifyRequest(fileHash, blobPath, rangeHeader)
+}
+
 // RecordShareLinkAccess records an access to a share link
 func (s *SharingService) RecordShareLinkAccess(shareLink *models.ShareLink, ipAddress, userAgent, action string) error {
 	accessLog := models.ShareLinkAccessLog{
@@ -282,13 +700,69 @@ func (s *SharingService) RecordShareLinkAccess(shareLink *models.ShareLink, ipAd
 		return fmt.Errorf("error recording access log: %w", err)
 	}
 
-	// Update download count if action is download
-	if action == "download" {
-		if err := s.db.Model(shareLink).Update("download_count", gorm.Expr("download_count + 1")).Error; err != nil {
-			return fmt.Errorf("error updating download count: %w", err)
+	return nil
+}
+
+// RecordShareDownload counts a download against a share link's limit and
+// adds it to the download stats, in one transaction. The count only goes up
+// while the link is still usable, so concurrent downloads can't overshoot
+// the limit; ErrShareLinkExhausted or ErrShareLinkExpired is returned when
+// the link ran out in the meantime.
+//
+// A burn-after-reading link is deactivated by the same update that counts
+// its download, so only one of several concurrent downloads gets through;
+// the others see ErrShareLinkRevoked.
+//
+// onCounted, when set, runs in the same transaction once the download has
+// been counted; an error from it undoes the count.
+func (s *SharingService) RecordShareDownload(shareLink *models.ShareLink, file *models.File, ipAddress, userAgent string, onCounted func(tx *gorm.DB) error) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.ShareLink{}).
+			Where("id = ? AND is_active = true", shareLink.ID).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Where("max_downloads IS NULL OR download_count < max_downloads").
+			Updates(map[string]interface{}{
+				"download_count": gorm.Expr("download_count + 1"),
+				"is_active":      gorm.Expr("NOT burn_after_reading"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("error updating download count: %w", result.Error)
 		}
+		if result.RowsAffected == 0 {
+			if shareLink.ExpiresAt != nil && !shareLink.ExpiresAt.After(now) {
+				return ErrShareLinkExpired
+			}
+			if shareLink.BurnAfterReading {
+				return ErrShareLinkRevoked
+			}
+			return ErrShareLinkExhausted
+		}
+
+		stat := models.DownloadStat{
+			FileID:       file.ID,
+			ShareLinkID:  &shareLink.ID,
+			FileName:     file.OriginalFilename,
+			IPAddress:    ipAddress,
+			UserAgent:    userAgent,
+			DownloadSize: file.Size,
+		}
+		if err := tx.Create(&stat).Error; err != nil {
+			return fmt.Errorf("error recording download: %w", err)
+		}
+		if onCounted != nil {
+			return onCounted(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	shareLink.DownloadCount++
+	if shareLink.BurnAfterReading {
+		shareLink.IsActive = false
+	}
 	return nil
 }
 
@@ -302,3 +776,34 @@ func (s *SharingService) generateShareToken() (string, error) {
 	hash := sha256.Sum256(bytes)
 	return hex.EncodeToString(hash[:]), nil
 }
+
+// RevokeSharesByID deactivates share links, file shares and folder shares
+// whoever created them, removing the access entries the file shares granted.
+// It backs the admin bulk revoke and runs in the caller's transaction.
+func RevokeSharesByID(tx *gorm.DB, linkIDs, fileShareIDs, folderShareIDs []uuid.UUID) error {
+	if len(linkIDs) > 0 {
+		if err := tx.Model(&models.ShareLink{}).Where("id IN ?", linkIDs).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("error revoking share links: %w", err)
+		}
+	}
+	if len(folderShareIDs) > 0 {
+		if err := tx.Model(&models.FolderShare{}).Where("id IN ?", folderShareIDs).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("error revoking folder shares: %w", err)
+		}
+	}
+	if len(fileShareIDs) > 0 {
+		var fileShares []models.FileShare
+		if err := tx.Select("id, file_id, shared_with").Where("id IN ?", fileShareIDs).Find(&fileShares).Error; err != nil {
+			return fmt.Errorf("error finding file shares: %w", err)
+		}
+		if err := tx.Model(&models.FileShare{}).Where("id IN ?", fileShareIDs).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("error revoking file shares: %w", err)
+		}
+		for _, share := range fileShares {
+			if err := revokeUserFileACL(tx, share.FileID, share.SharedWith); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
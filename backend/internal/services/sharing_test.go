@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/internal/testdb"
+)
+
+// createBurnLink creates a burn-after-reading link to the file
+func createBurnLink(t *testing.T, db *gorm.DB, file models.File) models.ShareLink {
+	t.Helper()
+	link := models.ShareLink{
+		FileID:           &file.ID,
+		CreatedBy:        file.OwnerID,
+		ShareToken:       uuid.NewString(),
+		Permission:       models.PermissionDownload,
+		IsActive:         true,
+		BurnAfterReading: true,
+	}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+	return link
+}
+
+// loadShareLink loads a link as a download request would
+func loadShareLink(t *testing.T, db *gorm.DB, id uuid.UUID) *models.ShareLink {
+	t.Helper()
+	var link models.ShareLink
+	if err := db.First(&link, "id = ?", id).Error; err != nil {
+		t.Fatalf("failed to load share link: %v", err)
+	}
+	return &link
+}
+
+func TestBurnAfterReadingAllowsOneDownload(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewSharingService(db, config.Load())
+	owner := createTestUser(t, db)
+	file := createTestFile(t, db, owner.ID)
+	link := createBurnLink(t, db, file)
+
+	// Both requests found the link active before either downloaded
+	first, second := loadShareLink(t, db, link.ID), loadShareLink(t, db, link.ID)
+
+	if err := s.RecordShareDownload(first, &file, "192.0.2.1", "test", nil); err != nil {
+		t.Fatalf("first download: %v", err)
+	}
+	if first.IsActive {
+		t.Error("link is still active after its first download")
+	}
+	if err := s.RecordShareDownload(second, &file, "192.0.2.2", "test", nil); !errors.Is(err, ErrShareLinkRevoked) {
+		t.Errorf("second download error = %v, want %v", err, ErrShareLinkRevoked)
+	}
+
+	if stored := loadShareLink(t, db, link.ID); stored.IsActive || stored.DownloadCount != 1 {
+		t.Errorf("stored link: active %v, downloads %d; want revoked after 1", stored.IsActive, stored.DownloadCount)
+	}
+}
+
+func TestBurnAfterReadingConcurrentDownloads(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewSharingService(db, config.Load())
+	owner := createTestUser(t, db)
+	file := createTestFile(t, db, owner.ID)
+	link := createBurnLink(t, db, file)
+
+	const downloads = 5
+	errs := make([]error, downloads)
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range errs {
+		shareLink := loadShareLink(t, db, link.ID)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			errs[i] = s.RecordShareDownload(shareLink, &file, "192.0.2.1", "test", nil)
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrShareLinkRevoked):
+			t.Errorf("download error = %v, want %v", err, ErrShareLinkRevoked)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d downloads succeeded, want 1", succeeded)
+	}
+}
+
+func TestBurnAfterReadingValidatedConcurrently(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewSharingService(db, config.Load())
+	owner := createTestUser(t, db)
+	file := createTestFile(t, db, owner.ID)
+	link := createBurnLink(t, db, file)
+
+	// Each download validates the link and then counts, as a request does,
+	// so validating mustn't write back a stale copy of the link
+	const downloads = 10
+	errs := make([]error, downloads)
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range errs {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			shareLink, err := s.ValidateShareLink(link.ShareToken, "")
+			if err == nil {
+				err = s.RecordShareDownload(shareLink, &file, "192.0.2.1", "test", nil)
+			}
+			errs[i] = err
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrShareLinkRevoked):
+			t.Errorf("download error = %v, want %v", err, ErrShareLinkRevoked)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d downloads succeeded, want 1", succeeded)
+	}
+	if stored := loadShareLink(t, db, link.ID); stored.IsActive || stored.DownloadCount != 1 {
+		t.Errorf("stored link: active %v, downloads %d; want revoked after 1", stored.IsActive, stored.DownloadCount)
+	}
+}
+
+func TestMaxDownloadsHeldUnderConcurrency(t *testing.T) {
+	db := testdb.Open(t)
+	s := NewSharingService(db, config.Load())
+	owner := createTestUser(t, db)
+	file := createTestFile(t, db, owner.ID)
+
+	const maxDownloads, downloads = 3, 10
+	limit := maxDownloads
+	link := models.ShareLink{
+		FileID:       &file.ID,
+		CreatedBy:    owner.ID,
+		ShareToken:   uuid.NewString(),
+		Permission:   models.PermissionDownload,
+		MaxDownloads: &limit,
+		IsActive:     true,
+	}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+
+	errs := make([]error, downloads)
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := range errs {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			shareLink, err := s.ValidateShareLink(link.ShareToken, "")
+			if err == nil {
+				err = s.RecordShareDownload(shareLink, &file, "192.0.2.1", "test", nil)
+			}
+			errs[i] = err
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrShareLinkExhausted):
+			t.Errorf("download error = %v, want %v", err, ErrShareLinkExhausted)
+		}
+	}
+	if succeeded != maxDownloads {
+		t.Errorf("%d downloads succeeded, want %d", succeeded, maxDownloads)
+	}
+	if stored := loadShareLink(t, db, link.ID); stored.DownloadCount != maxDownloads {
+		t.Errorf("download count = %d, want %d", stored.DownloadCount, maxDownloads)
+	}
+
+	var stats int64
+	if err := db.Model(&models.DownloadStat{}).Where("share_link_id = ?", link.ID).Count(&stats).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stats != maxDownloads {
+		t.Errorf("%d download stats recorded, want %d", stats, maxDownloads)
+	}
+}
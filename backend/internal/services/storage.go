@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"file-vault-system/backend/internal/config"
+)
+
+// S3StorageBackend is the name of the S3-compatible object storage backend
+const S3StorageBackend = "s3"
+
+// ErrBlobNotFound is returned when a blob isn't in storage
+var ErrBlobNotFound = errors.New("blob not found in storage")
+
+// Storage keeps content blobs, keyed by their content hash
+type Storage interface {
+	// Name identifies the backend in health reporting
+	Name() string
+	// Put stores the content read from r under hash, replacing any blob
+	// already there
+	Put(hash string, r io.Reader) error
+	// Get opens the blob stored under hash, or returns ErrBlobNotFound
+	Get(hash string) (io.ReadCloser, error)
+	// Delete removes the blob stored under hash; a missing blob isn't an error
+	Delete(hash string) error
+}
+
+// LocalBlobs is implemented by storage that keeps blobs as files on local
+// disk. Features that need a seekable file, such as chunk checksums and
+// thumbnails, are only available through it.
+type LocalBlobs interface {
+	// Path returns where the blob stored under hash lives on disk
+	Path(hash string) string
+}
+
+// NewStorage creates the storage backend selected by STORAGE_BACKEND,
+// encrypting blobs when ENCRYPTION_MASTER_KEY is set
+func NewStorage(cfg *config.Config) (Storage, error) {
+	storage, err := newStorageBackend(cfg)
+	if err != nil || cfg.EncryptionMasterKey == "" {
+		return storage, err
+	}
+	return NewEncryptedStorage(storage, cfg.EncryptionMasterKey)
+}
+
+func newStorageBackend(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageBackend {
+	case "", LocalStorageBackend:
+		return NewLocalStorage(cfg.StoragePath), nil
+	case S3StorageBackend:
+		return NewS3Storage(cfg)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+}
+
+// LocalStorage keeps blobs on disk under the storage path, sharded by hash
+// prefix as described by BlobStoragePath. Blobs stored before sharding stay
+// readable at storage/{hash} until ShardFlatBlobs relocates them.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates filesystem storage rooted at root
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// Name identifies the backend
+func (s *LocalStorage) Name() string {
+	return LocalStorageBackend
+}
+
+// Path returns where the blob stored under hash lives on disk: its sharded
+// location, unless it is still at its flat one
+func (s *LocalStorage) Path(hash string) string {
+	sharded := filepath.Join(s.root, filepath.FromSlash(BlobStoragePath(hash)))
+	if _, err := os.Stat(sharded); err != nil {
+		flat := filepath.Join(s.root, filepath.FromSlash(flatBlobStoragePath(hash)))
+		if _, err := os.Stat(flat); err == nil {
+			return flat
+		}
+	}
+	return sharded
+}
+
+// Put writes the blob to a temporary file first so a failed write never
+// leaves a partial blob in place
+func (s *LocalStorage) Put(hash string, r io.Reader) error {
+	path := s.Path(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %v", err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write blob: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to move blob into place: %v", err)
+	}
+	return nil
+}
+
+// Adopt moves a file already on the same disk into place as the blob for
+// hash, avoiding a copy of spooled uploads
+func (s *LocalStorage) Adopt(hash, path string) error {
+	dst := s.Path(hash)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	if err := os.Rename(path, dst); err != nil {
+		return fmt.Errorf("failed to move file to storage: %v", err)
+	}
+	return nil
+}
+
+// Get opens the blob. The returned file is seekable.
+func (s *LocalStorage) Get(hash string) (io.ReadCloser, error) {
+	file, err := os.Open(s.Path(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %v", err)
+	}
+	return file, nil
+}
+
+// Delete removes the blob from both its sharded and flat locations
+func (s *LocalStorage) Delete(hash string) error {
+	for _, path := range []string{BlobStoragePath(hash), flatBlobStoragePath(hash)} {
+		if err := os.Remove(filepath.Join(s.root, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove blob: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+	"file-vault-system/backend/pkg/utils"
+)
+
+// LocalStorageBackend is the name of the filesystem backend under StoragePath
+const LocalStorageBackend = "local"
+
+// StorageBackendStatus describes the health of one configured storage backend
+type StorageBackendStatus struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Location    string     `json:"location"`
+	Reachable   bool       `json:"reachable"`
+	ProbeError  string     `json:"probe_error,omitempty"`
+	FreeBytes   *uint64    `json:"free_bytes,omitempty"`
+	TotalBytes  *uint64    `json:"total_bytes,omitempty"`
+	BlobCount   int64      `json:"blob_count"`
+	BlobBytes   int64      `json:"blob_bytes"`
+	Operations  int64      `json:"recent_operations"`
+	Errors      int64      `json:"recent_errors"`
+	ErrorRate   float64    `json:"recent_error_rate"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// backendCounters holds per-minute operation and error counts for a backend
+type backendCounters struct {
+	operations  map[int64]int64
+	errors      map[int64]int64
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+// StorageBackends tracks the configured storage backends and the outcome of
+// recent I/O against them
+type StorageBackends struct {
+	db  *gorm.DB
+	cfg *config.Config
+
+	mu       sync.Mutex
+	counters map[string]*backendCounters
+}
+
+// NewStorageBackends creates the backend registry
+func NewStorageBackends(db *gorm.DB, cfg *config.Config) *StorageBackends {
+	return &StorageBackends{
+		db:       db,
+		cfg:      cfg,
+		counters: make(map[string]*backendCounters),
+	}
+}
+
+// RecordOperation counts an I/O operation against a backend; a non-nil err
+// counts as a failure
+func (s *StorageBackends) RecordOperation(backend string, err error) {
+	if s == nil {
+		return
+	}
+
+	now := time.Now()
+	minute := now.Unix() / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters, ok := s.counters[backend]
+	if !ok {
+		counters = &backendCounters{
+			operations: make(map[int64]int64),
+			errors:     make(map[int64]int64),
+		}
+		s.counters[backend] = counters
+	}
+
+	counters.operations[minute]++
+	if err != nil {
+		counters.errors[minute]++
+		counters.lastError = err.Error()
+		counters.lastErrorAt = &now
+	}
+
+	s.prune(counters, minute)
+}
+
+// prune drops counts older than the configured window
+func (s *StorageBackends) prune(counters *backendCounters, minute int64) {
+	oldest := minute - int64(s.window()) + 1
+	for m := range counters.operations {
+		if m < oldest {
+			delete(counters.operations, m)
+			delete(counters.errors, m)
+		}
+	}
+}
+
+// window returns the error rate window in minutes
+func (s *StorageBackends) window() int {
+	if s.cfg.StorageErrorWindow <= 0 {
+		return 15
+	}
+	return s.cfg.StorageErrorWindow
+}
+
+// Status probes every configured backend and reports its health
+func (s *StorageBackends) Status() ([]StorageBackendStatus, error) {
+	local, err := s.localStatus()
+	if err != nil {
+		return nil, err
+	}
+	return []StorageBackendStatus{local}, nil
+}
+
+// localStatus probes the filesystem backend by writing and removing a file
+func (s *StorageBackends) localStatus() (StorageBackendStatus, error) {
+	status := StorageBackendStatus{
+		Name:     LocalStorageBackend,
+		Type:     "filesystem",
+		Location: s.cfg.StoragePath,
+	}
+
+	if err := probeDirectory(s.cfg.StoragePath); err != nil {
+		status.ProbeError = err.Error()
+	} else {
+		status.Reachable = true
+	}
+
+	if free, total, err := utils.DiskSpace(s.cfg.StoragePath); err == nil {
+		status.FreeBytes = &free
+		status.TotalBytes = &total
+	}
+
+	// Every blob currently lives on the local backend
+	var blobs struct {
+		Count int64
+		Bytes int64
+	}
+	if err := s.db.Model(&models.FileHash{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(size), 0) AS bytes").
+		Scan(&blobs).Error; err != nil {
+		return status, fmt.Errorf("failed to count blobs: %w", err)
+	}
+	status.BlobCount = blobs.Count
+	status.BlobBytes = blobs.Bytes
+
+	s.mu.Lock()
+	if counters, ok := s.counters[LocalStorageBackend]; ok {
+		s.prune(counters, time.Now().Unix()/60)
+		for _, n := range counters.operations {
+			status.Operations += n
+		}
+		for _, n := range counters.errors {
+			status.Errors += n
+		}
+		status.LastError = counters.lastError
+		status.LastErrorAt = counters.lastErrorAt
+	}
+	s.mu.Unlock()
+
+	if status.Operations > 0 {
+		status.ErrorRate = float64(status.Errors) / float64(status.Operations)
+	}
+
+	return status, nil
+}
+
+// Writability of the storage path, as reported by the system health check
+const (
+	StorageWritable = "writable"
+	StorageReadOnly = "readonly"
+	StorageError    = "error"
+)
+
+// StorageWritability probes dir by writing and removing a file, telling a
+// read-only or unwritable directory apart from one that can't be reached
+func StorageWritability(dir string) (string, error) {
+	err := probeDirectory(dir)
+	switch {
+	case err == nil:
+		return StorageWritable, nil
+	case errors.Is(err, syscall.EROFS), errors.Is(err, fs.ErrPermission):
+		return StorageReadOnly, err
+	default:
+		return StorageError, err
+	}
+}
+
+// probeDirectory checks that dir exists and is writable
+func probeDirectory(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("storage path unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("storage path %s is not a directory", dir)
+	}
+
+	probePath := filepath.Join(dir, ".probe-"+uuid.New().String())
+	if err := os.WriteFile(probePath, []byte("ok"), 0644); err != nil {
+		return fmt.Errorf("storage path not writable: %w", err)
+	}
+	if err := os.Remove(probePath); err != nil {
+		return fmt.Errorf("failed to remove probe file: %w", err)
+	}
+	return nil
+}
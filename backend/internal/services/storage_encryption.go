@@ -0,0 +1,260 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// encryptedBlobMagic starts every encrypted blob, followed by the format
+// version and the blob's nonce prefix. Blobs without it were stored before
+// encryption was enabled and are read as they are.
+var encryptedBlobMagic = []byte("FFENC")
+
+const (
+	encryptedBlobVersion  = 1
+	encryptionSegmentSize = 64 * 1024 // plaintext bytes sealed together
+	noncePrefixSize       = 7
+)
+
+// ErrBlobAuthentication is returned when an encrypted blob was altered,
+// truncated or sealed under a different master key
+var ErrBlobAuthentication = errors.New("encrypted blob failed authentication")
+
+// EncryptedStorage encrypts blobs with AES-256-GCM before handing them to the
+// storage it wraps, and decrypts them transparently when they are read.
+//
+// Each blob's key is derived from its content hash under the master key
+// (convergent encryption), so identical content always gets the same key and
+// deduplication by hash works as before. The tradeoff is that equal content
+// is recognizable as equal, which the recorded hashes already reveal. Blobs
+// are sealed in segments so they stream rather than sit in memory; a segment's
+// nonce is a random per-blob prefix, the segment number and a final-segment
+// flag, so segments can't be reordered and a blob can't be truncated unseen.
+//
+// The blob carries its own nonce, so it can be read through Get by hash
+// alone. It doesn't implement LocalBlobs even over local storage, because the
+// file on disk is ciphertext.
+type EncryptedStorage struct {
+	inner     Storage
+	masterKey []byte
+}
+
+// NewEncryptedStorage wraps inner with encryption under masterKey, a 32-byte
+// key given as 64 hex digits or base64
+func NewEncryptedStorage(inner Storage, masterKey string) (*EncryptedStorage, error) {
+	key, err := parseMasterKey(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedStorage{inner: inner, masterKey: key}, nil
+}
+
+// parseMasterKey decodes a 32-byte key from hex or base64
+func parseMasterKey(value string) ([]byte, error) {
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("ENCRYPTION_MASTER_KEY must be 32 bytes, as 64 hex digits or base64")
+}
+
+// Name identifies the wrapped backend
+func (s *EncryptedStorage) Name() string {
+	return s.inner.Name()
+}
+
+// Put encrypts the content as it is streamed to the wrapped storage
+func (s *EncryptedStorage) Put(hash string, r io.Reader) error {
+	aead, err := s.blobCipher(hash)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(sealBlob(pw, r, aead, prefix, hash))
+	}()
+	err = s.inner.Put(hash, pr)
+	// Unblock the sealing goroutine if the backend stopped reading early
+	pr.CloseWithError(io.ErrClosedPipe)
+	return err
+}
+
+// Get opens the blob and decrypts it as it is read. The result isn't
+// seekable.
+func (s *EncryptedStorage) Get(hash string) (io.ReadCloser, error) {
+	blob, err := s.inner.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	src := bufio.NewReaderSize(blob, encryptionSegmentSize+64)
+	header, err := src.Peek(len(encryptedBlobMagic) + 1 + noncePrefixSize)
+	if err != nil || !bytes.Equal(header[:len(encryptedBlobMagic)], encryptedBlobMagic) {
+		// Stored before encryption was enabled
+		return struct {
+			io.Reader
+			io.Closer
+		}{src, blob}, nil
+	}
+	if version := header[len(encryptedBlobMagic)]; version != encryptedBlobVersion {
+		blob.Close()
+		return nil, fmt.Errorf("unsupported encrypted blob version %d", version)
+	}
+
+	aead, err := s.blobCipher(hash)
+	if err != nil {
+		blob.Close()
+		return nil, err
+	}
+	prefix := append([]byte(nil), header[len(encryptedBlobMagic)+1:]...)
+	src.Discard(len(header))
+
+	return &decryptingReader{
+		src:    src,
+		closer: blob,
+		aead:   aead,
+		prefix: prefix,
+		hash:   []byte(hash),
+		sealed: make([]byte, encryptionSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Delete removes the blob from the wrapped storage
+func (s *EncryptedStorage) Delete(hash string) error {
+	return s.inner.Delete(hash)
+}
+
+// blobCipher returns the AES-256-GCM cipher for the blob stored under hash
+func (s *EncryptedStorage) blobCipher(hash string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(hmacSHA256(s.masterKey, "blob-key:"+hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// segmentNonce builds the nonce of a blob segment
+func segmentNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// sealBlob writes the encrypted blob header followed by the content of r,
+// sealed segment by segment. Empty content still gets a final segment.
+func sealBlob(w io.Writer, r io.Reader, aead cipher.AEAD, prefix []byte, hash string) error {
+	header := append(append([]byte(nil), encryptedBlobMagic...), encryptedBlobVersion)
+	if _, err := w.Write(append(header, prefix...)); err != nil {
+		return err
+	}
+
+	src := bufio.NewReaderSize(r, encryptionSegmentSize)
+	plain := make([]byte, encryptionSegmentSize)
+	sealed := make([]byte, 0, encryptionSegmentSize+aead.Overhead())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(src, plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		final := err != nil
+		if !final {
+			if _, err := src.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if !final && counter == math.MaxUint32 {
+			return fmt.Errorf("content is too large to encrypt")
+		}
+
+		sealed = aead.Seal(sealed[:0], segmentNonce(prefix, counter, final), plain[:n], []byte(hash))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// decryptingReader reads an encrypted blob's content, opening one segment at
+// a time
+type decryptingReader struct {
+	src     *bufio.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	prefix  []byte
+	hash    []byte
+	counter uint32
+	sealed  []byte // buffer for the segment being opened
+	plain   []byte // unread content of the last opened segment
+	done    bool   // the final segment has been opened
+}
+
+func (d *decryptingReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.openSegment(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// openSegment reads and authenticates the next segment. A short segment, or
+// a full one with nothing after it, must be the final one.
+func (d *decryptingReader) openSegment() error {
+	n, err := io.ReadFull(d.src, d.sealed)
+	if err == io.EOF {
+		return ErrBlobAuthentication
+	} else if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	final := err != nil
+	if !final {
+		if _, err := d.src.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := d.aead.Open(d.sealed[:0], segmentNonce(d.prefix, d.counter, final), d.sealed[:n], d.hash)
+	if err != nil {
+		return ErrBlobAuthentication
+	}
+	d.plain = plain
+	d.done = final
+	d.counter++
+	return nil
+}
+
+func (d *decryptingReader) Close() error {
+	return d.closer.Close()
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"file-vault-system/backend/internal/config"
+)
+
+// S3Storage keeps blobs in an S3-compatible bucket as {prefix}/{hash}.
+// Requests are signed with AWS Signature Version 4, which AWS, MinIO and
+// most other S3-compatible services accept.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// NewS3Storage creates S3 storage from the S3_* settings
+func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
+	if cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required for the s3 storage backend")
+	}
+	if cfg.S3AccessKeyID == "" || cfg.S3SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 storage backend")
+	}
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.S3Region)
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", endpoint)
+	}
+
+	return &S3Storage{
+		endpoint:  parsed,
+		region:    cfg.S3Region,
+		bucket:    cfg.S3Bucket,
+		prefix:    strings.Trim(cfg.S3Prefix, "/"),
+		accessKey: cfg.S3AccessKeyID,
+		secretKey: cfg.S3SecretAccessKey,
+		pathStyle: cfg.S3PathStyle,
+		client:    &http.Client{Timeout: time.Duration(cfg.S3Timeout) * time.Second},
+	}, nil
+}
+
+// Name identifies the backend
+func (s *S3Storage) Name() string {
+	return S3StorageBackend
+}
+
+// Put uploads the blob. S3 needs the length up front, so content that can't
+// be measured by seeking is spooled to a temporary file first.
+func (s *S3Storage) Put(hash string, r io.Reader) error {
+	body, size, cleanup, err := sizedBody(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	resp, err := s.do(http.MethodPut, hash, body, size)
+	if err != nil {
+		return fmt.Errorf("failed to upload blob: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError("upload", resp)
+	}
+	return nil
+}
+
+// Get streams the blob. The body isn't seekable.
+func (s *S3Storage) Get(hash string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, hash, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob: %v", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	defer resp.Body.Close()
+	return nil, s.responseError("download", resp)
+}
+
+// Delete removes the blob. S3 answers 204 whether or not it existed.
+func (s *S3Storage) Delete(hash string) error {
+	resp, err := s.do(http.MethodDelete, hash, nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to delete blob: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError("delete", resp)
+	}
+	return nil
+}
+
+// objectURL returns the URL of the object holding the blob
+func (s *S3Storage) objectURL(hash string) *url.URL {
+	key := hash
+	if s.prefix != "" {
+		key = s.prefix + "/" + hash
+	}
+
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	return &u
+}
+
+// do sends a signed request for the blob's object
+func (s *S3Storage) do(method, hash string, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(hash).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers to the request. The payload is
+// left unsigned so blobs are streamed rather than hashed twice; TLS protects
+// it in transit.
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	signV4(req, now, s.region, s.accessKey, s.secretKey, "UNSIGNED-PAYLOAD")
+}
+
+// signV4 signs the request for S3 with AWS Signature Version 4, covering the
+// host and every header already set on the request. payloadHash is the
+// hex SHA-256 of the body or UNSIGNED-PAYLOAD.
+func signV4(req *http.Request, now time.Time, region, accessKey, secretKey, payloadHash string) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(sha256Sum([]byte(canonicalRequest))),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery sorts and encodes query parameters the way SigV4 expects;
+// a parameter without a value still gets its "="
+func canonicalQuery(query url.Values) string {
+	encoded := make(map[string][]string, len(query))
+	names := make([]string, 0, len(query))
+	for name, values := range query {
+		key := uriEncode(name, true)
+		names = append(names, key)
+		for _, value := range values {
+			encoded[key] = append(encoded[key], uriEncode(value, true))
+		}
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		values := encoded[name]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, name+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters, as
+// SigV4 requires. Slashes are kept in paths and encoded elsewhere.
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// responseError describes a failed S3 request, including the start of the
+// error document S3 returns
+func (s *S3Storage) responseError(op string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("blob %s failed with %s: %s", op, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// sizedBody returns r with its length, spooling it to a temporary file when
+// the length can't be found by seeking
+func sizedBody(r io.Reader) (io.Reader, int64, func(), error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := seeker.Seek(0, io.SeekEnd)
+			if err == nil {
+				if _, err := seeker.Seek(start, io.SeekStart); err == nil {
+					return seeker, end - start, func() {}, nil
+				}
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "blob-*.tmp")
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to spool blob: %v", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, fmt.Errorf("failed to spool blob: %v", err)
+	}
+	return tmp, size, cleanup, nil
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// FrameExtractor extracts a single still frame from a video file
+type FrameExtractor interface {
+	// Available reports whether the extractor can be used in this deployment
+	Available() bool
+	// ExtractFrame writes a JPEG frame taken at offset into outputPath
+	ExtractFrame(ctx context.Context, videoPath, outputPath string, offset time.Duration, maxDimension int) error
+}
+
+// FFmpegExtractor extracts video frames using the ffmpeg binary
+type FFmpegExtractor struct {
+	binary string
+}
+
+// NewFFmpegExtractor resolves the ffmpeg binary; the extractor reports itself
+// unavailable when the binary cannot be found
+func NewFFmpegExtractor(path string) *FFmpegExtractor {
+	binary, err := exec.LookPath(path)
+	if err != nil {
+		log.Printf("ffmpeg not found at %q, video thumbnails disabled", path)
+		return &FFmpegExtractor{}
+	}
+	return &FFmpegExtractor{binary: binary}
+}
+
+// Available reports whether ffmpeg was found
+func (e *FFmpegExtractor) Available() bool {
+	return e.binary != ""
+}
+
+// ExtractFrame runs ffmpeg to grab a single scaled frame at the given offset
+func (e *FFmpegExtractor) ExtractFrame(ctx context.Context, videoPath, outputPath string, offset time.Duration, maxDimension int) error {
+	if !e.Available() {
+		return fmt.Errorf("ffmpeg is not available")
+	}
+
+	scale := fmt.Sprintf("scale=w=%d:h=%d:force_original_aspect_ratio=decrease", maxDimension, maxDimension)
+	args := []string{
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64),
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-vf", scale,
+		outputPath,
+	}
+
+	output, err := exec.CommandContext(ctx, e.binary, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// ffmpeg exits successfully without writing a frame when the offset is past the end
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return fmt.Errorf("ffmpeg produced no frame at offset %s", offset)
+	}
+
+	return nil
+}
+
+// thumbnailJob is a request to generate the thumbnail for some content
+type thumbnailJob struct {
+	hash     string
+	mimeType string
+}
+
+// ThumbnailQueueStats describes the thumbnail worker pool and its queue
+type ThumbnailQueueStats struct {
+	Workers   int   `json:"workers"`
+	Capacity  int   `json:"capacity"`
+	Depth     int   `json:"depth"`
+	Active    int   `json:"active"`
+	Queued    int64 `json:"queued"`
+	Dropped   int64 `json:"dropped"` // left for lazy generation because the queue was full
+	Generated int64 `json:"generated"`
+	Failed    int64 `json:"failed"`
+}
+
+// ThumbnailService generates and locates thumbnails for stored content.
+// Generation runs on a fixed pool of workers fed by a bounded queue.
+type ThumbnailService struct {
+	db             *gorm.DB
+	cfg            *config.Config
+	storage        Storage
+	videoExtractor FrameExtractor
+
+	queue   chan thumbnailJob
+	mu      sync.Mutex
+	pending map[string]bool // hashes queued or being generated
+	stats   ThumbnailQueueStats
+}
+
+// NewThumbnailService creates a thumbnail service using ffmpeg for video frames
+func NewThumbnailService(db *gorm.DB, cfg *config.Config) *ThumbnailService {
+	queueSize := cfg.ThumbnailQueueSize
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &ThumbnailService{
+		db:             db,
+		cfg:            cfg,
+		videoExtractor: NewFFmpegExtractor(cfg.FFmpegPath),
+		queue:          make(chan thumbnailJob, queueSize),
+		pending:        make(map[string]bool),
+		stats:          ThumbnailQueueStats{Workers: cfg.ThumbnailWorkers, Capacity: queueSize},
+	}
+}
+
+// Start launches the generation workers; they stop when ctx is cancelled
+func (s *ThumbnailService) Start(ctx context.Context) {
+	if !s.Available() {
+		log.Printf("THUMBNAIL_WORKERS is %d, thumbnails will not be generated", s.cfg.ThumbnailWorkers)
+		return
+	}
+	for i := 0; i < s.cfg.ThumbnailWorkers; i++ {
+		go s.work(ctx)
+	}
+}
+
+// Available reports whether there are workers to generate thumbnails. Without
+// them nothing would take jobs off the queue.
+func (s *ThumbnailService) Available() bool {
+	return s.cfg.ThumbnailWorkers > 0
+}
+
+// Enqueue queues thumbnail generation for content. When the queue is full the
+// request is dropped and the thumbnail is generated lazily the first time it
+// is asked for. It reports whether generation is queued, which it never is
+// when the service is unavailable.
+func (s *ThumbnailService) Enqueue(hash string, mimeType string) bool {
+	if !s.Available() || !s.Supports(mimeType) {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending[hash] {
+		return true
+	}
+	select {
+	case s.queue <- thumbnailJob{hash: hash, mimeType: mimeType}:
+		s.pending[hash] = true
+		s.stats.Queued++
+		return true
+	default:
+		s.stats.Dropped++
+		return false
+	}
+}
+
+// QueueStats returns the current state of the worker pool
+func (s *ThumbnailService) QueueStats() ThumbnailQueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Depth = len(s.queue)
+	return stats
+}
+
+// work generates queued thumbnails one at a time
+func (s *ThumbnailService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.mu.Lock()
+			s.stats.Active++
+			s.mu.Unlock()
+
+			err := s.GenerateForHash(job.hash, job.mimeType)
+			if err != nil {
+				log.Printf("Thumbnail generation failed for %s: %v", job.hash, err)
+			}
+
+			s.mu.Lock()
+			s.stats.Active--
+			delete(s.pending, job.hash)
+			if err != nil {
+				s.stats.Failed++
+			} else {
+				s.stats.Generated++
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// SetStorage sets where images are read from for their thumbnails
+func (s *ThumbnailService) SetStorage(storage Storage) {
+	s.storage = storage
+}
+
+// SetVideoExtractor replaces the frame extractor used for video thumbnails
+func (s *ThumbnailService) SetVideoExtractor(extractor FrameExtractor) {
+	s.videoExtractor = extractor
+}
+
+// Supports reports whether a thumbnail can be generated for the MIME type
+func (s *ThumbnailService) Supports(mimeType string) bool {
+	if !s.cfg.ThumbnailsEnabled {
+		return false
+	}
+	if imageThumbnailTypes[mimeType] {
+		return true
+	}
+	if strings.HasPrefix(mimeType, "video/") {
+		return s.videoExtractor != nil && s.videoExtractor.Available()
+	}
+	return false
+}
+
+// GenerateForHash generates a thumbnail for the content identified by hash,
+// skipping content that already has one or failed before. Unsupported content
+// is ignored. A failure is recorded so the content gets the generic icon
+// rather than another attempt.
+func (s *ThumbnailService) GenerateForHash(hash string, mimeType string) error {
+	if !s.Supports(mimeType) {
+		return nil
+	}
+
+	var fileHash models.FileHash
+	if err := s.db.Where("hash = ?", hash).First(&fileHash).Error; err != nil {
+		return fmt.Errorf("failed to find file hash: %w", err)
+	}
+
+	if fileHash.ThumbnailPath != "" || fileHash.ThumbnailFailed {
+		return nil
+	}
+
+	thumbnailPath := fmt.Sprintf("thumbnails/%s.jpg", fileHash.Hash)
+	fullThumbnailPath := filepath.Join(s.cfg.StoragePath, thumbnailPath)
+	if err := os.MkdirAll(filepath.Dir(fullThumbnailPath), 0755); err != nil {
+		return fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
+
+	var err error
+	if imageThumbnailTypes[mimeType] {
+		err = s.generateImageThumbnail(fileHash.Hash, fullThumbnailPath, s.cfg.ThumbnailMaxDimension)
+	} else {
+		err = s.generateVideoThumbnail(fileHash.Hash, fullThumbnailPath)
+	}
+	if err != nil {
+		os.Remove(fullThumbnailPath)
+		if recordErr := s.db.Model(&fileHash).Update("thumbnail_failed", true).Error; recordErr != nil {
+			log.Printf("Failed to record thumbnail failure for %s: %v", fileHash.Hash, recordErr)
+		}
+		return err
+	}
+
+	if err := s.db.Model(&fileHash).Update("thumbnail_path", thumbnailPath).Error; err != nil {
+		return fmt.Errorf("failed to record thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+// generateVideoThumbnail copies the video stored under hash to a temporary
+// file for the frame extractor, which needs a seekable file, then extracts
+// its frame to outputPath. Blobs may be encrypted or kept in object storage,
+// so the stored file is never read directly.
+func (s *ThumbnailService) generateVideoThumbnail(hash, outputPath string) error {
+	if s.storage == nil {
+		return fmt.Errorf("no storage configured for video thumbnails")
+	}
+	blob, err := s.storage.Get(hash)
+	if err != nil {
+		return fmt.Errorf("failed to open video: %w", err)
+	}
+	defer blob.Close()
+
+	tmp, err := os.CreateTemp("", "thumbnail-source-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary video: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, blob); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to read video: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary video: %w", err)
+	}
+
+	return s.extractVideoFrame(tmp.Name(), outputPath)
+}
+
+// extractVideoFrame grabs the poster frame, falling back to the first frame
+// for videos shorter than the configured offset
+func (s *ThumbnailService) extractVideoFrame(sourcePath, outputPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offset := time.Duration(s.cfg.VideoThumbnailOffset * float64(time.Second))
+	err := s.videoExtractor.ExtractFrame(ctx, sourcePath, outputPath, offset, s.cfg.ThumbnailMaxDimension)
+	if err != nil && offset > 0 {
+		err = s.videoExtractor.ExtractFrame(ctx, sourcePath, outputPath, 0, s.cfg.ThumbnailMaxDimension)
+	}
+	return err
+}
+
+// ThumbnailFilePath returns the absolute path of a file hash's thumbnail, or
+// an empty string when none has been generated
+func (s *ThumbnailService) ThumbnailFilePath(fileHash *models.FileHash) string {
+	if fileHash.ThumbnailPath == "" {
+		return ""
+	}
+	return filepath.Join(s.cfg.StoragePath, fileHash.ThumbnailPath)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // registers GIF decoding
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxThumbnailSourcePixels bounds the images decoded for thumbnails, since a
+// small file can declare huge dimensions and decoding allocates all of them
+const maxThumbnailSourcePixels = 40_000_000
+
+// imageThumbnailTypes are the image types decoded for thumbnails
+var imageThumbnailTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// generateImageThumbnail decodes the image stored under hash and writes it,
+// scaled to fit within maxDimension, as a JPEG at outputPath
+func (s *ThumbnailService) generateImageThumbnail(hash, outputPath string, maxDimension int) error {
+	if s.storage == nil {
+		return fmt.Errorf("no storage configured for image thumbnails")
+	}
+	blob, err := s.storage.Get(hash)
+	if err != nil {
+		return fmt.Errorf("failed to open image: %w", err)
+	}
+	defer blob.Close()
+
+	src := bufio.NewReader(blob)
+	header, err := peekImageConfig(src)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	if header.Width <= 0 || header.Height <= 0 || header.Width*header.Height > maxThumbnailSourcePixels {
+		return fmt.Errorf("image of %dx%d is too large to thumbnail", header.Width, header.Height)
+	}
+
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return fmt.Errorf("failed to decode image: %w", err)
+	}
+	thumbnail := scaleToFit(img, maxDimension)
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+	if err := jpeg.Encode(tmp, thumbnail, &jpeg.Options{Quality: 80}); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to move thumbnail into place: %w", err)
+	}
+	return nil
+}
+
+// peekImageConfig reads an image's dimensions from its header without
+// consuming it, so the image can then be decoded from the same reader
+func peekImageConfig(src *bufio.Reader) (image.Config, error) {
+	header, err := src.Peek(64 * 1024)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return image.Config{}, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(header))
+	return config, err
+}
+
+// scaleToFit shrinks img to fit within maxDimension on its longer side,
+// averaging the source pixels each thumbnail pixel covers. Transparent areas
+// are flattened onto white, as JPEG has no alpha. Smaller images keep their
+// size.
+func scaleToFit(img image.Image, maxDimension int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if maxDimension > 0 && (srcW > maxDimension || srcH > maxDimension) {
+		if srcW >= srcH {
+			dstW, dstH = maxDimension, srcH*maxDimension/srcW
+		} else {
+			dstW, dstH = srcW*maxDimension/srcH, maxDimension
+		}
+		dstW, dstH = max(dstW, 1), max(dstH, 1)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			// Premultiplied, so adding the missing alpha as white flattens
+			white := (0xffff*n - a)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + white) / n >> 8),
+				G: uint8((g + white) / n >> 8),
+				B: uint8((b + white) / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+var (
+	genericThumbnailOnce sync.Once
+	genericThumbnail     []byte
+)
+
+// GenericThumbnail returns the PNG icon shown for content without a
+// thumbnail of its own: a blank page with a folded corner
+func GenericThumbnail() []byte {
+	genericThumbnailOnce.Do(func() {
+		const size = 256
+		icon := image.NewRGBA(image.Rect(0, 0, size, size))
+		page := color.RGBA{R: 0xf4, G: 0xf5, B: 0xf7, A: 0xff}
+		edge := color.RGBA{R: 0xb0, G: 0xb6, B: 0xbf, A: 0xff}
+		fold := color.RGBA{R: 0xd6, G: 0xda, B: 0xe0, A: 0xff}
+
+		left, top, right, bottom, corner := 56, 24, 200, 232, 48
+		draw.Draw(icon, image.Rect(left, top, right, bottom), &image.Uniform{C: edge}, image.Point{}, draw.Src)
+		draw.Draw(icon, image.Rect(left+3, top+3, right-3, bottom-3), &image.Uniform{C: page}, image.Point{}, draw.Src)
+		// Cut the top right corner along the diagonal and fold it down
+		for y := top; y < top+corner; y++ {
+			for x := right - corner; x < right; x++ {
+				switch d := (x - (right - corner)) - (y - top); {
+				case d > 0:
+					icon.Set(x, y, color.Transparent)
+				case d > -3:
+					icon.Set(x, y, edge)
+				default:
+					icon.Set(x, y, fold)
+				}
+			}
+		}
+		for line := 0; line < 5; line++ {
+			y := top + corner + 32 + line*24
+			draw.Draw(icon, image.Rect(left+24, y, right-24, y+6), &image.Uniform{C: fold}, image.Point{}, draw.Src)
+		}
+
+		var buf bytes.Buffer
+		png.Encode(&buf, icon)
+		genericThumbnail = buf.Bytes()
+	})
+	return genericThumbnail
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// PurgeFileRecord permanently deletes a file that no longer holds a
+// reference on its content, i.e. one already in trash, with its versions.
+// Content no other file or version, live or trashed, points at has its
+// record deleted too; those records are returned so the caller can remove
+// the blobs once the transaction commits.
+func PurgeFileRecord(tx *gorm.DB, file *models.File) ([]*models.FileHash, error) {
+	hashIDs := []uuid.UUID{file.FileHashID}
+	var versionHashIDs []uuid.UUID
+	if err := tx.Model(&models.FileVersion{}).Where("file_id = ?", file.ID).
+		Distinct().Pluck("file_hash_id", &versionHashIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get file versions: %w", err)
+	}
+	hashIDs = append(hashIDs, versionHashIDs...)
+
+	if err := tx.Where("file_id = ?", file.ID).Delete(&models.FileVersion{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete file versions: %w", err)
+	}
+	if err := tx.Unscoped().Delete(&models.File{}, "id = ?", file.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete file: %w", err)
+	}
+	return deleteUnreferencedContent(tx, hashIDs)
+}
+
+// deleteUnreferencedContent deletes the records of the given content that no
+// file or file version points at any more, returning them
+func deleteUnreferencedContent(tx *gorm.DB, hashIDs []uuid.UUID) ([]*models.FileHash, error) {
+	var fileHashes []*models.FileHash
+	if err := tx.Where("id IN ?", hashIDs).
+		Where("NOT EXISTS (SELECT 1 FROM files WHERE files.file_hash_id = file_hashes.id)").
+		Where("NOT EXISTS (SELECT 1 FROM file_versions WHERE file_versions.file_hash_id = file_hashes.id)").
+		Find(&fileHashes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get file content: %w", err)
+	}
+	for _, fileHash := range fileHashes {
+		if err := tx.Delete(fileHash).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete file content record: %w", err)
+		}
+	}
+	return fileHashes, nil
+}
+
+// PruneFileVersions deletes a file's oldest versions beyond the newest keep,
+// with the records of content nothing else points at. Those records are
+// returned so the caller can remove the blobs once the transaction commits.
+func PruneFileVersions(tx *gorm.DB, fileID uuid.UUID, keep int) ([]*models.FileHash, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	var pruned []models.FileVersion
+	if err := tx.Where("file_id = ?", fileID).Order("version DESC").Offset(keep).Find(&pruned).Error; err != nil {
+		return nil, fmt.Errorf("failed to find old file versions: %w", err)
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(pruned))
+	hashIDs := make([]uuid.UUID, len(pruned))
+	for i, version := range pruned {
+		ids[i] = version.ID
+		hashIDs[i] = version.FileHashID
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&models.FileVersion{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to prune file versions: %w", err)
+	}
+	return deleteUnreferencedContent(tx, hashIDs)
+}
+
+// RemoveContent deletes a purged blob from storage and its thumbnail from
+// under storagePath
+func RemoveContent(storage Storage, storagePath string, fileHash *models.FileHash) {
+	if err := storage.Delete(fileHash.Hash); err != nil {
+		log.Printf("Failed to remove content %s: %v", fileHash.StoragePath, err)
+	}
+	if fileHash.ThumbnailPath == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(storagePath, fileHash.ThumbnailPath)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove content %s: %v", fileHash.ThumbnailPath, err)
+	}
+}
+
+// TrashPurger permanently deletes files that have been in trash longer than
+// the retention period
+type TrashPurger struct {
+	db      *gorm.DB
+	cfg     *config.Config
+	storage Storage
+}
+
+// NewTrashPurger creates a trash purger
+func NewTrashPurger(db *gorm.DB, cfg *config.Config) *TrashPurger {
+	return &TrashPurger{
+		db:      db,
+		cfg:     cfg,
+		storage: NewLocalStorage(cfg.StoragePath),
+	}
+}
+
+// SetStorage sets where purged blobs are deleted from
+func (p *TrashPurger) SetStorage(storage Storage) {
+	p.storage = storage
+}
+
+// RunJob purges expired trash in batches until none is left or the batch
+// budget is spent. Each batch commits on its own.
+func (p *TrashPurger) RunJob(ctx context.Context, limits JobLimits) error {
+	if p.cfg.TrashRetentionDays <= 0 {
+		return nil
+	}
+	batchSize := limits.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	cutoff := p.cutoff()
+
+	for batch := 0; limits.MaxBatches <= 0 || batch < limits.MaxBatches; batch++ {
+		if batch > 0 {
+			if err := limits.Pause(ctx); err != nil {
+				return err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var files []models.File
+		var orphaned []*models.FileHash
+		err := p.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("is_deleted = true AND deleted_at < ?", cutoff).
+				Order("deleted_at ASC").Limit(batchSize).Find(&files).Error; err != nil {
+				return fmt.Errorf("failed to load expired trash: %w", err)
+			}
+			for i := range files {
+				fileHashes, err := PurgeFileRecord(tx, &files[i])
+				if err != nil {
+					return err
+				}
+				orphaned = append(orphaned, fileHashes...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, fileHash := range orphaned {
+			RemoveContent(p.storage, p.cfg.StoragePath, fileHash)
+		}
+		if len(files) < batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Reclaimable reports what a full purge of expired trash would free, without
+// purging anything. Content counts only once no file outside expired trash,
+// or version of one, still points at it.
+func (p *TrashPurger) Reclaimable(ctx context.Context) (ReclaimableCategory, error) {
+	var category ReclaimableCategory
+	if p.cfg.TrashRetentionDays <= 0 {
+		return category, nil
+	}
+	cutoff := p.cutoff()
+	db := p.db.WithContext(ctx)
+
+	if err := db.Model(&models.File{}).Where("is_deleted = true AND deleted_at < ?", cutoff).
+		Count(&category.Files).Error; err != nil {
+		return category, fmt.Errorf("failed to count expired trash: %w", err)
+	}
+	if err := db.Table("file_hashes").
+		Select("COUNT(*) AS blobs, COALESCE(SUM(size), 0) AS bytes").
+		Where("EXISTS (SELECT 1 FROM files WHERE files.file_hash_id = file_hashes.id) OR "+
+			"EXISTS (SELECT 1 FROM file_versions WHERE file_versions.file_hash_id = file_hashes.id)").
+		Where("NOT EXISTS (SELECT 1 FROM files WHERE files.file_hash_id = file_hashes.id "+
+			"AND NOT (files.is_deleted = true AND files.deleted_at < ?))", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM file_versions JOIN files ON files.id = file_versions.file_id "+
+			"WHERE file_versions.file_hash_id = file_hashes.id AND NOT (files.is_deleted = true AND files.deleted_at < ?))", cutoff).
+		Scan(&category).Error; err != nil {
+		return category, fmt.Errorf("failed to sum expired trash content: %w", err)
+	}
+	return category, nil
+}
+
+// cutoff is when files still in trash must have been deleted to be purged
+func (p *TrashPurger) cutoff() time.Time {
+	return time.Now().AddDate(0, 0, -p.cfg.TrashRetentionDays)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"file-vault-system/backend/internal/config"
+	"file-vault-system/backend/internal/models"
+)
+
+// UploadSessionCleaner aborts expired upload sessions and removes the partial
+// content they left on disk
+type UploadSessionCleaner struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// NewUploadSessionCleaner creates an upload session cleaner
+func NewUploadSessionCleaner(db *gorm.DB, cfg *config.Config) *UploadSessionCleaner {
+	return &UploadSessionCleaner{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// RunJob aborts expired pending sessions in batches until none are left or
+// the batch budget is spent
+func (u *UploadSessionCleaner) RunJob(ctx context.Context, limits JobLimits) error {
+	batchSize := limits.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	for batch := 0; limits.MaxBatches <= 0 || batch < limits.MaxBatches; batch++ {
+		if batch > 0 {
+			if err := limits.Pause(ctx); err != nil {
+				return err
+			}
+		}
+
+		var sessions []models.UploadSession
+		if err := u.db.Where("status = ? AND expires_at < ?", models.UploadSessionPending, time.Now()).
+			Order("expires_at ASC").Limit(batchSize).Find(&sessions).Error; err != nil {
+			return fmt.Errorf("failed to load expired upload sessions: %w", err)
+		}
+		if len(sessions) == 0 {
+			return nil
+		}
+
+		for i := range sessions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			session := &sessions[i]
+			if err := u.db.Model(session).Update("status", models.UploadSessionAborted).Error; err != nil {
+				return fmt.Errorf("failed to abort upload session %s: %w", session.ID, err)
+			}
+			os.Remove(filepath.Join(u.cfg.StoragePath, session.TempPath))
+		}
+
+		if len(sessions) < batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
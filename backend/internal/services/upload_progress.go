@@ -0,0 +1,93 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// UploadProgressEvent reports how far a streaming upload has got
+type UploadProgressEvent struct {
+	UploadID      uuid.UUID `json:"upload_id"`
+	BytesReceived int64     `json:"bytes_received"`
+	TotalSize     int64     `json:"total_size"`
+	Percent       float64   `json:"percent"`
+	State         string    `json:"state"`
+}
+
+// Final reports whether no further events will follow for the upload
+func (e UploadProgressEvent) Final() bool {
+	switch e.State {
+	case "completed", "aborted", "expired":
+		return true
+	}
+	return false
+}
+
+// UploadProgressHub fans upload progress out to in-process subscribers keyed
+// by upload ID. Slow subscribers miss intermediate events rather than
+// holding up the upload.
+type UploadProgressHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan UploadProgressEvent]struct{}
+}
+
+// NewUploadProgressHub creates an empty progress hub
+func NewUploadProgressHub() *UploadProgressHub {
+	return &UploadProgressHub{
+		subscribers: make(map[uuid.UUID]map[chan UploadProgressEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel of progress events for the upload and a
+// function that must be called to release it
+func (h *UploadProgressHub) Subscribe(uploadID uuid.UUID) (<-chan UploadProgressEvent, func()) {
+	ch := make(chan UploadProgressEvent, 16)
+
+	h.mu.Lock()
+	if h.subscribers[uploadID] == nil {
+		h.subscribers[uploadID] = make(map[chan UploadProgressEvent]struct{})
+	}
+	h.subscribers[uploadID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.subscribers[uploadID], ch)
+			if len(h.subscribers[uploadID]) == 0 {
+				delete(h.subscribers, uploadID)
+			}
+		})
+	}
+}
+
+// Publish delivers an event to every subscriber of its upload without
+// blocking. It is safe to call on a nil hub.
+func (h *UploadProgressHub) Publish(event UploadProgressEvent) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.UploadID] {
+		select {
+		case ch <- event:
+		default:
+			// The subscriber is behind; make room so it still sees the latest state
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package testdb connects tests to the PostgreSQL database they run against
+package testdb
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"file-vault-system/backend/pkg/database"
+)
+
+// EnvVar names the test database, as a DSN or postgres:// URL. Tests that
+// need a database are skipped without it.
+const EnvVar = "TEST_DATABASE_URL"
+
+// migrationLock keeps test binaries of different packages, which run at the
+// same time, from applying migrations together
+const migrationLock = 7236
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// Open connects to the test database, applying any pending migrations the
+// first time. Tests share the database, so each creates the users and content
+// it works with rather than expecting empty tables.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv(EnvVar)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping database test", EnvVar)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get test database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+	})
+
+	migrateOnce.Do(func() {
+		migrateErr = db.Connection(func(conn *gorm.DB) error {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLock).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLock)
+			return database.RunMigrationsFrom(conn, migrationsDir())
+		})
+	})
+	if migrateErr != nil {
+		t.Fatalf("failed to migrate test database: %v", migrateErr)
+	}
+	return db
+}
+
+// migrationsDir finds the migrations from this file, as tests run in their
+// own package's directory
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
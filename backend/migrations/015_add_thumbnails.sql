@@ -0,0 +1,5 @@
+-- Migration: 015_add_thumbnails
+-- Description: Track generated thumbnails (e.g. video poster frames) per unique content
+-- Created: 2025-09-20
+
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS thumbnail_path TEXT;
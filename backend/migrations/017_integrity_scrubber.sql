@@ -0,0 +1,22 @@
+-- Migration: 017_integrity_scrubber
+-- Description: Track per-blob integrity verification and scrubber run history
+-- Created: 2025-09-20
+
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS last_verified_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS integrity_status VARCHAR(20);
+
+CREATE INDEX IF NOT EXISTS idx_file_hashes_last_verified_at ON file_hashes(last_verified_at NULLS FIRST);
+CREATE INDEX IF NOT EXISTS idx_file_hashes_integrity_status ON file_hashes(integrity_status);
+
+CREATE TABLE IF NOT EXISTS integrity_scrub_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    checked INTEGER DEFAULT 0,
+    corrupt INTEGER DEFAULT 0,
+    missing INTEGER DEFAULT 0,
+    repaired INTEGER DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_integrity_scrub_runs_started_at ON integrity_scrub_runs(started_at);
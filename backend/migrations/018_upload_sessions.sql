@@ -0,0 +1,27 @@
+-- Migration: 018_upload_sessions
+-- Description: Add resumable streaming upload sessions with persisted hash state
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    folder_id UUID REFERENCES folders(id) ON DELETE SET NULL,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100),
+    total_size BIGINT NOT NULL,
+    bytes_received BIGINT DEFAULT 0,
+    declared_hash VARCHAR(64),
+    hash_state BYTEA,
+    temp_path TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_file_id UUID REFERENCES files(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_user_id ON upload_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_status_expires_at ON upload_sessions(status, expires_at);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_deleted_at ON upload_sessions(deleted_at);
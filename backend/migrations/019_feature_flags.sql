@@ -0,0 +1,18 @@
+-- Migration: 019_feature_flags
+-- Description: Add runtime feature flags toggled by admins
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    description TEXT,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO feature_flags (key, enabled, description) VALUES
+    ('registration', TRUE, 'Allow new users to register'),
+    ('sharing', TRUE, 'Allow sharing files with other users'),
+    ('public_links', TRUE, 'Allow creating and accessing public share links')
+ON CONFLICT (key) DO NOTHING;
@@ -0,0 +1,26 @@
+-- Migration: 020_folder_shares
+-- Description: Share folders with other users, inherited by everything inside them
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS folder_shares (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    folder_id UUID NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    shared_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    shared_with UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission VARCHAR(20) DEFAULT 'view' CHECK (permission IN ('view', 'download', 'edit')),
+    message TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(folder_id, shared_with)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folder_shares_folder_id ON folder_shares(folder_id);
+CREATE INDEX IF NOT EXISTS idx_folder_shares_shared_with ON folder_shares(shared_with);
+CREATE INDEX IF NOT EXISTS idx_folder_shares_deleted_at ON folder_shares(deleted_at);
+
+-- The Folder model soft deletes through deleted_at, which the folders table never had
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_folders_deleted_at ON folders(deleted_at);
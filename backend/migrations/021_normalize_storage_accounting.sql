@@ -0,0 +1,50 @@
+-- Migration: 021_normalize_storage_accounting
+-- Description: Recalculate storage stats with consistent logical and physical semantics
+-- Created: 2025-09-20
+
+-- Reference counts and storage stats are maintained by the application;
+-- these triggers counted every insert and delete a second time
+DROP TRIGGER IF EXISTS update_file_hash_references ON files;
+DROP TRIGGER IF EXISTS update_user_storage ON files;
+DROP FUNCTION IF EXISTS update_file_hash_ref_count();
+DROP FUNCTION IF EXISTS update_user_storage_usage();
+
+-- Reference counts track live files only
+UPDATE file_hashes
+SET reference_count = (
+    SELECT COUNT(*)
+    FROM files
+    WHERE files.file_hash_id = file_hashes.id
+    AND files.is_deleted = false
+);
+
+-- storage_used is logical: every live file counts in full
+UPDATE users
+SET storage_used = (
+    SELECT COALESCE(SUM(files.size), 0)
+    FROM files
+    WHERE files.owner_id = users.id
+    AND files.is_deleted = false
+);
+
+-- actual_storage_bytes is physical: each distinct content a user holds counts once
+UPDATE users
+SET actual_storage_bytes = (
+    SELECT COALESCE(SUM(file_hashes.size), 0)
+    FROM file_hashes
+    WHERE file_hashes.id IN (
+        SELECT DISTINCT files.file_hash_id
+        FROM files
+        WHERE files.owner_id = users.id
+        AND files.is_deleted = false
+    )
+);
+
+-- saved_bytes is the difference between the two
+UPDATE users
+SET saved_bytes = storage_used - actual_storage_bytes;
+
+-- The lifetime upload total can never be below what is currently stored
+UPDATE users
+SET total_uploaded_bytes = storage_used
+WHERE total_uploaded_bytes < storage_used;
@@ -0,0 +1,5 @@
+-- Migration: 022_file_access_expiry
+-- Description: Let owners set a date after which a file's content is no longer served
+-- Created: 2025-09-20
+
+ALTER TABLE files ADD COLUMN IF NOT EXISTS access_expires_at TIMESTAMP WITH TIME ZONE;
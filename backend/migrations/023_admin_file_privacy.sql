@@ -0,0 +1,6 @@
+-- Migration: 023_admin_file_privacy
+-- Description: Let users hide folders and files from the admin file listing
+-- Created: 2025-09-20
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS hidden_from_admin BOOLEAN DEFAULT FALSE;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS hidden_from_admin BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,39 @@
+-- Migration: 024_file_acl
+-- Description: Per-file access control lists, seeded from existing user file shares
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS file_acl_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    grantee_type VARCHAR(20) NOT NULL CHECK (grantee_type IN ('user', 'authenticated')),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    permission VARCHAR(20) DEFAULT 'view' CHECK (permission IN ('view', 'download', 'edit')),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    granted_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK ((grantee_type = 'user') = (user_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_acl_entries_file_id ON file_acl_entries(file_id);
+CREATE INDEX IF NOT EXISTS idx_file_acl_entries_user_id ON file_acl_entries(user_id);
+CREATE INDEX IF NOT EXISTS idx_file_acl_entries_deleted_at ON file_acl_entries(deleted_at);
+
+-- One live entry per grantee per file
+CREATE UNIQUE INDEX IF NOT EXISTS idx_file_acl_entries_user_grant
+    ON file_acl_entries(file_id, user_id) WHERE grantee_type = 'user' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_file_acl_entries_authenticated_grant
+    ON file_acl_entries(file_id) WHERE grantee_type = 'authenticated' AND deleted_at IS NULL;
+
+-- Carry over active user shares, keeping the strongest grant when a file was
+-- shared with the same user by more than one person
+INSERT INTO file_acl_entries (file_id, grantee_type, user_id, permission, expires_at, granted_by, created_at)
+SELECT DISTINCT ON (file_id, shared_with)
+    file_id, 'user', shared_with, permission, expires_at, shared_by, created_at
+FROM file_shares
+WHERE is_active = true AND deleted_at IS NULL
+ORDER BY file_id, shared_with,
+    CASE permission WHEN 'download' THEN 2 ELSE 1 END DESC,
+    expires_at DESC NULLS FIRST
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- Migration: 025_quota_grace
+-- Description: Track when users last went over quota on their grace allowance
+-- Created: 2025-09-20
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quota_grace_used_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quota_grace_count INTEGER DEFAULT 0;
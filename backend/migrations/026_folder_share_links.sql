@@ -0,0 +1,13 @@
+-- Migration: 026_folder_share_links
+-- Description: Allow share links to point at a folder, optionally served as an HTML index
+-- Created: 2025-09-20
+
+ALTER TABLE share_links ALTER COLUMN file_id DROP NOT NULL;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE CASCADE;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS html_index BOOLEAN DEFAULT false;
+
+ALTER TABLE share_links DROP CONSTRAINT IF EXISTS share_links_target_check;
+ALTER TABLE share_links ADD CONSTRAINT share_links_target_check
+    CHECK ((file_id IS NOT NULL AND folder_id IS NULL) OR (file_id IS NULL AND folder_id IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_share_links_folder_id ON share_links(folder_id);
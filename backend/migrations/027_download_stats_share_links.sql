@@ -0,0 +1,7 @@
+-- Migration: 027_download_stats_share_links
+-- Description: Link download stats to share links so share downloads can be counted per folder
+-- Created: 2025-09-20
+
+ALTER TABLE download_stats ADD COLUMN IF NOT EXISTS share_link_id UUID REFERENCES share_links(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_download_stats_share_link_id ON download_stats(share_link_id);
@@ -0,0 +1,5 @@
+-- Migration: 028_file_raw_filename
+-- Description: Keep the filename as uploaded when it is normalized
+-- Created: 2025-09-20
+
+ALTER TABLE files ADD COLUMN IF NOT EXISTS raw_filename BYTEA;
@@ -0,0 +1,22 @@
+-- Migration: 029_saved_searches
+-- Description: Named file searches users can rerun as smart folders
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query VARCHAR(255),
+    mime_category VARCHAR(20),
+    tags TEXT[],
+    min_size BIGINT,
+    max_size BIGINT,
+    created_after TIMESTAMP WITH TIME ZONE,
+    created_before TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON saved_searches(user_id);
+CREATE INDEX IF NOT EXISTS idx_saved_searches_deleted_at ON saved_searches(deleted_at);
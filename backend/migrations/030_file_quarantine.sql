@@ -0,0 +1,8 @@
+-- Migration: 030_file_quarantine
+-- Description: Hold uploads flagged by the malware scanner for admin review
+-- Created: 2025-09-20
+
+ALTER TABLE files ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'active';
+ALTER TABLE files ADD COLUMN IF NOT EXISTS quarantine_reason VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_files_quarantined ON files(created_at) WHERE status = 'quarantined';
@@ -0,0 +1,6 @@
+-- Migration: 031_chunk_checksums
+-- Description: Per-chunk checksums so served byte ranges can be verified
+-- Created: 2025-09-20
+
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS chunk_size BIGINT DEFAULT 0;
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS chunk_checksums BYTEA;
@@ -0,0 +1,6 @@
+-- Migration: 032_download_stats_file_name
+-- Description: Keep the file name with each download for users' download history
+-- Created: 2025-09-20
+
+ALTER TABLE download_stats ADD COLUMN IF NOT EXISTS file_name VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_download_stats_downloaded_by_at ON download_stats(downloaded_by, downloaded_at DESC);
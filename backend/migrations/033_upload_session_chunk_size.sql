@@ -0,0 +1,5 @@
+-- Migration: 033_upload_session_chunk_size
+-- Description: Record the chunk size of upload sessions so chunks can be sent by index
+-- Created: 2025-09-20
+
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS chunk_size BIGINT DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Migration: 034_rate_limit_lookup_index
+-- Description: Index the (user, endpoint) lookup made by the rate limiter on every request
+-- Created: 2025-09-20
+
+CREATE INDEX IF NOT EXISTS idx_api_rate_limits_user_endpoint ON api_rate_limits(user_id, endpoint);
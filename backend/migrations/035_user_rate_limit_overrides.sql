@@ -0,0 +1,6 @@
+-- Migration: 035_user_rate_limit_overrides
+-- Description: Let admins raise or lower the rate limit of individual users
+-- Created: 2025-09-20
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rate_limit_requests INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rate_limit_window INTEGER;
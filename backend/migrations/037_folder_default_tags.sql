@@ -0,0 +1,6 @@
+-- Migration: 037_folder_default_tags
+-- Description: Let folders tag the files uploaded into them, optionally with their parents' tags too
+-- Created: 2025-09-20
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS default_tags TEXT[];
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS inherit_tags BOOLEAN DEFAULT false;
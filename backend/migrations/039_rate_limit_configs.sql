@@ -0,0 +1,19 @@
+-- Migration: 039_rate_limit_configs
+-- Description: Configure rate limits per endpoint prefix instead of one limit for every endpoint
+-- Created: 2025-09-20
+
+CREATE TABLE IF NOT EXISTS rate_limit_configs (
+    endpoint_pattern VARCHAR(255) PRIMARY KEY,
+    max_requests INTEGER NOT NULL CHECK (max_requests > 0),
+    window_seconds INTEGER NOT NULL CHECK (window_seconds > 0),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Whole-file uploads get 10 a minute; the longer pattern keeps upload session
+-- chunks and status polls under a ceiling that doesn't stall chunked uploads
+INSERT INTO rate_limit_configs (endpoint_pattern, max_requests, window_seconds) VALUES
+    ('/api/v1/files/upload', 10, 60),
+    ('/api/v1/files/upload/', 600, 60)
+ON CONFLICT (endpoint_pattern) DO NOTHING;
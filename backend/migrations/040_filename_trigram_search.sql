@@ -0,0 +1,13 @@
+-- Migration: 040_filename_trigram_search
+-- Description: Index file and folder names by trigram for fast substring and fuzzy search
+-- Created: 2025-09-20
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Serve ILIKE '%term%' as well as the similarity operators used by fuzzy search
+CREATE INDEX IF NOT EXISTS idx_files_original_filename_trgm ON files USING gin (original_filename gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_folders_name_trgm ON folders USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_folders_path_trgm ON folders USING gin (path gin_trgm_ops);
+
+-- Saved searches can keep the fuzzy mode
+ALTER TABLE saved_searches ADD COLUMN IF NOT EXISTS fuzzy BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- Migration: 041_share_link_burn_after_reading
+-- Description: Let share links revoke themselves after their first download
+-- Created: 2025-09-20
+
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS burn_after_reading BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS burn_deletes_file BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+-- Migration: 042_sync_change_indexes
+-- Description: Index files and folders by when they last changed for the sync change listing
+-- Created: 2025-09-20
+
+CREATE INDEX IF NOT EXISTS idx_files_owner_changed_at ON files (owner_id, GREATEST(updated_at, deleted_at));
+CREATE INDEX IF NOT EXISTS idx_folders_owner_changed_at ON folders (owner_id, GREATEST(updated_at, deleted_at));
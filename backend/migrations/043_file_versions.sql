@@ -0,0 +1,22 @@
+-- Migration: 043_file_versions
+-- Description: Keep earlier contents of files re-uploaded under the same name as versions
+-- Created: 2025-09-20
+
+ALTER TABLE files ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS file_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    file_hash_id UUID NOT NULL REFERENCES file_hashes(id),
+    size BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    restored_from INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_file_versions_file_version UNIQUE (file_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_versions_file_hash_id ON file_versions(file_hash_id);
+
+-- Re-uploads look up the owner's file by folder and name
+CREATE INDEX IF NOT EXISTS idx_files_owner_folder_name ON files(owner_id, folder_id, original_filename) WHERE is_deleted = false;
@@ -0,0 +1,5 @@
+-- Migration: 044_thumbnail_failed
+-- Description: Record content whose thumbnail failed to generate so it gets the generic icon
+-- Created: 2025-09-20
+
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS thumbnail_failed BOOLEAN NOT NULL DEFAULT false;
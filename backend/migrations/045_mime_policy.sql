@@ -0,0 +1,13 @@
+-- Migration: 045_mime_policy
+-- Description: Store the MIME type allowlist and blocklist admins manage at runtime
+-- Created: 2025-09-20
+
+-- A single row; until an admin saves a policy, ALLOWED_MIME_TYPES applies
+CREATE TABLE IF NOT EXISTS mime_policies (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    allowed_types TEXT[] NOT NULL DEFAULT '{}',
+    blocked_types TEXT[] NOT NULL DEFAULT '{}',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+-- Migration: 046_rate_limit_patterns
+-- Description: Count rate limits per endpoint pattern, limit unauthenticated requests by client address and seed auth limits
+-- Created: 2025-09-20
+
+-- Unauthenticated requests are counted by client address instead of user
+ALTER TABLE api_rate_limits ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE api_rate_limits ADD COLUMN IF NOT EXISTS client_ip VARCHAR(45);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_rate_limits_client_endpoint
+    ON api_rate_limits(client_ip, endpoint) WHERE client_ip IS NOT NULL;
+
+-- Counts were kept per raw path; they restart per matching pattern
+DELETE FROM api_rate_limits;
+
+-- Sign-in, registration and verification get 5 a minute per client; the
+-- session check and token refresh a client makes routinely get more room
+INSERT INTO rate_limit_configs (endpoint_pattern, max_requests, window_seconds) VALUES
+    ('/api/v1/auth', 5, 60),
+    ('/api/v1/auth/me', 120, 60),
+    ('/api/v1/auth/refresh', 30, 60)
+ON CONFLICT (endpoint_pattern) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Migration: 047_content_quarantine
+-- Description: Quarantine flagged content as a whole rather than the file it was uploaded as
+-- Created: 2025-09-20
+
+ALTER TABLE file_hashes ADD COLUMN IF NOT EXISTS quarantine_reason VARCHAR(255);
+
+-- Content held through any file is held for all of them
+UPDATE file_hashes fh SET quarantine_reason = f.quarantine_reason
+FROM files f
+WHERE f.file_hash_id = fh.id AND f.status = 'quarantined' AND fh.quarantine_reason IS NULL;
+
+UPDATE files f SET status = 'quarantined', quarantine_reason = fh.quarantine_reason
+FROM file_hashes fh
+WHERE f.file_hash_id = fh.id AND fh.quarantine_reason IS NOT NULL AND f.status <> 'quarantined';
@@ -0,0 +1,9 @@
+-- Migration: 048_transfer_folders
+-- Description: Mark the folder a user's content was transferred into, so later transfers reuse it
+-- Created: 2025-09-20
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS transferred_from UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_transferred_from
+    ON folders(owner_id, transferred_from)
+    WHERE transferred_from IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- Migration: 049_drop_url_uploads_flag
+-- Description: Remove the url_uploads feature flag, there is no URL upload to gate
+-- Created: 2025-09-20
+
+DELETE FROM feature_flags WHERE key = 'url_uploads';
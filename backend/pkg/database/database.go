@@ -3,9 +3,12 @@ package database
 import (
 	"fmt"
 	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"file-vault-system/backend/internal/config"
 
@@ -16,15 +19,25 @@ import (
 
 // Initialize creates and configures the database connection
 func Initialize(cfg *config.Config) (*gorm.DB, error) {
-	// Configure GORM logger
+	// Configure GORM logger; slow queries are logged at warn level so they
+	// show up outside development too
 	logLevel := logger.Silent
 	if cfg.IsDevelopment() {
 		logLevel = logger.Info
+	} else if cfg.SlowQueryThreshold > 0 {
+		logLevel = logger.Warn
 	}
 
+	gormLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:             time.Duration(cfg.SlowQueryThreshold) * time.Millisecond,
+		LogLevel:                  logLevel,
+		IgnoreRecordNotFoundError: true,
+		Colorful:                  cfg.IsDevelopment(),
+	})
+
 	// Connect to database
 	db, err := gorm.Open(postgres.Open(cfg.GetDatabaseDSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger: gormLogger,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -45,14 +58,16 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 
 // RunMigrations executes SQL migration files in order
 func RunMigrations(db *gorm.DB, cfg *config.Config) error {
+	return RunMigrationsFrom(db, filepath.Join("./migrations"))
+}
+
+// RunMigrationsFrom executes the SQL migration files in migrationsDir in order
+func RunMigrationsFrom(db *gorm.DB, migrationsDir string) error {
 	// Create migrations tracking table first
 	if err := CreateMigrationTable(db); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	// Get the migrations directory path
-	migrationsDir := filepath.Join("./migrations")
-
 	// Read migration files
 	files, err := ioutil.ReadDir(migrationsDir)
 	if err != nil {
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// DefaultHashBufferSize is the buffer used when streaming content through a hasher
+const DefaultHashBufferSize = 1 << 20 // 1MB
+
+// ProgressHasher computes a SHA-256 digest incrementally, reporting the number
+// of bytes hashed so far. Its state can be saved and restored so hashing can
+// resume across requests.
+type ProgressHasher struct {
+	hash       hash.Hash
+	written    int64
+	onProgress func(written int64)
+}
+
+// NewProgressHasher creates a hasher; onProgress may be nil
+func NewProgressHasher(onProgress func(written int64)) *ProgressHasher {
+	return &ProgressHasher{
+		hash:       sha256.New(),
+		onProgress: onProgress,
+	}
+}
+
+// RestoreProgressHasher recreates a hasher from a state saved with State
+// after written bytes had been hashed
+func RestoreProgressHasher(state []byte, written int64, onProgress func(written int64)) (*ProgressHasher, error) {
+	h := NewProgressHasher(onProgress)
+	if err := h.hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, fmt.Errorf("failed to restore hash state: %w", err)
+	}
+	h.written = written
+	return h, nil
+}
+
+// Write adds p to the running hash
+func (h *ProgressHasher) Write(p []byte) (int, error) {
+	n, err := h.hash.Write(p)
+	h.written += int64(n)
+	if h.onProgress != nil {
+		h.onProgress(h.written)
+	}
+	return n, err
+}
+
+// Written returns the number of bytes hashed
+func (h *ProgressHasher) Written() int64 {
+	return h.written
+}
+
+// Sum returns the hex digest of the bytes hashed so far without affecting
+// the running state
+func (h *ProgressHasher) Sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
+
+// State serializes the running hash state
+func (h *ProgressHasher) State() ([]byte, error) {
+	state, err := h.hash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save hash state: %w", err)
+	}
+	return state, nil
+}
+
+// CopyWithHash copies src to dst through a fixed-size buffer, feeding every
+// byte written to hasher. Memory use is bounded by bufSize regardless of the
+// content length.
+func CopyWithHash(dst io.Writer, src io.Reader, hasher *ProgressHasher, bufSize int) (int64, error) {
+	if bufSize <= 0 {
+		bufSize = DefaultHashBufferSize
+	}
+	buf := make([]byte, bufSize)
+	return io.CopyBuffer(io.MultiWriter(dst, hasher), src, buf)
+}
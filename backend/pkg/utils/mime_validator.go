@@ -1,20 +1,92 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"mime"
 	"net/http"
 	"path/filepath"
 	"strings"
 )
 
+// MimeOverride forces the MIME type of uploads matching a file extension or
+// starting with a magic byte prefix, for formats detection gets wrong
+type MimeOverride struct {
+	Extension string // lower case, with the leading dot
+	Magic     []byte
+	MimeType  string
+}
+
+// ParseMimeOverrides reads overrides written as ".ext=type" or
+// "hex:0a1b2c=type"
+func ParseMimeOverrides(entries []string) ([]MimeOverride, error) {
+	var overrides []MimeOverride
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, mimeType, ok := strings.Cut(entry, "=")
+		key, mimeType = strings.TrimSpace(key), strings.TrimSpace(mimeType)
+		if !ok || key == "" || !strings.Contains(mimeType, "/") {
+			return nil, fmt.Errorf("invalid MIME type override %q, expected .ext=type or hex:prefix=type", entry)
+		}
+
+		override := MimeOverride{MimeType: strings.ToLower(mimeType)}
+		switch {
+		case strings.HasPrefix(key, "hex:"):
+			magic, err := hex.DecodeString(strings.TrimPrefix(key, "hex:"))
+			if err != nil || len(magic) == 0 {
+				return nil, fmt.Errorf("invalid magic bytes in MIME type override %q", entry)
+			}
+			override.Magic = magic
+		case strings.HasPrefix(key, ".") && len(key) > 1:
+			override.Extension = strings.ToLower(key)
+		default:
+			return nil, fmt.Errorf("invalid MIME type override %q, expected .ext=type or hex:prefix=type", entry)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
 // MimeTypeValidator provides MIME type validation functionality
-type MimeTypeValidator struct{}
+type MimeTypeValidator struct {
+	overrides []MimeOverride
+}
 
 // NewMimeTypeValidator creates a new MIME type validator
 func NewMimeTypeValidator() *MimeTypeValidator {
 	return &MimeTypeValidator{}
 }
 
+// NewMimeTypeValidatorWithOverrides creates a validator that applies
+// operator-configured type overrides after detection
+func NewMimeTypeValidatorWithOverrides(overrides []MimeOverride) *MimeTypeValidator {
+	return &MimeTypeValidator{overrides: overrides}
+}
+
+// overrideMimeType returns the forced type for content, or "" when no
+// override matches. Magic byte prefixes are more specific than extensions
+// and take precedence.
+func (v *MimeTypeValidator) overrideMimeType(content []byte, filename string) string {
+	for _, override := range v.overrides {
+		if override.Magic != nil && bytes.HasPrefix(content, override.Magic) {
+			return override.MimeType
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, override := range v.overrides {
+		if override.Extension != "" && override.Extension == ext {
+			return override.MimeType
+		}
+	}
+	return ""
+}
+
 // DetectMimeType detects the actual MIME type of file content
 func (v *MimeTypeValidator) DetectMimeType(content []byte) string {
 	return http.DetectContentType(content)
@@ -30,8 +102,14 @@ func (v *MimeTypeValidator) GetMimeTypeFromExtension(filename string) string {
 	return mimeType
 }
 
-// ValidateMimeType validates that the actual content matches the declared MIME type
+// ValidateMimeType validates that the actual content matches the declared MIME
+// type. A matching override replaces the detected type and is trusted as
+// valid; the overridden type is still subject to the allowed type list.
 func (v *MimeTypeValidator) ValidateMimeType(content []byte, declaredMimeType string, filename string) (bool, string, string) {
+	if overridden := v.overrideMimeType(content, filename); overridden != "" {
+		return true, overridden, ""
+	}
+
 	// Detect actual MIME type from content
 	actualMimeType := v.DetectMimeType(content)
 
@@ -142,21 +220,84 @@ func (v *MimeTypeValidator) isMimeTypeCompatible(mimeType1, mimeType2 string, gr
 	return false
 }
 
+// executableSignatures maps the magic bytes of executable formats to the
+// format name reported by DetectExecutable. Where the magic bytes alone are
+// shared with harmless content, match confirms the rest of the header.
+var executableSignatures = []struct {
+	magic  []byte
+	format string
+	match  func(content []byte) bool
+}{
+	{[]byte{0x7F, 'E', 'L', 'F'}, "elf", nil},
+	{[]byte{'M', 'Z'}, "pe", isPortableExecutable},
+	{[]byte{0xFE, 0xED, 0xFA, 0xCE}, "mach-o", nil}, // 32-bit big endian
+	{[]byte{0xFE, 0xED, 0xFA, 0xCF}, "mach-o", nil}, // 64-bit big endian
+	{[]byte{0xCE, 0xFA, 0xED, 0xFE}, "mach-o", nil}, // 32-bit little endian
+	{[]byte{0xCF, 0xFA, 0xED, 0xFE}, "mach-o", nil}, // 64-bit little endian
+	{[]byte{0xCA, 0xFE, 0xBA, 0xBE}, "mach-o", isMachOUniversal},
+	{[]byte{'#', '!', '/'}, "script", nil},
+}
+
+// isPortableExecutable reports whether content starting with "MZ" carries a
+// PE header: the DOS header's e_lfanew, at 0x3C, points at "PE\0\0". Plain
+// text can start with "MZ"; a PE header past the bytes given isn't found.
+func isPortableExecutable(content []byte) bool {
+	if len(content) < 0x40 {
+		return false
+	}
+	offset := binary.LittleEndian.Uint32(content[0x3C:])
+	if offset > uint32(len(content)-4) {
+		return false
+	}
+	return bytes.Equal(content[offset:offset+4], []byte{'P', 'E', 0, 0})
+}
+
+// isMachOUniversal tells a Mach-O universal binary from a Java class file,
+// which share 0xCAFEBABE. A universal binary follows it with a small count of
+// architectures; a class file follows it with its version, 45 or more.
+func isMachOUniversal(content []byte) bool {
+	if len(content) < 8 {
+		return false
+	}
+	architectures := binary.BigEndian.Uint32(content[4:])
+	return architectures > 0 && architectures < 20
+}
+
+// DetectExecutable reports the executable format content starts with, or an
+// empty string if it doesn't look like an executable or script. Only the
+// header is checked, so a renamed file is still caught.
+func (v *MimeTypeValidator) DetectExecutable(content []byte) string {
+	for _, signature := range executableSignatures {
+		if !bytes.HasPrefix(content, signature.magic) {
+			continue
+		}
+		if signature.match == nil || signature.match(content) {
+			return signature.format
+		}
+	}
+	return ""
+}
+
 // IsAllowedMimeType checks if a MIME type is in the allowed list
 func (v *MimeTypeValidator) IsAllowedMimeType(mimeType string, allowedTypes []string) bool {
 	if len(allowedTypes) == 0 {
 		return true // No restrictions
 	}
+	return MatchesMimeType(mimeType, allowedTypes)
+}
 
+// MatchesMimeType reports whether a MIME type matches any of the patterns,
+// which are exact types or wildcards like image/*
+func MatchesMimeType(mimeType string, patterns []string) bool {
 	mimeType = strings.Split(mimeType, ";")[0] // Remove charset, etc.
 
-	for _, allowed := range allowedTypes {
-		if allowed == mimeType {
+	for _, pattern := range patterns {
+		if pattern == mimeType {
 			return true
 		}
 		// Support wildcard matching (e.g., image/*)
-		if strings.HasSuffix(allowed, "/*") {
-			prefix := strings.TrimSuffix(allowed, "*")
+		if strings.HasSuffix(pattern, "/*") {
+			prefix := strings.TrimSuffix(pattern, "*")
 			if strings.HasPrefix(mimeType, prefix) {
 				return true
 			}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// peHeader returns a minimal DOS stub whose e_lfanew points at a PE header
+func peHeader() []byte {
+	content := make([]byte, 0x90)
+	copy(content, "MZ")
+	binary.LittleEndian.PutUint32(content[0x3C:], 0x80)
+	copy(content[0x80:], "PE\x00\x00")
+	return content
+}
+
+func TestDetectExecutable(t *testing.T) {
+	javaClass := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0x00, 0x00, 0x00, 0x37} // Java 11
+	universal := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0x00, 0x00, 0x00, 0x02} // two architectures
+
+	mzText := make([]byte, 0x90)
+	copy(mzText, "MZ is the postal code prefix used in this report, padded out.")
+
+	truncatedPE := peHeader()
+	binary.LittleEndian.PutUint32(truncatedPE[0x3C:], 0x1000)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"ELF", []byte{0x7F, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, "elf"},
+		{"PE", peHeader(), "pe"},
+		{"Mach-O 32-bit", []byte{0xFE, 0xED, 0xFA, 0xCE, 0x00, 0x00, 0x00, 0x07}, "mach-o"},
+		{"Mach-O 64-bit", []byte{0xFE, 0xED, 0xFA, 0xCF, 0x01, 0x00, 0x00, 0x07}, "mach-o"},
+		{"Mach-O 32-bit little endian", []byte{0xCE, 0xFA, 0xED, 0xFE, 0x07, 0x00, 0x00, 0x00}, "mach-o"},
+		{"Mach-O 64-bit little endian", []byte{0xCF, 0xFA, 0xED, 0xFE, 0x07, 0x00, 0x00, 0x01}, "mach-o"},
+		{"Mach-O universal", universal, "mach-o"},
+		{"shell script", []byte("#!/bin/sh\necho hello\n"), "script"},
+		{"env script", []byte("#!/usr/bin/env python3\n"), "script"},
+
+		{"Java class", javaClass, ""},
+		{"MZ without PE header", mzText, ""},
+		{"short MZ", []byte("MZ"), ""},
+		{"PE header out of range", truncatedPE, ""},
+		{"hash bang text", []byte("#!important notes\n"), ""},
+		{"PNG", []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}, ""},
+		{"empty", nil, ""},
+	}
+
+	validator := NewMimeTypeValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validator.DetectExecutable(tt.content); got != tt.want {
+				t.Errorf("DetectExecutable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMimeOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []MimeOverride
+		wantErr bool
+	}{
+		{"extension", []string{".stl=model/stl"}, []MimeOverride{{Extension: ".stl", MimeType: "model/stl"}}, false},
+		{"extension folded to lower case", []string{" .STL = Model/STL "}, []MimeOverride{{Extension: ".stl", MimeType: "model/stl"}}, false},
+		{"magic bytes", []string{"hex:0A1b=application/x-custom"}, []MimeOverride{{Magic: []byte{0x0A, 0x1B}, MimeType: "application/x-custom"}}, false},
+		{"several, blanks skipped", []string{".stl=model/stl", "", "  ", "hex:ff=application/x-ff"},
+			[]MimeOverride{{Extension: ".stl", MimeType: "model/stl"}, {Magic: []byte{0xFF}, MimeType: "application/x-ff"}}, false},
+		{"none", nil, nil, false},
+
+		{"extension without dot", []string{"stl=model/stl"}, nil, true},
+		{"dot alone", []string{".=model/stl"}, nil, true},
+		{"no type", []string{".stl"}, nil, true},
+		{"empty type", []string{".stl="}, nil, true},
+		{"type without subtype", []string{".stl=model"}, nil, true},
+		{"empty key", []string{"=model/stl"}, nil, true},
+		{"invalid hex", []string{"hex:zz=application/x-custom"}, nil, true},
+		{"empty hex", []string{"hex:=application/x-custom"}, nil, true},
+		{"one bad entry among good", []string{".stl=model/stl", "bad"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMimeOverrides(tt.entries)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMimeOverrides() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMimeOverrides() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseMimeOverrides() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Extension != tt.want[i].Extension || !bytes.Equal(got[i].Magic, tt.want[i].Magic) || got[i].MimeType != tt.want[i].MimeType {
+					t.Errorf("override %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMimeTypeOverrides(t *testing.T) {
+	overrides, err := ParseMimeOverrides([]string{".stl=model/stl", "hex:4B3344=application/x-k3d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASCII STL is detected as text, which matches no extension it comes with
+	asciiSTL := []byte("solid cube\n  facet normal 0 0 1\n  endfacet\nendsolid cube\n")
+	magic := append([]byte("K3D"), 0x00, 0x01, 0x02)
+
+	tests := []struct {
+		name      string
+		overrides []MimeOverride
+		content   []byte
+		filename  string
+		wantValid bool
+		wantType  string
+	}{
+		{"overridden extension", overrides, asciiSTL, "cube.stl", true, "model/stl"},
+		{"overridden extension in upper case", overrides, asciiSTL, "CUBE.STL", true, "model/stl"},
+		{"overridden magic bytes", overrides, magic, "scene.bin", true, "application/x-k3d"},
+		{"magic bytes win over extension", overrides, magic, "scene.stl", true, "application/x-k3d"},
+
+		{"extension not overridden", overrides, asciiSTL, "cube.png", false, "text/plain"},
+		{"without overrides", nil, asciiSTL, "cube.stl", false, "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewMimeTypeValidatorWithOverrides(tt.overrides)
+			valid, detected, _ := validator.ValidateMimeType(tt.content, "application/octet-stream", tt.filename)
+			if valid != tt.wantValid || detected != tt.wantType {
+				t.Errorf("ValidateMimeType() = %v, %q; want %v, %q", valid, detected, tt.wantValid, tt.wantType)
+			}
+		})
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 )
 
 // HashPassword hashes a password using bcrypt
@@ -51,6 +53,35 @@ func GenerateShareToken() (string, error) {
 	return GenerateRandomToken(32) // 64 character hex string
 }
 
+// GenerateAPIKey generates a new API key and the prefix used to identify it
+func GenerateAPIKey() (string, string, error) {
+	token, err := GenerateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	key := "fv_" + token
+	return key, key[:10], nil
+}
+
+// HashAPIKey returns the SHA-256 digest stored for an API key
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateRefreshToken generates a long-lived token exchanged for new access
+// tokens
+func GenerateRefreshToken() (string, error) {
+	return GenerateRandomToken(32)
+}
+
+// HashToken returns the SHA-256 digest stored for a bearer token, such as a
+// refresh or email verification token
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
 // CalculateFileHash calculates SHA-256 hash of a file
 func CalculateFileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -156,6 +187,25 @@ func SanitizeFilename(filename string) string {
 	return sanitized
 }
 
+// NormalizeFilename puts a filename into Unicode NFC form, replaces invalid
+// UTF-8 and strips control characters, so the same name always compares and
+// searches the same way however the client encoded it
+func NormalizeFilename(filename string) string {
+	normalized := norm.NFC.String(strings.ToValidUTF8(filename, "\uFFFD"))
+	normalized = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, normalized)
+
+	normalized = strings.TrimSpace(normalized)
+	if normalized == "" {
+		normalized = "untitled"
+	}
+	return normalized
+}
+
 // GenerateUniqueFilename generates a unique filename to prevent conflicts
 func GenerateUniqueFilename(originalName, storageDir string) (string, error) {
 	// Sanitize the original filename
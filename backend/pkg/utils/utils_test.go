@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestNormalizeFilename(t *testing.T) {
+	const precomposed = "caf\u00e9.txt" // é as one code point (NFC)
+	const decomposed = "cafe\u0301.txt" // e followed by a combining acute accent (NFD)
+
+	if precomposed == decomposed {
+		t.Fatal("test names must differ before normalization")
+	}
+	if got := NormalizeFilename(decomposed); got != precomposed {
+		t.Errorf("NormalizeFilename(NFD) = %q, want %q", got, precomposed)
+	}
+	if got := NormalizeFilename(precomposed); got != precomposed {
+		t.Errorf("NormalizeFilename(NFC) = %q, want it unchanged", got)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"decomposed Hangul", "\u1112\u1161\u11ab.doc", "\ud55c.doc"},
+		{"control characters", "re\x00port\x1b\t.pdf", "report.pdf"},
+		{"invalid UTF-8", "bad\xffname.txt", "bad\ufffdname.txt"},
+		{"surrounding space", "  notes.md  ", "notes.md"},
+		{"nothing left", "\x01\x02", "untitled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeFilename(tt.filename); got != tt.want {
+				t.Errorf("NormalizeFilename(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
@@ -71,14 +71,16 @@ export const PublicSharePage: React.FC = () => {
         const data = await response.json();
         setSharedFile(data);
         setPasswordRequired(false);
-      } else if (response.status === 404) {
+      } else if (response.status === 401) {
         const errorData = await response.json();
-        if (errorData.error.includes('password required')) {
-          setPasswordRequired(true);
-          setError('This shared file is password protected.');
-        } else {
-          setError('This share link is invalid, expired, or has been revoked.');
-        }
+        setPasswordRequired(true);
+        setError(errorData.error.includes('invalid password')
+          ? 'Incorrect password, please try again.'
+          : 'This shared file is password protected.');
+      } else if (response.status === 404 || response.status === 410) {
+        setError('This share link is invalid, expired, or has been revoked.');
+      } else if (response.status === 403) {
+        setError('This share link has reached its download limit.');
       } else {
         const errorData = await response.json();
         setError(errorData.error || 'Failed to load shared file.');